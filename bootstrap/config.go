@@ -19,5 +19,9 @@ func InitConfig(path string) *configs.Config {
 	if err != nil {
 		panic(fmt.Errorf("解析配置文件失败: %w", err))
 	}
+	err = configs.ResolveSecrets(&conf)
+	if err != nil {
+		panic(fmt.Errorf("解析配置密钥失败: %w", err))
+	}
 	return &conf
 }
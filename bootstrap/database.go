@@ -1,6 +1,7 @@
 package bootstrap
 
 import (
+	"encoding/base64"
 	"fmt"
 	"github.com/rs/zerolog"
 	"github.com/shaco-go/gkit-layout/configs"
@@ -13,13 +14,35 @@ import (
 	"time"
 )
 
+// models 通过RegisterModels注册、在启动时校验的模型
+var models []any
+
+// RegisterModels 注册需要在启动时通过gkit_gorm.ValidateModels校验的模型，需要在Init之前调用
+func RegisterModels(m ...any) {
+	models = append(models, m...)
+}
+
 func InitMysql(conf configs.Mysql, z zerolog.Logger) *gorm.DB {
 	dsn := gkit_gorm.DefaultDSN()
 	dsn.Host = conf.Host
 	dsn.Port = conf.Port
+	dsn.Socket = conf.Socket
 	dsn.Username = conf.Username
 	dsn.Password = conf.Password
 	dsn.DBName = conf.DBName
+	if err := dsn.Validate(); err != nil && !global.Conf.IsDev() {
+		panic(fmt.Errorf("数据库配置错误:%w", err))
+	}
+
+	if conf.EncryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(conf.EncryptionKey)
+		if err == nil {
+			err = gkit_gorm.SetEncryptionKey(key)
+		}
+		if err != nil {
+			panic(fmt.Errorf("数据库加密密钥配置错误:%w", err))
+		}
+	}
 
 	level, err := zerolog.ParseLevel(global.Conf.Log.LogLevel)
 	if err != nil {
@@ -37,5 +60,23 @@ func InitMysql(conf configs.Mysql, z zerolog.Logger) *gorm.DB {
 	if err != nil && !global.Conf.IsDev() {
 		panic(fmt.Errorf("初始化数据库失败:%w", err))
 	}
+	if db != nil {
+		// 注册语句超时插件，WithQueryTimeout依赖该插件
+		if err := db.Use(&gkit_gorm.QueryTimeoutPlugin{Default: conf.QueryTimeout}); err != nil {
+			panic(fmt.Errorf("注册语句超时插件失败:%w", err))
+		}
+		// 开发环境检测N+1查询，请求入口需要使用gkit_gorm.WithQueryTracking
+		if global.Conf.IsDev() {
+			if err := db.Use(&gkit_gorm.NPlusOnePlugin{}); err != nil {
+				panic(fmt.Errorf("注册N+1检测插件失败:%w", err))
+			}
+		}
+		if err := gkit_gorm.ValidateModels(db, models...); err != nil {
+			if !global.Conf.IsDev() {
+				panic(fmt.Errorf("模型校验失败:%w", err))
+			}
+			global.Log.Warn().Err(err).Msg("模型校验失败")
+		}
+	}
 	return db
 }
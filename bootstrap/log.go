@@ -12,6 +12,7 @@ func InitLog() zerolog.Logger {
 	logConf.Level, _ = zerolog.ParseLevel(global.Conf.Log.LogLevel)
 	logConf.HumanReadable = global.Conf.Log.HumanReadable
 	logConf.LogFileName = global.Conf.AppName + ".log"
+	logConf.WithStaticFields(global.Conf.Log.StaticFields)
 
 	// 初始化日志,分开因为gorm会自动生成错误行
 	return gkit_zerolog.New(logConf)
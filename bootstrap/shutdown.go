@@ -0,0 +1,49 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/global"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// Shutdown 退出前释放资源，先关闭缓存以刷新异步写入，再关闭Redis和数据库连接
+func Shutdown(ctx context.Context) error {
+	var errs []error
+	if err := cache.CloseAll(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if global.Redis != nil {
+		if err := global.Redis.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if global.DB != nil {
+		if sqlDB, err := global.DB.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ShutdownOnSignal 开始监听SIGINT和SIGTERM，返回的wait阻塞到收到信号，然后调用Shutdown并返回其错误
+// timeout为等待缓存刷新的最长时间；需要在Init之后调用，main在启动服务后调用wait，返回后再退出进程
+func ShutdownOnSignal(timeout time.Duration) (wait func() error) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	return func() error {
+		<-ctx.Done()
+		// 恢复默认的信号处理，关闭过程中再次收到信号时直接退出
+		stop()
+		global.Log.Info().Msg("收到退出信号，开始关闭")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return Shutdown(shutdownCtx)
+	}
+}
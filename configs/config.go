@@ -1,6 +1,9 @@
 package configs
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 type Config struct {
 	Env      string `mapstructure:"env"`      // 环境
@@ -12,17 +15,22 @@ type Config struct {
 }
 
 type Log struct {
-	Channel       []string `mapstructure:"channel"`
-	LogLevel      string   `mapstructure:"log_level"`      // 日志等级 info一下模式可打印sql
-	HumanReadable bool     `mapstructure:"human_readable"` // 是否使用可读格式
+	Channel       []string       `mapstructure:"channel"`
+	LogLevel      string         `mapstructure:"log_level"`      // 日志等级 info一下模式可打印sql
+	HumanReadable bool           `mapstructure:"human_readable"` // 是否使用可读格式
+	StaticFields  map[string]any `mapstructure:"static_fields"`  // 每条日志附带的静态字段
 }
 
 type Mysql struct {
 	Host     string `mapstructure:"host"`     // 主机
 	Port     int    `mapstructure:"port"`     // 端口
+	Socket   string `mapstructure:"socket"`   // unix socket路径，配置后不再使用主机和端口
 	Username string `mapstructure:"username"` // 用户名
 	Password string `mapstructure:"password"` // 密码
 	DBName   string `mapstructure:"db_name"`  // 数据库
+
+	QueryTimeout  time.Duration `mapstructure:"query_timeout"`  // 单条语句默认超时时间，例如5s，0表示不限制
+	EncryptionKey string        `mapstructure:"encryption_key"` // serializer:aesgcm字段的密钥，base64编码的16、24或32字节，建议使用${env:VAR}
 }
 
 type Redis struct {
@@ -0,0 +1,106 @@
+package configs
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ResolveSecrets 递归解析配置中的密钥引用，整个字符串为 ${env:VAR} 时替换为环境变量的值，
+// 为 ${file:/path} 时替换为文件内容(去除首尾空白)，避免把密码等敏感信息写在配置文件中
+// 参数:
+//   - v: 配置结构体指针
+//
+// 返回:
+//   - error: 引用的环境变量或文件不存在时返回错误，包含字段路径
+func ResolveSecrets(v any) error {
+	return resolveValue(reflect.ValueOf(v), "")
+}
+
+// resolveValue 解析单个值，path用于错误信息
+func resolveValue(val reflect.Value, path string) error {
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return nil
+		}
+		return resolveValue(val.Elem(), path)
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if err := resolveValue(val.Field(i), joinPath(path, field.Name)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if err := resolveValue(val.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// map的值不可寻址，解析后重新写回
+		for _, key := range val.MapKeys() {
+			elem := reflect.New(val.Type().Elem()).Elem()
+			elem.Set(val.MapIndex(key))
+			if elem.Kind() == reflect.Interface && !elem.IsNil() {
+				inner := reflect.New(elem.Elem().Type()).Elem()
+				inner.Set(elem.Elem())
+				elem = inner
+			}
+			if err := resolveValue(elem, joinPath(path, fmt.Sprint(key.Interface()))); err != nil {
+				return err
+			}
+			val.SetMapIndex(key, elem)
+		}
+	case reflect.String:
+		if !val.CanSet() {
+			return nil
+		}
+		resolved, err := resolveSecret(val.String())
+		if err != nil {
+			return fmt.Errorf("解析配置 %s 失败: %w", path, err)
+		}
+		val.SetString(resolved)
+	}
+	return nil
+}
+
+// resolveSecret 解析单个字符串，不是引用时原样返回
+func resolveSecret(s string) (string, error) {
+	if !strings.HasPrefix(s, "${") || !strings.HasSuffix(s, "}") {
+		return s, nil
+	}
+	ref := s[2 : len(s)-1]
+
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("环境变量 %s 未设置", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "file:"):
+		path := strings.TrimPrefix(ref, "file:")
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("读取密钥文件 %s 失败: %w", path, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	default:
+		return s, nil
+	}
+}
+
+// joinPath 拼接字段路径
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,50 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv("GKIT_TEST_DB_PASSWORD", "db-secret")
+	file := filepath.Join(t.TempDir(), "redis_password")
+	if err := os.WriteFile(file, []byte(" redis-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{
+		Database: Mysql{Password: "${env:GKIT_TEST_DB_PASSWORD}", Username: "root"},
+		Redis:    Redis{Password: "${file:" + file + "}"},
+		Log:      Log{StaticFields: map[string]any{"token": "${env:GKIT_TEST_DB_PASSWORD}", "shard": 1}},
+	}
+	if err := ResolveSecrets(c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Database.Password != "db-secret" {
+		t.Fatalf("env引用未解析: %q", c.Database.Password)
+	}
+	if c.Redis.Password != "redis-secret" {
+		t.Fatalf("file引用未解析或未去除空白: %q", c.Redis.Password)
+	}
+	if c.Database.Username != "root" {
+		t.Fatalf("普通字符串应保持不变: %q", c.Database.Username)
+	}
+	if c.Log.StaticFields["token"] != "db-secret" || c.Log.StaticFields["shard"] != 1 {
+		t.Fatalf("map中的引用未解析: %v", c.Log.StaticFields)
+	}
+}
+
+func TestResolveSecretsMissing(t *testing.T) {
+	c := &Config{Database: Mysql{Password: "${env:GKIT_TEST_NOT_SET}"}}
+	err := ResolveSecrets(c)
+	if err == nil || !strings.Contains(err.Error(), "Database.Password") {
+		t.Fatalf("错误应包含字段路径，实际%v", err)
+	}
+
+	c = &Config{Redis: Redis{Password: "${file:" + filepath.Join(t.TempDir(), "missing") + "}"}}
+	if err := ResolveSecrets(c); err == nil || !strings.Contains(err.Error(), "Redis.Password") {
+		t.Fatalf("文件不存在时应返回错误，实际%v", err)
+	}
+}
@@ -3,10 +3,18 @@ package main
 import (
 	"flag"
 	"github.com/shaco-go/gkit-layout/bootstrap"
+	"github.com/shaco-go/gkit-layout/global"
+	"os"
+	"time"
 )
 
 func main() {
 	path := flag.String("c", "configs/development.yaml", "config file path")
 	flag.Parse()
 	bootstrap.Init(*path)
+	wait := bootstrap.ShutdownOnSignal(10 * time.Second)
+	if err := wait(); err != nil {
+		global.Log.Error().Err(err).Msg("关闭失败")
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,78 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestActiveLocksRedis(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestRedis(t, cache.WithLockPrefix("lk:"))
+	tokenA, err := c.Lock(ctx, "a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenB, err := c.Lock(ctx, "b*", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不在LockPrefix下的键和信号量不会列出
+	if err := mr.Set("other", "x"); err != nil {
+		t.Fatal(err)
+	}
+	sem, err := cache.NewSemaphore(c, "s", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, err := sem.Acquire(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	locks, err := c.ActiveLocks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 2 {
+		t.Fatalf("期望2个锁，实际%+v", locks)
+	}
+	if locks[0].Key != "lk:a" || locks[0].Token != tokenA || locks[0].TTL <= 0 || locks[0].TTL > time.Minute {
+		t.Errorf("锁a不正确: %+v", locks[0])
+	}
+	if locks[1].Key != "lk:b*" || locks[1].Token != tokenB || locks[1].TTL <= 0 {
+		t.Errorf("锁b*不正确: %+v", locks[1])
+	}
+}
+
+func TestActiveLocksRedisWithoutPrefix(t *testing.T) {
+	c, _ := newTestRedis(t)
+	if _, err := c.ActiveLocks(context.Background()); !errors.Is(err, cache.ErrInvalidParams) {
+		t.Fatalf("LockPrefix为空时期望ErrInvalidParams，实际%v", err)
+	}
+}
+
+func TestActiveLocksMemory(t *testing.T) {
+	ctx := context.Background()
+	c := newTestMemory(t, cache.WithLockPrefix("lk:"))
+	tokenX, err := c.Lock(ctx, "x", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenY, err := c.Lock(ctx, "y", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locks, err := c.ActiveLocks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 2 || locks[0].Key != "lk:x" || locks[0].Token != tokenX || locks[1].Token != tokenY {
+		t.Fatalf("锁列表不正确: %+v", locks)
+	}
+}
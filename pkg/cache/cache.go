@@ -2,18 +2,24 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
+	"slices"
 	"time"
 
 	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog/log"
 )
 
 var (
 	// 定义错误类型
-	ErrNotFound      = errors.New("cache: key not found")
-	ErrLockAcquired  = errors.New("cache: lock already acquired")
-	ErrLockNotOwned  = errors.New("cache: lock not owned by caller")
-	ErrInvalidParams = errors.New("cache: invalid parameters")
+	ErrNotFound        = errors.New("cache: key not found")
+	ErrLockAcquired    = errors.New("cache: lock already acquired")
+	ErrLockNotOwned    = errors.New("cache: lock not owned by caller")
+	ErrInvalidParams   = errors.New("cache: invalid parameters")
+	ErrReadOnly        = errors.New("cache: cache is read-only")
+	ErrNotSupported    = errors.New("cache: operation not supported by backend")
+	ErrLockTimeout     = errors.New("cache: timed out waiting for lock")
+	ErrValueTooLarge   = errors.New("cache: value exceeds backend size limit")
+	ErrWriteBehindFull = errors.New("cache: write-behind queue is full")
 )
 
 // Cache 定义缓存接口
@@ -21,25 +27,110 @@ type Cache interface {
 	// Set 设置缓存，带过期时间
 	Set(ctx context.Context, key string, value any, expiration time.Duration) error
 
+	// SetCoalesced 合并写入，开启WithWriteCoalescing时缓冲最新值并按周期写入后端，否则等同于Set
+	SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error
+
 	// GetRaw 获取原始缓存数据
 	GetRaw(ctx context.Context, key string) ([]byte, error)
 
+	// MGetRaw 批量获取原始缓存数据，返回的map只包含命中的键
+	MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error)
+
+	// MSet 批量设置缓存，所有键使用相同的过期时间
+	MSet(ctx context.Context, values map[string]any, expiration time.Duration) error
+
 	// Exists 检查键是否存在
 	Exists(ctx context.Context, key string) (bool, error)
 
 	// SaveRaw 获取或设置原始缓存数据
 	SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error)
 
+	// SetIfNewer 仅当ts比已存储的时间戳更新时才写入，返回是否写入成功，ts必须为非负数
+	SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error)
+
+	// GetWithTimestamp 获取SetIfNewer写入的数据及其时间戳
+	GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error)
+
+	// Pipeline 缓冲fn中的写操作并一起提交，fn返回错误时不执行任何操作
+	// Redis使用MULTI/EXEC，单条命令执行失败不会回滚其他命令；内存缓存在同一把锁内执行
+	Pipeline(ctx context.Context, fn func(p Pipeliner) error) error
+
+	// RegisterRefresher 在key过期前refreshBefore主动调用loader刷新，热点key不会因过期而未命中
+	// 注册时同步加载一次，失败时返回错误且不启动刷新；刷新失败保留旧值，并以refreshBefore/4的间隔重试
+	// 刷新时间带有随机抖动，重复注册同一个key会替换之前的刷新；Close时停止所有刷新，只读模式返回ErrReadOnly
+	RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error
+
 	// Lock 获取分布式锁，返回锁的唯一标识符
 	Lock(ctx context.Context, key string, expiration time.Duration) (string, error)
 
 	// Unlock 释放分布式锁
 	Unlock(ctx context.Context, key string, value string) error
 
+	// ActiveLocks 列出当前持有的锁，用于排查死锁和未释放的锁，按键排序
+	// 内存缓存返回锁的标识符，不包含剩余时间；Redis通过SCAN遍历LockPrefix下的键，LockPrefix为空时返回ErrInvalidParams
+	ActiveLocks(ctx context.Context) ([]LockInfo, error)
+
+	// Publish 向频道发布消息，频道名同样会添加KeyPrefix；内存缓存返回ErrNotSupported
+	Publish(ctx context.Context, channel string, message []byte) error
+
+	// Subscribe 订阅频道，返回消息通道和取消函数，取消后通道关闭；ctx结束时同样会取消订阅
+	// 断线后会自动重连，重连期间发布的消息会丢失；内存缓存返回ErrNotSupported
+	Subscribe(ctx context.Context, channels ...string) (<-chan Message, func(), error)
+
+	// Backend 返回后端名称：memory、redis或chain，装饰器返回底层后端的名称
+	Backend() string
+
+	// Capabilities 返回后端支持的能力，用于特性检测后降级，而不是调用后再处理ErrNotSupported
+	// memory: 不支持PubSub，锁和信号量只在进程内生效
+	// redis: 支持全部能力
+	// chain: 写入、Pipeline和锁需要所有层都支持，PubSub任意一层支持即可，不支持信号量和计数器
+	// 只读模式: 只保留读取和PubSub
+	Capabilities() CacheCapabilities
+
+	// Raw 返回不添加KeyPrefix的视图，用于读写其他服务写入的外部键
+	// 视图只影响数据键，Lock/Unlock仍使用LockPrefix命名空间；关闭视图不会关闭底层连接
+	Raw() Cache
+
 	// Close 关闭缓存
 	Close() error
 }
 
+// LockInfo 一个当前持有的锁
+type LockInfo struct {
+	// Key 锁在后端中的完整键，包含LockPrefix
+	Key string
+	// Token 锁的标识符，即Lock的返回值，可以用于Unlock强制释放
+	Token string
+	// TTL 锁的剩余时间，0表示未知或不过期
+	TTL time.Duration
+}
+
+// CacheCapabilities 后端支持的能力
+type CacheCapabilities struct {
+	// Write 是否支持Set、MSet、SetIfNewer等写操作
+	Write bool
+	// Pipeline 是否支持Pipeline
+	Pipeline bool
+	// Lock 是否支持Lock/Unlock
+	Lock bool
+	// DistributedLock 锁和信号量是否跨进程生效
+	DistributedLock bool
+	// PubSub 是否支持Publish/Subscribe
+	PubSub bool
+	// Semaphore 是否支持NewSemaphore
+	Semaphore bool
+	// Counter 是否支持NewCounter
+	Counter bool
+}
+
+// Message 订阅收到的消息
+type Message struct {
+	// Channel 频道名，不包含KeyPrefix
+	Channel string
+	// Payload 消息内容
+	Payload []byte
+}
+
 // SaveOption 定义Save方法的可选参数
 type SaveOption func(*saveOptions)
 
@@ -52,6 +143,91 @@ type saveOptions struct {
 
 	// NilExpiration 空值的过期时间(防止缓存穿透时使用)
 	NilExpiration time.Duration
+
+	// LockPollInterval 其他请求正在加载时，重新检查缓存和锁的间隔
+	LockPollInterval time.Duration
+
+	// LockWaitTimeout 等待其他请求加载的最长时间，超过后返回ErrLockTimeout
+	LockWaitTimeout time.Duration
+
+	// ErrorFallback fn返回错误时是否返回旧值或兜底值
+	ErrorFallback bool
+
+	// UseStale 是否保存并返回旧值副本
+	UseStale bool
+
+	// Fallback 没有旧值时返回的兜底值
+	Fallback []byte
+
+	// DynamicTTL 根据fn返回的值计算过期时间，返回正数时覆盖expiration
+	DynamicTTL func(value []byte) time.Duration
+}
+
+// newSaveOptions 合并SaveOption，ctx携带绕过标记时强制刷新
+func newSaveOptions(ctx context.Context, options []SaveOption) *saveOptions {
+	opts := &saveOptions{
+		LockPollInterval: 100 * time.Millisecond,
+		LockWaitTimeout:  10 * time.Second,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+	if IsBypass(ctx) {
+		opts.ForceRefresh = true
+	}
+	return opts
+}
+
+// SaveOptions SaveOption合并后的结果，供cachetest等其他包中的Cache实现读取
+type SaveOptions struct {
+	// ForceRefresh 是否强制刷新缓存
+	ForceRefresh bool
+	// PreventCacheMiss 当fn返回空值时是否仍然缓存
+	PreventCacheMiss bool
+	// NilExpiration 空值的过期时间
+	NilExpiration time.Duration
+	// ErrorFallback fn返回错误时是否返回旧值或兜底值
+	ErrorFallback bool
+	// UseStale 是否保存并返回旧值副本
+	UseStale bool
+	// Fallback 没有旧值时返回的兜底值
+	Fallback []byte
+	// DynamicTTL 根据fn返回的值计算过期时间，返回正数时覆盖expiration
+	DynamicTTL func(value []byte) time.Duration
+}
+
+// Expiration fn返回value时使用的过期时间，DynamicTTL返回正数时覆盖expiration
+func (o SaveOptions) Expiration(value []byte, expiration time.Duration) time.Duration {
+	return dynamicExpiration(o.DynamicTTL, value, expiration)
+}
+
+// ResolveSaveOptions 合并SaveOption，ctx携带绕过标记时ForceRefresh为true
+func ResolveSaveOptions(ctx context.Context, options ...SaveOption) SaveOptions {
+	opts := newSaveOptions(ctx, options)
+	return SaveOptions{
+		ForceRefresh:     opts.ForceRefresh,
+		PreventCacheMiss: opts.PreventCacheMiss,
+		NilExpiration:    opts.NilExpiration,
+		ErrorFallback:    opts.ErrorFallback,
+		UseStale:         opts.UseStale,
+		Fallback:         opts.Fallback,
+		DynamicTTL:       opts.DynamicTTL,
+	}
+}
+
+// expiration fn返回value时使用的过期时间，DynamicTTL返回正数时覆盖expiration
+func (o *saveOptions) expiration(value []byte, expiration time.Duration) time.Duration {
+	return dynamicExpiration(o.DynamicTTL, value, expiration)
+}
+
+func dynamicExpiration(ttl func([]byte) time.Duration, value []byte, expiration time.Duration) time.Duration {
+	if ttl == nil {
+		return expiration
+	}
+	if d := ttl(value); d > 0 {
+		return d
+	}
+	return expiration
 }
 
 // WithForceRefresh 强制刷新缓存，不管是否存在都会调用fn
@@ -62,6 +238,7 @@ func WithForceRefresh() SaveOption {
 }
 
 // WithPreventCacheMiss 防止缓存穿透，当数据不存在时也缓存一个空占位符
+// key来自外部输入时建议同时配置WithNegativeCacheLimit，限制空值占用的空间
 func WithPreventCacheMiss(expiration time.Duration) SaveOption {
 	return func(o *saveOptions) {
 		o.PreventCacheMiss = true
@@ -69,24 +246,128 @@ func WithPreventCacheMiss(expiration time.Duration) SaveOption {
 	}
 }
 
+// WithDynamicTTL 根据加载的值决定过期时间，在fn返回后调用，返回正数时覆盖Save的expiration，否则仍使用expiration
+// 例如缓存访问令牌直到令牌本身过期；value为fn返回的原始值，Save[T]中为JSON，不受压缩、加密等装饰器的影响
+func WithDynamicTTL(fn func(value []byte) time.Duration) SaveOption {
+	return func(o *saveOptions) {
+		o.DynamicTTL = fn
+	}
+}
+
+// decodeDynamicTTL 改写值的装饰器将fn包装后交给内层时使用，让DynamicTTL收到包装前的值，decode失败时使用expiration
+func decodeDynamicTTL(options []SaveOption, decode func([]byte) ([]byte, error)) []SaveOption {
+	return append(slices.Clip(options), func(o *saveOptions) {
+		ttl := o.DynamicTTL
+		if ttl == nil {
+			return
+		}
+		o.DynamicTTL = func(value []byte) time.Duration {
+			plain, err := decode(value)
+			if err != nil {
+				return 0
+			}
+			return ttl(plain)
+		}
+	})
+}
+
+// WithLockPollInterval 设置等待其他请求加载时的轮询间隔，默认100毫秒
+func WithLockPollInterval(d time.Duration) SaveOption {
+	return func(o *saveOptions) {
+		if d > 0 {
+			o.LockPollInterval = d
+		}
+	}
+}
+
+// WithLockWaitTimeout 设置等待其他请求加载的最长时间，默认10秒
+func WithLockWaitTimeout(d time.Duration) SaveOption {
+	return func(o *saveOptions) {
+		if d > 0 {
+			o.LockWaitTimeout = d
+		}
+	}
+}
+
 // New 创建一个新的缓存实例
 func New(opts ...Option) (Cache, error) {
 	options := &Options{
-		Type: MemoryCache,
+		Type:   MemoryCache,
+		Logger: log.Logger,
+		clock:  realClock{},
 	}
 
 	for _, opt := range opts {
 		opt(options)
 	}
+	if err := validateOptions(options); err != nil {
+		return nil, err
+	}
 
+	var (
+		c   Cache
+		err error
+	)
 	switch options.Type {
 	case MemoryCache:
-		return newMemoryCache(options)
+		c, err = newMemoryCache(options)
 	case RedisCache:
-		return newRedisCache(options)
+		c, err = newRedisCache(options)
 	default:
 		return nil, errors.New("cache: unsupported cache type")
 	}
+	if err != nil {
+		return nil, err
+	}
+	return decorate(c, options)
+}
+
+// validateOptions 在创建后端之前检查选项，避免返回错误时后端已经启动
+func validateOptions(options *Options) error {
+	if options.WriteBehind != nil && options.WriteBehindInterval <= 0 {
+		return errors.Wrap(ErrInvalidParams, "cache: write-behind interval must be positive")
+	}
+	return nil
+}
+
+// decorate 按选项包装内置装饰器，WithMiddleware添加的装饰器在最外层
+func decorate(c Cache, options *Options) (Cache, error) {
+	// 外层在前，写入依次经过热点统计、只读检查、合并写入、异步持久化、版本标记、压缩、加密和慢操作日志
+	mws := append([]Middleware{}, options.Middlewares...)
+	if options.HotKeyTopN > 0 {
+		mws = append(mws, func(c Cache) Cache { return newHotKeyCache(c, options.HotKeyTopN) })
+	}
+	if options.ReadOnly {
+		mws = append(mws, func(c Cache) Cache { return newReadOnlyCache(c, options) })
+	}
+	if options.WriteCoalescing > 0 {
+		mws = append(mws, func(c Cache) Cache { return newCoalescingCache(c, options.WriteCoalescing, options.Logger) })
+	}
+	if options.WriteBehind != nil {
+		mws = append(mws, func(c Cache) Cache {
+			return newWriteBehindCache(c, options.WriteBehind, options.WriteBehindInterval, options.WriteBehindBatch, options.Logger)
+		})
+	}
+	if options.SchemaVersion > 0 {
+		mws = append(mws, func(c Cache) Cache { return newVersionedCache(c, options.SchemaVersion) })
+	}
+	if options.CompressionThreshold > 0 {
+		// 在加密之前压缩，加密后的数据无法压缩
+		mws = append(mws, func(c Cache) Cache {
+			return newCompressedCache(c, options.CompressionThreshold, options.AdaptiveCompression, compressionMaxSize)
+		})
+	}
+	if options.EncryptionKey != nil {
+		mw, err := encryptionMiddleware(options.EncryptionKey, options.KeyPrefix)
+		if err != nil {
+			return nil, err
+		}
+		mws = append(mws, mw)
+	}
+	if options.SlowLogThreshold > 0 {
+		mws = append(mws, func(c Cache) Cache { return newSlowLogCache(c, options.SlowLogThreshold, options.SlowLogger) })
+	}
+	return Wrap(c, mws...), nil
 }
 
 // 泛型辅助函数
@@ -108,7 +389,7 @@ func Get[T any](ctx context.Context, cache Cache, key string) (T, error) {
 	// 反序列化数据
 	err = Unmarshal(data, &value)
 	if err != nil {
-		return value, errors.Wrap(err, "cache: failed to unmarshal value")
+		return value, wrapSerializationError(err, "cache: failed to unmarshal value")
 	}
 
 	return value, nil
@@ -128,44 +409,180 @@ func Save[T any](ctx context.Context, cache Cache, key string, fn func() (T, err
 		// 序列化结果
 		data, err := Marshal(result)
 		if err != nil {
-			return nil, errors.Wrap(err, "cache: failed to marshal value")
+			return nil, wrapSerializationError(err, "cache: failed to marshal value")
 		}
 
 		return data, nil
 	}
 
-	// 调用原始的SaveRaw方法
+	// 调用原始的SaveRaw方法，返回旧值或兜底值时同样反序列化，并返回*FallbackError
 	rawData, err := cache.SaveRaw(ctx, key, rawFn, expiration, options...)
-	if err != nil {
+	if err != nil && !IsFallback(err) {
 		return value, err
 	}
 
 	// 如果数据为空，直接返回零值
 	if len(rawData) == 0 {
-		return value, nil
+		return value, err
 	}
 
 	// 反序列化数据
-	err = Unmarshal(rawData, &value)
+	if unmarshalErr := Unmarshal(rawData, &value); unmarshalErr != nil {
+		return value, wrapSerializationError(unmarshalErr, "cache: failed to unmarshal value")
+	}
+
+	return value, err
+}
+
+// SaveMany 批量获取缓存数据，未命中的键通过一次loader调用加载并写回缓存
+// 参数:
+//   - keys: 需要获取的键
+//   - loader: 加载函数，只会收到未命中的键，且最多调用一次；返回的map中缺少的键不会被缓存
+//   - expiration: 写回缓存的过期时间
+//
+// 返回:
+//   - map[string]T: 命中和加载的数据合并后的结果
+//   - error: 读取、加载或写回过程中发生的错误
+func SaveMany[T any](ctx context.Context, cache Cache, keys []string, loader func(missingKeys []string) (map[string]T, error), expiration time.Duration) (map[string]T, error) {
+	result := make(map[string]T, len(keys))
+
+	// 1.批量读取缓存
+	cached, err := cache.MGetRaw(ctx, keys)
 	if err != nil {
-		return value, errors.Wrap(err, "cache: failed to unmarshal value")
+		return nil, err
 	}
 
-	return value, nil
+	// 2.反序列化命中的数据，收集未命中的键
+	missing := make([]string, 0)
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		data, ok := cached[key]
+		if !ok {
+			missing = append(missing, key)
+			continue
+		}
+		var value T
+		if err := Unmarshal(data, &value); err != nil {
+			return nil, wrapSerializationError(err, "cache: failed to unmarshal value")
+		}
+		result[key] = value
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	// 3.一次性加载未命中的数据并写回缓存
+	loaded, err := loader(missing)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]any, len(loaded))
+	for key, value := range loaded {
+		data, err := Marshal(value)
+		if err != nil {
+			return nil, wrapSerializationError(err, "cache: failed to marshal value")
+		}
+		values[key] = data
+		result[key] = value
+	}
+	if len(values) > 0 {
+		if err := cache.MSet(ctx, values, expiration); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// SaveMixed 批量获取缓存数据，每个键使用各自的加载函数，适用于从不同数据源拼装列表
+// 先通过一次MGetRaw读取所有键，命中的键不会调用加载函数；未命中的键依次通过Save加载并写回，同一个键的并发加载只执行一次
+// 参数:
+//   - loaders: 键到加载函数的映射
+//   - expiration: 写回缓存的过期时间
+//   - options: 未命中的键加载时使用的可选参数，与Save一致
+//
+// 返回:
+//   - map[string]T: 命中和加载的数据合并后的结果
+//   - error: 读取、加载或写回过程中的第一个错误
+func SaveMixed[T any](ctx context.Context, cache Cache, loaders map[string]func() (T, error), expiration time.Duration, options ...SaveOption) (map[string]T, error) {
+	result := make(map[string]T, len(loaders))
+	if len(loaders) == 0 {
+		return result, nil
+	}
+
+	// 1.批量读取缓存
+	keys := make([]string, 0, len(loaders))
+	for key := range loaders {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	cached, err := cache.MGetRaw(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2.反序列化命中的数据，未命中的键调用各自的加载函数
+	for _, key := range keys {
+		if data, ok := cached[key]; ok {
+			var value T
+			if err := Unmarshal(data, &value); err != nil {
+				return nil, wrapSerializationError(err, "cache: failed to unmarshal value")
+			}
+			result[key] = value
+			continue
+		}
+		// 开启WithErrorFallback时旧值或兜底值同样放入结果
+		value, err := Save(ctx, cache, key, loaders[key], expiration, options...)
+		if err != nil && !IsFallback(err) {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// SetString 直接存储字符串的UTF-8字节，不经过JSON序列化
+// 通过SetString写入的值必须通过GetString读取，使用Get[string]读取会因缺少JSON引号而解析失败
+func SetString(ctx context.Context, cache Cache, key string, value string, expiration time.Duration) error {
+	return cache.Set(ctx, key, []byte(value), expiration)
+}
+
+// GetString 读取SetString写入的原始字符串
+func GetString(ctx context.Context, cache Cache, key string) (string, error) {
+	data, err := cache.GetRaw(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SetBytes 直接存储原始字节，不经过JSON序列化
+func SetBytes(ctx context.Context, cache Cache, key string, value []byte, expiration time.Duration) error {
+	return cache.Set(ctx, key, value, expiration)
+}
+
+// GetBytes 读取SetBytes写入的原始字节
+func GetBytes(ctx context.Context, cache Cache, key string) ([]byte, error) {
+	return cache.GetRaw(ctx, key)
 }
 
-// Marshal 序列化数据
+// Marshal 序列化数据，行为可以通过SetJSONOptions配置
 func Marshal(v interface{}) ([]byte, error) {
 	if v == nil {
 		return nil, nil
 	}
-	return json.Marshal(v)
+	return marshalJSON(v)
 }
 
-// Unmarshal 反序列化数据
+// Unmarshal 反序列化数据，行为可以通过SetJSONOptions配置
 func Unmarshal(data []byte, v interface{}) error {
 	if len(data) == 0 {
 		return nil
 	}
-	return json.Unmarshal(data, v)
+	return unmarshalJSON(data, v)
 }
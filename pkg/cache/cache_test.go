@@ -0,0 +1,145 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestStringAndBytesRoundTrip(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		if err := cache.SetString(ctx, c, "s", "你好 \"world\"", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := cache.GetString(ctx, c, "s"); err != nil || v != "你好 \"world\"" {
+			t.Fatalf("got %q %v", v, err)
+		}
+		raw := []byte{0x00, 0x01, 0xff}
+		if err := cache.SetBytes(ctx, c, "b", raw, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := cache.GetBytes(ctx, c, "b"); err != nil || !bytes.Equal(v, raw) {
+			t.Fatalf("got %v %v", v, err)
+		}
+	})
+}
+
+func TestSetStringNotJSONQuoted(t *testing.T) {
+	c, mr := newTestRedis(t)
+	if err := cache.SetString(context.Background(), c, "s", "hello", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := mr.Get("s"); v != "hello" {
+		t.Fatalf("存储的值不应带JSON引号: %q", v)
+	}
+	if err := c.Set(context.Background(), "j", "hello", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := mr.Get("j"); v != `"hello"` {
+		t.Fatalf("Set应当按JSON序列化: %q", v)
+	}
+}
+
+func TestSaveManyLoadsOnlyMissingKeys(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		if err := c.Set(ctx, "a", 1, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+
+		calls := 0
+		result, err := cache.SaveMany(ctx, c, []string{"a", "b", "c"}, func(missing []string) (map[string]int, error) {
+			calls++
+			if !reflect.DeepEqual(missing, []string{"b", "c"}) {
+				t.Errorf("loader应只收到未命中的键，实际%v", missing)
+			}
+			return map[string]int{"b": 2, "c": 3}, nil
+		}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != 1 {
+			t.Fatalf("loader应调用1次，实际%d次", calls)
+		}
+		if !reflect.DeepEqual(result, map[string]int{"a": 1, "b": 2, "c": 3}) {
+			t.Fatalf("结果不正确: %v", result)
+		}
+
+		// 加载的数据已写回缓存，全部命中时不再调用loader
+		result, err = cache.SaveMany(ctx, c, []string{"a", "b", "c"}, func(missing []string) (map[string]int, error) {
+			t.Errorf("全部命中时不应调用loader，收到%v", missing)
+			return nil, nil
+		}, time.Minute)
+		if err != nil || len(result) != 3 {
+			t.Fatalf("got %v %v", result, err)
+		}
+	})
+}
+
+func TestSaveMixedLoadsOnlyMissingKeys(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		if err := c.Set(ctx, "a", 1, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+
+		calls := map[string]int{}
+		loader := func(key string, v int) func() (int, error) {
+			return func() (int, error) {
+				calls[key]++
+				return v, nil
+			}
+		}
+		result, err := cache.SaveMixed(ctx, c, map[string]func() (int, error){
+			"a": loader("a", 100), "b": loader("b", 2), "c": loader("c", 3),
+		}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result, map[string]int{"a": 1, "b": 2, "c": 3}) {
+			t.Fatalf("结果不正确: %v", result)
+		}
+		if !reflect.DeepEqual(calls, map[string]int{"b": 1, "c": 1}) {
+			t.Fatalf("只应调用未命中键的loader，实际%v", calls)
+		}
+
+		// 加载的数据已写回缓存，再次调用时不执行loader
+		result, err = cache.SaveMixed(ctx, c, map[string]func() (int, error){
+			"b": loader("b", 20), "c": loader("c", 30),
+		}, time.Minute)
+		if err != nil || !reflect.DeepEqual(result, map[string]int{"b": 2, "c": 3}) {
+			t.Fatalf("got %v %v", result, err)
+		}
+		if calls["b"] != 1 || calls["c"] != 1 {
+			t.Fatalf("全部命中时不应调用loader，实际%v", calls)
+		}
+	})
+}
+
+func TestSkipOversized(t *testing.T) {
+	ctx := context.Background()
+	big := bytes.Repeat([]byte("a"), 4096)
+	load := func() ([]byte, error) { return big, nil }
+
+	// 默认返回ErrValueTooLarge
+	c := newTestMemory(t, cache.WithCacheSize(512*1024))
+	if _, err := c.SaveRaw(ctx, "k", load, time.Minute); !errors.Is(err, cache.ErrValueTooLarge) {
+		t.Fatalf("SaveRaw err = %v, want ErrValueTooLarge", err)
+	}
+
+	// 跳过缓存，返回加载的值且不写入
+	c = newTestMemory(t, cache.WithCacheSize(512*1024), cache.WithSkipOversized())
+	data, err := c.SaveRaw(ctx, "k", load, time.Minute)
+	if err != nil || !bytes.Equal(data, big) {
+		t.Fatalf("SaveRaw = %d bytes, %v", len(data), err)
+	}
+	if ok, err := c.Exists(ctx, "k"); err != nil || ok {
+		t.Fatalf("oversized value should not be cached: exists=%v err=%v", ok, err)
+	}
+}
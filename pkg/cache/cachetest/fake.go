@@ -0,0 +1,590 @@
+// Package cachetest 提供基于map的cache.Cache实现，用于不依赖freecache或miniredis的单元测试
+package cachetest
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// entry 一条缓存数据
+type entry struct {
+	data     []byte
+	ts       int64
+	hasTS    bool      // 是否由SetIfNewer写入
+	expireAt time.Time // 零值表示不过期
+}
+
+// lockEntry 一把锁
+type lockEntry struct {
+	value    string
+	expireAt time.Time
+}
+
+// Fake 基于map的缓存，过期时间使用可控的时钟，可以为任意方法注入错误
+// 所有方法都是并发安全的；锁和发布订阅只在当前实例内生效
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	entries map[string]entry
+	stale   map[string]entry // WithErrorFallback保存的旧值副本
+	locks   map[string]lockEntry
+	errs    map[string]error
+	subs    map[string][]chan cache.Message
+	closed  bool
+}
+
+var _ cache.Cache = (*Fake)(nil)
+
+// NewFake 创建一个空的Fake，时钟从当前时间开始，只有Advance或SetNow才会推进
+func NewFake() *Fake {
+	return &Fake{
+		now:     time.Now(),
+		entries: make(map[string]entry),
+		stale:   make(map[string]entry),
+		locks:   make(map[string]lockEntry),
+		errs:    make(map[string]error),
+		subs:    make(map[string][]chan cache.Message),
+	}
+}
+
+// ForceError 让名为method的方法返回err，例如ForceError("GetRaw", err)；err为nil时取消注入
+func (f *Fake) ForceError(method string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errs, method)
+		return
+	}
+	f.errs[method] = err
+}
+
+// Advance 将时钟向前推进d，过期的数据和锁随之失效
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// SetNow 将时钟设置为t
+func (f *Fake) SetNow(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
+
+// Now 返回当前时钟
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Entries 返回所有未过期数据的副本，SetIfNewer写入的数据只包含值
+func (f *Fake) Entries() map[string][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := make(map[string][]byte, len(f.entries))
+	for key, e := range f.entries {
+		if f.alive(e.expireAt) {
+			result[key] = append([]byte(nil), e.data...)
+		}
+	}
+	return result
+}
+
+// fail 返回为method注入的错误，调用方需持有f.mu
+func (f *Fake) fail(method string) error {
+	return f.errs[method]
+}
+
+// alive 判断过期时间是否还未到达，调用方需持有f.mu
+func (f *Fake) alive(expireAt time.Time) bool {
+	return expireAt.IsZero() || f.now.Before(expireAt)
+}
+
+// expireAt 根据过期时间计算到期时刻，调用方需持有f.mu
+func (f *Fake) expireAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return f.now.Add(expiration)
+}
+
+// get 读取未过期的数据，调用方需持有f.mu
+func (f *Fake) get(key string) (entry, bool) {
+	e, ok := f.entries[key]
+	if !ok {
+		return entry{}, false
+	}
+	if !f.alive(e.expireAt) {
+		delete(f.entries, key)
+		return entry{}, false
+	}
+	return e, true
+}
+
+// set 写入数据，调用方需持有f.mu
+func (f *Fake) set(key string, data []byte, expiration time.Duration) {
+	f.entries[key] = entry{data: append([]byte(nil), data...), expireAt: f.expireAt(expiration)}
+}
+
+// encode 按cache.Set的规则序列化value
+func encode(value any) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if data, ok := value.([]byte); ok {
+		return data, nil
+	}
+	data, err := cache.Marshal(value)
+	if err != nil {
+		return nil, errors.WithStack(&cache.BackendError{Kind: cache.KindSerialization, Msg: "cache: failed to marshal value", Err: err})
+	}
+	return data, nil
+}
+
+func (f *Fake) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	data, err := encode(value)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("Set"); err != nil {
+		return err
+	}
+	f.set(key, data, expiration)
+	return nil
+}
+
+func (f *Fake) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	f.mu.Lock()
+	err := f.fail("SetCoalesced")
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return f.Set(ctx, key, value, expiration)
+}
+
+func (f *Fake) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("GetRaw"); err != nil {
+		return nil, err
+	}
+	if cache.IsBypass(ctx) {
+		return nil, cache.ErrNotFound
+	}
+	e, ok := f.get(key)
+	if !ok || e.hasTS {
+		return nil, cache.ErrNotFound
+	}
+	return append([]byte(nil), e.data...), nil
+}
+
+func (f *Fake) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("MGetRaw"); err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte, len(keys))
+	if cache.IsBypass(ctx) {
+		return result, nil
+	}
+	for _, key := range keys {
+		if e, ok := f.get(key); ok && !e.hasTS {
+			result[key] = append([]byte(nil), e.data...)
+		}
+	}
+	return result, nil
+}
+
+func (f *Fake) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	encoded := make(map[string][]byte, len(values))
+	for key, value := range values {
+		data, err := encode(value)
+		if err != nil {
+			return err
+		}
+		encoded[key] = data
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("MSet"); err != nil {
+		return err
+	}
+	for key, data := range encoded {
+		f.set(key, data, expiration)
+	}
+	return nil
+}
+
+func (f *Fake) Exists(ctx context.Context, key string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("Exists"); err != nil {
+		return false, err
+	}
+	_, ok := f.get(key)
+	return ok, nil
+}
+
+// SaveRaw 与内存缓存的语义一致，但不使用锁，fn的并发调用由调用方控制
+func (f *Fake) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...cache.SaveOption) ([]byte, error) {
+	f.mu.Lock()
+	err := f.fail("SaveRaw")
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := cache.ResolveSaveOptions(ctx, options...)
+	if !opts.ForceRefresh {
+		data, err := f.GetRaw(ctx, key)
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, cache.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	result, err := fn()
+	if err != nil {
+		return f.errorFallback(key, opts, err)
+	}
+	expiration = opts.Expiration(result, expiration)
+	if len(result) == 0 && opts.PreventCacheMiss && opts.NilExpiration > 0 {
+		expiration = opts.NilExpiration
+	}
+	if err := f.Set(ctx, key, result, expiration); err != nil {
+		return nil, err
+	}
+	if opts.ErrorFallback && opts.UseStale {
+		f.mu.Lock()
+		f.stale[key] = entry{data: append([]byte(nil), result...), expireAt: f.expireAt(2 * expiration)}
+		f.mu.Unlock()
+	}
+	return result, nil
+}
+
+// errorFallback 与cache.WithErrorFallback的语义一致，旧值副本的过期时间为原过期时间的两倍
+func (f *Fake) errorFallback(key string, opts cache.SaveOptions, err error) ([]byte, error) {
+	if !opts.ErrorFallback {
+		return nil, err
+	}
+	if opts.UseStale {
+		f.mu.Lock()
+		e, ok := f.stale[key]
+		ok = ok && f.alive(e.expireAt)
+		f.mu.Unlock()
+		if ok {
+			return append([]byte(nil), e.data...), &cache.FallbackError{Stale: true, Err: err}
+		}
+	}
+	if opts.Fallback != nil {
+		return append([]byte(nil), opts.Fallback...), &cache.FallbackError{Err: err}
+	}
+	return nil, err
+}
+
+func (f *Fake) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("SetIfNewer"); err != nil {
+		return false, err
+	}
+	if ts < 0 {
+		return false, cache.ErrInvalidParams
+	}
+	if e, ok := f.get(key); ok && e.hasTS && e.ts >= ts {
+		return false, nil
+	}
+	f.entries[key] = entry{data: append([]byte(nil), value...), ts: ts, hasTS: true, expireAt: f.expireAt(expiration)}
+	return true, nil
+}
+
+func (f *Fake) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("GetWithTimestamp"); err != nil {
+		return nil, 0, err
+	}
+	e, ok := f.get(key)
+	if !ok || !e.hasTS {
+		return nil, 0, cache.ErrNotFound
+	}
+	return append([]byte(nil), e.data...), e.ts, nil
+}
+
+// Pipeline fn成功后在同一把锁内执行所有操作
+func (f *Fake) Pipeline(ctx context.Context, fn func(p cache.Pipeliner) error) error {
+	f.mu.Lock()
+	err := f.fail("Pipeline")
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	p := &pipeline{}
+	if err := fn(p); err != nil {
+		return err
+	}
+	if p.err != nil {
+		return p.err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, op := range p.ops {
+		switch {
+		case op.del:
+			delete(f.entries, op.key)
+		case op.incr:
+			var current int64
+			e, ok := f.get(op.key)
+			if ok {
+				current, err = strconv.ParseInt(string(e.data), 10, 64)
+				if err != nil {
+					return errors.Wrapf(err, "cache: value of key %s is not an integer", op.key)
+				}
+			}
+			e.data = []byte(strconv.FormatInt(current+op.delta, 10))
+			f.entries[op.key] = e
+		default:
+			f.set(op.key, op.data, op.expiration)
+		}
+	}
+	return nil
+}
+
+// pipelineOp 一个缓冲的操作
+type pipelineOp struct {
+	key        string
+	data       []byte
+	expiration time.Duration
+	delta      int64
+	del        bool
+	incr       bool
+}
+
+// pipeline 记录Pipeline中的操作
+type pipeline struct {
+	ops []pipelineOp
+	err error
+}
+
+func (p *pipeline) Set(key string, value any, expiration time.Duration) {
+	data, err := encode(value)
+	if err != nil {
+		p.err = errors.CombineErrors(p.err, err)
+		return
+	}
+	p.ops = append(p.ops, pipelineOp{key: key, data: data, expiration: expiration})
+}
+
+func (p *pipeline) Delete(keys ...string) {
+	for _, key := range keys {
+		p.ops = append(p.ops, pipelineOp{key: key, del: true})
+	}
+}
+
+func (p *pipeline) Incr(key string, delta int64) {
+	p.ops = append(p.ops, pipelineOp{key: key, delta: delta, incr: true})
+}
+
+// RegisterRefresher 只同步执行首次加载，不会在后台刷新，需要模拟刷新时直接调用Set
+func (f *Fake) RegisterRefresher(key string, loader cache.RefreshLoader, ttl, refreshBefore time.Duration) error {
+	f.mu.Lock()
+	err := f.fail("RegisterRefresher")
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if loader == nil || ttl <= 0 || refreshBefore <= 0 || refreshBefore >= ttl {
+		return cache.ErrInvalidParams
+	}
+
+	data, err := loader(context.Background())
+	if err != nil {
+		return err
+	}
+	return f.Set(context.Background(), key, data, ttl)
+}
+
+func (f *Fake) Lock(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("Lock"); err != nil {
+		return "", err
+	}
+	if l, ok := f.locks[key]; ok && f.alive(l.expireAt) {
+		return "", cache.ErrLockAcquired
+	}
+	value := uuid.NewString()
+	f.locks[key] = lockEntry{value: value, expireAt: f.expireAt(expiration)}
+	return value, nil
+}
+
+func (f *Fake) Unlock(ctx context.Context, key string, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("Unlock"); err != nil {
+		return err
+	}
+	l, ok := f.locks[key]
+	if !ok || !f.alive(l.expireAt) || l.value != value {
+		return cache.ErrLockNotOwned
+	}
+	delete(f.locks, key)
+	return nil
+}
+
+// ActiveLocks 返回未过期的锁，键为Lock的key参数，剩余时间按Fake的时钟计算
+func (f *Fake) ActiveLocks(ctx context.Context) ([]cache.LockInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("ActiveLocks"); err != nil {
+		return nil, err
+	}
+	locks := make([]cache.LockInfo, 0, len(f.locks))
+	for key, l := range f.locks {
+		if !f.alive(l.expireAt) {
+			continue
+		}
+		info := cache.LockInfo{Key: key, Token: l.value}
+		if !l.expireAt.IsZero() {
+			info.TTL = l.expireAt.Sub(f.now)
+		}
+		locks = append(locks, info)
+	}
+	slices.SortFunc(locks, func(a, b cache.LockInfo) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	return locks, nil
+}
+
+// Publish 投递给当前实例的订阅者，订阅者的通道已满时丢弃消息
+func (f *Fake) Publish(ctx context.Context, channel string, message []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("Publish"); err != nil {
+		return err
+	}
+	for _, ch := range f.subs[channel] {
+		select {
+		case ch <- cache.Message{Channel: channel, Payload: append([]byte(nil), message...)}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Subscribe 订阅当前实例的频道，通道缓冲100条消息
+func (f *Fake) Subscribe(ctx context.Context, channels ...string) (<-chan cache.Message, func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("Subscribe"); err != nil {
+		return nil, nil, err
+	}
+	if len(channels) == 0 {
+		return nil, nil, cache.ErrInvalidParams
+	}
+
+	ch := make(chan cache.Message, 100)
+	for _, channel := range channels {
+		f.subs[channel] = append(f.subs[channel], ch)
+	}
+
+	var once sync.Once
+	done := make(chan struct{})
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.unsubscribe(ch, channels)
+		})
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			stop()
+		case <-done:
+		}
+	}()
+	return ch, stop, nil
+}
+
+// unsubscribe 移除订阅并关闭通道，调用方需持有f.mu
+func (f *Fake) unsubscribe(ch chan cache.Message, channels []string) {
+	for _, channel := range channels {
+		subs := f.subs[channel]
+		for i, c := range subs {
+			if c == ch {
+				f.subs[channel] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(f.subs[channel]) == 0 {
+			delete(f.subs, channel)
+		}
+	}
+	// Close已经关闭了所有通道
+	if !f.closed {
+		close(ch)
+	}
+}
+
+func (f *Fake) Backend() string {
+	return "fake"
+}
+
+// Capabilities 除信号量和计数器外的能力都可用，锁只在当前实例内生效
+func (f *Fake) Capabilities() cache.CacheCapabilities {
+	return cache.CacheCapabilities{
+		Write:    true,
+		Pipeline: true,
+		Lock:     true,
+		PubSub:   true,
+	}
+}
+
+// Raw Fake没有键前缀，返回自身
+func (f *Fake) Raw() cache.Cache {
+	return f
+}
+
+// Close 关闭所有订阅通道，已写入的数据仍然可以读取
+func (f *Fake) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.fail("Close"); err != nil {
+		return err
+	}
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	closed := make(map[chan cache.Message]struct{})
+	for _, subs := range f.subs {
+		for _, ch := range subs {
+			if _, ok := closed[ch]; !ok {
+				close(ch)
+				closed[ch] = struct{}{}
+			}
+		}
+	}
+	f.subs = make(map[string][]chan cache.Message)
+	return nil
+}
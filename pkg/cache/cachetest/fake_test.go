@@ -0,0 +1,114 @@
+package cachetest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+	"github.com/shaco-go/gkit-layout/pkg/cache/cachetest"
+)
+
+func TestFakeExpiry(t *testing.T) {
+	f := cachetest.NewFake()
+	ctx := context.Background()
+	if err := f.Set(ctx, "a", map[string]int{"x": 1}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(f.Entries()["a"]); got != `{"x":1}` {
+		t.Fatalf("Entries()[a] = %q", got)
+	}
+
+	f.Advance(59 * time.Second)
+	if ok, err := f.Exists(ctx, "a"); err != nil || !ok {
+		t.Fatalf("Exists before expiry = %v, %v", ok, err)
+	}
+	f.Advance(time.Second)
+	if _, err := f.GetRaw(ctx, "a"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("GetRaw after expiry = %v, want ErrNotFound", err)
+	}
+
+	// 锁同样随时钟过期
+	value, err := f.Lock(ctx, "l", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Lock(ctx, "l", time.Second); !errors.Is(err, cache.ErrLockAcquired) {
+		t.Fatalf("second Lock = %v, want ErrLockAcquired", err)
+	}
+	f.Advance(time.Second)
+	if err := f.Unlock(ctx, "l", value); !errors.Is(err, cache.ErrLockNotOwned) {
+		t.Fatalf("Unlock after expiry = %v, want ErrLockNotOwned", err)
+	}
+}
+
+func TestFakeForceError(t *testing.T) {
+	f := cachetest.NewFake()
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	f.ForceError("GetRaw", boom)
+	if _, err := f.GetRaw(ctx, "a"); !errors.Is(err, boom) {
+		t.Fatalf("GetRaw = %v, want injected error", err)
+	}
+	// 只影响注入的方法
+	if err := f.Set(ctx, "a", "v", time.Minute); err != nil {
+		t.Fatalf("Set = %v, want nil", err)
+	}
+
+	f.ForceError("GetRaw", nil)
+	if _, err := f.GetRaw(ctx, "a"); err != nil {
+		t.Fatalf("GetRaw after clearing = %v", err)
+	}
+}
+
+func TestFakeSaveAndPipeline(t *testing.T) {
+	f := cachetest.NewFake()
+	ctx := context.Background()
+	calls := 0
+	for i := 0; i < 2; i++ {
+		v, err := cache.Save(ctx, f, "s", func() (int, error) {
+			calls++
+			return 3, nil
+		}, time.Minute)
+		if err != nil || v != 3 {
+			t.Fatalf("Save = %d, %v", v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("loader calls = %d, want 1", calls)
+	}
+
+	err := f.Pipeline(ctx, func(p cache.Pipeliner) error {
+		p.Incr("n", 2)
+		p.Incr("n", 3)
+		p.Delete("s")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := cache.Get[int](ctx, f, "n"); err != nil || n != 5 {
+		t.Fatalf("Get(n) = %d, %v, want 5", n, err)
+	}
+	if ok, _ := f.Exists(ctx, "s"); ok {
+		t.Error("s should be deleted by the pipeline")
+	}
+}
+
+func TestFakeActiveLocks(t *testing.T) {
+	f := cachetest.NewFake()
+	ctx := context.Background()
+	token, err := f.Lock(ctx, "k", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locks, err := f.ActiveLocks(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 1 || locks[0].Token != token || locks[0].TTL != time.Second {
+		t.Fatalf("锁列表不正确: %+v", locks)
+	}
+}
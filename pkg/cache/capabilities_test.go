@@ -0,0 +1,53 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestCapabilities(t *testing.T) {
+	redisCache, _ := newTestRedis(t)
+	tests := []struct {
+		name    string
+		c       cache.Cache
+		backend string
+		want    cache.CacheCapabilities
+	}{
+		{
+			name:    "memory",
+			c:       newTestMemory(t),
+			backend: "memory",
+			want:    cache.CacheCapabilities{Write: true, Pipeline: true, Lock: true, Semaphore: true, Counter: true},
+		},
+		{
+			name:    "redis",
+			c:       redisCache,
+			backend: "redis",
+			want: cache.CacheCapabilities{Write: true, Pipeline: true, Lock: true, DistributedLock: true,
+				PubSub: true, Semaphore: true, Counter: true},
+		},
+		{
+			name:    "read-only memory",
+			c:       newTestMemory(t, cache.WithReadOnly()),
+			backend: "memory",
+			want:    cache.CacheCapabilities{},
+		},
+		{
+			name:    "chain",
+			c:       cache.NewChain(newTestMemory(t), redisCache),
+			backend: "chain",
+			want:    cache.CacheCapabilities{Write: true, Pipeline: true, Lock: true, DistributedLock: true, PubSub: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.Backend(); got != tt.backend {
+				t.Errorf("Backend() = %q, want %q", got, tt.backend)
+			}
+			if got := tt.c.Capabilities(); got != tt.want {
+				t.Errorf("Capabilities() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
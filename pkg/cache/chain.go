@@ -0,0 +1,344 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultChainBackfillExpiration 读取命中后回填到前面各级缓存的默认过期时间
+const defaultChainBackfillExpiration = time.Minute
+
+// chainLockSeparator 组合锁标识符的分隔符
+const chainLockSeparator = "|"
+
+// chainCache 多级缓存，按顺序排列，通常为 内存 -> Redis -> 返回默认值的兜底缓存
+type chainCache struct {
+	caches      []Cache
+	refreshers  *refresherGroup
+	backfillTTL time.Duration
+	raw         bool // Raw返回的视图，与原缓存共享刷新协程，不负责关闭
+}
+
+// NewChain 将多个缓存组合为一个多级缓存
+//   - 读取: 按顺序查找，命中后以1分钟的过期时间回填到前面的各级缓存，需要其他过期时间时使用NewChainWithBackfill
+//   - 写入: 从最后一级开始依次写入所有缓存，返回所有失败的错误
+//   - SaveRaw: 未命中时由最后一级缓存加载(使用其防击穿锁)，再回填前面的各级
+//   - Lock/Unlock: 依次在所有缓存上加锁，任意一级失败会释放已获取的锁，标识符由各级的标识符组合而成
+//   - Pipeline: fn只执行一次，记录的操作依次提交到每一级
+//   - Publish/Subscribe: 使用最后一个支持发布订阅的缓存
+//   - RegisterRefresher: 刷新协程由多级缓存持有，刷新结果写入所有层
+func NewChain(caches ...Cache) Cache {
+	return NewChainWithBackfill(defaultChainBackfillExpiration, caches...)
+}
+
+// NewChainWithBackfill 与NewChain相同，读取命中后以backfillTTL回填前面的各级缓存
+// 回填的过期时间应不超过后面各级缓存中值的过期时间，否则前面各级可能在源数据过期后仍返回旧值
+// 参数:
+//   - backfillTTL: 回填的过期时间，小于等于0时使用默认的1分钟
+//   - caches: 各级缓存，按读取顺序排列
+//
+// 返回:
+//   - Cache: 多级缓存
+func NewChainWithBackfill(backfillTTL time.Duration, caches ...Cache) Cache {
+	if backfillTTL <= 0 {
+		backfillTTL = defaultChainBackfillExpiration
+	}
+	return &chainCache{caches: caches, refreshers: newRefresherGroup(log.Logger), backfillTTL: backfillTTL}
+}
+
+// fanOut 从最后一级开始对每个缓存执行fn，合并所有错误
+func (c *chainCache) fanOut(fn func(Cache) error) error {
+	var errs error
+	for i := len(c.caches) - 1; i >= 0; i-- {
+		if err := fn(c.caches[i]); err != nil {
+			errs = errors.CombineErrors(errs, err)
+		}
+	}
+	return errs
+}
+
+// backfill 将命中的值写入level之前的各级缓存，回填失败不影响读取结果
+func (c *chainCache) backfill(ctx context.Context, level int, key string, data []byte, expiration time.Duration) {
+	for i := 0; i < level; i++ {
+		_ = c.caches[i].Set(ctx, key, data, expiration)
+	}
+}
+
+func (c *chainCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.fanOut(func(cache Cache) error {
+		return cache.Set(ctx, key, value, expiration)
+	})
+}
+
+func (c *chainCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.fanOut(func(cache Cache) error {
+		return cache.SetCoalesced(ctx, key, value, expiration)
+	})
+}
+
+func (c *chainCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	for i, cache := range c.caches {
+		data, err := cache.GetRaw(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.backfill(ctx, i, key, data, c.backfillTTL)
+		return data, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (c *chainCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	missing := keys
+	for i, cache := range c.caches {
+		if len(missing) == 0 {
+			break
+		}
+		found, err := cache.MGetRaw(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make([]string, 0, len(missing)-len(found))
+		for _, key := range missing {
+			data, ok := found[key]
+			if !ok {
+				next = append(next, key)
+				continue
+			}
+			result[key] = data
+			c.backfill(ctx, i, key, data, c.backfillTTL)
+		}
+		missing = next
+	}
+	return result, nil
+}
+
+func (c *chainCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	return c.fanOut(func(cache Cache) error {
+		return cache.MSet(ctx, values, expiration)
+	})
+}
+
+func (c *chainCache) Exists(ctx context.Context, key string) (bool, error) {
+	for _, cache := range c.caches {
+		ok, err := cache.Exists(ctx, key)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (c *chainCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	if len(c.caches) == 0 {
+		return fn()
+	}
+
+	// 前面各级命中时直接返回，最后一级交给其SaveRaw处理
+	last := len(c.caches) - 1
+	if !newSaveOptions(ctx, options).ForceRefresh {
+		for i, cache := range c.caches[:last] {
+			data, err := cache.GetRaw(ctx, key)
+			if err == nil {
+				c.backfill(ctx, i, key, data, c.backfillTTL)
+				return data, nil
+			}
+			if !errors.Is(err, ErrNotFound) {
+				return nil, err
+			}
+		}
+	}
+
+	data, err := c.caches[last].SaveRaw(ctx, key, fn, expiration, options...)
+	if err != nil {
+		// 旧值或兜底值不回填到前面各级
+		if IsFallback(err) {
+			return data, err
+		}
+		return nil, err
+	}
+	c.backfill(ctx, last, key, data, newSaveOptions(ctx, options).expiration(data, expiration))
+	return data, nil
+}
+
+// SetIfNewer 写入所有缓存，返回最后一级的结果
+func (c *chainCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	var written bool
+	err := c.fanOut(func(cache Cache) error {
+		ok, err := cache.SetIfNewer(ctx, key, value, ts, expiration)
+		if cache == c.caches[len(c.caches)-1] {
+			written = ok
+		}
+		return err
+	})
+	return written, err
+}
+
+func (c *chainCache) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	for i, cache := range c.caches {
+		data, ts, err := cache.GetWithTimestamp(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, earlier := range c.caches[:i] {
+			_, _ = earlier.SetIfNewer(ctx, key, data, ts, c.backfillTTL)
+		}
+		return data, ts, nil
+	}
+	return nil, 0, ErrNotFound
+}
+
+func (c *chainCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	p := &pipeline{}
+	if err := fn(p); err != nil {
+		return err
+	}
+	if p.err != nil {
+		return p.err
+	}
+	return c.fanOut(func(cache Cache) error {
+		return cache.Pipeline(ctx, func(dst Pipeliner) error {
+			p.replay(dst)
+			return nil
+		})
+	})
+}
+
+// RegisterRefresher 刷新结果写入所有层
+func (c *chainCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.refreshers.register(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *chainCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.refreshers.register(target, key, loader, ttl, refreshBefore)
+}
+
+func (c *chainCache) stopRefreshers() {
+	c.refreshers.stop()
+}
+
+func (c *chainCache) Lock(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	values := make([]string, 0, len(c.caches))
+	for i, cache := range c.caches {
+		value, err := cache.Lock(ctx, key, expiration)
+		if err != nil {
+			// 释放已获取的锁
+			for j := i - 1; j >= 0; j-- {
+				_ = c.caches[j].Unlock(ctx, key, values[j])
+			}
+			return "", err
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, chainLockSeparator), nil
+}
+
+func (c *chainCache) Unlock(ctx context.Context, key string, value string) error {
+	values := strings.Split(value, chainLockSeparator)
+	if len(values) != len(c.caches) {
+		return ErrLockNotOwned
+	}
+	var errs error
+	for i := len(c.caches) - 1; i >= 0; i-- {
+		if err := c.caches[i].Unlock(ctx, key, values[i]); err != nil {
+			errs = errors.CombineErrors(errs, err)
+		}
+	}
+	return errs
+}
+
+// ActiveLocks 合并每一层的锁，同一个锁在每一层各出现一次
+func (c *chainCache) ActiveLocks(ctx context.Context) ([]LockInfo, error) {
+	var locks []LockInfo
+	for _, cache := range c.caches {
+		tierLocks, err := cache.ActiveLocks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, tierLocks...)
+	}
+	sortLocks(locks)
+	return locks, nil
+}
+
+// pubSub 返回最后一个支持发布订阅的缓存
+func (c *chainCache) pubSub(try func(Cache) error) error {
+	for i := len(c.caches) - 1; i >= 0; i-- {
+		if err := try(c.caches[i]); !errors.Is(err, ErrNotSupported) {
+			return err
+		}
+	}
+	return ErrNotSupported
+}
+
+func (c *chainCache) Publish(ctx context.Context, channel string, message []byte) error {
+	return c.pubSub(func(cache Cache) error {
+		return cache.Publish(ctx, channel, message)
+	})
+}
+
+func (c *chainCache) Subscribe(ctx context.Context, channels ...string) (<-chan Message, func(), error) {
+	var (
+		ch     <-chan Message
+		cancel func()
+	)
+	err := c.pubSub(func(cache Cache) error {
+		var err error
+		ch, cancel, err = cache.Subscribe(ctx, channels...)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch, cancel, nil
+}
+
+func (c *chainCache) Backend() string {
+	return "chain"
+}
+
+// Capabilities 写入、Pipeline和锁需要所有层都支持，任意一层为分布式锁时锁即跨进程生效
+func (c *chainCache) Capabilities() CacheCapabilities {
+	caps := CacheCapabilities{Write: true, Pipeline: true, Lock: true}
+	for _, tier := range c.caches {
+		tc := tier.Capabilities()
+		caps.Write = caps.Write && tc.Write
+		caps.Pipeline = caps.Pipeline && tc.Pipeline
+		caps.Lock = caps.Lock && tc.Lock
+		caps.DistributedLock = caps.DistributedLock || tc.DistributedLock
+		caps.PubSub = caps.PubSub || tc.PubSub
+	}
+	caps.DistributedLock = caps.DistributedLock && caps.Lock
+	return caps
+}
+
+func (c *chainCache) Raw() Cache {
+	caches := make([]Cache, len(c.caches))
+	for i, cache := range c.caches {
+		caches[i] = cache.Raw()
+	}
+	// 与原缓存共享刷新协程，由原缓存负责停止
+	return &chainCache{caches: caches, refreshers: c.refreshers, backfillTTL: c.backfillTTL, raw: true}
+}
+
+func (c *chainCache) Close() error {
+	if c.raw {
+		return nil
+	}
+	c.refreshers.stop()
+	return c.fanOut(func(cache Cache) error {
+		return cache.Close()
+	})
+}
@@ -0,0 +1,63 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestChainBackfillExpiration(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		chain func(l1, l2 cache.Cache) cache.Cache
+		want  time.Duration
+	}{
+		{name: "default", chain: func(l1, l2 cache.Cache) cache.Cache { return cache.NewChain(l1, l2) }, want: time.Minute},
+		{name: "configured", chain: func(l1, l2 cache.Cache) cache.Cache { return cache.NewChainWithBackfill(5*time.Second, l1, l2) }, want: 5 * time.Second},
+		{name: "non-positive", chain: func(l1, l2 cache.Cache) cache.Cache { return cache.NewChainWithBackfill(0, l1, l2) }, want: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l1, mr := newTestRedis(t)
+			l2 := newTestMemory(t)
+			if err := l2.Set(ctx, "k", []byte("v"), time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := tt.chain(l1, l2).GetRaw(ctx, "k")
+			if err != nil || string(data) != "v" {
+				t.Fatalf("GetRaw = %q, %v", data, err)
+			}
+			if got := mr.TTL("k"); got != tt.want {
+				t.Errorf("backfill TTL = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainRawSharesRefreshers(t *testing.T) {
+	ch := cache.NewChain(newTestMemory(t), newTestMemory(t))
+	raw := ch.Raw()
+	loader := func(ctx context.Context) ([]byte, error) { return []byte("v"), nil }
+
+	if err := raw.RegisterRefresher("k", loader, time.Minute, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	// 关闭视图不影响原缓存的刷新协程
+	if err := raw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.RegisterRefresher("k2", loader, time.Minute, time.Second); err != nil {
+		t.Fatalf("RegisterRefresher after raw Close: %v", err)
+	}
+	// 关闭原缓存同时停止视图注册的刷新协程
+	if err := ch.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := raw.RegisterRefresher("k3", loader, time.Minute, time.Second); err == nil {
+		t.Error("RegisterRefresher on raw view after Close succeeded, want error")
+	}
+}
@@ -0,0 +1,36 @@
+package cache
+
+import "time"
+
+// clock 时间来源，默认使用系统时间，测试时可以通过withClock注入可控的时钟，使过期相关的测试不依赖真实等待
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock 系统时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// withClock 替换缓存使用的时钟，只用于测试
+func withClock(c clock) Option {
+	return func(o *Options) {
+		o.clock = c
+	}
+}
+
+// freecacheTimer 让freecache按clock计算过期时间
+type freecacheTimer struct {
+	clock clock
+}
+
+func (t freecacheTimer) Now() uint32 {
+	return uint32(t.clock.Now().Unix())
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeClock 手动推进的时钟，After在Advance越过截止时间时触发
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance 推进时钟并触发已到期的After
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// newClockCache 创建使用fakeClock的缓存
+func newClockCache(t *testing.T, clk *fakeClock, opts ...Option) Cache {
+	t.Helper()
+	c, err := New(append([]Option{withClock(clk)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// newClockRedis 创建使用fakeClock、连接到miniredis的缓存，miniredis的过期需要通过FastForward推进
+func newClockRedis(t *testing.T, clk *fakeClock, opts ...Option) (Cache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return newClockCache(t, clk, append([]Option{WithRedis(client)}, opts...)...), mr
+}
+
+func TestClockMemoryExpiry(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "freecache", opts: []Option{WithMemory()}},
+		{name: "lru", opts: []Option{WithMemory(), WithLRU(10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clk := newFakeClock()
+			c := newClockCache(t, clk, tt.opts...)
+			if err := c.Set(ctx, "k", "v", 2*time.Second); err != nil {
+				t.Fatal(err)
+			}
+
+			clk.Advance(time.Second)
+			if _, err := c.GetRaw(ctx, "k"); err != nil {
+				t.Fatalf("GetRaw before expiry: %v", err)
+			}
+			clk.Advance(2 * time.Second)
+			if _, err := c.GetRaw(ctx, "k"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("GetRaw after expiry err = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestClockLRURemainingTTL(t *testing.T) {
+	clk := newFakeClock()
+	s := newLRUStore(10, clk)
+	if err := s.Set([]byte("k"), []byte("v"), 10); err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(4 * time.Second)
+	if ttl, err := s.TTL([]byte("k")); err != nil || ttl != 6 {
+		t.Fatalf("TTL = %d, %v, want 6", ttl, err)
+	}
+}
@@ -0,0 +1,303 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog"
+	gkit_zerolog "github.com/shaco-go/gkit-layout/pkg/zerolog"
+)
+
+// pendingWrite 等待刷新的写入，gen用于判断刷新期间是否被新的写入替换
+type pendingWrite struct {
+	data       []byte
+	expiration time.Duration
+	gen        uint64
+}
+
+// coalescingKeyLocks 按key分段的锁数量
+const coalescingKeyLocks = 64
+
+// coalescingCache 合并写入的缓存，SetCoalesced写入的值先缓冲，每个周期最多向后端写入一次
+// 刷新时缓冲值移入inflight，写入后端完成之前读取仍然返回该值；
+// flush与Set、MSet、Pipeline按key互斥，直接写入的新值不会被正在刷新的旧值覆盖
+type coalescingCache struct {
+	Cache
+	mu       sync.Mutex
+	pending  map[string]pendingWrite
+	inflight map[string]pendingWrite
+	gen      uint64
+	keyLocks [coalescingKeyLocks]sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+
+	unregister func()
+	closeOnce  sync.Once
+	closeErr   error
+}
+
+func newCoalescingCache(c Cache, interval time.Duration, logger zerolog.Logger) Cache {
+	cc := &coalescingCache{
+		Cache:    c,
+		pending:  make(map[string]pendingWrite),
+		inflight: make(map[string]pendingWrite),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	// 进程退出前通过CloseAll写入缓冲的值
+	cc.unregister = Register(cc)
+
+	gkit_zerolog.Go(logger, func() {
+		defer close(cc.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = cc.flush(context.Background())
+			case <-cc.stop:
+				return
+			}
+		}
+	})
+
+	return cc
+}
+
+// SetCoalesced 缓冲key的最新值，在下一个刷新周期写入后端
+func (c *coalescingCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	data, ok := value.([]byte)
+	if !ok {
+		var err error
+		data, err = Marshal(value)
+		if err != nil {
+			return wrapSerializationError(err, "cache: failed to marshal value")
+		}
+	}
+
+	c.mu.Lock()
+	c.gen++
+	c.pending[key] = pendingWrite{data: data, expiration: expiration, gen: c.gen}
+	c.mu.Unlock()
+	return nil
+}
+
+// lockKeys 锁定keys所在的分段，按分段顺序加锁避免死锁，返回解锁函数
+func (c *coalescingCache) lockKeys(keys ...string) (unlock func()) {
+	idx := make([]int, 0, len(keys))
+	for _, key := range keys {
+		idx = append(idx, int(xxhash.Sum64String(key)%coalescingKeyLocks))
+	}
+	slices.Sort(idx)
+	idx = slices.Compact(idx)
+	for _, i := range idx {
+		c.keyLocks[i].Lock()
+	}
+	return func() {
+		for _, i := range idx {
+			c.keyLocks[i].Unlock()
+		}
+	}
+}
+
+// discard 丢弃keys尚未刷新和正在刷新的缓冲值，调用方需要持有keys的锁
+func (c *coalescingCache) discard(keys ...string) {
+	c.mu.Lock()
+	for _, key := range keys {
+		delete(c.pending, key)
+		delete(c.inflight, key)
+	}
+	c.mu.Unlock()
+}
+
+// Set 直接写入后端，并丢弃该key的缓冲值，避免旧值覆盖新值
+func (c *coalescingCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	unlock := c.lockKeys(key)
+	defer unlock()
+	c.discard(key)
+	return c.Cache.Set(ctx, key, value, expiration)
+}
+
+func (c *coalescingCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	unlock := c.lockKeys(keys...)
+	defer unlock()
+	c.discard(keys...)
+	return c.Cache.MSet(ctx, values, expiration)
+}
+
+// Pipeline 提交前丢弃管道中涉及的key的缓冲值，并锁定这些key直到提交完成，Incr之前先把缓冲值写入同一个管道
+func (c *coalescingCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	unlock := func() {}
+	defer func() { unlock() }()
+	return c.Cache.Pipeline(ctx, func(p Pipeliner) error {
+		cp := &coalescingPipeliner{Pipeliner: p, c: c}
+		if err := fn(cp); err != nil {
+			return err
+		}
+
+		unlock = c.lockKeys(cp.keys...)
+		c.discard(cp.keys...)
+		return nil
+	})
+}
+
+// coalescingPipeliner 记录管道涉及的key
+type coalescingPipeliner struct {
+	Pipeliner
+	c    *coalescingCache
+	keys []string
+}
+
+func (p *coalescingPipeliner) Set(key string, value any, expiration time.Duration) {
+	p.keys = append(p.keys, key)
+	p.Pipeliner.Set(key, value, expiration)
+}
+
+func (p *coalescingPipeliner) Delete(keys ...string) {
+	p.keys = append(p.keys, keys...)
+	p.Pipeliner.Delete(keys...)
+}
+
+func (p *coalescingPipeliner) Incr(key string, delta int64) {
+	if w, ok := p.c.bufferedWrite(key); ok {
+		p.Pipeliner.Set(key, w.data, w.expiration)
+	}
+	p.keys = append(p.keys, key)
+	p.Pipeliner.Incr(key, delta)
+}
+
+// bufferedWrite 返回key尚未写入后端的缓冲值，等待刷新的值比正在刷新的值更新
+func (c *coalescingCache) bufferedWrite(key string) (pendingWrite, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if w, ok := c.pending[key]; ok {
+		return w, true
+	}
+	w, ok := c.inflight[key]
+	return w, ok
+}
+
+// buffered 返回key尚未写入后端的缓冲值
+func (c *coalescingCache) buffered(key string) ([]byte, bool) {
+	w, ok := c.bufferedWrite(key)
+	return w.data, ok
+}
+
+func (c *coalescingCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	if data, ok := c.buffered(key); ok && !IsBypass(ctx) {
+		return data, nil
+	}
+	return c.Cache.GetRaw(ctx, key)
+}
+
+func (c *coalescingCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result, err := c.Cache.MGetRaw(ctx, keys)
+	if err != nil || IsBypass(ctx) {
+		return result, err
+	}
+	for _, key := range keys {
+		if data, ok := c.buffered(key); ok {
+			result[key] = data
+		}
+	}
+	return result, nil
+}
+
+func (c *coalescingCache) Exists(ctx context.Context, key string) (bool, error) {
+	if _, ok := c.buffered(key); ok {
+		return true, nil
+	}
+	return c.Cache.Exists(ctx, key)
+}
+
+func (c *coalescingCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	if data, ok := c.buffered(key); ok && !newSaveOptions(ctx, options).ForceRefresh {
+		return data, nil
+	}
+	return c.Cache.SaveRaw(ctx, key, fn, expiration, options...)
+}
+
+// flush 将缓冲的值写入后端，写入失败的值在没有被更新时保留到下个周期重试
+// 缓冲值在写入完成之前保留在inflight中，读取仍能看到；被Set等直接写入丢弃的值不再写入
+func (c *coalescingCache) flush(ctx context.Context) error {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[string]pendingWrite, len(pending))
+	for key, w := range pending {
+		c.inflight[key] = w
+	}
+	c.mu.Unlock()
+
+	var errs error
+	for key, w := range pending {
+		errs = errors.CombineErrors(errs, c.flushKey(ctx, key, w))
+	}
+	return errs
+}
+
+// flushKey 持有key的锁写入一个缓冲值
+func (c *coalescingCache) flushKey(ctx context.Context, key string, w pendingWrite) error {
+	unlock := c.lockKeys(key)
+	defer unlock()
+
+	c.mu.Lock()
+	cur, ok := c.inflight[key]
+	c.mu.Unlock()
+	if !ok || cur.gen != w.gen {
+		return nil
+	}
+
+	err := c.Cache.Set(ctx, key, w.data, w.expiration)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cur, ok := c.inflight[key]; ok && cur.gen == w.gen {
+		delete(c.inflight, key)
+	}
+	if err != nil {
+		if _, ok := c.pending[key]; !ok {
+			c.pending[key] = w
+		}
+	}
+	return err
+}
+
+// RegisterRefresher 刷新结果同样经过当前装饰器写入
+func (c *coalescingCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.registerRefresher(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *coalescingCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		return r.registerRefresher(target, key, loader, ttl, refreshBefore)
+	}
+	return c.Cache.RegisterRefresher(key, loader, ttl, refreshBefore)
+}
+
+func (c *coalescingCache) stopRefreshers() {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		r.stopRefreshers()
+	}
+}
+
+// Close 停止主动刷新和合并写入协程，写入剩余的缓冲值后关闭后端
+// Close 可以重复调用，CloseAll已经关闭时返回第一次关闭的结果
+func (c *coalescingCache) Close() error {
+	c.closeOnce.Do(func() {
+		c.unregister()
+		c.stopRefreshers()
+		close(c.stop)
+		<-c.done
+		err := c.flush(context.Background())
+		c.closeErr = errors.CombineErrors(err, c.Cache.Close())
+	})
+	return c.closeErr
+}
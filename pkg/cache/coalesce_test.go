@@ -0,0 +1,190 @@
+package cache_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// countHook 统计执行的redis命令，包括管道中的命令
+type countHook struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (h *countHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *countHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.record(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *countHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			h.record(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (h *countHook) record(cmd redis.Cmder) {
+	h.mu.Lock()
+	h.names = append(h.names, strings.ToLower(cmd.Name()))
+	h.mu.Unlock()
+}
+
+// count 返回名称为name的命令执行次数
+func (h *countHook) count(name string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, v := range h.names {
+		if v == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWriteCoalescing(t *testing.T) {
+	hook := &countHook{}
+	c, mr := newHookedRedis(t, hook, cache.WithWriteCoalescing(50*time.Millisecond))
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		if err := c.SetCoalesced(ctx, "k", i, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if mr.Exists("k") {
+		t.Fatal("刷新周期之前不应写入后端")
+	}
+	if v, err := cache.Get[int](ctx, c, "k"); err != nil || v != 99 {
+		t.Fatalf("应读到缓冲的最新值，实际%d %v", v, err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !mr.Exists("k") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s, _ := mr.Get("k"); s != "99" {
+		t.Fatalf("后端应写入最终值99，实际%q", s)
+	}
+	if n := hook.count("set"); n != 1 {
+		t.Fatalf("100次合并写入应只产生1次后端写入，实际%d次", n)
+	}
+}
+
+func TestWriteCoalescingFlushOnClose(t *testing.T) {
+	c, mr := newTestRedis(t, cache.WithWriteCoalescing(time.Hour))
+	if err := c.SetCoalesced(context.Background(), "k", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := mr.Get("k"); s != `"v"` {
+		t.Fatalf("Close应写入缓冲的值，实际%q", s)
+	}
+}
+
+// blockSetHook 阻塞写入值为value的SET命令，直到release关闭，用于模拟刷新过程中的慢写入
+type blockSetHook struct {
+	value   string
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (h *blockSetHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h *blockSetHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		h.wait(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (h *blockSetHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			h.wait(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (h *blockSetHook) wait(cmd redis.Cmder) {
+	args := cmd.Args()
+	if strings.ToLower(cmd.Name()) != "set" || len(args) < 3 {
+		return
+	}
+	if v, ok := args[2].([]byte); !ok || string(v) != h.value {
+		return
+	}
+	h.once.Do(func() { close(h.started) })
+	<-h.release
+}
+
+func TestWriteCoalescingSetDuringFlush(t *testing.T) {
+	hook := &blockSetHook{value: `"v1"`, started: make(chan struct{}), release: make(chan struct{})}
+	c, mr := newHookedRedis(t, hook, cache.WithWriteCoalescing(10*time.Millisecond))
+	ctx := context.Background()
+	// 测试提前失败时也放行刷新，避免关闭缓存时一直等待
+	var releaseOnce sync.Once
+	release := func() { releaseOnce.Do(func() { close(hook.release) }) }
+	t.Cleanup(release)
+
+	if err := c.SetCoalesced(ctx, "k", "v1", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-hook.started:
+	case <-time.After(time.Second):
+		t.Fatal("刷新没有开始")
+	}
+
+	// 刷新尚未写入后端时仍能读到缓冲的值
+	if mr.Exists("k") {
+		t.Fatal("刷新完成之前后端不应有值")
+	}
+	if v, err := cache.Get[string](ctx, c, "k"); err != nil || v != "v1" {
+		t.Fatalf("刷新期间应读到缓冲的值，实际%q %v", v, err)
+	}
+
+	// 直接写入等待正在刷新的旧值写完，之后写入的新值不会被覆盖
+	setDone := make(chan error, 1)
+	go func() { setDone <- c.Set(ctx, "k", "v2", time.Minute) }()
+	select {
+	case err := <-setDone:
+		t.Fatalf("Set应等待正在进行的刷新，实际已返回%v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	if err := <-setDone; err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := mr.Get("k"); s != `"v2"` {
+		t.Fatalf("后端应为直接写入的新值，实际%q", s)
+	}
+	if v, err := cache.Get[string](ctx, c, "k"); err != nil || v != "v2" {
+		t.Fatalf("应读到直接写入的新值，实际%q %v", v, err)
+	}
+}
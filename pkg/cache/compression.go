@@ -0,0 +1,366 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// compressionMagic 压缩标记的开头，0xfe不会出现在合法的UTF-8和JSON中，后面一个字节为编码方式
+var compressionMagic = []byte{0xfe, 'z'}
+
+const (
+	// compressionCodecNone 未压缩，原值恰好以压缩标记开头时使用，避免读取时误判
+	compressionCodecNone byte = 0
+	// compressionCodecFlate DEFLATE压缩
+	compressionCodecFlate byte = 1
+)
+
+// compressionHeaderSize 标记和编码方式的长度
+const compressionHeaderSize = 3
+
+// compressionMinSaving 压缩后至少减少10%才视为有收益
+const compressionMinSaving = 0.1
+
+// compressionResampleEvery 自适应模式下没有收益的key每写入多少次重新尝试压缩一次，值的内容可能已经变化
+const compressionResampleEvery = 16
+
+// compressionStatsLimit 自适应模式最多记录的key数，超过后清空重新统计
+const compressionStatsLimit = 10000
+
+// CompressionHint 告诉缓存写入的值是否值得压缩
+type CompressionHint int
+
+const (
+	// CompressionAuto 按阈值判断，开启自适应时同时参考该key之前的压缩效果
+	CompressionAuto CompressionHint = iota
+	// CompressionAlways 不论大小和之前的效果都尝试压缩，适用于JSON等文本，压缩后没有变小时仍保存原值
+	CompressionAlways
+	// CompressionNever 不压缩，适用于图片、已压缩的数据等二进制值
+	CompressionNever
+)
+
+type compressionHintKey struct{}
+
+// WithCompressionHint 返回带有压缩提示的ctx，使用该ctx的Set、MSet、SaveRaw和Pipeline写入时按提示决定是否压缩
+// 没有开启WithCompression时提示不生效
+func WithCompressionHint(ctx context.Context, hint CompressionHint) context.Context {
+	return context.WithValue(ctx, compressionHintKey{}, hint)
+}
+
+// compressionHintFrom 返回ctx中的压缩提示，没有时为CompressionAuto
+func compressionHintFrom(ctx context.Context) CompressionHint {
+	hint, _ := ctx.Value(compressionHintKey{}).(CompressionHint)
+	return hint
+}
+
+// SetTyped 按压缩提示写入缓存，等同于使用WithCompressionHint的ctx调用Set
+// 读取时根据值中的标记解压，与写入时的提示无关
+func SetTyped(ctx context.Context, c Cache, key string, value any, expiration time.Duration, hint CompressionHint) error {
+	return c.Set(WithCompressionHint(ctx, hint), key, value, expiration)
+}
+
+// flateWriters 复用压缩器，创建flate.Writer的开销较大
+var flateWriters = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// compressionStat 自适应模式下单个key的压缩效果
+type compressionStat struct {
+	skip   bool   // 上次压缩没有收益
+	writes uint32 // 跳过压缩后的写入次数
+}
+
+// compressedCache 写入前压缩达到阈值的值，读取时按值中的标记解压，没有标记的值原样返回
+// 空值和十进制整数不压缩，以便Pipeline的Incr和防穿透的空值正常工作
+type compressedCache struct {
+	Cache
+	threshold int
+	adaptive  bool
+	maxSize   int // 解压后的最大字节数，超过时读取返回错误
+
+	mu    sync.Mutex
+	stats map[string]*compressionStat
+}
+
+func newCompressedCache(c Cache, threshold int, adaptive bool, maxSize int) Cache {
+	return &compressedCache{Cache: c, threshold: threshold, adaptive: adaptive, maxSize: maxSize, stats: make(map[string]*compressionStat)}
+}
+
+// compressionMaxSize 解压后的最大字节数，与Redis单个值的上限一致
+// freecache的大小限制作用于压缩后的数据，压缩正是为了让较大的值放得下，因此内存缓存同样使用该上限
+const compressionMaxSize = redisMaxValueSize
+
+// shouldTry 判断本次写入是否尝试压缩
+func (c *compressedCache) shouldTry(key string, size int, hint CompressionHint) bool {
+	switch hint {
+	case CompressionNever:
+		return false
+	case CompressionAlways:
+		return true
+	}
+	if size < c.threshold {
+		return false
+	}
+	if !c.adaptive {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stat, ok := c.stats[key]
+	if !ok || !stat.skip {
+		return true
+	}
+	stat.writes++
+	return stat.writes%compressionResampleEvery == 0
+}
+
+// record 自适应模式下记录本次压缩是否有收益
+func (c *compressedCache) record(key string, beneficial bool) {
+	if !c.adaptive {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stat, ok := c.stats[key]
+	if !ok {
+		if beneficial {
+			// 有收益的key按阈值处理即可，不需要记录
+			return
+		}
+		if len(c.stats) >= compressionStatsLimit {
+			c.stats = make(map[string]*compressionStat)
+		}
+		stat = &compressionStat{}
+		c.stats[key] = stat
+	}
+	stat.skip = !beneficial
+	stat.writes = 0
+}
+
+// compress 按提示压缩，格式为 标记 + 编码方式 + 数据
+func (c *compressedCache) compress(ctx context.Context, key string, data []byte) ([]byte, error) {
+	if len(data) == 0 || isIntegerValue(data) {
+		return data, nil
+	}
+	hint := compressionHintFrom(ctx)
+	// 超过大小上限的值无法在读取时解压，按原值写入，由后端返回ErrValueTooLarge
+	if len(data) <= c.maxSize && c.shouldTry(key, len(data), hint) {
+		var buf bytes.Buffer
+		buf.Grow(len(data) / 2)
+		buf.Write(compressionMagic)
+		buf.WriteByte(compressionCodecFlate)
+		w := flateWriters.Get().(*flate.Writer)
+		w.Reset(&buf)
+		_, err := w.Write(data)
+		if err == nil {
+			err = w.Close()
+		}
+		flateWriters.Put(w)
+		if err != nil {
+			return nil, wrapSerializationError(err, "cache: failed to compress value")
+		}
+
+		beneficial := float64(buf.Len()) <= float64(len(data))*(1-compressionMinSaving)
+		if hint == CompressionAuto {
+			c.record(key, beneficial)
+		}
+		if beneficial {
+			return buf.Bytes(), nil
+		}
+	}
+	if hasCompressionMagic(data) {
+		plain := make([]byte, 0, compressionHeaderSize+len(data))
+		plain = append(plain, compressionMagic...)
+		plain = append(plain, compressionCodecNone)
+		return append(plain, data...), nil
+	}
+	return data, nil
+}
+
+// hasCompressionMagic 判断值是否以压缩标记开头
+func hasCompressionMagic(data []byte) bool {
+	return len(data) >= len(compressionMagic) && data[0] == compressionMagic[0] && data[1] == compressionMagic[1]
+}
+
+// decompress 按标记中的编码方式解压，没有压缩标记的值原样返回
+func (c *compressedCache) decompress(data []byte) ([]byte, error) {
+	if !hasCompressionMagic(data) {
+		return data, nil
+	}
+	if len(data) < compressionHeaderSize {
+		return nil, wrapSerializationError(errors.New("truncated compression header"), "cache: failed to decompress value")
+	}
+	switch data[len(compressionMagic)] {
+	case compressionCodecNone:
+		return data[compressionHeaderSize:], nil
+	case compressionCodecFlate:
+		r := flate.NewReader(bytes.NewReader(data[compressionHeaderSize:]))
+		defer r.Close()
+		// 多读一个字节判断是否超过上限，避免构造的数据解压后耗尽内存
+		plain, err := io.ReadAll(io.LimitReader(r, int64(c.maxSize)+1))
+		if err != nil {
+			return nil, wrapSerializationError(err, "cache: failed to decompress value")
+		}
+		if len(plain) > c.maxSize {
+			return nil, wrapSerializationError(errors.Wrapf(ErrValueTooLarge, "decompressed size exceeds %d bytes", c.maxSize),
+				"cache: failed to decompress value")
+		}
+		return plain, nil
+	default:
+		return nil, wrapSerializationError(errors.Newf("unknown codec %d", data[len(compressionMagic)]), "cache: failed to decompress value")
+	}
+}
+
+// encode 按Set的规则序列化value并压缩
+func (c *compressedCache) encode(ctx context.Context, key string, value any) ([]byte, error) {
+	data, ok := value.([]byte)
+	if !ok && value != nil {
+		var err error
+		data, err = Marshal(value)
+		if err != nil {
+			return nil, wrapSerializationError(err, "cache: failed to marshal value")
+		}
+	}
+	return c.compress(ctx, key, data)
+}
+
+func (c *compressedCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	data, err := c.encode(ctx, key, value)
+	if err != nil {
+		return err
+	}
+	return c.Cache.Set(ctx, key, data, expiration)
+}
+
+func (c *compressedCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.Set(ctx, key, value, expiration)
+}
+
+func (c *compressedCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	compressed := make(map[string]any, len(values))
+	for key, value := range values {
+		data, err := c.encode(ctx, key, value)
+		if err != nil {
+			return err
+		}
+		compressed[key] = data
+	}
+	return c.Cache.MSet(ctx, compressed, expiration)
+}
+
+func (c *compressedCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.Cache.GetRaw(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.decompress(data)
+}
+
+func (c *compressedCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result, err := c.Cache.MGetRaw(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for key, data := range result {
+		if result[key], err = c.decompress(data); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (c *compressedCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	data, err := c.Cache.SaveRaw(ctx, key, func() ([]byte, error) {
+		data, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		// 返回给调用方的值由下面统一解压
+		return c.compress(ctx, key, data)
+	}, expiration, decodeDynamicTTL(options, c.decompress)...)
+	if err != nil && !IsFallback(err) {
+		return nil, err
+	}
+	// 旧值是压缩后保存的，兜底值没有压缩标记会原样返回
+	data, decompressErr := c.decompress(data)
+	if decompressErr != nil {
+		return nil, decompressErr
+	}
+	return data, err
+}
+
+func (c *compressedCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	data, err := c.compress(ctx, key, value)
+	if err != nil {
+		return false, err
+	}
+	return c.Cache.SetIfNewer(ctx, key, data, ts, expiration)
+}
+
+func (c *compressedCache) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	data, ts, err := c.Cache.GetWithTimestamp(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err = c.decompress(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, ts, nil
+}
+
+// Pipeline Set的值同样压缩
+func (c *compressedCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	var compressErr error
+	return c.Cache.Pipeline(ctx, func(p Pipeliner) error {
+		if err := fn(&compressedPipeliner{Pipeliner: p, ctx: ctx, c: c, err: &compressErr}); err != nil {
+			return err
+		}
+		return compressErr
+	})
+}
+
+// compressedPipeliner 压缩Set的值，失败时由Pipeline返回错误，不提交任何操作
+type compressedPipeliner struct {
+	Pipeliner
+	ctx context.Context
+	c   *compressedCache
+	err *error
+}
+
+func (p *compressedPipeliner) Set(key string, value any, expiration time.Duration) {
+	data, err := p.c.encode(p.ctx, key, value)
+	if err != nil {
+		*p.err = errors.CombineErrors(*p.err, err)
+		return
+	}
+	p.Pipeliner.Set(key, data, expiration)
+}
+
+// RegisterRefresher 刷新结果同样压缩
+func (c *compressedCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.registerRefresher(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *compressedCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		return r.registerRefresher(target, key, loader, ttl, refreshBefore)
+	}
+	return c.Cache.RegisterRefresher(key, loader, ttl, refreshBefore)
+}
+
+func (c *compressedCache) stopRefreshers() {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		r.stopRefreshers()
+	}
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestDecompressLimit(t *testing.T) {
+	ctx := context.Background()
+	backend, err := New(WithMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = backend.Close() })
+
+	// 4KB的重复数据压缩后只有几十字节，解压上限为1KB时读取失败
+	writer := newCompressedCache(backend, 1, false, compressionMaxSize)
+	reader := newCompressedCache(backend, 1, false, 1024)
+	plain := bytes.Repeat([]byte("a"), 4096)
+	if err := writer.Set(ctx, "bomb", plain, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := backend.GetRaw(ctx, "bomb")
+	if err != nil || !hasCompressionMagic(stored) || len(stored) >= 1024 {
+		t.Fatalf("stored %d bytes, compressed %v, err %v", len(stored), hasCompressionMagic(stored), err)
+	}
+
+	_, err = reader.GetRaw(ctx, "bomb")
+	var be *BackendError
+	if !errors.As(err, &be) || be.Kind != KindSerialization || !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("GetRaw err = %v, want KindSerialization wrapping ErrValueTooLarge", err)
+	}
+
+	// 未超过上限时正常解压
+	if data, err := writer.GetRaw(ctx, "bomb"); err != nil || !bytes.Equal(data, plain) {
+		t.Fatalf("GetRaw = %d bytes, %v", len(data), err)
+	}
+}
+
+func TestCompressSkipsOversized(t *testing.T) {
+	ctx := context.Background()
+	backend, err := New(WithMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = backend.Close() })
+
+	// 超过上限的值按原值写入，读取时不需要解压
+	c := newCompressedCache(backend, 1, false, 1024)
+	plain := bytes.Repeat([]byte("a"), 2048)
+	if err := c.Set(ctx, "big", plain, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := backend.GetRaw(ctx, "big")
+	if err != nil || !bytes.Equal(stored, plain) {
+		t.Fatalf("stored %d bytes, %v, want uncompressed", len(stored), err)
+	}
+}
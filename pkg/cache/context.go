@@ -0,0 +1,17 @@
+package cache
+
+import "context"
+
+type bypassKey struct{}
+
+// WithBypass 返回带有绕过缓存标记的ctx
+// 携带该标记时GetRaw始终返回ErrNotFound，SaveRaw等同于开启WithForceRefresh，可用于中间件按请求排查问题
+func WithBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// IsBypass 判断ctx是否携带绕过缓存标记
+func IsBypass(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
@@ -0,0 +1,41 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestBypass(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		if err := c.Set(ctx, "k", []byte("cached"), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		bypass := cache.WithBypass(ctx)
+		if !cache.IsBypass(bypass) || cache.IsBypass(ctx) {
+			t.Fatal("IsBypass结果不正确")
+		}
+
+		// GetRaw始终未命中
+		if _, err := c.GetRaw(bypass, "k"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("期望ErrNotFound，实际%v", err)
+		}
+
+		// SaveRaw强制调用fn并写入新值
+		calls := 0
+		data, err := c.SaveRaw(bypass, "k", func() ([]byte, error) {
+			calls++
+			return []byte("fresh"), nil
+		}, time.Minute)
+		if err != nil || string(data) != "fresh" || calls != 1 {
+			t.Fatalf("got %q %v calls=%d", data, err, calls)
+		}
+		if data, err := c.GetRaw(ctx, "k"); err != nil || string(data) != "fresh" {
+			t.Fatalf("got %q %v", data, err)
+		}
+	})
+}
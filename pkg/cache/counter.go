@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Counter 按时间窗口分桶的计数器，键为 name:窗口开始时间(毫秒)，过期的桶会自动删除
+// 可用于限流和统计，内存缓存的过期精度为秒
+type Counter struct {
+	redis   *redisCache
+	memory  *memoryCache
+	sharded *shardedCache
+	clock   clock
+}
+
+// NewCounter 基于缓存创建计数器，键会添加KeyPrefix
+func NewCounter(c Cache) (*Counter, error) {
+	switch b := unwrapCache(c).(type) {
+	case *redisCache:
+		return &Counter{redis: b, clock: b.clock}, nil
+	case *shardedCache:
+		return &Counter{sharded: b, clock: b.shards[0].clock}, nil
+	case *memoryCache:
+		return &Counter{memory: b, clock: b.clock}, nil
+	case *readOnlyCache:
+		return nil, ErrReadOnly
+	default:
+		return nil, ErrNotSupported
+	}
+}
+
+// Add 将name在当前窗口的计数增加delta，返回增加后当前窗口的总数
+// 桶在窗口结束后再保留一个窗口，便于读取上一个窗口的数据
+func (c *Counter) Add(ctx context.Context, name string, window time.Duration, delta int64) (int64, error) {
+	if window <= 0 {
+		return 0, ErrInvalidParams
+	}
+	now := c.clock.Now()
+	start := now.Truncate(window)
+	key := name + ":" + strconv.FormatInt(start.UnixMilli(), 10)
+	ttl := start.Add(2 * window).Sub(now)
+
+	if c.memory != nil {
+		// freecache按秒过期，不足一秒会被当作永不过期，因此向上取整
+		ttl = (ttl + time.Second - 1).Truncate(time.Second)
+		fullKey := acquireKey(c.memory.prefix, key)
+		defer releaseKey(fullKey)
+
+		c.memory.mu.Lock()
+		defer c.memory.mu.Unlock()
+		return c.memory.incr(*fullKey, delta, ttl)
+	}
+
+	// 只在新建桶时设置过期时间，保证INCRBY和PEXPIRE的原子性
+	const luaScript = `
+local total = redis.call("INCRBY", KEYS[1], ARGV[1])
+if redis.call("PTTL", KEYS[1]) < 0 then
+    redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return total`
+
+	// 分片缓存按name路由，同一个计数器的所有桶在同一个实例
+	rc := c.redis
+	if c.sharded != nil {
+		rc = c.sharded.shard(name)
+	}
+	ctx, cancel := rc.operationContext(ctx)
+	defer cancel()
+	total, err := rc.client.Eval(ctx, luaScript, []string{joinKey(rc.prefix, key)}, delta, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, wrapBackendError(err, "cache: failed to add counter")
+	}
+	return total, nil
+}
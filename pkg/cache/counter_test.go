@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCounterWindow(t *testing.T) {
+	backends := map[string]func(t *testing.T, clk *fakeClock) (Cache, func(time.Duration)){
+		"memory": func(t *testing.T, clk *fakeClock) (Cache, func(time.Duration)) {
+			return newClockCache(t, clk, WithMemory()), clk.Advance
+		},
+		"redis": func(t *testing.T, clk *fakeClock) (Cache, func(time.Duration)) {
+			c, mr := newClockRedis(t, clk)
+			return c, func(d time.Duration) {
+				clk.Advance(d)
+				mr.FastForward(d)
+			}
+		},
+	}
+	for name, newBackend := range backends {
+		t.Run(name, func(t *testing.T) {
+			clk := newFakeClock()
+			c, advance := newBackend(t, clk)
+			counter, err := NewCounter(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := context.Background()
+			window := time.Minute
+			firstBucket := "req:" + strconv.FormatInt(clk.Now().Truncate(window).UnixMilli(), 10)
+
+			// 同一窗口内累加
+			for i, want := range []int64{2, 5, 6} {
+				got, err := counter.Add(ctx, "req", window, []int64{2, 3, 1}[i])
+				if err != nil || got != want {
+					t.Fatalf("第%d次期望%d，实际%d %v", i+1, want, got, err)
+				}
+			}
+			// 不同name互不影响
+			if got, err := counter.Add(ctx, "other", window, 1); err != nil || got != 1 {
+				t.Fatalf("期望1，实际%d %v", got, err)
+			}
+
+			// 进入下一个窗口后重新计数
+			advance(window)
+			if got, err := counter.Add(ctx, "req", window, 1); err != nil || got != 1 {
+				t.Fatalf("新窗口期望1，实际%d %v", got, err)
+			}
+
+			// 桶在窗口结束后再保留一个窗口，之后过期删除
+			if ok, err := c.Exists(ctx, firstBucket); err != nil || !ok {
+				t.Fatalf("上一个窗口的桶应保留，实际%v %v", ok, err)
+			}
+			advance(2 * window)
+			if ok, err := c.Exists(ctx, firstBucket); err != nil || ok {
+				t.Fatalf("过期的桶应被删除，实际%v %v", ok, err)
+			}
+			if got, err := counter.Add(ctx, "req", window, 4); err != nil || got != 4 {
+				t.Fatalf("期望4，实际%d %v", got, err)
+			}
+		})
+	}
+}
+
+func TestCounterInvalidWindow(t *testing.T) {
+	counter, err := NewCounter(newClockCache(t, newFakeClock(), WithMemory()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := counter.Add(context.Background(), "req", 0, 1); !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("期望ErrInvalidParams，实际%v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package cache_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+	"github.com/shaco-go/gkit-layout/pkg/cache/cachetest"
+)
+
+// accessToken 第三方接口返回的令牌，过期时间由接口决定
+type accessToken struct {
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+func tokenTTL(t *testing.T) cache.SaveOption {
+	return cache.WithDynamicTTL(func(value []byte) time.Duration {
+		var tok accessToken
+		if err := json.Unmarshal(value, &tok); err != nil {
+			t.Errorf("DynamicTTL received undecoded value %q: %v", value, err)
+			return 0
+		}
+		return time.Duration(tok.ExpiresIn) * time.Second
+	})
+}
+
+func TestDynamicTTLShorterThanDefault(t *testing.T) {
+	ctx := context.Background()
+	load := func() (accessToken, error) {
+		return accessToken{Token: strings.Repeat("t", 2000), ExpiresIn: 30}, nil
+	}
+	noExpiry := func() (accessToken, error) { return accessToken{Token: "x"}, nil }
+
+	tests := []struct {
+		name string
+		opts []cache.Option
+	}{
+		{name: "plain"},
+		// 装饰器改变了写入的字节，DynamicTTL收到的仍是fn返回的值
+		{name: "decorated", opts: []cache.Option{
+			cache.WithSchemaVersion(2), cache.WithCompression(100), cache.WithEncryption(testEncryptionKey),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mr := newTestRedis(t, tt.opts...)
+			if _, err := cache.Save(ctx, c, "tok", load, time.Hour, tokenTTL(t)); err != nil {
+				t.Fatal(err)
+			}
+			if got := mr.TTL("tok"); got != 30*time.Second {
+				t.Errorf("TTL = %v, want 30s", got)
+			}
+
+			// 返回0时使用Save的expiration
+			if _, err := cache.Save(ctx, c, "tok2", noExpiry, time.Hour, tokenTTL(t)); err != nil {
+				t.Fatal(err)
+			}
+			if got := mr.TTL("tok2"); got != time.Hour {
+				t.Errorf("TTL = %v, want 1h", got)
+			}
+		})
+	}
+}
+
+func TestDynamicTTLFake(t *testing.T) {
+	ctx := context.Background()
+	f := cachetest.NewFake()
+	load := func() (accessToken, error) { return accessToken{Token: "t", ExpiresIn: 30}, nil }
+	if _, err := cache.Save(ctx, f, "tok", load, time.Hour, tokenTTL(t)); err != nil {
+		t.Fatal(err)
+	}
+	f.Advance(31 * time.Second)
+	if ok, err := f.Exists(ctx, "tok"); err != nil || ok {
+		t.Errorf("Exists after 31s = %v, %v, want expired", ok, err)
+	}
+}
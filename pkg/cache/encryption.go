@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// encryptionMagic 加密值的开头，0xfe不会出现在合法的UTF-8和JSON中，没有该标记的值视为未加密的旧值
+var encryptionMagic = []byte{0xfe, 'e'}
+
+// encryptionKeyID 当前密钥的编号，写在标记之后，为以后的密钥轮换预留
+const encryptionKeyID byte = 0
+
+// encryptionHeaderSize 标记和密钥编号的长度
+const encryptionHeaderSize = 3
+
+// encryptedCache 写入前使用AES-GCM加密序列化后的值，读取时解密，包含KeyPrefix的完整键作为附加数据，值被复制到其他key后无法解密
+// 除空值外的所有写入都加密，十进制整数同样加密；空值不加密，以便防穿透的空值正常工作
+// Pipeline的Incr直接作用于后端，计数以明文保存，读取时没有加密标记的值原样返回；需要Incr的key不要通过Set写入初始值
+type encryptedCache struct {
+	Cache
+	aead   cipher.AEAD
+	prefix string // 附加数据中的键前缀，Raw视图的key已包含前缀，为空
+}
+
+// encryptionMiddleware 校验密钥并返回加密装饰器，密钥无效时返回错误，prefix为后端的KeyPrefix
+func encryptionMiddleware(key []byte, prefix string) (Middleware, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "cache: invalid encryption key")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "cache: failed to create AES-GCM")
+	}
+	return func(c Cache) Cache {
+		return &encryptedCache{Cache: c, aead: aead, prefix: prefix}
+	}, nil
+}
+
+// additionalData 返回key对应的附加数据，即后端中的完整键
+func (c *encryptedCache) additionalData(key string) []byte {
+	return []byte(joinKey(c.prefix, key))
+}
+
+// seal 加密，格式为 标记 + 密钥编号 + 随机nonce + 密文
+func (c *encryptedCache) seal(key string, data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	nonceSize := c.aead.NonceSize()
+	sealed := make([]byte, encryptionHeaderSize+nonceSize, encryptionHeaderSize+nonceSize+len(data)+c.aead.Overhead())
+	copy(sealed, encryptionMagic)
+	sealed[len(encryptionMagic)] = encryptionKeyID
+	nonce := sealed[encryptionHeaderSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "cache: failed to generate nonce")
+	}
+	return c.aead.Seal(sealed, nonce, data, c.additionalData(key)), nil
+}
+
+// open 解密，没有加密标记的值原样返回
+func (c *encryptedCache) open(key string, data []byte) ([]byte, error) {
+	if len(data) < len(encryptionMagic) || data[0] != encryptionMagic[0] || data[1] != encryptionMagic[1] {
+		return data, nil
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(data) < encryptionHeaderSize+nonceSize || data[len(encryptionMagic)] != encryptionKeyID {
+		return nil, wrapSerializationError(errors.New("unknown ciphertext format or key id"), "cache: failed to decrypt value")
+	}
+	nonce := data[encryptionHeaderSize : encryptionHeaderSize+nonceSize]
+	plain, err := c.aead.Open(nil, nonce, data[encryptionHeaderSize+nonceSize:], c.additionalData(key))
+	if err != nil {
+		return nil, wrapSerializationError(err, "cache: failed to decrypt value")
+	}
+	return plain, nil
+}
+
+// encode 按Set的规则序列化value并加密
+func (c *encryptedCache) encode(key string, value any) ([]byte, error) {
+	data, ok := value.([]byte)
+	if !ok && value != nil {
+		var err error
+		data, err = Marshal(value)
+		if err != nil {
+			return nil, wrapSerializationError(err, "cache: failed to marshal value")
+		}
+	}
+	return c.seal(key, data)
+}
+
+func (c *encryptedCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	data, err := c.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return c.Cache.Set(ctx, key, data, expiration)
+}
+
+func (c *encryptedCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.Set(ctx, key, value, expiration)
+}
+
+func (c *encryptedCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	sealed := make(map[string]any, len(values))
+	for key, value := range values {
+		data, err := c.encode(key, value)
+		if err != nil {
+			return err
+		}
+		sealed[key] = data
+	}
+	return c.Cache.MSet(ctx, sealed, expiration)
+}
+
+func (c *encryptedCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.Cache.GetRaw(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return c.open(key, data)
+}
+
+func (c *encryptedCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result, err := c.Cache.MGetRaw(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for key, data := range result {
+		if result[key], err = c.open(key, data); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (c *encryptedCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	data, err := c.Cache.SaveRaw(ctx, key, func() ([]byte, error) {
+		data, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		return c.seal(key, data)
+	}, expiration, decodeDynamicTTL(options, func(data []byte) ([]byte, error) {
+		return c.open(key, data)
+	})...)
+	if err != nil && !IsFallback(err) {
+		return nil, err
+	}
+	// 旧值是加密后保存的，兜底值没有加密标记会原样返回
+	data, openErr := c.open(key, data)
+	if openErr != nil {
+		return nil, openErr
+	}
+	return data, err
+}
+
+func (c *encryptedCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	data, err := c.seal(key, value)
+	if err != nil {
+		return false, err
+	}
+	return c.Cache.SetIfNewer(ctx, key, data, ts, expiration)
+}
+
+func (c *encryptedCache) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	data, ts, err := c.Cache.GetWithTimestamp(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err = c.open(key, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, ts, nil
+}
+
+// Pipeline Set的值同样加密
+func (c *encryptedCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	var sealErr error
+	return c.Cache.Pipeline(ctx, func(p Pipeliner) error {
+		if err := fn(&encryptedPipeliner{Pipeliner: p, c: c, err: &sealErr}); err != nil {
+			return err
+		}
+		return sealErr
+	})
+}
+
+// encryptedPipeliner 加密Set的值，失败时由Pipeline返回错误，不提交任何操作
+type encryptedPipeliner struct {
+	Pipeliner
+	c   *encryptedCache
+	err *error
+}
+
+func (p *encryptedPipeliner) Set(key string, value any, expiration time.Duration) {
+	data, err := p.c.encode(key, value)
+	if err != nil {
+		*p.err = errors.CombineErrors(*p.err, err)
+		return
+	}
+	p.Pipeliner.Set(key, data, expiration)
+}
+
+// Raw 返回不带键前缀的视图，读写同样加密和解密，与原缓存写入的值可以互相读取
+func (c *encryptedCache) Raw() Cache {
+	return &encryptedCache{Cache: c.Cache.Raw(), aead: c.aead}
+}
+
+// RegisterRefresher 刷新结果同样加密
+func (c *encryptedCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.registerRefresher(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *encryptedCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		return r.registerRefresher(target, key, loader, ttl, refreshBefore)
+	}
+	return c.Cache.RegisterRefresher(key, loader, ttl, refreshBefore)
+}
+
+func (c *encryptedCache) stopRefreshers() {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		r.stopRefreshers()
+	}
+}
@@ -0,0 +1,139 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+var testEncryptionKey = bytes.Repeat([]byte{7}, 32)
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		type profile struct{ Email string }
+		if err := c.Set(ctx, "u1", profile{Email: "a@b.c"}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		got, err := cache.Get[profile](ctx, c, "u1")
+		if err != nil || got.Email != "a@b.c" {
+			t.Fatalf("Get = %+v, %v", got, err)
+		}
+
+		err = c.Pipeline(ctx, func(p cache.Pipeliner) error {
+			p.Set("p1", "x", time.Minute)
+			p.Incr("n", 5)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := c.MGetRaw(ctx, []string{"p1", "n"})
+		if err != nil || string(m["p1"]) != `"x"` || string(m["n"]) != "5" {
+			t.Fatalf("MGetRaw = %q, %v", m, err)
+		}
+
+		data, err := c.SaveRaw(ctx, "s1", func() ([]byte, error) { return []byte("loaded"), nil }, time.Minute)
+		if err != nil || string(data) != "loaded" {
+			t.Fatalf("SaveRaw = %q, %v", data, err)
+		}
+		data, err = c.SaveRaw(ctx, "s1", func() ([]byte, error) { return nil, errors.New("loader called") }, time.Minute)
+		if err != nil || string(data) != "loaded" {
+			t.Fatalf("SaveRaw hit = %q, %v", data, err)
+		}
+
+		if ok, err := c.SetIfNewer(ctx, "t1", []byte("tv"), 5, time.Minute); err != nil || !ok {
+			t.Fatalf("SetIfNewer = %v, %v", ok, err)
+		}
+		data, ts, err := c.GetWithTimestamp(ctx, "t1")
+		if err != nil || string(data) != "tv" || ts != 5 {
+			t.Fatalf("GetWithTimestamp = %q, %d, %v", data, ts, err)
+		}
+	}, cache.WithEncryption(testEncryptionKey))
+}
+
+func TestEncryptionStoresCiphertext(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestRedis(t, cache.WithEncryption(testEncryptionKey), cache.WithKeyPrefix("app:"))
+	if err := c.Set(ctx, "u1", "a@b.c", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "n1", 5, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"app:u1", "app:n1"} {
+		stored, err := mr.Get(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored[0] != 0xfe || bytes.Contains([]byte(stored), []byte("a@b.c")) || stored == "5" {
+			t.Errorf("%s stored as plaintext: %q", key, stored)
+		}
+	}
+
+	// Raw视图使用同一密钥，能读取带前缀写入的值，写入的值同样加密
+	raw := c.Raw()
+	if data, err := raw.GetRaw(ctx, "app:u1"); err != nil || string(data) != `"a@b.c"` {
+		t.Fatalf("Raw GetRaw = %q, %v", data, err)
+	}
+	if err := raw.Set(ctx, "app:u2", "secret", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := mr.Get("app:u2"); stored[0] != 0xfe {
+		t.Errorf("Raw Set stored as plaintext: %q", stored)
+	}
+	if data, err := c.GetRaw(ctx, "u2"); err != nil || string(data) != `"secret"` {
+		t.Fatalf("GetRaw after Raw Set = %q, %v", data, err)
+	}
+}
+
+func TestEncryptionTamper(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestRedis(t, cache.WithEncryption(testEncryptionKey))
+	if err := c.Set(ctx, "u1", "secret", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := mr.Get("u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []byte(stored)
+	tampered[len(tampered)-1] ^= 1
+	if err := mr.Set("u1", string(tampered)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetRaw(ctx, "u1"); errorKind(err) != cache.KindSerialization {
+		t.Errorf("GetRaw tampered err = %v, want KindSerialization", err)
+	}
+
+	// 密文被复制到其他key后附加数据不一致，无法解密
+	if err := mr.Set("u2", stored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetRaw(ctx, "u2"); errorKind(err) != cache.KindSerialization {
+		t.Errorf("GetRaw copied err = %v, want KindSerialization", err)
+	}
+}
+
+func TestEncryptionLegacyPlaintext(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestRedis(t, cache.WithEncryption(testEncryptionKey))
+	if err := mr.Set("legacy", `"plain"`); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := c.GetRaw(ctx, "legacy"); err != nil || string(data) != `"plain"` {
+		t.Fatalf("GetRaw legacy = %q, %v", data, err)
+	}
+}
+
+func TestEncryptionInvalidKey(t *testing.T) {
+	if _, err := cache.New(cache.WithMemory(), cache.WithEncryption([]byte("short"))); err == nil {
+		t.Fatal("New with 5-byte key succeeded, want error")
+	}
+}
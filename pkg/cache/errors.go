@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/cockroachdb/errors"
+	"github.com/coocood/freecache"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrorKind 后端错误的分类
+type ErrorKind int
+
+const (
+	// KindUnknown 无法归类的错误
+	KindUnknown ErrorKind = iota
+	// KindTimeout 操作超时，包括ctx截止时间和连接池等待超时
+	KindTimeout
+	// KindConnection 连接被拒绝、断开或客户端已关闭
+	KindConnection
+	// KindSerialization 值序列化或反序列化失败
+	KindSerialization
+	// KindNotFound 键不存在
+	KindNotFound
+	// KindWrongType 键对应的值类型与操作不符，例如对非整数执行Incr
+	KindWrongType
+)
+
+// String 错误分类名称
+func (k ErrorKind) String() string {
+	switch k {
+	case KindTimeout:
+		return "timeout"
+	case KindConnection:
+		return "connection"
+	case KindSerialization:
+		return "serialization"
+	case KindNotFound:
+		return "not found"
+	case KindWrongType:
+		return "wrong type"
+	default:
+		return "unknown"
+	}
+}
+
+// BackendError 后端操作失败的错误，调用方可以通过errors.As获取Kind区分处理，Err为原始错误
+// Kind为KindNotFound时同时满足errors.Is(err, ErrNotFound)
+type BackendError struct {
+	Kind ErrorKind
+	Msg  string
+	Err  error
+}
+
+func (e *BackendError) Error() string {
+	return e.Msg + ": " + e.Err.Error()
+}
+
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BackendError) Is(target error) bool {
+	return e.Kind == KindNotFound && target == ErrNotFound
+}
+
+// wrapBackendError 按原始错误分类包装后端错误
+func wrapBackendError(err error, msg string) error {
+	return errors.WithStackDepth(&BackendError{Kind: errorKind(err), Msg: msg, Err: err}, 1)
+}
+
+// wrapSerializationError 包装序列化错误
+func wrapSerializationError(err error, msg string) error {
+	return errors.WithStackDepth(&BackendError{Kind: KindSerialization, Msg: msg, Err: err}, 1)
+}
+
+// errorKind 将go-redis、freecache和网络错误映射为错误分类
+func errorKind(err error) ErrorKind {
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, redis.Nil), errors.Is(err, freecache.ErrNotFound):
+		return KindNotFound
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.Is(err, redis.ErrPoolTimeout):
+		return KindTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return KindTimeout
+	case errors.Is(err, redis.ErrClosed), errors.Is(err, net.ErrClosed), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE):
+		return KindConnection
+	case netErr != nil:
+		return KindConnection
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return KindSerialization
+	case strings.HasPrefix(err.Error(), "WRONGTYPE"), strings.Contains(err.Error(), "not an integer"):
+		return KindWrongType
+	default:
+		return KindUnknown
+	}
+}
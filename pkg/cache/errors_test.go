@@ -0,0 +1,98 @@
+package cache_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// errorKind 返回err中BackendError的分类，没有BackendError时返回-1
+func errorKind(err error) cache.ErrorKind {
+	var be *cache.BackendError
+	if !errors.As(err, &be) {
+		return -1
+	}
+	return be.Kind
+}
+
+// newSilentRedis 创建连接到只接受连接、从不响应的服务端的缓存
+func newSilentRedis(t *testing.T) cache.Cache {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				_ = conn.Close()
+			}
+		}()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: ln.Addr().String(), ReadTimeout: 50 * time.Millisecond,
+		MaxRetries: -1, Protocol: 2, DisableIdentity: true})
+	t.Cleanup(func() { _ = client.Close() })
+	c, err := cache.New(cache.WithRedis(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestBackendErrorKindTimeout(t *testing.T) {
+	c := newSilentRedis(t)
+
+	// 读取超时
+	_, err := c.GetRaw(context.Background(), "k")
+	if kind := errorKind(err); kind != cache.KindTimeout {
+		t.Fatalf("read timeout: kind = %v, err = %v", kind, err)
+	}
+
+	// ctx截止时间
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.GetRaw(ctx, "k")
+	if kind := errorKind(err); kind != cache.KindTimeout {
+		t.Fatalf("ctx deadline: kind = %v, err = %v", kind, err)
+	}
+}
+
+func TestBackendErrorKindOthers(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestRedis(t)
+
+	if err := c.Set(ctx, "k", make(chan int), time.Minute); errorKind(err) != cache.KindSerialization {
+		t.Errorf("marshal chan: kind = %v, err = %v", errorKind(err), err)
+	}
+
+	if err := mr.Set("s", "text"); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Pipeline(ctx, func(p cache.Pipeliner) error {
+		p.Incr("s", 1)
+		return nil
+	})
+	if errorKind(err) != cache.KindWrongType {
+		t.Errorf("incr text: kind = %v, err = %v", errorKind(err), err)
+	}
+
+	mr.Close()
+	if err := c.Set(ctx, "k", "v", time.Minute); errorKind(err) != cache.KindConnection {
+		t.Errorf("closed server: kind = %v, err = %v", errorKind(err), err)
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog"
+)
+
+// staleKeyPrefix 旧值副本的key前缀
+const staleKeyPrefix = "stale:"
+
+// FallbackError fn失败后SaveRaw按WithErrorFallback返回了旧值或兜底值，此时返回的数据可以使用
+type FallbackError struct {
+	// Stale 返回的是否为旧值，为false时返回的是兜底值
+	Stale bool
+	// Err fn返回的原始错误
+	Err error
+}
+
+func (e *FallbackError) Error() string {
+	if e.Stale {
+		return "cache: load failed, serving stale value: " + e.Err.Error()
+	}
+	return "cache: load failed, serving fallback value: " + e.Err.Error()
+}
+
+func (e *FallbackError) Unwrap() error {
+	return e.Err
+}
+
+// WithErrorFallback fn返回错误时不直接返回错误，而是返回旧值或兜底值，同时返回包装了原始错误的*FallbackError
+// useStale为true时每次加载成功都会额外保存一份过期时间为expiration两倍的副本，缓存过期后的一个expiration内仍可作为旧值返回
+// 没有旧值时返回fallback，fallback为nil时返回原始错误
+func WithErrorFallback(useStale bool, fallback []byte) SaveOption {
+	return func(o *saveOptions) {
+		o.ErrorFallback = true
+		o.UseStale = useStale
+		o.Fallback = fallback
+	}
+}
+
+// staleExpiration 旧值副本的过期时间，expiration为0时同样不过期
+func staleExpiration(expiration time.Duration) time.Duration {
+	return 2 * expiration
+}
+
+// storeStale 保存旧值副本，写入失败不影响本次加载的结果
+func storeStale(ctx context.Context, c Cache, logger zerolog.Logger, key string, data []byte, expiration time.Duration, opts *saveOptions) {
+	if !opts.ErrorFallback || !opts.UseStale {
+		return
+	}
+	if err := c.Set(ctx, staleKeyPrefix+key, data, staleExpiration(expiration)); err != nil {
+		logger.Warn().Err(err).Str("key", key).Msg("cache: failed to store stale copy")
+	}
+}
+
+// errorFallback fn失败时按WithErrorFallback返回旧值或兜底值，未设置时返回原始错误
+func errorFallback(ctx context.Context, c Cache, key string, opts *saveOptions, err error) ([]byte, error) {
+	if !opts.ErrorFallback {
+		return nil, err
+	}
+	if opts.UseStale {
+		if data, getErr := c.GetRaw(ctx, staleKeyPrefix+key); getErr == nil {
+			return data, &FallbackError{Stale: true, Err: err}
+		}
+	}
+	if opts.Fallback != nil {
+		return bytes.Clone(opts.Fallback), &FallbackError{Err: err}
+	}
+	return nil, err
+}
+
+// IsFallback 判断SaveRaw或Save的错误是否为fn失败后返回了旧值或兜底值
+func IsFallback(err error) bool {
+	var fe *FallbackError
+	return errors.As(err, &fe)
+}
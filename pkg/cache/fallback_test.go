@@ -0,0 +1,94 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+var errLoad = errors.New("load failed")
+
+func loadOK() ([]byte, error)   { return []byte("v1"), nil }
+func loadFail() ([]byte, error) { return nil, errLoad }
+
+func TestErrorFallback(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		opt := cache.WithErrorFallback(true, []byte("fb"))
+		if _, err := c.SaveRaw(ctx, "k", loadOK, time.Minute, opt); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Pipeline(ctx, func(p cache.Pipeliner) error { p.Delete("k"); return nil }); err != nil {
+			t.Fatal(err)
+		}
+
+		// 缓存被删除后加载失败，返回旧值
+		data, err := c.SaveRaw(ctx, "k", loadFail, time.Minute, opt)
+		var fe *cache.FallbackError
+		if !errors.As(err, &fe) || !fe.Stale || string(data) != "v1" || !errors.Is(err, errLoad) {
+			t.Fatalf("stale SaveRaw = %q, %v", data, err)
+		}
+
+		// 没有旧值时返回兜底值
+		data, err = c.SaveRaw(ctx, "other", loadFail, time.Minute, opt)
+		if !errors.As(err, &fe) || fe.Stale || string(data) != "fb" {
+			t.Fatalf("fallback SaveRaw = %q, %v", data, err)
+		}
+
+		// 没有旧值也没有兜底值时返回原始错误
+		data, err = c.SaveRaw(ctx, "other", loadFail, time.Minute, cache.WithErrorFallback(true, nil))
+		if !errors.Is(err, errLoad) || cache.IsFallback(err) || data != nil {
+			t.Fatalf("no fallback SaveRaw = %q, %v", data, err)
+		}
+		data, err = c.SaveRaw(ctx, "other", loadFail, time.Minute)
+		if !errors.Is(err, errLoad) || cache.IsFallback(err) || data != nil {
+			t.Fatalf("plain SaveRaw = %q, %v", data, err)
+		}
+
+		type point struct{ N int }
+		v, err := cache.Save(ctx, c, "p", func() (point, error) { return point{}, errLoad }, time.Minute,
+			cache.WithErrorFallback(false, []byte(`{"N":7}`)))
+		if v.N != 7 || !cache.IsFallback(err) {
+			t.Fatalf("Save = %+v, %v", v, err)
+		}
+	})
+}
+
+func TestErrorFallbackEncryptedVersioned(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestRedis(t, cache.WithEncryption(bytes.Repeat([]byte{1}, 16)), cache.WithSchemaVersion(3))
+	opt := cache.WithErrorFallback(true, nil)
+	if _, err := c.SaveRaw(ctx, "k", loadOK, time.Minute, opt); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Pipeline(ctx, func(p cache.Pipeliner) error { p.Delete("k"); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.SaveRaw(ctx, "k", loadFail, time.Minute, opt)
+	if string(data) != "v1" || !cache.IsFallback(err) {
+		t.Fatalf("stale SaveRaw = %q, %v", data, err)
+	}
+}
+
+func TestErrorFallbackStaleWindow(t *testing.T) {
+	ctx := context.Background()
+	c, mr := newTestRedis(t)
+	opt := cache.WithErrorFallback(true, nil)
+	if _, err := c.SaveRaw(ctx, "k", loadOK, time.Minute, opt); err != nil {
+		t.Fatal(err)
+	}
+
+	// 缓存过期后的一个expiration内仍返回旧值
+	mr.FastForward(90 * time.Second)
+	if data, err := c.SaveRaw(ctx, "k", loadFail, time.Minute, opt); string(data) != "v1" || !cache.IsFallback(err) {
+		t.Fatalf("SaveRaw within window = %q, %v", data, err)
+	}
+	mr.FastForward(time.Minute)
+	if _, err := c.SaveRaw(ctx, "k", loadFail, time.Minute, opt); cache.IsFallback(err) || !errors.Is(err, errLoad) {
+		t.Fatalf("SaveRaw after window err = %v, want original error", err)
+	}
+}
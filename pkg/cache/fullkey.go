@@ -0,0 +1,39 @@
+package cache
+
+import "sync"
+
+// keyBufPool 复用拼接完整键的缓冲区，避免热路径上 []byte(prefix+key) 的分配
+// 只需要临时读取完整键的地方使用acquireKey，例如内存缓存的读写、空值记录和分片的哈希计算
+var keyBufPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 64)
+		return &buf
+	},
+}
+
+// acquireKey 将prefix和key拼接到复用的缓冲区，用完后调用releaseKey归还
+// 缓冲区归还后会被复用，不能保留；freecache和lruStore都会复制键，可以直接传入
+func acquireKey(prefix, key string) *[]byte {
+	buf := keyBufPool.Get().(*[]byte)
+	*buf = append(append((*buf)[:0], prefix...), key...)
+	return buf
+}
+
+// releaseKey 归还缓冲区，过大的缓冲区直接丢弃，避免长期占用内存
+func releaseKey(buf *[]byte) {
+	if cap(*buf) > 1024 {
+		return
+	}
+	keyBufPool.Put(buf)
+}
+
+// joinKey 拼接Redis命令使用的完整键，prefix为空时直接返回key，不分配内存
+// go-redis的命令参数是string，并且会保留在命令对象中，钩子可能在命令返回后读取，
+// 因此不能引用归还到keyBufPool的缓冲区，最多分配一次大小刚好的字符串；
+// 所有Redis命令(Set、GetRaw、Exists、Lock以及Pipeline中的Delete等)都通过这里拼接
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + key
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+func FuzzFullKey(f *testing.F) {
+	f.Add("", "user:1")
+	f.Add("app:", "")
+	f.Add("app:", "user:1")
+	f.Add("前缀:", "键\x00值")
+	f.Fuzz(func(t *testing.T, prefix, key string) {
+		want := prefix + key
+
+		buf := acquireKey(prefix, key)
+		if string(*buf) != want {
+			t.Fatalf("acquireKey(%q, %q) = %q", prefix, key, *buf)
+		}
+		releaseKey(buf)
+
+		// 复用的缓冲区不能残留上一次的内容
+		other := acquireKey(key, prefix)
+		if string(*other) != key+prefix {
+			t.Fatalf("复用的缓冲区内容不正确: %q", *other)
+		}
+		releaseKey(other)
+
+		if got := joinKey(prefix, key); got != want {
+			t.Fatalf("joinKey(%q, %q) = %q", prefix, key, got)
+		}
+
+		c := &shardedCache{prefix: prefix, shards: make([]*redisCache, 7)}
+		if got, want := c.shardIndex(key), jumpHash(xxhash.Sum64String(want), 7); got != want {
+			t.Fatalf("分片编号应与完整键的哈希一致，实际%d，期望%d", got, want)
+		}
+	})
+}
+
+var (
+	benchmarkKeySink   int
+	benchmarkBytesSink []byte
+	// 变量而不是常量，避免编译器在编译期完成拼接
+	benchmarkPrefix = "service:cache:"
+	benchmarkKey    = "user:profile:1234567890"
+)
+
+func BenchmarkFullKey(b *testing.B) {
+	prefix, key := benchmarkPrefix, benchmarkKey
+
+	// 改造前的写法: 每次操作分配一次拼接后的键
+	b.Run("concat", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchmarkBytesSink = []byte(prefix + key)
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fullKey := acquireKey(prefix, key)
+			benchmarkKeySink += len(*fullKey)
+			releaseKey(fullKey)
+		}
+	})
+	b.Run("shard_concat", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fullKey := prefix + key
+			benchmarkKeySink += jumpHash(xxhash.Sum64String(fullKey), 8)
+		}
+	})
+	b.Run("shard_pooled", func(b *testing.B) {
+		c := &shardedCache{prefix: prefix, shards: make([]*redisCache, 8)}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchmarkKeySink += c.shardIndex(key)
+		}
+	})
+	b.Run("memory_exists", func(b *testing.B) {
+		c, err := New(WithMemory(), WithKeyPrefix(prefix), WithCacheSize(1<<20))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer c.Close()
+		ctx := context.Background()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = c.Exists(ctx, key)
+		}
+	})
+}
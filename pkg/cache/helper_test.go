@@ -0,0 +1,60 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// newTestRedis 创建连接到miniredis的redis缓存
+func newTestRedis(t *testing.T, opts ...cache.Option) (cache.Cache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	c, err := cache.New(append([]cache.Option{cache.WithRedis(client)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, mr
+}
+
+// newHookedRedis 创建连接到miniredis、注册了hook的redis缓存，用于模拟延迟或统计命令
+func newHookedRedis(t *testing.T, hook redis.Hook, opts ...cache.Option) (cache.Cache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	client.AddHook(hook)
+	t.Cleanup(func() { _ = client.Close() })
+	c, err := cache.New(append([]cache.Option{cache.WithRedis(client)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c, mr
+}
+
+// newTestMemory 创建内存缓存
+func newTestMemory(t *testing.T, opts ...cache.Option) cache.Cache {
+	t.Helper()
+	c, err := cache.New(append([]cache.Option{cache.WithMemory()}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// forEachBackend 对内存和redis缓存分别执行fn，用于验证两种后端的行为一致
+func forEachBackend(t *testing.T, fn func(t *testing.T, c cache.Cache), opts ...cache.Option) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, newTestMemory(t, opts...))
+	})
+	t.Run("redis", func(t *testing.T) {
+		c, _ := newTestRedis(t, opts...)
+		fn(t, c)
+	})
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// hotKeySampleRate 平均每hotKeySampleRate次读取采样一次
+const hotKeySampleRate = 8
+
+// KeyCount 热点key及其估算的读取次数
+type KeyCount struct {
+	Key   string
+	Count uint64
+}
+
+// hotKeyCache 采样统计读取次数的缓存，使用Space-Saving算法保留有限数量的候选key
+type hotKeyCache struct {
+	Cache
+	topN int
+
+	mu      sync.Mutex
+	counts  map[string]uint64
+	maxKeys int
+}
+
+func newHotKeyCache(c Cache, topN int) Cache {
+	return &hotKeyCache{
+		Cache:   c,
+		topN:    topN,
+		counts:  make(map[string]uint64),
+		maxKeys: max(topN*4, 64),
+	}
+}
+
+// record 按采样率记录一次读取
+func (c *hotKeyCache) record(key string) {
+	if rand.Uint32()%hotKeySampleRate != 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.counts[key]; ok || len(c.counts) < c.maxKeys {
+		c.counts[key]++
+		return
+	}
+
+	// 候选已满时替换计数最小的key，新key继承其计数，保证真正的热点key不会被低估
+	var minKey string
+	var minCount uint64
+	for k, n := range c.counts {
+		if minKey == "" || n < minCount {
+			minKey, minCount = k, n
+		}
+	}
+	delete(c.counts, minKey)
+	c.counts[key] = minCount + 1
+}
+
+// HotKeys 返回读取次数最多的topN个key，按次数降序排列，次数为按采样率放大后的估算值
+func (c *hotKeyCache) HotKeys() []KeyCount {
+	c.mu.Lock()
+	keys := make([]KeyCount, 0, len(c.counts))
+	for k, n := range c.counts {
+		keys = append(keys, KeyCount{Key: k, Count: n * hotKeySampleRate})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	if len(keys) > c.topN {
+		keys = keys[:c.topN]
+	}
+	return keys
+}
+
+func (c *hotKeyCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	c.record(key)
+	return c.Cache.GetRaw(ctx, key)
+}
+
+func (c *hotKeyCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	for _, key := range keys {
+		c.record(key)
+	}
+	return c.Cache.MGetRaw(ctx, keys)
+}
+
+func (c *hotKeyCache) Exists(ctx context.Context, key string) (bool, error) {
+	c.record(key)
+	return c.Cache.Exists(ctx, key)
+}
+
+func (c *hotKeyCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	c.record(key)
+	return c.Cache.SaveRaw(ctx, key, fn, expiration, options...)
+}
+
+func (c *hotKeyCache) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	c.record(key)
+	return c.Cache.GetWithTimestamp(ctx, key)
+}
+
+// HotKeys 返回开启WithHotKeyTracking的缓存中读取最多的key，未开启时返回nil
+// WithMiddleware添加的装饰器需要实现Unwrap() Cache才能被穿过；多级缓存返回第一个开启统计的层级的结果
+func HotKeys(c Cache) []KeyCount {
+	for {
+		switch cc := c.(type) {
+		case *hotKeyCache:
+			return cc.HotKeys()
+		case *chainCache:
+			for _, layer := range cc.caches {
+				if hot := HotKeys(layer); hot != nil {
+					return hot
+				}
+			}
+			return nil
+		case *readOnlyCache:
+			c = cc.Cache
+		default:
+			inner, ok := unwrapDecorator(c)
+			if !ok {
+				return nil
+			}
+			c = inner
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestHotKeysRanking(t *testing.T) {
+	c := newTestMemory(t, cache.WithHotKeyTracking(3))
+	ctx := context.Background()
+	// hot-a、hot-b、hot-c的读取次数依次减半，同时夹杂大量只读取一次的key
+	for i := 0; i < 2000; i++ {
+		_, _ = c.GetRaw(ctx, "hot-a")
+		if i%2 == 0 {
+			_, _ = c.GetRaw(ctx, "hot-b")
+		}
+		if i%4 == 0 {
+			_, _ = c.Exists(ctx, "hot-c")
+		}
+		_, _ = c.GetRaw(ctx, "cold-"+strconv.Itoa(i))
+	}
+
+	hot := cache.HotKeys(c)
+	if len(hot) != 3 || hot[0].Key != "hot-a" || hot[1].Key != "hot-b" || hot[2].Key != "hot-c" {
+		t.Fatalf("HotKeys = %+v, want hot-a, hot-b, hot-c", hot)
+	}
+	if hot[0].Count < hot[1].Count || hot[1].Count < hot[2].Count {
+		t.Errorf("HotKeys counts not descending: %+v", hot)
+	}
+}
+
+func TestHotKeysDisabled(t *testing.T) {
+	if hot := cache.HotKeys(newTestMemory(t)); hot != nil {
+		t.Fatalf("HotKeys without tracking = %+v, want nil", hot)
+	}
+}
+
+func TestHotKeysThroughWrappers(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	metrics := func(c cache.Cache) cache.Cache { return &traceCache{Cache: c, name: "metrics", log: &log} }
+	c := newTestMemory(t, cache.WithMiddleware(metrics), cache.WithHotKeyTracking(1))
+	for i := 0; i < 10; i++ {
+		_, _ = c.GetRaw(ctx, "hot")
+	}
+	// 自定义装饰器在热点统计之外
+	if hot := cache.HotKeys(c); len(hot) != 1 || hot[0].Key != "hot" {
+		t.Fatalf("HotKeys with middleware = %+v, want hot", hot)
+	}
+
+	// 多级缓存返回开启统计的层级的结果
+	ch := cache.NewChain(newTestMemory(t), c)
+	if hot := cache.HotKeys(ch); len(hot) != 1 || hot[0].Key != "hot" {
+		t.Fatalf("HotKeys on chain = %+v, want hot", hot)
+	}
+}
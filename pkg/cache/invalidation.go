@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	gkit_zerolog "github.com/shaco-go/gkit-layout/pkg/zerolog"
+)
+
+// streamInvalidationField 失效消息中保存key的字段，多个key以换行分隔
+const streamInvalidationField = "keys"
+
+// streamRetryInterval 读取失败后重试的间隔
+const streamRetryInterval = time.Second
+
+// StreamInvalidator 通过Redis Stream广播本地缓存失效，每个节点使用独立的消费组
+// 与发布订阅不同，断线期间写入的消息在重连后仍会送达；删除本地缓存失败或确认前崩溃的消息会重新投递，因此至少送达一次
+type StreamInvalidator struct {
+	local    Cache
+	client   redis.UniversalClient
+	stream   string
+	group    string
+	consumer string
+	maxLen   int64
+	block    time.Duration
+	logger   zerolog.Logger
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// StreamInvalidatorOption 定义了NewStreamInvalidator的函数式选项类型
+type StreamInvalidatorOption func(*StreamInvalidator)
+
+// WithInvalidationStream 设置保存失效消息的Stream，默认"cache:invalidation"，所有节点需要一致
+func WithInvalidationStream(key string) StreamInvalidatorOption {
+	return func(s *StreamInvalidator) {
+		if key != "" {
+			s.stream = key
+		}
+	}
+}
+
+// WithInvalidationGroup 设置当前节点的消费组，每个节点必须不同，默认使用主机名
+// 名称应当在节点重启后保持不变，否则Redis中会残留不再使用的消费组
+func WithInvalidationGroup(group string) StreamInvalidatorOption {
+	return func(s *StreamInvalidator) {
+		if group != "" {
+			s.group = group
+		}
+	}
+}
+
+// WithInvalidationMaxLen 设置Stream保留的最大消息数(近似值)，默认10000
+func WithInvalidationMaxLen(n int64) StreamInvalidatorOption {
+	return func(s *StreamInvalidator) {
+		if n > 0 {
+			s.maxLen = n
+		}
+	}
+}
+
+// WithInvalidationLogger 设置消费失败时使用的日志
+func WithInvalidationLogger(logger zerolog.Logger) StreamInvalidatorOption {
+	return func(s *StreamInvalidator) {
+		s.logger = logger
+	}
+}
+
+// NewStreamInvalidator 创建基于Redis Stream的失效广播，并在后台消费其他节点发送的失效消息
+// 消费组不存在时从最新的消息开始创建，节点启动前的消息不会处理，此时本地缓存本来就是空的
+// 参数:
+//   - local: 需要删除失效key的本地缓存，通常为多级缓存中的内存缓存
+//   - client: Redis客户端，所有节点连接同一个Redis
+//   - opts: 可选的配置选项
+//
+// 返回:
+//   - *StreamInvalidator: 失效广播，使用完毕后需要调用Close
+//   - error: 创建消费组失败时返回错误
+func NewStreamInvalidator(local Cache, client redis.UniversalClient, opts ...StreamInvalidatorOption) (*StreamInvalidator, error) {
+	if local == nil || client == nil {
+		return nil, ErrInvalidParams
+	}
+	hostname, _ := os.Hostname()
+	s := &StreamInvalidator{
+		local:    local,
+		client:   client,
+		stream:   "cache:invalidation",
+		group:    hostname,
+		consumer: hostname,
+		maxLen:   10000,
+		block:    5 * time.Second,
+		logger:   log.Logger,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.group == "" {
+		return nil, errors.New("cache: invalidation consumer group is required")
+	}
+	if s.consumer == "" {
+		s.consumer = s.group
+	}
+
+	if err := s.createGroup(context.Background()); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	gkit_zerolog.Go(s.logger, func() {
+		defer close(s.done)
+		s.consume(ctx)
+	})
+	return s, nil
+}
+
+// createGroup 创建消费组，已存在时忽略
+func (s *StreamInvalidator) createGroup(ctx context.Context) error {
+	err := s.client.XGroupCreateMkStream(ctx, s.stream, s.group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return wrapBackendError(err, "cache: failed to create invalidation consumer group")
+	}
+	return nil
+}
+
+// Invalidate 删除本地缓存中的keys，并通知其他节点删除
+// 本节点同样会收到这条消息，重复删除没有影响
+func (s *StreamInvalidator) Invalidate(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := s.evict(ctx, keys); err != nil {
+		return err
+	}
+	err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: map[string]any{streamInvalidationField: strings.Join(keys, "\n")},
+	}).Err()
+	if err != nil {
+		return wrapBackendError(err, "cache: failed to publish invalidation")
+	}
+	return nil
+}
+
+// evict 删除本地缓存中的keys
+func (s *StreamInvalidator) evict(ctx context.Context, keys []string) error {
+	return s.local.Pipeline(ctx, func(p Pipeliner) error {
+		p.Delete(keys...)
+		return nil
+	})
+}
+
+// consume 循环读取消息，启动和出错后先处理已投递但未确认的消息，再读取新消息
+func (s *StreamInvalidator) consume(ctx context.Context) {
+	id := "0"
+	for ctx.Err() == nil {
+		streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  []string{s.stream, id},
+			Count:    100,
+			Block:    s.block,
+		}).Result()
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			s.logger.Warn().Err(err).Str("stream", s.stream).Str("group", s.group).Msg("cache: failed to read invalidations")
+			// Stream被删除后需要重新创建消费组
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				_ = s.createGroup(ctx)
+			}
+			id = "0"
+			s.wait(ctx)
+			continue
+		}
+
+		var messages []redis.XMessage
+		if len(streams) > 0 {
+			messages = streams[0].Messages
+		}
+		if id == "0" && len(messages) == 0 {
+			// 未确认的消息已经处理完
+			id = ">"
+			continue
+		}
+		if !s.handle(ctx, messages) {
+			id = "0"
+			s.wait(ctx)
+		}
+	}
+}
+
+// handle 删除消息中的key并确认，返回是否全部处理成功，失败的消息保留在待确认列表中等待重新投递
+func (s *StreamInvalidator) handle(ctx context.Context, messages []redis.XMessage) bool {
+	for _, msg := range messages {
+		value, _ := msg.Values[streamInvalidationField].(string)
+		if value != "" {
+			if err := s.evict(ctx, strings.Split(value, "\n")); err != nil {
+				s.logger.Warn().Err(err).Str("id", msg.ID).Msg("cache: failed to evict invalidated keys")
+				return false
+			}
+		}
+		if err := s.client.XAck(ctx, s.stream, s.group, msg.ID).Err(); err != nil {
+			s.logger.Warn().Err(err).Str("id", msg.ID).Msg("cache: failed to ack invalidation")
+			return false
+		}
+	}
+	return true
+}
+
+// wait 出错后等待一段时间再重试
+func (s *StreamInvalidator) wait(ctx context.Context) {
+	select {
+	case <-time.After(streamRetryInterval):
+	case <-ctx.Done():
+	}
+}
+
+// Close 停止消费，消费组保留在Redis中，同名节点重新启动后继续消费
+func (s *StreamInvalidator) Close() error {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		<-s.done
+	})
+	return nil
+}
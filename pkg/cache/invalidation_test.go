@@ -0,0 +1,141 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// failHook 开关打开时让指定命令失败，模拟连接断开，failed记录失败的次数
+type failHook struct {
+	cmd    string
+	fail   *atomic.Bool
+	failed *atomic.Int32
+}
+
+func (h failHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h failHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == h.cmd && h.fail.Load() {
+			time.Sleep(10 * time.Millisecond)
+			err := errors.New("connection reset")
+			cmd.SetErr(err)
+			h.failed.Add(1)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h failHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// eventually 在timeout内反复检查cond，超时后测试失败
+func eventually(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("等待超时")
+}
+
+// newTestInvalidator 创建连接到mr的失效广播，本地缓存为内存缓存
+func newTestInvalidator(t *testing.T, mr *miniredis.Miniredis, group string, hooks ...redis.Hook) (*cache.StreamInvalidator, cache.Cache, *redis.Client) {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	for _, hook := range hooks {
+		client.AddHook(hook)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	local := newTestMemory(t)
+	inv, err := cache.NewStreamInvalidator(local, client, cache.WithInvalidationGroup(group), cache.WithInvalidationLogger(zerolog.Nop()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = inv.Close() })
+	return inv, local, client
+}
+
+func TestStreamInvalidator(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	var readFail, ackFail atomic.Bool
+	var readFailed, ackFailed atomic.Int32
+	a, la, ca := newTestInvalidator(t, mr, "node-a")
+	_, lb, _ := newTestInvalidator(t, mr, "node-b",
+		failHook{"xreadgroup", &readFail, &readFailed}, failHook{"xack", &ackFail, &ackFailed})
+
+	missing := func(c cache.Cache, key string) bool {
+		_, err := c.GetRaw(ctx, key)
+		return errors.Is(err, cache.ErrNotFound)
+	}
+	for _, c := range []cache.Cache{la, lb} {
+		for _, key := range []string{"k1", "k2", "k3"} {
+			if err := c.Set(ctx, key, "v", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// 发送方立即删除本地缓存，其他节点消费后删除
+	if err := a.Invalidate(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if !missing(la, "k1") {
+		t.Fatal("发送方的本地缓存未删除")
+	}
+	eventually(t, 3*time.Second, func() bool { return missing(lb, "k1") })
+
+	// 断开期间发送的消息在重连后收到
+	// 开关打开前已经阻塞的读取仍可能返回消息，等观察到读取失败后才算断开
+	readFail.Store(true)
+	eventually(t, 8*time.Second, func() bool { return readFailed.Load() > 0 })
+	if err := a.Invalidate(ctx, "k2"); err != nil {
+		t.Fatal(err)
+	}
+	// 发送之后的下一次读取同样失败
+	failed := readFailed.Load()
+	eventually(t, 3*time.Second, func() bool { return readFailed.Load() > failed })
+	if missing(lb, "k2") {
+		t.Fatal("断开期间不应收到消息")
+	}
+	readFail.Store(false)
+	eventually(t, 8*time.Second, func() bool { return missing(lb, "k2") })
+
+	// 确认失败的消息保留在待处理列表中并再次投递
+	ackFail.Store(true)
+	if err := a.Invalidate(ctx, "k3"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, 8*time.Second, func() bool { return missing(lb, "k3") })
+	if err := lb.Set(ctx, "k3", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// 等到重新投递的消息再次确认失败，之后的投递确认成功
+	failed = ackFailed.Load()
+	eventually(t, 3*time.Second, func() bool { return ackFailed.Load() > failed })
+	ackFail.Store(false)
+	eventually(t, 8*time.Second, func() bool { return missing(lb, "k3") })
+	eventually(t, 3*time.Second, func() bool {
+		pending, err := ca.XPending(ctx, "cache:invalidation", "node-b").Result()
+		return err == nil && pending.Count == 0
+	})
+}
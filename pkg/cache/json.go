@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+)
+
+// jsonConfig Marshal和Unmarshal的行为
+type jsonConfig struct {
+	escapeHTML bool // 是否将<、>、&转义为\u003c等
+	useNumber  bool // 解码到any时数字是否保留为json.Number
+}
+
+// jsonSettings 当前的JSON配置，默认与encoding/json一致
+var jsonSettings atomic.Pointer[jsonConfig]
+
+func init() {
+	jsonSettings.Store(&jsonConfig{escapeHTML: true})
+}
+
+// JSONOption 定义了SetJSONOptions的函数式选项类型
+type JSONOption func(*jsonConfig)
+
+// WithJSONNoHTMLEscape 序列化时不转义HTML字符，缓存HTML片段时保持原样
+func WithJSONNoHTMLEscape() JSONOption {
+	return func(c *jsonConfig) {
+		c.escapeHTML = false
+	}
+}
+
+// WithJSONUseNumber 反序列化到any或map[string]any时数字保留为json.Number，超过2^53的int64 ID不会因float64丢失精度
+func WithJSONUseNumber() JSONOption {
+	return func(c *jsonConfig) {
+		c.useNumber = true
+	}
+}
+
+// SetJSONOptions 设置Marshal和Unmarshal的行为，对所有缓存实例和Get、Save等函数生效，应在程序启动时调用
+// 不传选项时恢复为encoding/json的默认行为
+func SetJSONOptions(opts ...JSONOption) {
+	c := &jsonConfig{escapeHTML: true}
+	for _, opt := range opts {
+		opt(c)
+	}
+	jsonSettings.Store(c)
+}
+
+// marshalJSON 按当前配置序列化
+func marshalJSON(v any) ([]byte, error) {
+	if jsonSettings.Load().escapeHTML {
+		return json.Marshal(v)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encoder会在末尾添加换行
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// unmarshalJSON 按当前配置反序列化
+func unmarshalJSON(data []byte, v any) error {
+	if !jsonSettings.Load().useNumber {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// 与json.Unmarshal一致，值之后不能有其他内容
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
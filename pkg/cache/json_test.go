@@ -0,0 +1,78 @@
+package cache_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// largeID 超过2^53，转换为float64后精度丢失
+const largeID int64 = 9007199254740993
+
+const htmlSnippet = `<b>a & b</b>`
+
+func TestJSONDefaults(t *testing.T) {
+	ctx := context.Background()
+	c := newTestMemory(t)
+	if err := c.Set(ctx, "x", map[string]any{"id": largeID, "html": htmlSnippet}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := c.GetRaw(ctx, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"html":"\u003cb\u003ea \u0026 b\u003c/b\u003e","id":9007199254740993}`; string(raw) != want {
+		t.Errorf("默认应转义HTML字符，期望%s，实际%s", want, raw)
+	}
+	v, err := cache.Get[map[string]any](ctx, c, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v["id"].(float64); !ok {
+		t.Errorf("默认应解码为float64，实际%T", v["id"])
+	}
+}
+
+func TestJSONOptionsRoundTrip(t *testing.T) {
+	cache.SetJSONOptions(cache.WithJSONNoHTMLEscape(), cache.WithJSONUseNumber())
+	t.Cleanup(func() { cache.SetJSONOptions() })
+
+	ctx := context.Background()
+	c := newTestMemory(t)
+	if err := c.Set(ctx, "x", map[string]any{"id": largeID, "html": htmlSnippet}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := c.GetRaw(ctx, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"html":"<b>a & b</b>","id":9007199254740993}`; string(raw) != want {
+		t.Errorf("期望%s，实际%s", want, raw)
+	}
+
+	v, err := cache.Get[map[string]any](ctx, c, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	number, ok := v["id"].(json.Number)
+	if !ok {
+		t.Fatalf("期望json.Number，实际%T", v["id"])
+	}
+	if id, err := number.Int64(); err != nil || id != largeID {
+		t.Errorf("期望%d，实际%s %v", largeID, number, err)
+	}
+	if v["html"] != htmlSnippet {
+		t.Errorf("期望%s，实际%v", htmlSnippet, v["html"])
+	}
+
+	s, err := cache.Save(ctx, c, "s", func() (string, error) { return htmlSnippet, nil }, time.Minute)
+	if err != nil || s != htmlSnippet {
+		t.Fatalf("Save = %q, %v", s, err)
+	}
+	if err := cache.Unmarshal([]byte(`{"a":1} x`), &v); err == nil {
+		t.Error("多余的数据应返回错误")
+	}
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// KeyBuilder 统一构建缓存键，避免各处用fmt.Sprintf拼接导致格式不一致
+type KeyBuilder struct {
+	sep      string
+	replacer *strings.Replacer
+}
+
+// NewKeyBuilder 创建键构建器，sep为空时使用":"
+func NewKeyBuilder(sep string) *KeyBuilder {
+	if sep == "" {
+		sep = ":"
+	}
+	return &KeyBuilder{
+		sep: sep,
+		// 先转义反斜杠再转义分隔符，保证不同的parts不会拼出相同的键
+		replacer: strings.NewReplacer(`\`, `\\`, sep, `\`+sep),
+	}
+}
+
+// Build 用分隔符连接各部分，值中出现的分隔符会被转义
+// 各部分按fmt.Sprint格式化，因此Build("user", 42)与Build("user", "42")结果相同
+func (b *KeyBuilder) Build(parts ...any) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = b.replacer.Replace(fmt.Sprint(part))
+	}
+	return strings.Join(escaped, b.sep)
+}
+
+// StableKey 将任意可以JSON序列化的值编码为确定的键，适用于以查询参数等复合值作为缓存键的一部分
+// 编码前按encoding/json规范化: map按键排序，结构体按字段声明顺序，因此逻辑上相等的map无论构造顺序如何结果相同
+// 返回规范化JSON的SHA-256前16字节的十六进制，长度固定为32，不包含分隔符
+// 自定义MarshalJSON需要自行保证输出确定；无法序列化的值返回KindSerialization的BackendError
+func StableKey(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", wrapSerializationError(err, "cache: failed to encode stable key")
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16]), nil
+}
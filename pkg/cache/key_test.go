@@ -0,0 +1,86 @@
+package cache_test
+
+import (
+	"testing"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestKeyBuilderDeterministic(t *testing.T) {
+	b := cache.NewKeyBuilder("")
+	if got := b.Build("user", 42); got != "user:42" {
+		t.Fatalf("期望user:42，实际%s", got)
+	}
+	for i := 0; i < 10; i++ {
+		if b.Build("user", 42) != b.Build("user", "42") {
+			t.Fatal("Build(\"user\", 42)与Build(\"user\", \"42\")应相同")
+		}
+	}
+	if got := cache.NewKeyBuilder("|").Build("user", 42); got != "user|42" {
+		t.Fatalf("期望user|42，实际%s", got)
+	}
+}
+
+func TestKeyBuilderSeparatorCollision(t *testing.T) {
+	b := cache.NewKeyBuilder(":")
+	cases := [][]any{
+		{"a:b", "c"},
+		{"a", "b:c"},
+		{"a", "b", "c"},
+		{`a\`, "b"},
+		{`a\:b`},
+		{"a", `\:b`},
+	}
+	seen := make(map[string][]any, len(cases))
+	for _, parts := range cases {
+		key := b.Build(parts...)
+		if prev, ok := seen[key]; ok {
+			t.Fatalf("%v与%v生成了相同的键%q", prev, parts, key)
+		}
+		seen[key] = parts
+	}
+	if got := b.Build("a:b", "c"); got != `a\:b:c` {
+		t.Fatalf("分隔符应被转义，实际%s", got)
+	}
+}
+
+func TestStableKeyMapOrder(t *testing.T) {
+	type query struct {
+		Filters map[string]any
+		Page    int
+	}
+	forward := make(map[string]int)
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		forward[k] = len(k)
+	}
+	want, err := cache.StableKey(query{Filters: map[string]any{"x": forward, "y": 1}, Page: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 32 {
+		t.Fatalf("期望长度32，实际%d", len(want))
+	}
+
+	// 多次以不同的插入顺序构造相同的map，map的遍历顺序随机
+	for i := 0; i < 50; i++ {
+		backward := make(map[string]int)
+		for _, k := range []string{"g", "f", "e", "d", "c", "b", "a"} {
+			backward[k] = len(k)
+		}
+		got, err := cache.StableKey(query{Filters: map[string]any{"y": 1, "x": backward}, Page: 2})
+		if err != nil || got != want {
+			t.Fatalf("期望%s，实际%s %v", want, got, err)
+		}
+	}
+
+	other, _ := cache.StableKey(query{Filters: map[string]any{"x": forward, "y": 2}, Page: 2})
+	if other == want {
+		t.Fatal("不同的值生成了相同的键")
+	}
+}
+
+func TestStableKeyUnsupported(t *testing.T) {
+	if _, err := cache.StableKey(func() {}); errorKind(err) != cache.KindSerialization {
+		t.Fatalf("期望KindSerialization，实际%v", err)
+	}
+}
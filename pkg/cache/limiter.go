@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+)
+
+// loadLimiter 限制所有key的加载函数并发执行数量，nil表示不限制
+type loadLimiter chan struct{}
+
+// newLoadLimiter 创建加载限流器，n<=0时不限制
+func newLoadLimiter(n int) loadLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(loadLimiter, n)
+}
+
+// run 获取到执行名额后调用fn，排队期间遵循ctx的取消和截止时间
+func (l loadLimiter) run(ctx context.Context, fn func() ([]byte, error)) ([]byte, error) {
+	if l == nil {
+		return fn()
+	}
+
+	select {
+	case l <- struct{}{}:
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "cache: waiting for load slot")
+	}
+	defer func() { <-l }()
+
+	return fn()
+}
@@ -0,0 +1,73 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestMaxConcurrentLoads(t *testing.T) {
+	c, err := cache.New(cache.WithMemory(), cache.WithMaxConcurrentLoads(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := c.SaveRaw(context.Background(), fmt.Sprint("key", i), func() ([]byte, error) {
+				n := running.Add(1)
+				for {
+					m := peak.Load()
+					if n <= m || peak.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+				return []byte("v"), nil
+			}, time.Minute)
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := peak.Load(); got != 3 {
+		t.Fatalf("期望最多3个并发加载，实际%d", got)
+	}
+}
+
+func TestMaxConcurrentLoadsContextCanceled(t *testing.T) {
+	c, _ := cache.New(cache.WithMemory(), cache.WithMaxConcurrentLoads(1))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_, _ = c.SaveRaw(context.Background(), "slow", func() ([]byte, error) {
+			close(started)
+			<-release
+			return []byte("v"), nil
+		}, time.Minute)
+	}()
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	called := false
+	_, err := c.SaveRaw(ctx, "queued", func() ([]byte, error) {
+		called = true
+		return nil, nil
+	}, time.Minute)
+	if !errors.Is(err, context.DeadlineExceeded) || called {
+		t.Fatalf("期望排队超时且不调用fn，err=%v called=%v", err, called)
+	}
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// LockOption 定义了WithLock的函数式选项类型
+type LockOption func(*lockOptions)
+
+type lockOptions struct {
+	// Wait 锁被持有时是否等待
+	Wait bool
+}
+
+// WaitForLock 锁被其他请求持有时每50毫秒重试一次，直到获取成功或ctx结束
+func WaitForLock() LockOption {
+	return func(o *lockOptions) {
+		o.Wait = true
+	}
+}
+
+// WithLock 获取key的锁后执行fn，fn返回或panic后都会释放锁，不需要手动管理锁标识符
+// 参数:
+//   - ctx: 控制获取锁的等待时间，释放锁不受ctx取消的影响
+//   - c: 缓存实例
+//   - key: 锁名称
+//   - ttl: 锁的过期时间，应当大于fn的最长执行时间
+//   - fn: 持有锁期间执行的函数
+//   - options: 可选的配置选项，默认锁被持有时立即返回ErrLockAcquired
+//
+// 返回:
+//   - error: 获取锁失败时返回ErrLockAcquired或ctx的错误，否则返回fn的错误
+func WithLock(ctx context.Context, c Cache, key string, ttl time.Duration, fn func() error, options ...LockOption) error {
+	opts := &lockOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	if opts.Wait {
+		release, err := LockMulti(ctx, c, []string{key}, ttl)
+		if err != nil {
+			return err
+		}
+		defer release()
+		return fn()
+	}
+
+	value, err := c.Lock(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = c.Unlock(context.WithoutCancel(ctx), key, value)
+	}()
+	return fn()
+}
+
+// sortLocks 按键排序，sharded和chain合并多个后端的结果后同样排序
+func sortLocks(locks []LockInfo) {
+	slices.SortFunc(locks, func(a, b LockInfo) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+}
@@ -0,0 +1,80 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestWithLockReleasesOnPanic(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		func() {
+			defer func() {
+				if r := recover(); r != "boom" {
+					t.Fatalf("recover() = %v, want boom", r)
+				}
+			}()
+			_ = cache.WithLock(ctx, c, "job", time.Minute, func() error { panic("boom") })
+		}()
+
+		// panic后锁已释放，可以再次获取
+		value, err := c.Lock(ctx, "job", time.Minute)
+		if err != nil {
+			t.Fatalf("Lock after panic: %v", err)
+		}
+		_ = c.Unlock(ctx, "job", value)
+	})
+}
+
+func TestWithLockHeld(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		fnErr := errors.New("fn failed")
+		err := cache.WithLock(ctx, c, "job", time.Minute, func() error {
+			// 锁被持有时默认立即返回
+			if err := cache.WithLock(ctx, c, "job", time.Minute, func() error { return nil }); !errors.Is(err, cache.ErrLockAcquired) {
+				t.Errorf("nested WithLock err = %v, want ErrLockAcquired", err)
+			}
+			tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer cancel()
+			err := cache.WithLock(tctx, c, "job", time.Minute, func() error { return nil }, cache.WaitForLock())
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("waiting WithLock err = %v, want DeadlineExceeded", err)
+			}
+			return fnErr
+		})
+		if !errors.Is(err, fnErr) {
+			t.Fatalf("WithLock err = %v, want fn error", err)
+		}
+	})
+}
+
+func TestWithLockWaitsForRelease(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		value, err := c.Lock(ctx, "job", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- cache.WithLock(ctx, c, "job", time.Minute, func() error { return nil }, cache.WaitForLock())
+		}()
+		time.Sleep(80 * time.Millisecond)
+		if err := c.Unlock(ctx, "job", value); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("释放锁后WithLock仍在等待")
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package cache
+
+import "strings"
+
+// LockKeyFunc 生成Redis锁的完整键
+// lockPrefix为LockPrefix；name为锁名称，即Lock的key参数，SaveRaw内部使用"lock:"+key；
+// dataKey为锁保护的数据键，已包含KeyPrefix
+type LockKeyFunc func(lockPrefix, name, dataKey string) string
+
+// HashTagLockKey 使用数据键作为Redis Cluster的哈希标签，使锁与数据键落在同一个slot
+// 数据键本身带有哈希标签时沿用该标签，LockPrefix中不能包含'{'，否则标签会被前缀截断
+// 没有有效标签且包含'}'的数据键无法作为标签，此时退化为LockPrefix+name
+func HashTagLockKey(lockPrefix, name, dataKey string) string {
+	tag := hashTag(dataKey)
+	if strings.IndexByte(tag, '}') >= 0 {
+		return lockPrefix + name
+	}
+	return lockPrefix + "{" + tag + "}:" + name
+}
+
+// hashTag 返回Redis Cluster计算slot时使用的部分，规则与服务端一致
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
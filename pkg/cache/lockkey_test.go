@@ -0,0 +1,57 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// clusterSlot 按Redis Cluster的规则计算key的slot: 有非空哈希标签时只计算标签，CRC16-XMODEM取模16384
+func clusterSlot(key string) uint16 {
+	if s := strings.IndexByte(key, '{'); s >= 0 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+1+e]
+		}
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc % 16384
+}
+
+func TestHashTagLockKeySameSlot(t *testing.T) {
+	for _, key := range []string{"user:1", "{u1}:profile", "a{b", "order:{42}:items"} {
+		dataKey := "app:" + key
+		lockKey := cache.HashTagLockKey("lock:", key, dataKey)
+		if clusterSlot(lockKey) != clusterSlot(dataKey) {
+			t.Errorf("key %q: lock %q slot %d, data %q slot %d", key, lockKey, clusterSlot(lockKey), dataKey, clusterSlot(dataKey))
+		}
+	}
+}
+
+func TestWithLockHashTag(t *testing.T) {
+	c, mr := newTestRedis(t, cache.WithKeyPrefix("app:"), cache.WithLockPrefix("lock:"), cache.WithLockHashTag())
+	ctx := context.Background()
+	value, err := c.Lock(ctx, "user:1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := mr.Keys()
+	if len(keys) != 1 || clusterSlot(keys[0]) != clusterSlot("app:user:1") {
+		t.Fatalf("lock keys = %v, want one key in the slot of app:user:1", keys)
+	}
+	if err := c.Unlock(ctx, "user:1", value); err != nil {
+		t.Fatal(err)
+	}
+}
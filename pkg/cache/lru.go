@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/coocood/freecache"
+)
+
+// memoryStore 内存缓存的底层存储，freecache.Cache直接满足该接口
+type memoryStore interface {
+	Get(key []byte) ([]byte, error)
+	Set(key, value []byte, expireSeconds int) error
+	Del(key []byte) bool
+	TTL(key []byte) (uint32, error)
+}
+
+// lruStore 按条目数淘汰最久未访问键的存储
+type lruStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	clock      clock
+}
+
+type lruEntry struct {
+	key      string
+	value    []byte
+	deadline time.Time // 零值表示不过期
+}
+
+func newLRUStore(maxEntries int, clock clock) *lruStore {
+	return &lruStore{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		clock:      clock,
+	}
+}
+
+// Get 未命中或已过期时返回freecache.ErrNotFound，与freecache保持一致
+func (s *lruStore) Get(key []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[string(key)]
+	if !ok {
+		return nil, freecache.ErrNotFound
+	}
+	entry := elem.Value.(*lruEntry)
+	if !entry.deadline.IsZero() && s.clock.Now().After(entry.deadline) {
+		s.removeElement(elem)
+		return nil, freecache.ErrNotFound
+	}
+
+	s.ll.MoveToFront(elem)
+	return append([]byte(nil), entry.value...), nil
+}
+
+func (s *lruStore) Set(key, value []byte, expireSeconds int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deadline time.Time
+	if expireSeconds > 0 {
+		deadline = s.clock.Now().Add(time.Duration(expireSeconds) * time.Second)
+	}
+	// 复制一份，避免调用方修改已缓存的数据
+	value = append([]byte(nil), value...)
+
+	if elem, ok := s.items[string(key)]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.deadline = deadline
+		s.ll.MoveToFront(elem)
+		return nil
+	}
+
+	s.items[string(key)] = s.ll.PushFront(&lruEntry{key: string(key), value: value, deadline: deadline})
+	for s.ll.Len() > s.maxEntries {
+		s.removeElement(s.ll.Back())
+	}
+	return nil
+}
+
+func (s *lruStore) Del(key []byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[string(key)]
+	if ok {
+		s.removeElement(elem)
+	}
+	return ok
+}
+
+// TTL 返回剩余的过期秒数，不过期的键返回0
+func (s *lruStore) TTL(key []byte) (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[string(key)]
+	if !ok {
+		return 0, freecache.ErrNotFound
+	}
+	deadline := elem.Value.(*lruEntry).deadline
+	if deadline.IsZero() {
+		return 0, nil
+	}
+	left := deadline.Sub(s.clock.Now())
+	if left <= 0 {
+		s.removeElement(elem)
+		return 0, freecache.ErrNotFound
+	}
+	return uint32((left + time.Second - 1) / time.Second), nil
+}
+
+func (s *lruStore) removeElement(elem *list.Element) {
+	s.ll.Remove(elem)
+	delete(s.items, elem.Value.(*lruEntry).key)
+}
@@ -0,0 +1,97 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+const (
+	zipfKeys     = 100000 // 键空间大小
+	zipfCapacity = 10000  // 缓存能容纳的条目数
+)
+
+// benchmarkZipfHitRatio 按Zipf分布访问键，未命中时写入，报告命中率
+func benchmarkZipfHitRatio(b *testing.B, c cache.Cache) {
+	ctx := context.Background()
+	value := make([]byte, 100)
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, zipfKeys-1)
+
+	hits := 0
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := "k" + strconv.FormatUint(zipf.Uint64(), 10)
+		if _, err := c.GetRaw(ctx, key); err == nil {
+			hits++
+			continue
+		}
+		if err := c.Set(ctx, key, value, time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+}
+
+func BenchmarkZipfHitRatio(b *testing.B) {
+	b.Run("freecache", func(b *testing.B) {
+		// 按条目数估算相同的字节预算: 值、键和freecache的24字节条目头
+		c, err := cache.New(cache.WithMemory(), cache.WithCacheSize(zipfCapacity*(100+8+24)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer c.Close()
+		benchmarkZipfHitRatio(b, c)
+	})
+	b.Run("lru", func(b *testing.B) {
+		c, err := cache.New(cache.WithLRU(zipfCapacity))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer c.Close()
+		benchmarkZipfHitRatio(b, c)
+	})
+}
+
+func TestMaxEntriesEviction(t *testing.T) {
+	ctx := context.Background()
+	const n = 20
+	c := newTestMemory(t, cache.WithMaxEntries(n))
+	for i := 0; i < n; i++ {
+		if err := c.Set(ctx, fmt.Sprint("k", i), i, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 访问k0使其成为最近使用的键，之后写入的10个键淘汰k1到k10
+	if _, err := c.GetRaw(ctx, "k0"); err != nil {
+		t.Fatal(err)
+	}
+	for i := n; i < n+10; i++ {
+		if err := c.Set(ctx, fmt.Sprint("k", i), i, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := 0
+	for i := 0; i < n+10; i++ {
+		key := fmt.Sprint("k", i)
+		ok, err := c.Exists(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			count++
+		}
+		if evicted := i >= 1 && i <= 10; ok == evicted {
+			t.Errorf("%s exists = %v, want %v", key, ok, !evicted)
+		}
+	}
+	if count != n {
+		t.Errorf("期望保留%d个键，实际%d", n, count)
+	}
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// CallCached 以参数作为缓存键缓存函数的结果，相同参数的重复调用只执行一次fn，适用于请求到响应的纯函数
+// 缓存键为prefix+StableKey(in)，in需要能JSON序列化，逻辑上相等的参数命中同一个缓存
+// fn返回ErrNotFound时，设置了WithPreventCacheMiss才缓存为空值，之后在空值过期前直接返回ErrNotFound而不再调用fn；否则不缓存
+// 参数:
+//   - ctx: 上下文
+//   - cache: 缓存实例
+//   - prefix: 缓存键前缀，用于区分不同的函数，例如"user:profile:"
+//   - in: 函数参数
+//   - fn: 被缓存的函数，找不到结果时返回ErrNotFound
+//   - ttl: 结果的过期时间
+//   - options: 与Save相同的可选参数
+//
+// 返回:
+//   - Out: 函数的结果，缓存为空值时为零值
+//   - error: fn返回的错误、结果为空时的ErrNotFound或缓存操作错误
+func CallCached[In, Out any](ctx context.Context, cache Cache, prefix string, in In, fn func(In) (Out, error), ttl time.Duration, options ...SaveOption) (Out, error) {
+	var value Out
+	key, err := StableKey(in)
+	if err != nil {
+		return value, err
+	}
+	key = prefix + key
+	negative := ResolveSaveOptions(ctx, options...).PreventCacheMiss
+
+	rawFn := func() ([]byte, error) {
+		result, err := fn(in)
+		if err != nil {
+			// 空值表示结果不存在，由SaveRaw按NilExpiration缓存
+			if negative && errors.Is(err, ErrNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		data, err := Marshal(result)
+		if err != nil {
+			return nil, wrapSerializationError(err, "cache: failed to marshal value")
+		}
+		return data, nil
+	}
+
+	rawData, err := cache.SaveRaw(ctx, key, rawFn, ttl, options...)
+	if err != nil && !IsFallback(err) {
+		return value, err
+	}
+	if len(rawData) == 0 {
+		if err != nil {
+			return value, err
+		}
+		return value, ErrNotFound
+	}
+	if unmarshalErr := Unmarshal(rawData, &value); unmarshalErr != nil {
+		return value, wrapSerializationError(unmarshalErr, "cache: failed to unmarshal value")
+	}
+	return value, err
+}
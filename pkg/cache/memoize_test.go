@@ -0,0 +1,76 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+type memoizeRequest struct {
+	ID int
+}
+
+// countingLoader 统计每个参数的调用次数，ID为0时返回ErrNotFound
+type countingLoader struct {
+	calls map[int]int
+}
+
+func (l *countingLoader) load(r memoizeRequest) (string, error) {
+	if l.calls == nil {
+		l.calls = make(map[int]int)
+	}
+	l.calls[r.ID]++
+	if r.ID == 0 {
+		return "", cache.ErrNotFound
+	}
+	return fmt.Sprintf("u%d", r.ID), nil
+}
+
+func TestCallCachedOncePerInput(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		var loader countingLoader
+		for i := 0; i < 3; i++ {
+			for _, id := range []int{1, 2} {
+				v, err := cache.CallCached(ctx, c, "p:", memoizeRequest{ID: id}, loader.load, time.Minute)
+				if err != nil || v != fmt.Sprintf("u%d", id) {
+					t.Fatalf("CallCached(%d) = %q, %v", id, v, err)
+				}
+			}
+		}
+		if loader.calls[1] != 1 || loader.calls[2] != 1 {
+			t.Errorf("每个参数应只调用一次fn，实际%v", loader.calls)
+		}
+	})
+}
+
+func TestCallCachedNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		// 没有WithPreventCacheMiss时不缓存ErrNotFound
+		var plain countingLoader
+		for i := 0; i < 2; i++ {
+			if _, err := cache.CallCached(ctx, c, "p:", memoizeRequest{}, plain.load, time.Minute); !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("CallCached err = %v, want ErrNotFound", err)
+			}
+		}
+		if plain.calls[0] != 2 {
+			t.Errorf("期望调用2次，实际%d", plain.calls[0])
+		}
+
+		var negative countingLoader
+		for i := 0; i < 2; i++ {
+			_, err := cache.CallCached(ctx, c, "n:", memoizeRequest{}, negative.load, time.Minute, cache.WithPreventCacheMiss(time.Minute))
+			if !errors.Is(err, cache.ErrNotFound) {
+				t.Fatalf("CallCached err = %v, want ErrNotFound", err)
+			}
+		}
+		if negative.calls[0] != 1 {
+			t.Errorf("空值缓存后应只调用1次，实际%d", negative.calls[0])
+		}
+	})
+}
@@ -2,22 +2,39 @@ package cache
 
 import (
 	"context"
+	"encoding/binary"
 	"github.com/google/uuid"
 	"runtime/debug"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/coocood/freecache"
+	"github.com/rs/zerolog"
+	gkit_zerolog "github.com/shaco-go/gkit-layout/pkg/zerolog"
 )
 
 type memoryCache struct {
-	cache   *freecache.Cache
-	mu      sync.RWMutex
+	cache   memoryStore
+	mu      *sync.RWMutex
 	prefix  string
 	lockKey string
 	locks   map[string]string // key -> identifier
-	lockMu  sync.Mutex
+	sems    map[string]*localSemaphore
+	lockMu  *sync.Mutex
+	loader  loadLimiter
+	logger  zerolog.Logger
+	raw     bool // 是否为不带前缀的视图
+	clock   clock
+
+	skipOversized bool           // SaveRaw遇到超过大小限制的值时是否跳过缓存
+	negative      *negativeCache // 防穿透的空值，nil时空值写入主存储
+
+	refreshers *refresherGroup
+
+	gcPercent     int // 创建时设置的GC百分比，0表示未修改
+	prevGCPercent int // 修改前的GC百分比
 }
 
 func newMemoryCache(opts *Options) (Cache, error) {
@@ -27,33 +44,49 @@ func newMemoryCache(opts *Options) (Cache, error) {
 		cacheSize = opts.CacheSize
 	}
 
-	// 创建freecache实例
-	cache := freecache.NewCache(cacheSize)
+	// 默认使用freecache，配置了LRU时按条目数淘汰
+	var cache memoryStore
+	if opts.LRUEntries > 0 {
+		cache = newLRUStore(opts.LRUEntries, opts.clock)
+	} else {
+		cache = freecache.NewCacheCustomTimer(cacheSize, freecacheTimer{clock: opts.clock})
+	}
 
-	// 设置GC百分比为20%
-	if opts.SetGCPercent {
-		debug.SetGCPercent(20)
+	// 设置进程的GC百分比，Close时恢复
+	gcPercent := opts.GCPercent
+	if gcPercent == 0 && opts.SetGCPercent {
+		gcPercent = 20
+	}
+	prevGCPercent := 0
+	if gcPercent != 0 {
+		prevGCPercent = debug.SetGCPercent(gcPercent)
 	}
 
 	c := &memoryCache{
 		cache:   cache,
+		mu:      &sync.RWMutex{},
 		locks:   make(map[string]string),
+		sems:    make(map[string]*localSemaphore),
+		lockMu:  &sync.Mutex{},
 		prefix:  opts.KeyPrefix,
 		lockKey: opts.LockPrefix,
+		loader:  newLoadLimiter(opts.MaxConcurrentLoads),
+		logger:  opts.Logger,
+		clock:   opts.clock,
+
+		skipOversized: opts.SkipOversized,
+		negative:      newNegativeCache(opts.NegativeCacheLimit, opts.clock),
+
+		refreshers: newRefresherGroup(opts.Logger),
+
+		gcPercent:     gcPercent,
+		prevGCPercent: prevGCPercent,
 	}
 
 	return c, nil
 }
 
 func (c *memoryCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
-	fullKey := c.prefix + key
-
-	// 计算过期时间（秒）
-	var expireSeconds int
-	if expiration > 0 {
-		expireSeconds = int(expiration.Seconds())
-	}
-
 	// 序列化值
 	var data []byte
 	var err error
@@ -65,54 +98,114 @@ func (c *memoryCache) Set(ctx context.Context, key string, value any, expiration
 	} else {
 		data, err = Marshal(value)
 		if err != nil {
-			return errors.Wrap(err, "cache: failed to marshal value")
+			return wrapSerializationError(err, "cache: failed to marshal value")
 		}
 	}
 
+	fullKey := acquireKey(c.prefix, key)
+	defer releaseKey(fullKey)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.set(*fullKey, data, expiration)
+}
+
+// set 写入底层存储，调用方需持有c.mu
+func (c *memoryCache) set(fullKey []byte, data []byte, expiration time.Duration) error {
+	// 计算过期时间（秒）
+	var expireSeconds int
+	if expiration > 0 {
+		expireSeconds = int(expiration.Seconds())
+	}
+
 	// 设置到freecache
-	err = c.cache.Set([]byte(fullKey), data, expireSeconds)
+	err := c.cache.Set(fullKey, data, expireSeconds)
+	if err == freecache.ErrLargeEntry || err == freecache.ErrLargeKey {
+		return errors.Wrap(ErrValueTooLarge, err.Error())
+	}
 	if err != nil {
-		return errors.Wrap(err, "cache: failed to set value in freecache")
+		return wrapBackendError(err, "cache: failed to set value in freecache")
 	}
 
 	return nil
 }
 
-func (c *memoryCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
-	fullKey := c.prefix + key
-
+// get 读取底层存储，调用方需持有c.mu
+func (c *memoryCache) get(fullKey []byte) ([]byte, error) {
 	// 从freecache获取数据
-	data, err := c.cache.Get([]byte(fullKey))
+	data, err := c.cache.Get(fullKey)
 	if err == freecache.ErrNotFound {
 		return nil, ErrNotFound
 	}
 	if err != nil {
-		return nil, errors.Wrap(err, "cache: failed to get value from freecache")
+		return nil, wrapBackendError(err, "cache: failed to get value from freecache")
 	}
 
 	return data, nil
 }
 
+func (c *memoryCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.Set(ctx, key, value, expiration)
+}
+
+func (c *memoryCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	if IsBypass(ctx) {
+		return nil, ErrNotFound
+	}
+
+	fullKey := acquireKey(c.prefix, key)
+	defer releaseKey(fullKey)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.get(*fullKey)
+}
+
+func (c *memoryCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		data, err := c.GetRaw(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[key] = data
+	}
+	return result, nil
+}
+
+func (c *memoryCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	for key, value := range values {
+		if err := c.Set(ctx, key, value, expiration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *memoryCache) Exists(ctx context.Context, key string) (bool, error) {
-	fullKey := c.prefix + key
+	fullKey := acquireKey(c.prefix, key)
+	defer releaseKey(fullKey)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
 	// 检查键是否存在
-	_, err := c.cache.Get([]byte(fullKey))
-	if errors.Is(err, freecache.ErrNotFound) {
+	_, err := c.get(*fullKey)
+	if errors.Is(err, ErrNotFound) {
 		return false, nil
 	}
 	if err != nil {
-		return false, errors.Wrap(err, "cache: failed to check key existence")
+		return false, wrapBackendError(err, "cache: failed to check key existence")
 	}
 
 	return true, nil
 }
 
 func (c *memoryCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
-	opts := &saveOptions{}
-	for _, opt := range options {
-		opt(opts)
-	}
+	opts := newSaveOptions(ctx, options)
 
 	// 如果不是强制刷新，先尝试从缓存获取
 	if !opts.ForceRefresh {
@@ -123,13 +216,17 @@ func (c *memoryCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte
 		if !errors.Is(err, ErrNotFound) {
 			return nil, err
 		}
+		if c.negative.contains(c.prefix, key) {
+			return nil, nil
+		}
 	}
 
 	// 缓存未命中或强制刷新，调用函数获取数据
-	result, err := fn()
+	result, err := c.loader.run(ctx, fn)
 	if err != nil {
-		return nil, err
+		return errorFallback(ctx, c, key, opts, err)
 	}
+	expiration = opts.expiration(result, expiration)
 
 	// 处理缓存穿透 - 即使结果为空值，仍然缓存
 	if (result == nil || len(result) == 0) && opts.PreventCacheMiss {
@@ -137,24 +234,166 @@ func (c *memoryCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte
 		if opts.NilExpiration > 0 {
 			exp = opts.NilExpiration
 		}
+		if c.negative != nil {
+			// 空值记录在单独的有上限的存储中
+			c.negative.add(c.prefix, key, exp)
+			return result, nil
+		}
 		err = c.Set(ctx, key, result, exp)
 	} else {
 		err = c.Set(ctx, key, result, expiration)
 	}
 
+	if c.skipOversized && errors.Is(err, ErrValueTooLarge) {
+		return result, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	storeStale(ctx, c, c.logger, key, result, expiration, opts)
 
 	return result, nil
 }
 
+func (c *memoryCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	if ts < 0 {
+		return false, ErrInvalidParams
+	}
+	fullKey := acquireKey(c.prefix, key)
+	defer releaseKey(fullKey)
+
+	// 比较和写入在同一把锁内完成，保证原子性
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := c.get(*fullKey)
+	if err == nil {
+		_, current, err := decodeTimestamp(data)
+		if err != nil {
+			return false, err
+		}
+		if current >= ts {
+			return false, nil
+		}
+	} else if !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+
+	// 前8字节存储时间戳，后面是原始数据
+	data = make([]byte, 8+len(value))
+	binary.BigEndian.PutUint64(data, uint64(ts))
+	copy(data[8:], value)
+
+	if err := c.set(*fullKey, data, expiration); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *memoryCache) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	data, err := c.GetRaw(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decodeTimestamp(data)
+}
+
+// decodeTimestamp 拆分SetIfNewer写入的时间戳和数据
+func decodeTimestamp(data []byte) ([]byte, int64, error) {
+	if len(data) < 8 {
+		return nil, 0, errors.New("cache: value was not written by SetIfNewer")
+	}
+	return data[8:], int64(binary.BigEndian.Uint64(data)), nil
+}
+
+func (c *memoryCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	p := &pipeline{}
+	if err := fn(p); err != nil {
+		return err
+	}
+	if p.err != nil {
+		return p.err
+	}
+
+	// 持有写锁依次执行，其他读写在执行完成前不会看到中间状态
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, op := range p.ops {
+		var err error
+		fullKey := acquireKey(c.prefix, op.key)
+		switch op.kind {
+		case pipelineSet:
+			err = c.set(*fullKey, op.data, op.expiration)
+		case pipelineDelete:
+			c.cache.Del(*fullKey)
+		case pipelineIncr:
+			_, err = c.incr(*fullKey, op.delta, 0)
+		}
+		releaseKey(fullKey)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// incr 将十进制整数值增加delta并保留剩余过期时间，键不存在时视为0并使用expiration，调用方需持有c.mu
+func (c *memoryCache) incr(fullKey []byte, delta int64, expiration time.Duration) (int64, error) {
+	var current int64
+	data, err := c.get(fullKey)
+	switch {
+	case err == nil:
+		current, err = strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return 0, errors.Wrap(ErrInvalidParams, "cache: value is not an integer")
+		}
+		ttl, err := c.cache.TTL(fullKey)
+		if err != nil && err != freecache.ErrNotFound {
+			return 0, wrapBackendError(err, "cache: failed to get ttl from freecache")
+		}
+		expiration = time.Duration(ttl) * time.Second
+	case !errors.Is(err, ErrNotFound):
+		return 0, err
+	}
+
+	total := current + delta
+	return total, c.set(fullKey, []byte(strconv.FormatInt(total, 10)), expiration)
+}
+
+func (c *memoryCache) Backend() string {
+	return "memory"
+}
+
+func (c *memoryCache) Capabilities() CacheCapabilities {
+	return CacheCapabilities{
+		Write:     true,
+		Pipeline:  true,
+		Lock:      true,
+		Semaphore: true,
+		Counter:   true,
+	}
+}
+
+func (c *memoryCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.refreshers.register(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *memoryCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.refreshers.register(target, key, loader, ttl, refreshBefore)
+}
+
+func (c *memoryCache) stopRefreshers() {
+	c.refreshers.stop()
+}
+
 func (c *memoryCache) Lock(ctx context.Context, key string, expiration time.Duration) (string, error) {
 	c.lockMu.Lock()
 	defer c.lockMu.Unlock()
 
-	lockKey := c.lockKey + key
-	if _, exists := c.locks[lockKey]; exists {
+	fullKey := acquireKey(c.lockKey, key)
+	defer releaseKey(fullKey)
+	if _, exists := c.locks[string(*fullKey)]; exists {
 		return "", ErrLockAcquired
 	}
 
@@ -164,24 +403,26 @@ func (c *memoryCache) Lock(ctx context.Context, key string, expiration time.Dura
 		return "", errors.WithStack(err)
 	}
 
-	// 设置锁
+	// 设置锁，只有获取成功时才需要分配键
+	lockKey := string(*fullKey)
 	c.locks[lockKey] = u.String()
 
 	// 设置自动过期
 	if expiration > 0 {
-		go func(key string, value string, d time.Duration) {
+		value := u.String()
+		gkit_zerolog.Go(c.logger, func() {
 			select {
-			case <-time.After(d):
+			case <-c.clock.After(expiration):
 				c.lockMu.Lock()
 				defer c.lockMu.Unlock()
 				// 确保锁还是被同一个值持有
-				if v, exists := c.locks[key]; exists && v == value {
-					delete(c.locks, key)
+				if v, exists := c.locks[lockKey]; exists && v == value {
+					delete(c.locks, lockKey)
 				}
 			case <-ctx.Done():
 				return
 			}
-		}(lockKey, u.String(), expiration)
+		})
 	}
 
 	return u.String(), nil
@@ -191,16 +432,73 @@ func (c *memoryCache) Unlock(ctx context.Context, key string, value string) erro
 	c.lockMu.Lock()
 	defer c.lockMu.Unlock()
 
-	lockKey := c.lockKey + key
-	if val, exists := c.locks[lockKey]; !exists || val != value {
+	fullKey := acquireKey(c.lockKey, key)
+	defer releaseKey(fullKey)
+	if val, exists := c.locks[string(*fullKey)]; !exists || val != value {
 		return ErrLockNotOwned
 	}
 
-	delete(c.locks, lockKey)
+	delete(c.locks, string(*fullKey))
 	return nil
 }
 
+// ActiveLocks 返回进程内持有的锁，过期时间由后台goroutine处理，不记录剩余时间
+func (c *memoryCache) ActiveLocks(ctx context.Context) ([]LockInfo, error) {
+	c.lockMu.Lock()
+	locks := make([]LockInfo, 0, len(c.locks))
+	for key, token := range c.locks {
+		locks = append(locks, LockInfo{Key: key, Token: token})
+	}
+	c.lockMu.Unlock()
+	sortLocks(locks)
+	return locks, nil
+}
+
+// semaphore 返回key对应的进程内信号量，同名信号量共享许可，limit以第一次创建时为准
+func (c *memoryCache) semaphore(key string, limit int) *localSemaphore {
+	c.lockMu.Lock()
+	defer c.lockMu.Unlock()
+
+	s, ok := c.sems[key]
+	if !ok {
+		s = newLocalSemaphore(limit)
+		c.sems[key] = s
+	}
+	return s
+}
+
+func (c *memoryCache) Publish(ctx context.Context, channel string, message []byte) error {
+	return ErrNotSupported
+}
+
+func (c *memoryCache) Subscribe(ctx context.Context, channels ...string) (<-chan Message, func(), error) {
+	return nil, nil, ErrNotSupported
+}
+
+func (c *memoryCache) Raw() Cache {
+	// 共享底层存储、锁和互斥量，只去掉键前缀
+	view := *c
+	view.prefix = ""
+	view.raw = true
+	return &view
+}
+
 func (c *memoryCache) Close() error {
-	// freecache没有显式的Close方法
+	// freecache没有显式的Close方法，视图与原缓存共享刷新协程，由原缓存负责停止
+	if !c.raw {
+		c.refreshers.stop()
+		c.restoreGCPercent()
+	}
 	return nil
 }
+
+// restoreGCPercent 恢复创建时修改的GC百分比，期间被其他代码修改过时保留其设置
+func (c *memoryCache) restoreGCPercent() {
+	if c.gcPercent == 0 {
+		return
+	}
+	if current := debug.SetGCPercent(c.prevGCPercent); current != c.gcPercent {
+		debug.SetGCPercent(current)
+	}
+	c.gcPercent = 0
+}
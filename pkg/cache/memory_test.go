@@ -0,0 +1,71 @@
+package cache_test
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// currentGCPercent 读取当前的GC百分比
+func currentGCPercent() int {
+	p := debug.SetGCPercent(100)
+	debug.SetGCPercent(p)
+	return p
+}
+
+func TestGCPercentRestoredOnClose(t *testing.T) {
+	orig := debug.SetGCPercent(100)
+	t.Cleanup(func() { debug.SetGCPercent(orig) })
+
+	tests := []struct {
+		name string
+		opt  cache.Option
+		want int
+	}{
+		{name: "WithGCPercent", opt: cache.WithGCPercent(35), want: 35},
+		{name: "WithSetGCPercent", opt: cache.WithSetGCPercent(true), want: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := cache.New(tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := currentGCPercent(); got != tt.want {
+				t.Fatalf("GC percent after New = %d, want %d", got, tt.want)
+			}
+			// Raw视图关闭时不恢复
+			if err := c.Raw().Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := currentGCPercent(); got != tt.want {
+				t.Fatalf("GC percent after closing Raw view = %d, want %d", got, tt.want)
+			}
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := currentGCPercent(); got != 100 {
+				t.Fatalf("GC percent after Close = %d, want 100", got)
+			}
+		})
+	}
+}
+
+func TestGCPercentKeepsLaterChange(t *testing.T) {
+	orig := debug.SetGCPercent(100)
+	t.Cleanup(func() { debug.SetGCPercent(orig) })
+
+	c, err := cache.New(cache.WithGCPercent(35))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 期间被其他代码修改过时Close保留其设置
+	debug.SetGCPercent(50)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentGCPercent(); got != 50 {
+		t.Fatalf("GC percent after Close = %d, want 50", got)
+	}
+}
@@ -0,0 +1,17 @@
+package cache
+
+// Middleware 缓存装饰器，接收内层缓存并返回包装后的缓存，例如指标、重试、熔断
+// 装饰器通常嵌入内层Cache，只覆盖需要处理的方法；实现Unwrap() Cache时NewSemaphore等需要底层后端的功能可以穿过该装饰器
+type Middleware func(Cache) Cache
+
+// Wrap 按顺序组合装饰器，第一个装饰器在最外层，Wrap(base, metrics, retry)等同于metrics(retry(base))
+// 调用时先经过metrics再经过retry，重试的每一次尝试都不会被metrics重复统计
+func Wrap(base Cache, mws ...Middleware) Cache {
+	c := base
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i] != nil {
+			c = mws[i](c)
+		}
+	}
+	return c
+}
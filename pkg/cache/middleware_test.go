@@ -0,0 +1,85 @@
+package cache_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+	"github.com/shaco-go/gkit-layout/pkg/cache/cachetest"
+)
+
+// traceCache 记录GetRaw的进入和返回
+type traceCache struct {
+	cache.Cache
+	name string
+	log  *[]string
+}
+
+func (c *traceCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	*c.log = append(*c.log, c.name+">")
+	data, err := c.Cache.GetRaw(ctx, key)
+	*c.log = append(*c.log, "<"+c.name)
+	return data, err
+}
+
+func (c *traceCache) Unwrap() cache.Cache { return c.Cache }
+
+// retryCache 失败时重试GetRaw，最多3次
+type retryCache struct {
+	cache.Cache
+	log *[]string
+}
+
+func (c *retryCache) GetRaw(ctx context.Context, key string) (data []byte, err error) {
+	for i := 0; i < 3; i++ {
+		*c.log = append(*c.log, "try")
+		if data, err = c.Cache.GetRaw(ctx, key); err == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
+
+func TestWrapOrder(t *testing.T) {
+	var log []string
+	metrics := func(c cache.Cache) cache.Cache { return &traceCache{Cache: c, name: "metrics", log: &log} }
+	retry := func(c cache.Cache) cache.Cache { return &retryCache{Cache: c, log: &log} }
+
+	f := cachetest.NewFake()
+	f.ForceError("GetRaw", errors.New("boom"))
+	if _, err := cache.Wrap(f, metrics, retry).GetRaw(context.Background(), "k"); err == nil {
+		t.Fatal("GetRaw succeeded, want injected error")
+	}
+	// 第一个装饰器在最外层，重试的每次尝试不会被metrics重复记录
+	want := []string{"metrics>", "try", "try", "try", "<metrics"}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("call order = %v, want %v", log, want)
+	}
+}
+
+func TestWithMiddlewareOutermost(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	metrics := func(c cache.Cache) cache.Cache { return &traceCache{Cache: c, name: "metrics", log: &log} }
+	c := newTestMemory(t, cache.WithMiddleware(metrics), cache.WithSchemaVersion(2))
+
+	if err := c.Set(ctx, "a", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// 内置的版本装饰器在自定义装饰器之内，读取到的是去掉版本标记后的值
+	v, err := cache.Get[int](ctx, c, "a")
+	if err != nil || v != 1 {
+		t.Fatalf("Get = %v, %v", v, err)
+	}
+	if want := []string{"metrics>", "<metrics"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("call order = %v, want %v", log, want)
+	}
+
+	// 实现了Unwrap的装饰器不妨碍NewSemaphore找到底层后端
+	if _, err := cache.NewSemaphore(c, "s", 1); err != nil {
+		t.Fatalf("NewSemaphore through middleware: %v", err)
+	}
+}
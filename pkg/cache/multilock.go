@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// lockMultiPollInterval 锁被其他请求持有时的重试间隔
+const lockMultiPollInterval = 50 * time.Millisecond
+
+// LockMulti 同时获取多个key的锁，例如两个账户之间转账
+// key去重后按字典序依次获取，所有调用方的获取顺序一致，不会互相等待形成死锁
+// 某个锁被持有时每50毫秒重试一次直到ctx结束；获取失败时释放已经获取的锁
+// 参数:
+//   - ctx: 控制等待时间
+//   - c: 缓存实例，内存缓存的锁只在进程内生效
+//   - keys: 需要锁定的key
+//   - ttl: 每个锁的过期时间
+//
+// 返回:
+//   - release: 释放所有锁，可以重复调用，锁已过期或被其他请求持有时忽略
+//   - err: keys为空、ctx结束或获取锁出错时返回错误
+func LockMulti(ctx context.Context, c Cache, keys []string, ttl time.Duration) (release func(), err error) {
+	if len(keys) == 0 {
+		return nil, ErrInvalidParams
+	}
+	sorted := slices.Clone(keys)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	values := make([]string, 0, len(sorted))
+	unlockAll := func() {
+		// 按获取的相反顺序释放，ctx可能已经结束
+		for i := len(values) - 1; i >= 0; i-- {
+			_ = c.Unlock(context.Background(), sorted[i], values[i])
+		}
+	}
+
+	for _, key := range sorted {
+		for {
+			value, err := c.Lock(ctx, key, ttl)
+			if err == nil {
+				values = append(values, value)
+				break
+			}
+			if !errors.Is(err, ErrLockAcquired) {
+				unlockAll()
+				return nil, err
+			}
+
+			select {
+			case <-time.After(lockMultiPollInterval):
+			case <-ctx.Done():
+				unlockAll()
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(unlockAll)
+	}, nil
+}
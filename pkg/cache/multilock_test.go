@@ -0,0 +1,73 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestLockMultiOppositeOrders(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		// 死锁时ctx超时，LockMulti返回错误
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var inside atomic.Int32
+		var wg sync.WaitGroup
+		for _, keys := range [][]string{{"acct:a", "acct:b"}, {"acct:b", "acct:a", "acct:b"}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 10; i++ {
+					release, err := cache.LockMulti(ctx, c, keys, time.Minute)
+					if err != nil {
+						t.Errorf("LockMulti(%v): %v", keys, err)
+						return
+					}
+					if inside.Add(1) != 1 {
+						t.Error("两个调用方同时持有锁")
+					}
+					time.Sleep(time.Millisecond)
+					inside.Add(-1)
+					release()
+					release()
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestLockMultiReleasesPartial(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		release, err := cache.LockMulti(ctx, c, []string{"b"}, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+
+		tctx, tcancel := context.WithTimeout(ctx, 120*time.Millisecond)
+		defer tcancel()
+		if _, err := cache.LockMulti(tctx, c, []string{"a", "b"}, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("LockMulti on held key err = %v, want DeadlineExceeded", err)
+		}
+		// 获取b失败后已经获取的a被释放
+		value, err := c.Lock(ctx, "a", time.Minute)
+		if err != nil {
+			t.Fatalf("Lock a after failed LockMulti: %v", err)
+		}
+		_ = c.Unlock(ctx, "a", value)
+	})
+}
+
+func TestLockMultiEmptyKeys(t *testing.T) {
+	if _, err := cache.LockMulti(context.Background(), newTestMemory(t), nil, time.Minute); !errors.Is(err, cache.ErrInvalidParams) {
+		t.Fatalf("LockMulti(nil) err = %v, want ErrInvalidParams", err)
+	}
+}
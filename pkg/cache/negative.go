@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"time"
+)
+
+// negativeCache 单独保存防穿透的空值，条目数有上限，空值不再写入主存储，不会挤占正常数据
+// 只在本进程内生效，SaveRaw先读取主存储，其他实例写入的正常数据仍然优先于这里记录的空值
+type negativeCache struct {
+	store *lruStore
+}
+
+// newNegativeCache entries不大于0时返回nil，表示空值仍然写入主存储
+func newNegativeCache(entries int, clock clock) *negativeCache {
+	if entries <= 0 {
+		return nil
+	}
+	return &negativeCache{store: newLRUStore(entries, clock)}
+}
+
+// add 记录prefix+key不存在，超过条目数上限时淘汰最久未访问的记录
+func (n *negativeCache) add(prefix, key string, expiration time.Duration) {
+	var expireSeconds int
+	if expiration > 0 {
+		expireSeconds = int(expiration.Seconds())
+	}
+	fullKey := acquireKey(prefix, key)
+	defer releaseKey(fullKey)
+	_ = n.store.Set(*fullKey, nil, expireSeconds)
+}
+
+// contains 判断prefix+key是否记录为不存在，n为nil时返回false
+func (n *negativeCache) contains(prefix, key string) bool {
+	if n == nil {
+		return false
+	}
+	fullKey := acquireKey(prefix, key)
+	defer releaseKey(fullKey)
+	_, err := n.store.Get(*fullKey)
+	return err == nil
+}
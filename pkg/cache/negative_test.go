@@ -0,0 +1,75 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestNegativeCacheLimit(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	miss := func() ([]byte, error) {
+		calls++
+		return nil, nil
+	}
+	saveMissing := func(c cache.Cache, key string) []byte {
+		data, err := c.SaveRaw(ctx, key, miss, time.Minute, cache.WithPreventCacheMiss(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	check := func(t *testing.T, c cache.Cache) {
+		for i := 0; i < 50; i++ {
+			if err := c.Set(ctx, fmt.Sprintf("real%d", i), i, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// 大量不存在的key只保留最近的10个空值，不会挤掉正常数据
+		for i := 0; i < 1000; i++ {
+			saveMissing(c, fmt.Sprintf("missing%d", i))
+		}
+		for i := 0; i < 50; i++ {
+			if v, err := cache.Get[int](ctx, c, fmt.Sprintf("real%d", i)); err != nil || v != i {
+				t.Fatalf("real%d = %v, %v, want %d", i, v, err, i)
+			}
+		}
+
+		calls = 0
+		for i := 990; i < 1000; i++ {
+			saveMissing(c, fmt.Sprintf("missing%d", i))
+		}
+		if calls != 0 {
+			t.Errorf("recent negative entries reloaded %d times, want 0", calls)
+		}
+		saveMissing(c, "missing0")
+		if calls != 1 {
+			t.Errorf("evicted negative entry loaded %d times, want 1", calls)
+		}
+
+		// 正常数据写入后优先于空值
+		if err := c.Set(ctx, "missing999", 7, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if data := saveMissing(c, "missing999"); string(data) != "7" {
+			t.Errorf("SaveRaw after Set = %q, want 7", data)
+		}
+	}
+
+	t.Run("memory", func(t *testing.T) {
+		check(t, newTestMemory(t, cache.WithMaxEntries(100), cache.WithNegativeCacheLimit(10)))
+	})
+	t.Run("redis", func(t *testing.T) {
+		c, mr := newTestRedis(t, cache.WithNegativeCacheLimit(10))
+		check(t, c)
+		// 空值不写入redis
+		if n := len(mr.Keys()); n != 51 {
+			t.Errorf("redis keys = %d, want 51", n)
+		}
+	})
+}
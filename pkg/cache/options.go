@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"time"
+
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
 )
 
 // CacheType 缓存类型
@@ -28,11 +31,85 @@ type Options struct {
 	// LockPrefix 锁前缀
 	LockPrefix string
 
+	// LockKeyFunc 自定义Redis锁键的生成方式，nil时为LockPrefix+锁名称
+	LockKeyFunc LockKeyFunc
+
 	// CacheSize 内存缓存大小(字节)
 	CacheSize int
 
+	// LRUEntries 内存缓存改用LRU淘汰时的最大条目数，0表示使用freecache
+	LRUEntries int
+
+	// NegativeCacheLimit 防穿透空值单独保存的最大条目数，0表示空值写入主存储
+	NegativeCacheLimit int
+
 	// SetGCPercent 是否设置GC百分比
+	//
+	// Deprecated: 使用GCPercent
 	SetGCPercent bool
+
+	// GCPercent 创建内存缓存时设置的GC百分比，0表示不修改；该设置作用于整个进程
+	GCPercent int
+
+	// MaxConcurrentLoads SaveRaw中加载函数的全局最大并发数，0表示不限制
+	MaxConcurrentLoads int
+
+	// ReadOnly 只读模式，写操作返回ErrReadOnly
+	ReadOnly bool
+
+	// OperationTimeout 单次后端操作的超时时间，0表示不限制
+	OperationTimeout time.Duration
+
+	// OperationTimeoutAlways 调用方的ctx已有截止时间时是否仍然应用OperationTimeout
+	OperationTimeoutAlways bool
+
+	// PingOnInit 创建redis缓存时是否检查连接，默认在首次使用时才连接
+	PingOnInit bool
+
+	// WriteCoalescing SetCoalesced的刷新周期，0表示不合并
+	WriteCoalescing time.Duration
+
+	// EncryptionKey AES密钥，长度为16、24或32字节，nil表示不加密
+	EncryptionKey []byte
+
+	// CompressionThreshold 达到该长度的值写入前压缩，0表示不压缩
+	CompressionThreshold int
+
+	// AdaptiveCompression 是否按key记录压缩效果，跳过压缩没有收益的key
+	AdaptiveCompression bool
+
+	// SchemaVersion 缓存值的结构版本，0表示不添加版本标记
+	SchemaVersion int
+
+	// WriteBehind 写入缓存后异步持久化到二级存储的函数，nil表示不启用
+	WriteBehind WriteBehindFunc
+
+	// WriteBehindInterval 异步持久化的周期
+	WriteBehindInterval time.Duration
+
+	// WriteBehindBatch 每批持久化的最大数量，待持久化数量达到该值时立即刷新
+	WriteBehindBatch int
+
+	// SkipOversized SaveRaw加载的值超过后端大小限制时不缓存，直接返回加载的值，默认返回ErrValueTooLarge
+	SkipOversized bool
+
+	// HotKeyTopN 统计读取次数最多的key的数量，0表示不统计
+	HotKeyTopN int
+
+	// SlowLogThreshold 单次操作耗时超过该值时输出警告，0表示不记录
+	SlowLogThreshold time.Duration
+
+	// SlowLogger 输出慢操作警告的日志
+	SlowLogger zerolog.Logger
+
+	// Middlewares 自定义装饰器，包装在所有内置装饰器之外
+	Middlewares []Middleware
+
+	// Logger 后台协程panic时使用的日志，默认使用zerolog的全局日志
+	Logger zerolog.Logger
+
+	// clock 时间来源，nil时使用系统时间
+	clock clock
 }
 
 // Option 配置函数类型
@@ -53,6 +130,35 @@ func WithMemory() Option {
 	}
 }
 
+// WithLRU 使用按条目数淘汰最久未访问键的内存缓存，适合访问局部性强的场景
+func WithLRU(maxEntries int) Option {
+	return func(o *Options) {
+		o.Type = MemoryCache
+		o.LRUEntries = maxEntries
+	}
+}
+
+// WithMiddleware 在内置装饰器之外按顺序添加自定义装饰器，第一个在最外层，多次调用时追加到已有装饰器之后
+func WithMiddleware(mws ...Middleware) Option {
+	return func(o *Options) {
+		o.Middlewares = append(o.Middlewares, mws...)
+	}
+}
+
+// WithMaxEntries 内存缓存最多保存n个条目，超过后淘汰最久未访问的键，等同于WithLRU(n)
+// 适用于基数有上限且值大小相近的缓存，例如按用户保存的会话数据，比字节预算更容易估算
+func WithMaxEntries(n int) Option {
+	return WithLRU(n)
+}
+
+// WithNegativeCacheLimit WithPreventCacheMiss缓存的空值单独保存在本进程内，最多entries个，超过后淘汰最久未访问的空值
+// 大量不存在的key（例如攻击者枚举的ID）不会挤占正常数据，也不会在redis中堆积；正常数据写入后优先于空值
+func WithNegativeCacheLimit(entries int) Option {
+	return func(o *Options) {
+		o.NegativeCacheLimit = entries
+	}
+}
+
 // WithKeyPrefix 设置键前缀
 func WithKeyPrefix(prefix string) Option {
 	return func(o *Options) {
@@ -67,16 +173,173 @@ func WithLockPrefix(prefix string) Option {
 	}
 }
 
-// WithCacheSize 设置内存缓存大小(字节)
+// WithLockKeyFunc 自定义Redis锁键的生成方式，内存缓存不受影响
+func WithLockKeyFunc(fn LockKeyFunc) Option {
+	return func(o *Options) {
+		o.LockKeyFunc = fn
+	}
+}
+
+// WithLockHashTag 锁键携带数据键的哈希标签，在Redis Cluster中与数据键落在同一个slot
+// 例如KeyPrefix为"app:"时，Lock("user:1")的键为LockPrefix+"{app:user:1}:user:1"
+func WithLockHashTag() Option {
+	return WithLockKeyFunc(HashTagLockKey)
+}
+
+// WithCacheSize 设置内存缓存大小(字节)，默认100MB
+// freecache只能按字节预算淘汰，无法限制条目数；需要按条目数限制时使用WithMaxEntries，此时该选项不生效
 func WithCacheSize(size int) Option {
 	return func(o *Options) {
 		o.CacheSize = size
 	}
 }
 
-// WithSetGCPercent 设置是否调整GC百分比
+// WithSetGCPercent 设置是否将GC百分比调整为20
+//
+// Deprecated: 使用WithGCPercent明确指定百分比
 func WithSetGCPercent(set bool) Option {
 	return func(o *Options) {
 		o.SetGCPercent = set
 	}
 }
+
+// WithGCPercent 创建内存缓存时调用debug.SetGCPercent(percent)，Close时恢复原来的值
+// 注意这会改变整个进程的GC行为，而不只是缓存；只有在进程专门用于缓存等明确需要时才使用
+// freecache的数据不受GC扫描影响，降低百分比可以减少其他堆对象的内存峰值，但会增加GC的CPU开销
+func WithGCPercent(percent int) Option {
+	return func(o *Options) {
+		o.GCPercent = percent
+	}
+}
+
+// WithMaxConcurrentLoads 限制SaveRaw加载函数的全局并发数，超出的调用排队等待直到ctx结束
+func WithMaxConcurrentLoads(n int) Option {
+	return func(o *Options) {
+		o.MaxConcurrentLoads = n
+	}
+}
+
+// WithReadOnly 只读模式，用于维护期间或只读副本
+// Set、Lock等写操作返回ErrReadOnly，SaveRaw命中时返回缓存，未命中时只调用fn而不写入
+func WithReadOnly() Option {
+	return func(o *Options) {
+		o.ReadOnly = true
+	}
+}
+
+// WithOperationTimeout 为每次redis操作设置超时时间，默认只在调用方的ctx没有截止时间时生效
+// 内存缓存的操作不会阻塞，不受该选项影响
+func WithOperationTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.OperationTimeout = d
+	}
+}
+
+// WithPingOnInit 创建redis缓存时执行PING，连接失败时New直接返回错误，便于启动阶段发现配置问题
+// 超时时间使用OperationTimeout，未设置时为5秒
+func WithPingOnInit() Option {
+	return func(o *Options) {
+		o.PingOnInit = true
+	}
+}
+
+// WithOperationTimeoutAlways 即使调用方的ctx已有截止时间也应用OperationTimeout，两者中更早的截止时间生效
+func WithOperationTimeoutAlways() Option {
+	return func(o *Options) {
+		o.OperationTimeoutAlways = true
+	}
+}
+
+// WithWriteCoalescing 合并SetCoalesced的写入，每个key只保留最新值并按interval周期写入后端
+// 读取会优先返回尚未刷新的值，Close时写入剩余的值；Raw视图不经过合并
+func WithWriteCoalescing(interval time.Duration) Option {
+	return func(o *Options) {
+		o.WriteCoalescing = interval
+	}
+}
+
+// WithSchemaVersion 写入时为值添加结构版本标记，读取到其他版本或没有标记的值时视为未命中并重新加载
+// 修改缓存结构体后递增版本，部署时无需手动清空缓存；空值不添加标记，Pipeline的Incr计数不区分版本，Raw视图不处理版本
+func WithSchemaVersion(v int) Option {
+	return func(o *Options) {
+		o.SchemaVersion = v
+	}
+}
+
+// WithEncryption 使用AES-GCM加密写入的值，读取时解密，适用于缓存中包含个人信息等需要加密存储的数据
+// key长度为16、24或32字节，分别对应AES-128、AES-192和AES-256；每次写入使用随机nonce，包含KeyPrefix的完整键作为附加数据
+// 没有加密标记的旧值按原样读取，便于灰度开启；解密失败时返回KindSerialization的BackendError
+// 空值不加密，Pipeline的Incr计数以明文保存；Raw视图同样加密；暂不支持密钥轮换，加密值中预留了密钥编号
+func WithEncryption(key []byte) Option {
+	return func(o *Options) {
+		o.EncryptionKey = key
+	}
+}
+
+// WithCompression 写入前使用DEFLATE压缩长度达到threshold字节的值，读取时按值中的标记解压，threshold小于等于0时为1024
+// 没有压缩标记的旧值按原样读取，关闭压缩前需要等待已压缩的值过期；空值和十进制整数不压缩，Raw视图不处理压缩
+// 压缩后没有减少10%时保存原值；可以通过SetTyped或WithCompressionHint按值的类型指定是否压缩
+// 解压后超过512MB(Redis单个值的上限)时返回KindSerialization的BackendError，超过该大小的值不压缩
+func WithCompression(threshold int) Option {
+	return func(o *Options) {
+		if threshold <= 0 {
+			threshold = 1024
+		}
+		o.CompressionThreshold = threshold
+	}
+}
+
+// WithAdaptiveCompression 开启压缩并按key记录压缩效果，上次压缩没有收益的key之后跳过压缩，每16次写入重新尝试一次
+// 避免对已压缩的图片等无法压缩的值反复消耗CPU；未设置WithCompression时阈值为1024，只对CompressionAuto的写入生效
+func WithAdaptiveCompression() Option {
+	return func(o *Options) {
+		if o.CompressionThreshold <= 0 {
+			o.CompressionThreshold = 1024
+		}
+		o.AdaptiveCompression = true
+	}
+}
+
+// WithWriteBehind 写入缓存后异步调用flush持久化到二级存储，同一个key只保留最新值
+// 缓存本身仍然同步写入；按interval周期或积累batch个值时分批刷新，失败的值在下个周期重试，最多尝试3次
+// 队列已满时写入返回ErrWriteBehindFull，此时缓存已经写入；Close时刷新剩余的值，Raw视图不会持久化
+// interval必须大于0，否则New返回ErrInvalidParams
+func WithWriteBehind(flush WriteBehindFunc, interval time.Duration, batch int) Option {
+	return func(o *Options) {
+		o.WriteBehind = flush
+		o.WriteBehindInterval = interval
+		o.WriteBehindBatch = batch
+	}
+}
+
+// WithSkipOversized SaveRaw加载的值超过后端大小限制时跳过缓存，仍然返回加载的值
+// freecache单条数据不能超过缓存大小的1/1024，Redis单个值不能超过512MB
+func WithSkipOversized() Option {
+	return func(o *Options) {
+		o.SkipOversized = true
+	}
+}
+
+// WithHotKeyTracking 采样统计读取次数最多的topN个key，通过HotKeys获取，用于判断哪些key适合放入本地缓存
+// 统计自创建缓存以来的读取，次数为估算值；Raw视图的读取不计入
+func WithHotKeyTracking(topN int) Option {
+	return func(o *Options) {
+		o.HotKeyTopN = topN
+	}
+}
+
+// WithSlowLog 单次后端操作耗时超过threshold时通过z输出警告，包含方法名、key和耗时，默认不记录
+// SaveRaw的耗时不包含fn的执行时间；Raw视图的操作不记录
+func WithSlowLog(threshold time.Duration, z zerolog.Logger) Option {
+	return func(o *Options) {
+		o.SlowLogThreshold = threshold
+		o.SlowLogger = z
+	}
+}
+
+// WithLogger 设置后台协程panic时使用的日志
+func WithLogger(logger zerolog.Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
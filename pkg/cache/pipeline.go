@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Pipeliner 缓冲在Pipeline中的写操作，fn返回后一起提交
+type Pipeliner interface {
+	// Set 写入键值，value按Set的规则序列化
+	Set(key string, value any, expiration time.Duration)
+	// Delete 删除键
+	Delete(keys ...string)
+	// Incr 将十进制整数值增加delta，键不存在时视为0，保留原有过期时间
+	Incr(key string, delta int64)
+}
+
+type pipelineKind int
+
+const (
+	pipelineSet pipelineKind = iota
+	pipelineDelete
+	pipelineIncr
+)
+
+// pipelineOp 一个缓冲的操作
+type pipelineOp struct {
+	kind       pipelineKind
+	key        string
+	data       []byte
+	expiration time.Duration
+	delta      int64
+}
+
+// pipeline Pipeliner的默认实现，只记录操作，由各后端负责执行
+type pipeline struct {
+	ops []pipelineOp
+	err error
+}
+
+func (p *pipeline) Set(key string, value any, expiration time.Duration) {
+	data, ok := value.([]byte)
+	if !ok {
+		var err error
+		data, err = Marshal(value)
+		if err != nil {
+			p.err = errors.CombineErrors(p.err, wrapSerializationError(err, "cache: failed to marshal value"))
+			return
+		}
+	}
+	p.ops = append(p.ops, pipelineOp{kind: pipelineSet, key: key, data: data, expiration: expiration})
+}
+
+func (p *pipeline) Delete(keys ...string) {
+	for _, key := range keys {
+		p.ops = append(p.ops, pipelineOp{kind: pipelineDelete, key: key})
+	}
+}
+
+func (p *pipeline) Incr(key string, delta int64) {
+	p.ops = append(p.ops, pipelineOp{kind: pipelineIncr, key: key, delta: delta})
+}
+
+// replay 将记录的操作按顺序写入另一个Pipeliner
+func (p *pipeline) replay(dst Pipeliner) {
+	for _, op := range p.ops {
+		switch op.kind {
+		case pipelineSet:
+			dst.Set(op.key, op.data, op.expiration)
+		case pipelineDelete:
+			dst.Delete(op.key)
+		case pipelineIncr:
+			dst.Incr(op.key, op.delta)
+		}
+	}
+}
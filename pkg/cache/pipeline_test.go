@@ -0,0 +1,85 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestPipeline(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		if err := c.Set(ctx, "n", 5, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Set(ctx, "d", "x", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+
+		err := c.Pipeline(ctx, func(p cache.Pipeliner) error {
+			p.Set("s", "v", time.Minute)
+			p.Incr("n", 3)
+			p.Incr("m", 2)
+			p.Delete("d")
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := cache.Get[int](ctx, c, "n"); err != nil || n != 8 {
+			t.Fatalf("n期望8，实际%d %v", n, err)
+		}
+		if m, err := cache.Get[int](ctx, c, "m"); err != nil || m != 2 {
+			t.Fatalf("不存在的键应从0开始，实际%d %v", m, err)
+		}
+		if s, err := cache.Get[string](ctx, c, "s"); err != nil || s != "v" {
+			t.Fatalf("s期望v，实际%q %v", s, err)
+		}
+		if ok, err := c.Exists(ctx, "d"); err != nil || ok {
+			t.Fatalf("d应被删除，实际%v %v", ok, err)
+		}
+	})
+}
+
+func TestPipelineAllOrNothing(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		if err := c.Set(ctx, "n", 5, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+
+		// fn返回错误时不执行任何操作
+		errAbort := errors.New("abort")
+		err := c.Pipeline(ctx, func(p cache.Pipeliner) error {
+			p.Set("s", "v", time.Minute)
+			p.Incr("n", 3)
+			p.Delete("n")
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("应返回fn的错误，实际%v", err)
+		}
+
+		// 序列化失败时同样不执行任何操作
+		err = c.Pipeline(ctx, func(p cache.Pipeliner) error {
+			p.Set("s", "v", time.Minute)
+			p.Set("bad", make(chan int), time.Minute)
+			p.Incr("n", 3)
+			return nil
+		})
+		var backendErr *cache.BackendError
+		if !errors.As(err, &backendErr) || backendErr.Kind != cache.KindSerialization {
+			t.Fatalf("期望KindSerialization，实际%v", err)
+		}
+
+		if ok, _ := c.Exists(ctx, "s"); ok {
+			t.Fatal("失败的Pipeline不应写入s")
+		}
+		if n, err := cache.Get[int](ctx, c, "n"); err != nil || n != 5 {
+			t.Fatalf("失败的Pipeline不应修改n，实际%d %v", n, err)
+		}
+	})
+}
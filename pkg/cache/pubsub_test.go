@@ -0,0 +1,76 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	c, mr := newTestRedis(t, cache.WithKeyPrefix("app:"))
+	ctx := context.Background()
+
+	messages, cancel, err := c.Subscribe(ctx, "events", "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := mr.PubSubNumSub("app:events"); n["app:events"] != 1 {
+		t.Fatalf("频道应添加前缀并完成订阅，实际%v", n)
+	}
+
+	if err := c.Publish(ctx, "events", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-messages:
+		if m.Channel != "events" || string(m.Payload) != "hello" {
+			t.Fatalf("消息不正确: %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("未收到消息")
+	}
+
+	// 取消后通道关闭
+	cancel()
+	select {
+	case _, ok := <-messages:
+		if ok {
+			t.Fatal("取消后不应再收到消息")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("取消后通道未关闭")
+	}
+}
+
+func TestSubscribeContextDone(t *testing.T) {
+	c, _ := newTestRedis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	messages, stop, err := c.Subscribe(ctx, "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	cancel()
+	select {
+	case _, ok := <-messages:
+		if ok {
+			t.Fatal("ctx结束后不应再收到消息")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ctx结束后通道未关闭")
+	}
+}
+
+func TestPubSubMemoryNotSupported(t *testing.T) {
+	c := newTestMemory(t)
+	if err := c.Publish(context.Background(), "events", []byte("hello")); !errors.Is(err, cache.ErrNotSupported) {
+		t.Fatalf("期望ErrNotSupported，实际%v", err)
+	}
+	if _, _, err := c.Subscribe(context.Background(), "events"); !errors.Is(err, cache.ErrNotSupported) {
+		t.Fatalf("期望ErrNotSupported，实际%v", err)
+	}
+}
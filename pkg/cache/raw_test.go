@@ -0,0 +1,44 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestRawView(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		raw := c.Raw()
+
+		// 其他服务写入的不带前缀的键
+		if err := raw.Set(ctx, "external", "v", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := cache.Get[string](ctx, raw, "external"); err != nil || v != "v" {
+			t.Fatalf("got %q %v", v, err)
+		}
+		if _, err := c.GetRaw(ctx, "external"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("带前缀的缓存不应读到外部键: %v", err)
+		}
+
+		// 带前缀写入的键在视图中使用完整键读取
+		if err := c.Set(ctx, "own", "o", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := cache.Get[string](ctx, raw, "app:own"); err != nil || v != "o" {
+			t.Fatalf("got %q %v", v, err)
+		}
+
+		// 关闭视图不影响原缓存
+		if err := raw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if v, err := cache.Get[string](ctx, c, "own"); err != nil || v != "o" {
+			t.Fatalf("got %q %v", v, err)
+		}
+	}, cache.WithKeyPrefix("app:"))
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// readOnlyCache 只读模式的缓存，读操作透传给底层缓存，写操作返回ErrReadOnly
+type readOnlyCache struct {
+	Cache
+	loader loadLimiter
+}
+
+func newReadOnlyCache(c Cache, opts *Options) Cache {
+	return &readOnlyCache{
+		Cache:  c,
+		loader: newLoadLimiter(opts.MaxConcurrentLoads),
+	}
+}
+
+func (c *readOnlyCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return ErrReadOnly
+}
+
+func (c *readOnlyCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return ErrReadOnly
+}
+
+func (c *readOnlyCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	return ErrReadOnly
+}
+
+func (c *readOnlyCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	return false, ErrReadOnly
+}
+
+// SaveRaw 命中时返回缓存数据，未命中时直接返回fn的结果，不写入缓存
+func (c *readOnlyCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	opts := newSaveOptions(ctx, options)
+
+	if !opts.ForceRefresh {
+		data, err := c.Cache.GetRaw(ctx, key)
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	data, err := c.loader.run(ctx, fn)
+	if err != nil {
+		return errorFallback(ctx, c.Cache, key, opts, err)
+	}
+	return data, nil
+}
+
+func (c *readOnlyCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	return ErrReadOnly
+}
+
+func (c *readOnlyCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return ErrReadOnly
+}
+
+func (c *readOnlyCache) Lock(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return "", ErrReadOnly
+}
+
+func (c *readOnlyCache) Unlock(ctx context.Context, key string, value string) error {
+	return ErrReadOnly
+}
+
+// Capabilities 只保留读取和PubSub
+func (c *readOnlyCache) Capabilities() CacheCapabilities {
+	return CacheCapabilities{PubSub: c.Cache.Capabilities().PubSub}
+}
+
+func (c *readOnlyCache) Raw() Cache {
+	return &readOnlyCache{
+		Cache:  c.Cache.Raw(),
+		loader: c.loader,
+	}
+}
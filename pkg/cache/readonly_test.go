@@ -0,0 +1,63 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestReadOnly(t *testing.T) {
+	c, mr := newTestRedis(t, cache.WithReadOnly())
+	ctx := context.Background()
+	if err := mr.Set("k", `"v"`); err != nil {
+		t.Fatal(err)
+	}
+
+	// 读操作正常
+	if v, err := cache.Get[string](ctx, c, "k"); err != nil || v != "v" {
+		t.Fatalf("got %q %v", v, err)
+	}
+	if ok, err := c.Exists(ctx, "k"); !ok || err != nil {
+		t.Fatalf("got %v %v", ok, err)
+	}
+	data, err := c.SaveRaw(ctx, "k", func() ([]byte, error) {
+		t.Fatal("命中时不应调用fn")
+		return nil, nil
+	}, time.Minute)
+	if err != nil || string(data) != `"v"` {
+		t.Fatalf("got %q %v", data, err)
+	}
+
+	// 未命中时返回fn的结果但不写入
+	data, err = c.SaveRaw(ctx, "miss", func() ([]byte, error) { return []byte("loaded"), nil }, time.Minute)
+	if err != nil || string(data) != "loaded" {
+		t.Fatalf("got %q %v", data, err)
+	}
+	if mr.Exists("miss") {
+		t.Fatal("只读模式不应写入")
+	}
+
+	// 写操作返回ErrReadOnly
+	writes := map[string]error{
+		"Set":          c.Set(ctx, "k", "x", time.Minute),
+		"SetCoalesced": c.SetCoalesced(ctx, "k", "x", time.Minute),
+		"MSet":         c.MSet(ctx, map[string]any{"k": "x"}, time.Minute),
+		"Pipeline":     c.Pipeline(ctx, func(p cache.Pipeliner) error { return nil }),
+	}
+	_, writes["SetIfNewer"] = c.SetIfNewer(ctx, "k", []byte("x"), 1, time.Minute)
+	_, writes["Lock"] = c.Lock(ctx, "k", time.Minute)
+	for name, err := range writes {
+		if !errors.Is(err, cache.ErrReadOnly) {
+			t.Errorf("%s: 期望ErrReadOnly，实际%v", name, err)
+		}
+	}
+	if v, _ := mr.Get("k"); v != `"v"` {
+		t.Fatalf("值被修改: %q", v)
+	}
+	if c.Capabilities().Write {
+		t.Fatal("只读模式不应声明写能力")
+	}
+}
@@ -3,10 +3,15 @@ package cache
 import (
 	"context"
 	"github.com/google/uuid"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cockroachdb/errors"
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	gkit_zerolog "github.com/shaco-go/gkit-layout/pkg/zerolog"
 )
 
 type redisCache struct {
@@ -14,22 +19,74 @@ type redisCache struct {
 	prefix    string
 	lockKey   string
 	lockValue string
+	lockFunc  LockKeyFunc // 自定义锁键的生成方式，nil时为LockPrefix+锁名称
+	loader    loadLimiter
+	raw       bool // 是否为不带前缀的视图
+	logger    zerolog.Logger
+
+	skipOversized bool           // SaveRaw遇到超过大小限制的值时是否跳过缓存
+	negative      *negativeCache // 防穿透的空值，nil时空值写入主存储
+
+	refreshers *refresherGroup
+	clock      clock
+
+	opTimeout       time.Duration // 单次操作超时时间
+	opTimeoutAlways bool          // 调用方已设置截止时间时是否仍然应用超时
 }
 
+// redisPingTimeout PingOnInit未设置OperationTimeout时的超时时间
+const redisPingTimeout = 5 * time.Second
+
 func newRedisCache(opts *Options) (Cache, error) {
 	if opts.Redis == nil {
 		return nil, errors.New("cache: redis client is required")
 	}
 
+	if opts.PingOnInit {
+		timeout := opts.OperationTimeout
+		if timeout <= 0 {
+			timeout = redisPingTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := opts.Redis.Ping(ctx).Err(); err != nil {
+			return nil, wrapBackendError(err, "cache: failed to ping redis")
+		}
+	}
+
 	return &redisCache{
-		client:  opts.Redis,
-		prefix:  opts.KeyPrefix,
-		lockKey: opts.LockPrefix,
+		client:   opts.Redis,
+		prefix:   opts.KeyPrefix,
+		lockKey:  opts.LockPrefix,
+		lockFunc: opts.LockKeyFunc,
+		loader:   newLoadLimiter(opts.MaxConcurrentLoads),
+		logger:   opts.Logger,
+
+		skipOversized: opts.SkipOversized,
+		negative:      newNegativeCache(opts.NegativeCacheLimit, opts.clock),
+
+		refreshers: newRefresherGroup(opts.Logger),
+		clock:      opts.clock,
+
+		opTimeout:       opts.OperationTimeout,
+		opTimeoutAlways: opts.OperationTimeoutAlways,
 	}, nil
 }
 
+// operationContext 为单次redis操作派生带超时的ctx
+// context.WithTimeout会保留更早的截止时间，所以不会缩短调用方设置的更严格的超时
+func (c *redisCache) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok && !c.opTimeoutAlways {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.opTimeout)
+}
+
 func (c *redisCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
-	fullKey := c.prefix + key
+	fullKey := joinKey(c.prefix, key)
 
 	// 序列化值
 	var data []byte
@@ -42,43 +99,128 @@ func (c *redisCache) Set(ctx context.Context, key string, value any, expiration
 		// 否则序列化为JSON
 		data, err = Marshal(value)
 		if err != nil {
-			return errors.Wrap(err, "cache: failed to marshal value")
+			return wrapSerializationError(err, "cache: failed to marshal value")
 		}
 	}
 
-	return c.client.Set(ctx, fullKey, data, expiration).Err()
+	if err := checkRedisValueSize(data); err != nil {
+		return err
+	}
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	if err := c.client.Set(ctx, fullKey, data, expiration).Err(); err != nil {
+		return wrapBackendError(err, "cache: failed to set value in redis")
+	}
+	return nil
+}
+
+// redisMaxValueSize Redis单个字符串值的大小上限
+const redisMaxValueSize = 512 << 20
+
+// checkRedisValueSize 在发送前检查值的大小，避免传输大量数据后才被服务端拒绝
+func checkRedisValueSize(data []byte) error {
+	if len(data) > redisMaxValueSize {
+		return errors.Wrapf(ErrValueTooLarge, "value size %d exceeds redis limit", len(data))
+	}
+	return nil
+}
+
+func (c *redisCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.Set(ctx, key, value, expiration)
 }
 
 func (c *redisCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
-	fullKey := c.prefix + key
+	if IsBypass(ctx) {
+		return nil, ErrNotFound
+	}
+	fullKey := joinKey(c.prefix, key)
 
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
 	data, err := c.client.Get(ctx, fullKey).Bytes()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, ErrNotFound
 		}
-		return nil, errors.Wrap(err, "cache: failed to get value from redis")
+		return nil, wrapBackendError(err, "cache: failed to get value from redis")
 	}
 
 	return data, nil
 }
 
+func (c *redisCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	if len(keys) == 0 || IsBypass(ctx) {
+		return result, nil
+	}
+
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = joinKey(c.prefix, key)
+	}
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	values, err := c.client.MGet(ctx, fullKeys...).Result()
+	if err != nil {
+		return nil, wrapBackendError(err, "cache: failed to get values from redis")
+	}
+
+	for i, value := range values {
+		// 不存在的键返回nil
+		if str, ok := value.(string); ok {
+			result[keys[i]] = []byte(str)
+		}
+	}
+	return result, nil
+}
+
+func (c *redisCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	// MSET不支持过期时间，使用pipeline批量执行SET
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	pipe := c.client.Pipeline()
+	for key, value := range values {
+		data, ok := value.([]byte)
+		if !ok {
+			var err error
+			data, err = Marshal(value)
+			if err != nil {
+				return wrapSerializationError(err, "cache: failed to marshal value")
+			}
+		}
+		if err := checkRedisValueSize(data); err != nil {
+			return err
+		}
+		pipe.Set(ctx, joinKey(c.prefix, key), data, expiration)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return wrapBackendError(err, "cache: failed to set values in redis")
+	}
+	return nil
+}
+
 func (c *redisCache) Exists(ctx context.Context, key string) (bool, error) {
-	fullKey := c.prefix + key
+	fullKey := joinKey(c.prefix, key)
 
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
 	count, err := c.client.Exists(ctx, fullKey).Result()
 	if err != nil {
-		return false, errors.Wrap(err, "cache: failed to check key existence")
+		return false, wrapBackendError(err, "cache: failed to check key existence")
 	}
 
 	return count > 0, nil
 }
 
 func (c *redisCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
-	opts := &saveOptions{}
-	for _, opt := range options {
-		opt(opts)
-	}
+	opts := newSaveOptions(ctx, options)
 
 	// 如果不是强制刷新，先尝试从缓存获取
 	if !opts.ForceRefresh {
@@ -89,45 +231,55 @@ func (c *redisCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte,
 		if err != ErrNotFound {
 			return nil, err
 		}
+		if c.negative.contains(c.prefix, key) {
+			return nil, nil
+		}
 	}
 
 	// 使用分布式锁防止缓存击穿（多个请求同时获取不存在的缓存）
-	lockKey := "lock:" + key
-
-	// 尝试获取锁，防止缓存击穿
-	locked := false
-	lockValue, err := c.Lock(ctx, lockKey, 5*time.Second)
-	if err == nil {
-		locked = true
-		defer c.Unlock(ctx, lockKey, lockValue)
-	} else if err != ErrLockAcquired {
-		// 如果是其他错误，则直接返回
-		return nil, err
-	}
+	lockKey := c.lockFullKey("lock:"+key, key)
+	deadline := c.clock.Now().Add(opts.LockWaitTimeout)
 
-	// 如果获取到锁或者锁已被其他请求获取但尝试再次从缓存获取
-	if locked || err == ErrLockAcquired {
-		// 再次尝试从缓存获取，可能其他持有锁的请求已经设置了缓存
-		data, err := c.GetRaw(ctx, key)
+	for {
+		lockValue, err := c.lock(ctx, lockKey, 5*time.Second)
 		if err == nil {
-			return data, nil
-		}
-		if err != ErrNotFound {
+			defer c.unlock(ctx, lockKey, lockValue)
+		} else if err != ErrLockAcquired {
+			// 如果是其他错误，则直接返回
 			return nil, err
 		}
 
-		// 如果没有获取到锁，等待一段时间后再重试
-		if !locked {
-			time.Sleep(100 * time.Millisecond)
-			return c.SaveRaw(ctx, key, fn, expiration, options...)
+		// 再次尝试从缓存获取，可能其他持有锁的请求已经设置了缓存
+		if !opts.ForceRefresh {
+			data, err := c.GetRaw(ctx, key)
+			if err == nil {
+				return data, nil
+			}
+			if err != ErrNotFound {
+				return nil, err
+			}
+		}
+		if lockValue != "" {
+			break
+		}
+
+		// 没有获取到锁，等待一段时间后再重试
+		if c.clock.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+		select {
+		case <-c.clock.After(opts.LockPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
 
 	// 缓存未命中或强制刷新，调用函数获取数据
-	result, err := fn()
+	result, err := c.loader.run(ctx, fn)
 	if err != nil {
-		return nil, err
+		return errorFallback(ctx, c, key, opts, err)
 	}
+	expiration = opts.expiration(result, expiration)
 
 	// 处理缓存穿透 - 即使结果为空值，仍然缓存
 	if (result == nil || len(result) == 0) && opts.PreventCacheMiss {
@@ -135,21 +287,179 @@ func (c *redisCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte,
 		if opts.NilExpiration > 0 {
 			exp = opts.NilExpiration
 		}
+		if c.negative != nil {
+			// 空值记录在单独的有上限的存储中
+			c.negative.add(c.prefix, key, exp)
+			return result, nil
+		}
 		err = c.Set(ctx, key, result, exp)
 	} else {
 		err = c.Set(ctx, key, result, expiration)
 	}
 
+	if c.skipOversized && errors.Is(err, ErrValueTooLarge) {
+		return result, nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	storeStale(ctx, c, c.logger, key, result, expiration, opts)
 
 	return result, nil
 }
 
+func (c *redisCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	if ts < 0 {
+		return false, ErrInvalidParams
+	}
+	if err := checkRedisValueSize(value); err != nil {
+		return false, err
+	}
+	fullKey := joinKey(c.prefix, key)
+
+	// 使用hash存储{ts, value}，在服务端比较时间戳
+	// Lua的数字是双精度浮点，纳秒时间戳会丢失精度，所以按十进制字符串比较
+	const luaScript = `
+local function newer(a, b)
+    if #a ~= #b then
+        return #a > #b
+    end
+    return a > b
+end
+local current = redis.call("HGET", KEYS[1], "ts")
+if current and not newer(ARGV[1], current) then
+    return 0
+end
+redis.call("HSET", KEYS[1], "ts", ARGV[1], "value", ARGV[2])
+if tonumber(ARGV[3]) > 0 then
+    redis.call("PEXPIRE", KEYS[1], ARGV[3])
+else
+    redis.call("PERSIST", KEYS[1])
+end
+return 1`
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	result, err := c.client.Eval(ctx, luaScript, []string{fullKey}, strconv.FormatInt(ts, 10), value, expiration.Milliseconds()).Int64()
+	if err != nil {
+		return false, wrapBackendError(err, "cache: failed to set value if newer")
+	}
+
+	return result == 1, nil
+}
+
+func (c *redisCache) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	fullKey := joinKey(c.prefix, key)
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	values, err := c.client.HMGet(ctx, fullKey, "ts", "value").Result()
+	if err != nil {
+		return nil, 0, wrapBackendError(err, "cache: failed to get value from redis")
+	}
+	if values[0] == nil {
+		return nil, 0, ErrNotFound
+	}
+
+	ts, err := strconv.ParseInt(values[0].(string), 10, 64)
+	if err != nil {
+		return nil, 0, wrapSerializationError(err, "cache: invalid timestamp")
+	}
+	var data []byte
+	if v, ok := values[1].(string); ok {
+		data = []byte(v)
+	}
+
+	return data, ts, nil
+}
+
+func (c *redisCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	p := &pipeline{}
+	if err := fn(p); err != nil {
+		return err
+	}
+	if p.err != nil {
+		return p.err
+	}
+	if len(p.ops) == 0 {
+		return nil
+	}
+	for _, op := range p.ops {
+		if err := checkRedisValueSize(op.data); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, op := range p.ops {
+			fullKey := joinKey(c.prefix, op.key)
+			switch op.kind {
+			case pipelineSet:
+				pipe.Set(ctx, fullKey, op.data, op.expiration)
+			case pipelineDelete:
+				pipe.Del(ctx, fullKey)
+			case pipelineIncr:
+				pipe.IncrBy(ctx, fullKey, op.delta)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return wrapBackendError(err, "cache: failed to exec pipeline")
+	}
+	return nil
+}
+
+// lockFullKey 生成锁的完整键
+// 参数:
+//   - name: 锁名称
+//   - key: 锁保护的数据键，不包含KeyPrefix
+//
+// 返回:
+//   - string: 锁在Redis中的键
+func (c *redisCache) lockFullKey(name, key string) string {
+	if c.lockFunc != nil {
+		return c.lockFunc(c.lockKey, name, joinKey(c.prefix, key))
+	}
+	return joinKey(c.lockKey, name)
+}
+
+func (c *redisCache) Backend() string {
+	return "redis"
+}
+
+func (c *redisCache) Capabilities() CacheCapabilities {
+	return CacheCapabilities{
+		Write:           true,
+		Pipeline:        true,
+		Lock:            true,
+		DistributedLock: true,
+		PubSub:          true,
+		Semaphore:       true,
+		Counter:         true,
+	}
+}
+
+func (c *redisCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.refreshers.register(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *redisCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.refreshers.register(target, key, loader, ttl, refreshBefore)
+}
+
+func (c *redisCache) stopRefreshers() {
+	c.refreshers.stop()
+}
+
 func (c *redisCache) Lock(ctx context.Context, key string, expiration time.Duration) (string, error) {
-	fullKey := c.lockKey + key
+	return c.lock(ctx, c.lockFullKey(key, key), expiration)
+}
 
+// lock 使用完整的锁键获取锁
+func (c *redisCache) lock(ctx context.Context, fullKey string, expiration time.Duration) (string, error) {
 	// 生成唯一的锁标识符
 	u, err := uuid.NewUUID()
 	if err != nil {
@@ -158,9 +468,11 @@ func (c *redisCache) Lock(ctx context.Context, key string, expiration time.Durat
 
 	// 使用SET NX命令（只在键不存在时设置）来实现分布式锁
 	// 相当于执行 SET key value NX PX expiration
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
 	success, err := c.client.SetNX(ctx, fullKey, u.String(), expiration).Result()
 	if err != nil {
-		return "", errors.Wrap(err, "cache: failed to acquire lock")
+		return "", wrapBackendError(err, "cache: failed to acquire lock")
 	}
 
 	if !success {
@@ -171,8 +483,11 @@ func (c *redisCache) Lock(ctx context.Context, key string, expiration time.Durat
 }
 
 func (c *redisCache) Unlock(ctx context.Context, key string, value string) error {
-	fullKey := c.lockKey + key
+	return c.unlock(ctx, c.lockFullKey(key, key), value)
+}
 
+// unlock 使用完整的锁键释放锁
+func (c *redisCache) unlock(ctx context.Context, fullKey string, value string) error {
 	// 使用Lua脚本确保只删除由当前持有者设置的锁
 	// 这防止了一个客户端意外删除另一个客户端的锁
 	const luaScript = `
@@ -182,9 +497,11 @@ else
     return 0
 end`
 
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
 	result, err := c.client.Eval(ctx, luaScript, []string{fullKey}, value).Result()
 	if err != nil {
-		return errors.Wrap(err, "cache: failed to release lock")
+		return wrapBackendError(err, "cache: failed to release lock")
 	}
 
 	if result.(int64) == 0 {
@@ -194,6 +511,167 @@ end`
 	return nil
 }
 
+// lockScanCount 每次SCAN返回的键数量提示
+const lockScanCount = 1000
+
+// ActiveLocks 使用SCAN遍历LockPrefix下的键并读取值和剩余时间，Redis Cluster时遍历每个主节点
+// 信号量的键不是锁，不会返回；遍历期间释放或过期的锁会被忽略
+func (c *redisCache) ActiveLocks(ctx context.Context) ([]LockInfo, error) {
+	if c.lockKey == "" {
+		// 没有前缀时无法区分锁和数据键
+		return nil, ErrInvalidParams
+	}
+	match := globEscaper.Replace(c.lockKey) + "*"
+	semaphorePrefix := c.lockKey + "semaphore:"
+
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, match, lockScanCount).Iterator()
+		for iter.Next(ctx) {
+			if key := iter.Val(); !strings.HasPrefix(key, semaphorePrefix) {
+				mu.Lock()
+				keys = append(keys, key)
+				mu.Unlock()
+			}
+		}
+		return iter.Err()
+	}
+	var err error
+	if cluster, ok := c.client.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+	} else {
+		err = scan(ctx, c.client)
+	}
+	if err != nil {
+		return nil, wrapBackendError(err, "cache: failed to scan locks")
+	}
+	if len(keys) == 0 {
+		return []LockInfo{}, nil
+	}
+
+	pipe := c.client.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = pipe.Get(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	// 单个键已释放或类型不符时命令返回错误，逐个检查
+	_, _ = pipe.Exec(ctx)
+
+	locks := make([]LockInfo, 0, len(keys))
+	for i, key := range keys {
+		token, err := gets[i].Result()
+		if errors.Is(err, redis.Nil) || isWrongType(err) {
+			continue
+		}
+		if err != nil {
+			return nil, wrapBackendError(err, "cache: failed to read lock")
+		}
+		ttl := ttls[i].Val()
+		if ttl < 0 {
+			// -1表示不过期，-2表示已经不存在
+			ttl = 0
+		}
+		locks = append(locks, LockInfo{Key: key, Token: token, TTL: ttl})
+	}
+	sortLocks(locks)
+	return locks, nil
+}
+
+// globEscaper 转义SCAN MATCH中的通配符
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// isWrongType 判断是否为键类型不符的错误
+func isWrongType(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+func (c *redisCache) Publish(ctx context.Context, channel string, message []byte) error {
+	ctx, cancel := c.operationContext(ctx)
+	defer cancel()
+	if err := c.client.Publish(ctx, joinKey(c.prefix, channel), message).Err(); err != nil {
+		return wrapBackendError(err, "cache: failed to publish message")
+	}
+	return nil
+}
+
+func (c *redisCache) Subscribe(ctx context.Context, channels ...string) (<-chan Message, func(), error) {
+	if len(channels) == 0 {
+		return nil, nil, ErrInvalidParams
+	}
+	fullChannels := make([]string, len(channels))
+	for i, channel := range channels {
+		fullChannels[i] = joinKey(c.prefix, channel)
+	}
+
+	// 等待订阅确认，保证返回之后发布的消息都能收到
+	pubsub := c.client.Subscribe(ctx, fullChannels...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, nil, wrapBackendError(err, "cache: failed to subscribe")
+	}
+
+	out := make(chan Message)
+	done := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			_ = pubsub.Close()
+		})
+	}
+
+	// go-redis的Channel会在断线后自动重新订阅
+	gkit_zerolog.Go(c.logger, func() {
+		defer close(out)
+		defer stop()
+		ch := pubsub.Channel()
+		for {
+			select {
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				m := Message{
+					Channel: strings.TrimPrefix(msg.Channel, c.prefix),
+					Payload: []byte(msg.Payload),
+				}
+				select {
+				case out <- m:
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				}
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+
+	return out, stop, nil
+}
+
+func (c *redisCache) Raw() Cache {
+	view := *c
+	view.prefix = ""
+	view.raw = true
+	return &view
+}
+
 func (c *redisCache) Close() error {
+	// 视图与原缓存共享客户端，由原缓存负责关闭
+	if c.raw {
+		return nil
+	}
+	c.refreshers.stop()
 	return c.client.Close()
 }
@@ -0,0 +1,80 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestRedisSaveRawLockWaitTimeout(t *testing.T) {
+	c, _ := newTestRedis(t)
+	ctx := context.Background()
+
+	// 模拟其他进程持有加载锁且迟迟不写入缓存
+	if _, err := c.Lock(ctx, "lock:k", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	called := false
+	start := time.Now()
+	_, err := c.SaveRaw(ctx, "k", func() ([]byte, error) {
+		called = true
+		return []byte("v"), nil
+	}, time.Minute, cache.WithLockPollInterval(5*time.Millisecond), cache.WithLockWaitTimeout(50*time.Millisecond))
+	if !errors.Is(err, cache.ErrLockTimeout) {
+		t.Fatalf("期望ErrLockTimeout，实际%v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("应在等待超时后返回，耗时%s", elapsed)
+	}
+	if called {
+		t.Fatal("未获取到锁时不应调用fn")
+	}
+
+	// 其他键不受影响
+	data, err := c.SaveRaw(ctx, "other", func() ([]byte, error) { return []byte("v"), nil }, time.Minute)
+	if err != nil || string(data) != "v" {
+		t.Fatalf("got %q %v", data, err)
+	}
+}
+
+func TestRedisSaveRawLockWaitContextCanceled(t *testing.T) {
+	c, _ := newTestRedis(t)
+	if _, err := c.Lock(context.Background(), "lock:k", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	_, err := c.SaveRaw(ctx, "k", func() ([]byte, error) { return []byte("v"), nil }, time.Minute,
+		cache.WithLockPollInterval(5*time.Millisecond), cache.WithLockWaitTimeout(time.Minute))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ctx结束时应停止等待，实际%v", err)
+	}
+}
+
+func TestRedisPingOnInit(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+
+	if _, err := cache.New(cache.WithRedis(client), cache.WithPingOnInit()); err != nil {
+		t.Fatalf("New with reachable redis: %v", err)
+	}
+
+	mr.Close()
+	_, err := cache.New(cache.WithRedis(client), cache.WithPingOnInit(), cache.WithOperationTimeout(time.Second))
+	var be *cache.BackendError
+	if !errors.As(err, &be) || be.Kind != cache.KindConnection {
+		t.Fatalf("New with unreachable redis: err = %v, want a connection BackendError", err)
+	}
+	// 不开启时不检查连接
+	if _, err := cache.New(cache.WithRedis(client)); err != nil {
+		t.Fatalf("New without PingOnInit: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog"
+	gkit_zerolog "github.com/shaco-go/gkit-layout/pkg/zerolog"
+)
+
+// RefreshLoader 主动刷新时加载最新值
+type RefreshLoader func(ctx context.Context) ([]byte, error)
+
+// refreshRegistrar 持有刷新协程的缓存，装饰器通过它把刷新写入自身
+type refreshRegistrar interface {
+	registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error
+	stopRefreshers()
+}
+
+// refresherGroup 管理主动刷新的协程，同一个key重复注册时替换旧的协程
+type refresherGroup struct {
+	logger zerolog.Logger
+
+	mu      sync.Mutex
+	entries map[string]*refreshEntry
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+func newRefresherGroup(logger zerolog.Logger) *refresherGroup {
+	return &refresherGroup{
+		logger:  logger,
+		entries: make(map[string]*refreshEntry),
+	}
+}
+
+// refreshEntry 一个刷新协程
+type refreshEntry struct {
+	cancel context.CancelFunc
+}
+
+// register 同步加载并写入一次，成功后启动刷新协程
+// 参数:
+//   - target: 写入刷新结果的缓存
+//   - key: 缓存键
+//   - loader: 加载函数
+//   - ttl: 缓存过期时间
+//   - refreshBefore: 在过期前多久刷新，必须小于ttl
+//
+// 返回:
+//   - error: 参数无效或首次加载失败时返回错误
+func (g *refresherGroup) register(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	if loader == nil || ttl <= 0 || refreshBefore <= 0 || refreshBefore >= ttl {
+		return ErrInvalidParams
+	}
+
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return errors.New("cache: cannot register refresher on closed cache")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &refreshEntry{cancel: cancel}
+	if prev, ok := g.entries[key]; ok {
+		prev.cancel()
+	}
+	g.entries[key] = entry
+	g.wg.Add(1)
+	g.mu.Unlock()
+
+	refresh := func() error {
+		data, err := loader(ctx)
+		if err != nil {
+			return err
+		}
+		return target.Set(ctx, key, data, ttl)
+	}
+	if err := refresh(); err != nil {
+		g.release(key, entry)
+		g.wg.Done()
+		return err
+	}
+
+	gkit_zerolog.Go(g.logger, func() {
+		defer g.wg.Done()
+		defer g.release(key, entry)
+
+		// 随机提前最多refreshBefore/2，避免多个实例同时刷新
+		next := func() time.Duration {
+			return ttl - refreshBefore - time.Duration(rand.Int64N(int64(refreshBefore/2)+1))
+		}
+		timer := time.NewTimer(next())
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return
+			}
+
+			// 失败时保留旧值，在剩余时间内以refreshBefore/4的间隔重试
+			if err := refresh(); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				g.logger.Warn().Err(err).Str("key", key).Msg("cache: refresh failed, keeping old value")
+				timer.Reset(max(refreshBefore/4, time.Millisecond))
+				continue
+			}
+			timer.Reset(next())
+		}
+	})
+	return nil
+}
+
+// release 移除key对应的协程，key已被重新注册时不处理
+func (g *refresherGroup) release(key string, entry *refreshEntry) {
+	entry.cancel()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.entries[key] == entry {
+		delete(g.entries, key)
+	}
+}
+
+// stop 停止所有刷新协程并等待退出，之后不能再注册
+func (g *refresherGroup) stop() {
+	g.mu.Lock()
+	g.closed = true
+	for _, entry := range g.entries {
+		entry.cancel()
+	}
+	g.entries = make(map[string]*refreshEntry)
+	g.mu.Unlock()
+	g.wg.Wait()
+}
@@ -0,0 +1,63 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestRefresherNoMissOnFastTTL(t *testing.T) {
+	c := newTestMemory(t)
+	ctx := context.Background()
+	var loads atomic.Int32
+	// 内存缓存的过期时间精确到秒，2秒的TTL在1到2秒之间过期，刷新间隔不超过0.5秒
+	err := c.RegisterRefresher("hot", func(ctx context.Context) ([]byte, error) {
+		n := loads.Add(1)
+		if n == 2 {
+			return nil, errors.New("transient failure")
+		}
+		return []byte(strconv.Itoa(int(n))), nil
+	}, 2*time.Second, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2500 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if _, err := c.GetRaw(ctx, "hot"); err != nil {
+			t.Fatalf("read path missed after %d loads: %v", loads.Load(), err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := loads.Load(); n < 3 {
+		t.Fatalf("loads = %d, want the refresher to retry after the failure", n)
+	}
+
+	// 关闭后刷新协程停止，不能再注册
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	stopped := loads.Load()
+	time.Sleep(600 * time.Millisecond)
+	if n := loads.Load(); n != stopped {
+		t.Errorf("loads after Close = %d, want %d", n, stopped)
+	}
+	if err := c.RegisterRefresher("k", func(context.Context) ([]byte, error) { return nil, nil }, time.Second, time.Millisecond); err == nil {
+		t.Error("RegisterRefresher after Close succeeded, want error")
+	}
+}
+
+func TestRefresherInvalidParams(t *testing.T) {
+	c := newTestMemory(t)
+	loader := func(context.Context) ([]byte, error) { return []byte("v"), nil }
+	for _, tt := range []struct{ ttl, before time.Duration }{{0, 0}, {time.Second, 0}, {time.Second, time.Second}} {
+		if err := c.RegisterRefresher("k", loader, tt.ttl, tt.before); !errors.Is(err, cache.ErrInvalidParams) {
+			t.Errorf("RegisterRefresher(ttl=%v, before=%v) = %v, want ErrInvalidParams", tt.ttl, tt.before, err)
+		}
+	}
+}
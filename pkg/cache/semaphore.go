@@ -0,0 +1,221 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/google/uuid"
+)
+
+// Semaphore 计数信号量，同一名称最多limit个许可同时被持有
+// Redis使用有序集合记录持有者及其过期时间，进程崩溃后许可在TTL后自动释放；内存缓存只在进程内生效
+type Semaphore struct {
+	key          string
+	limit        int
+	ttl          time.Duration
+	pollInterval time.Duration
+	redis        *redisCache
+	local        *localSemaphore
+}
+
+// SemaphoreOption 定义了NewSemaphore的函数式选项类型
+type SemaphoreOption func(*Semaphore)
+
+// WithSemaphoreTTL 设置许可的最长持有时间，超过后视为持有者已崩溃，默认30秒
+func WithSemaphoreTTL(ttl time.Duration) SemaphoreOption {
+	return func(s *Semaphore) {
+		if ttl > 0 {
+			s.ttl = ttl
+		}
+	}
+}
+
+// WithSemaphorePollInterval 设置Redis许可不足时的重试间隔，默认50毫秒
+func WithSemaphorePollInterval(d time.Duration) SemaphoreOption {
+	return func(s *Semaphore) {
+		if d > 0 {
+			s.pollInterval = d
+		}
+	}
+}
+
+// NewSemaphore 基于缓存创建名为name的信号量，键使用LockPrefix
+func NewSemaphore(c Cache, name string, limit int, opts ...SemaphoreOption) (*Semaphore, error) {
+	if limit <= 0 {
+		return nil, ErrInvalidParams
+	}
+	s := &Semaphore{
+		limit:        limit,
+		ttl:          30 * time.Second,
+		pollInterval: 50 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	switch b := unwrapCache(c).(type) {
+	case *redisCache:
+		s.key = b.lockKey + "semaphore:" + name
+		s.redis = b
+	case *shardedCache:
+		shard := b.shard(name)
+		s.key = shard.lockKey + "semaphore:" + name
+		s.redis = shard
+	case *memoryCache:
+		s.key = b.lockKey + "semaphore:" + name
+		s.local = b.semaphore(s.key, limit)
+	case *readOnlyCache:
+		return nil, ErrReadOnly
+	default:
+		return nil, ErrNotSupported
+	}
+	return s, nil
+}
+
+// unwrapCache 去掉装饰器，返回底层缓存；只读缓存原样返回
+func unwrapCache(c Cache) Cache {
+	for {
+		inner, ok := unwrapDecorator(c)
+		if !ok {
+			return c
+		}
+		c = inner
+	}
+}
+
+// unwrapDecorator 去掉一层内置装饰器或实现了Unwrap() Cache的装饰器，c不是装饰器时返回false
+func unwrapDecorator(c Cache) (Cache, bool) {
+	switch cc := c.(type) {
+	case *coalescingCache:
+		return cc.Cache, true
+	case *writeBehindCache:
+		return cc.Cache, true
+	case *versionedCache:
+		return cc.Cache, true
+	case *encryptedCache:
+		return cc.Cache, true
+	case *compressedCache:
+		return cc.Cache, true
+	case *hotKeyCache:
+		return cc.Cache, true
+	case *slowLogCache:
+		return cc.Cache, true
+	case interface{ Unwrap() Cache }:
+		return cc.Unwrap(), true
+	default:
+		return nil, false
+	}
+}
+
+// Acquire 获取n个许可，许可不足时阻塞直到ctx结束
+// 返回的release用于归还许可，可以重复调用；Redis的许可超过TTL未归还会被其他获取者清理
+func (s *Semaphore) Acquire(ctx context.Context, n int) (release func(), err error) {
+	if n <= 0 || n > s.limit {
+		return nil, ErrInvalidParams
+	}
+	if s.local != nil {
+		return s.local.acquire(ctx, n)
+	}
+	return s.acquireRedis(ctx, n)
+}
+
+func (s *Semaphore) acquireRedis(ctx context.Context, n int) (func(), error) {
+	// 每个许可是有序集合的一个成员，分数为过期时间(毫秒)；使用服务端时间避免各进程时钟不一致
+	const luaScript = `
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if redis.call("ZCARD", KEYS[1]) + #ARGV - 2 > tonumber(ARGV[1]) then
+    return 0
+end
+for i = 3, #ARGV do
+    redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), ARGV[i])
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1`
+
+	u, err := uuid.NewUUID()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	members := make([]any, n)
+	args := []any{s.limit, s.ttl.Milliseconds()}
+	for i := range members {
+		members[i] = u.String() + ":" + strconv.Itoa(i)
+		args = append(args, members[i])
+	}
+
+	for {
+		opCtx, cancel := s.redis.operationContext(ctx)
+		ok, err := s.redis.client.Eval(opCtx, luaScript, []string{s.key}, args...).Int()
+		cancel()
+		if err != nil {
+			return nil, wrapBackendError(err, "cache: failed to acquire semaphore")
+		}
+		if ok == 1 {
+			break
+		}
+
+		select {
+		case <-time.After(s.pollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ctx, cancel := s.redis.operationContext(context.Background())
+			defer cancel()
+			_ = s.redis.client.ZRem(ctx, s.key, members...).Err()
+		})
+	}, nil
+}
+
+// localSemaphore 进程内的信号量，slots中的元素数即已持有的许可数
+type localSemaphore struct {
+	slots chan struct{}
+	// mu 保证一次只有一个获取者在占用许可，避免多个获取者各自占用部分许可而互相等待
+	mu chan struct{}
+}
+
+func newLocalSemaphore(limit int) *localSemaphore {
+	return &localSemaphore{
+		slots: make(chan struct{}, limit),
+		mu:    make(chan struct{}, 1),
+	}
+}
+
+func (s *localSemaphore) acquire(ctx context.Context, n int) (func(), error) {
+	select {
+	case s.mu <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-s.mu }()
+
+	for i := 0; i < n; i++ {
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			// 归还已经占用的许可
+			for ; i > 0; i-- {
+				<-s.slots
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			for i := 0; i < n; i++ {
+				<-s.slots
+			}
+		})
+	}, nil
+}
@@ -0,0 +1,83 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestSemaphore(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		s, err := cache.NewSemaphore(c, "api", 3,
+			cache.WithSemaphorePollInterval(5*time.Millisecond), cache.WithSemaphoreTTL(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.Background()
+
+		// 获取到上限
+		release1, err := s.Acquire(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release2, err := s.Acquire(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 第N+1个许可阻塞到ctx结束
+		timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if _, err := s.Acquire(timeout, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("许可不足时应阻塞到ctx结束，实际%v", err)
+		}
+
+		// 归还后阻塞的获取者可以继续，重复归还不会多归还许可
+		acquired := make(chan func(), 1)
+		go func() {
+			release, err := s.Acquire(ctx, 2)
+			if err != nil {
+				t.Error(err)
+				close(acquired)
+				return
+			}
+			acquired <- release
+		}()
+		release1()
+		release1()
+		var release3 func()
+		select {
+		case release3 = <-acquired:
+		case <-time.After(time.Second):
+			t.Fatal("归还许可后获取者未被唤醒")
+		}
+		if release3 == nil {
+			return
+		}
+
+		full, cancelFull := context.WithTimeout(ctx, 30*time.Millisecond)
+		defer cancelFull()
+		if _, err := s.Acquire(full, 1); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("重复归还不应增加许可，实际%v", err)
+		}
+		release2()
+		release3()
+	})
+}
+
+func TestSemaphoreInvalidParams(t *testing.T) {
+	c := newTestMemory(t)
+	if _, err := cache.NewSemaphore(c, "api", 0); !errors.Is(err, cache.ErrInvalidParams) {
+		t.Fatalf("limit为0时期望ErrInvalidParams，实际%v", err)
+	}
+	s, err := cache.NewSemaphore(c, "api", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Acquire(context.Background(), 3); !errors.Is(err, cache.ErrInvalidParams) {
+		t.Fatalf("超过limit时期望ErrInvalidParams，实际%v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/cockroachdb/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	gkit_zerolog "github.com/shaco-go/gkit-layout/pkg/zerolog"
+)
+
+// shardedCache 按key的一致性哈希将数据分布到多个独立的Redis实例
+type shardedCache struct {
+	shards []*redisCache
+	prefix string
+	logger zerolog.Logger
+}
+
+// NewShardedCache 基于多个独立的Redis实例(非Cluster模式)创建分片缓存
+// 使用xxhash对添加KeyPrefix后的完整key做Jump一致性哈希选择实例，增加实例时只有约1/n的key需要迁移
+//   - 单key操作、Lock/Unlock和RegisterRefresher: 路由到key所在的实例，锁与数据落在同一个实例
+//   - MGetRaw/MSet: 按实例分组后依次执行
+//   - Pipeline: 按实例拆分，每个实例内为MULTI/EXEC，不同实例之间不保证原子性
+//   - Publish/Subscribe: 按频道名路由，订阅多个频道时合并为一个通道
+//   - NewSemaphore/NewCounter: 按名称路由到一个实例
+//
+// 参数:
+//   - clients: Redis客户端，顺序决定分片编号，调整顺序会导致大部分key迁移
+//   - opts: 与New相同的选项，WithRedis和WithMemory会被忽略
+//
+// 返回:
+//   - Cache: 分片缓存
+//   - error: clients为空、选项无效或创建单个实例失败时返回错误
+func NewShardedCache(clients []redis.UniversalClient, opts ...Option) (Cache, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("cache: at least one redis client is required")
+	}
+
+	options := &Options{
+		Type:   RedisCache,
+		Logger: log.Logger,
+		clock:  realClock{},
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if err := validateOptions(options); err != nil {
+		return nil, err
+	}
+
+	c := &shardedCache{
+		shards: make([]*redisCache, 0, len(clients)),
+		prefix: options.KeyPrefix,
+		logger: options.Logger,
+	}
+	for _, client := range clients {
+		shardOptions := *options
+		shardOptions.Redis = client
+		shard, err := newRedisCache(&shardOptions)
+		if err != nil {
+			return nil, err
+		}
+		c.shards = append(c.shards, shard.(*redisCache))
+	}
+	return decorate(c, options)
+}
+
+// shardIndex 计算key所在的分片编号
+func (c *shardedCache) shardIndex(key string) int {
+	fullKey := acquireKey(c.prefix, key)
+	defer releaseKey(fullKey)
+	return jumpHash(xxhash.Sum64(*fullKey), len(c.shards))
+}
+
+// shard 返回key所在的分片
+func (c *shardedCache) shard(key string) *redisCache {
+	return c.shards[c.shardIndex(key)]
+}
+
+// jumpHash Jump一致性哈希，见 https://arxiv.org/abs/1406.2294
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
+
+// groupKeys 按分片分组key
+func (c *shardedCache) groupKeys(keys []string) map[int][]string {
+	groups := make(map[int][]string)
+	for _, key := range keys {
+		i := c.shardIndex(key)
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+func (c *shardedCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.shard(key).Set(ctx, key, value, expiration)
+}
+
+func (c *shardedCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.shard(key).SetCoalesced(ctx, key, value, expiration)
+}
+
+func (c *shardedCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	return c.shard(key).GetRaw(ctx, key)
+}
+
+func (c *shardedCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(keys))
+	for i, group := range c.groupKeys(keys) {
+		found, err := c.shards[i].MGetRaw(ctx, group)
+		if err != nil {
+			return nil, err
+		}
+		for key, data := range found {
+			result[key] = data
+		}
+	}
+	return result, nil
+}
+
+// MSet 按分片分组写入，某个分片失败时其他分片的写入不会回滚
+func (c *shardedCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	groups := make(map[int]map[string]any)
+	for key, value := range values {
+		i := c.shardIndex(key)
+		if groups[i] == nil {
+			groups[i] = make(map[string]any)
+		}
+		groups[i][key] = value
+	}
+
+	var errs error
+	for i, group := range groups {
+		errs = errors.CombineErrors(errs, c.shards[i].MSet(ctx, group, expiration))
+	}
+	return errs
+}
+
+func (c *shardedCache) Exists(ctx context.Context, key string) (bool, error) {
+	return c.shard(key).Exists(ctx, key)
+}
+
+func (c *shardedCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	return c.shard(key).SaveRaw(ctx, key, fn, expiration, options...)
+}
+
+func (c *shardedCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	return c.shard(key).SetIfNewer(ctx, key, value, ts, expiration)
+}
+
+func (c *shardedCache) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	return c.shard(key).GetWithTimestamp(ctx, key)
+}
+
+// Pipeline fn只执行一次，记录的操作按分片拆分后分别提交，同一分片内保持原有顺序
+func (c *shardedCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	p := &pipeline{}
+	if err := fn(p); err != nil {
+		return err
+	}
+	if p.err != nil {
+		return p.err
+	}
+
+	groups := make(map[int]*pipeline)
+	for _, op := range p.ops {
+		i := c.shardIndex(op.key)
+		if groups[i] == nil {
+			groups[i] = &pipeline{}
+		}
+		groups[i].ops = append(groups[i].ops, op)
+	}
+
+	var errs error
+	for i, group := range groups {
+		errs = errors.CombineErrors(errs, c.shards[i].Pipeline(ctx, func(dst Pipeliner) error {
+			group.replay(dst)
+			return nil
+		}))
+	}
+	return errs
+}
+
+func (c *shardedCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.registerRefresher(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *shardedCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.shard(key).registerRefresher(target, key, loader, ttl, refreshBefore)
+}
+
+func (c *shardedCache) stopRefreshers() {
+	for _, shard := range c.shards {
+		shard.stopRefreshers()
+	}
+}
+
+func (c *shardedCache) Lock(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return c.shard(key).Lock(ctx, key, expiration)
+}
+
+func (c *shardedCache) Unlock(ctx context.Context, key string, value string) error {
+	return c.shard(key).Unlock(ctx, key, value)
+}
+
+// ActiveLocks 合并所有分片的锁
+func (c *shardedCache) ActiveLocks(ctx context.Context) ([]LockInfo, error) {
+	var locks []LockInfo
+	for _, shard := range c.shards {
+		shardLocks, err := shard.ActiveLocks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, shardLocks...)
+	}
+	sortLocks(locks)
+	return locks, nil
+}
+
+func (c *shardedCache) Publish(ctx context.Context, channel string, message []byte) error {
+	return c.shard(channel).Publish(ctx, channel, message)
+}
+
+// Subscribe 在每个频道所在的分片上订阅，消息合并到同一个通道，任意分片订阅失败时取消已有的订阅
+func (c *shardedCache) Subscribe(ctx context.Context, channels ...string) (<-chan Message, func(), error) {
+	if len(channels) == 0 {
+		return nil, nil, ErrInvalidParams
+	}
+
+	groups := c.groupKeys(channels)
+	subs := make([]<-chan Message, 0, len(groups))
+	cancels := make([]func(), 0, len(groups))
+	cancelAll := func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+	for i, group := range groups {
+		ch, cancel, err := c.shards[i].Subscribe(ctx, group...)
+		if err != nil {
+			cancelAll()
+			return nil, nil, err
+		}
+		subs = append(subs, ch)
+		cancels = append(cancels, cancel)
+	}
+	if len(subs) == 1 {
+		return subs[0], cancels[0], nil
+	}
+
+	out := make(chan Message)
+	done := make(chan struct{})
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			cancelAll()
+		})
+	}
+
+	// 各分片的通道在取消或ctx结束后关闭，全部关闭后关闭合并的通道
+	var wg sync.WaitGroup
+	for _, ch := range subs {
+		wg.Add(1)
+		gkit_zerolog.Go(c.logger, func() {
+			defer wg.Done()
+			for msg := range ch {
+				select {
+				case out <- msg:
+				case <-done:
+					return
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+	gkit_zerolog.Go(c.logger, func() {
+		wg.Wait()
+		close(out)
+		stop()
+	})
+	return out, stop, nil
+}
+
+func (c *shardedCache) Backend() string {
+	return "redis"
+}
+
+// Capabilities 与单个Redis一致，但Pipeline跨分片时不保证原子性
+func (c *shardedCache) Capabilities() CacheCapabilities {
+	return c.shards[0].Capabilities()
+}
+
+func (c *shardedCache) Raw() Cache {
+	view := &shardedCache{
+		shards: make([]*redisCache, len(c.shards)),
+		logger: c.logger,
+	}
+	for i, shard := range c.shards {
+		view.shards[i] = shard.Raw().(*redisCache)
+	}
+	return view
+}
+
+func (c *shardedCache) Close() error {
+	var errs error
+	for _, shard := range c.shards {
+		errs = errors.CombineErrors(errs, shard.Close())
+	}
+	return errs
+}
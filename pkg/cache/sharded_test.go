@@ -0,0 +1,146 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// newTestShards 创建n个miniredis及其客户端
+func newTestShards(t *testing.T, n int) ([]*miniredis.Miniredis, []redis.UniversalClient) {
+	t.Helper()
+	servers := make([]*miniredis.Miniredis, n)
+	clients := make([]redis.UniversalClient, n)
+	for i := range servers {
+		servers[i] = miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: servers[i].Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		clients[i] = client
+	}
+	return servers, clients
+}
+
+// shardOf 返回保存fullKey的分片编号，不存在时返回-1，存在于多个分片时测试失败
+func shardOf(t *testing.T, servers []*miniredis.Miniredis, fullKey string) int {
+	t.Helper()
+	owner := -1
+	for i, s := range servers {
+		if s.Exists(fullKey) {
+			if owner != -1 {
+				t.Fatalf("%s exists on shards %d and %d", fullKey, owner, i)
+			}
+			owner = i
+		}
+	}
+	return owner
+}
+
+func TestShardedKeyPlacementStable(t *testing.T) {
+	ctx := context.Background()
+	servers, clients := newTestShards(t, 4)
+	c, err := cache.NewShardedCache(clients, cache.WithKeyPrefix("svc:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	placement := make(map[string]int)
+	used := make(map[int]bool)
+	for i := 0; i < 40; i++ {
+		key := "k" + strconv.Itoa(i)
+		if err := c.Set(ctx, key, "v", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		placement[key] = shardOf(t, servers, "svc:"+key)
+		used[placement[key]] = true
+	}
+	if len(used) != len(servers) {
+		t.Fatalf("keys use shards %v, want all %d", used, len(servers))
+	}
+
+	// 另一个使用相同客户端顺序的实例读取到同样的分片
+	other, err := cache.NewShardedCache(clients, cache.WithKeyPrefix("svc:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key := range placement {
+		if _, err := other.GetRaw(ctx, key); err != nil {
+			t.Fatalf("other instance GetRaw(%s) = %v", key, err)
+		}
+	}
+
+	// 增加一个分片时只有部分key迁移到新分片，其他key保持原位
+	_, extra := newTestShards(t, 1)
+	grown, err := cache.NewShardedCache(append(clients[:len(clients):len(clients)], extra...), cache.WithKeyPrefix("svc:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for key := range placement {
+		if _, err := grown.GetRaw(ctx, key); err != nil {
+			moved++
+		}
+	}
+	if moved == 0 || moved > len(placement)/2 {
+		t.Errorf("%d of %d keys moved after adding a shard, want roughly 1/5", moved, len(placement))
+	}
+}
+
+func TestShardedMultiKeyFanOut(t *testing.T) {
+	ctx := context.Background()
+	servers, clients := newTestShards(t, 4)
+	c, err := cache.NewShardedCache(clients, cache.WithKeyPrefix("svc:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := make(map[string]any)
+	keys := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		key := "m" + strconv.Itoa(i)
+		keys = append(keys, key)
+		values[key] = i
+	}
+	if err := c.MSet(ctx, values, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	used := make(map[int]bool)
+	for _, key := range keys {
+		used[shardOf(t, servers, "svc:"+key)] = true
+	}
+	if len(used) < 2 {
+		t.Fatalf("MSet wrote to shards %v, want a fan-out", used)
+	}
+
+	found, err := c.MGetRaw(ctx, append(keys, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != len(keys) {
+		t.Fatalf("MGetRaw found %d keys, want %d", len(found), len(keys))
+	}
+
+	// Pipeline中的操作按分片分组提交
+	err = c.Pipeline(ctx, func(p cache.Pipeliner) error {
+		for _, key := range keys {
+			p.Incr(key, 10)
+		}
+		p.Delete("m0", "m1")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := c.GetRaw(ctx, "m3"); err != nil || string(data) != "13" {
+		t.Fatalf("GetRaw(m3) = %q, %v, want 13", data, err)
+	}
+	for _, key := range []string{"m0", "m1"} {
+		if ok, _ := c.Exists(ctx, key); ok {
+			t.Errorf("%s should be deleted", key)
+		}
+	}
+}
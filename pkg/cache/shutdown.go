@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog/log"
+	gkit_zerolog "github.com/shaco-go/gkit-layout/pkg/zerolog"
+)
+
+// registration 一个注册到CloseAll的对象，使用指针标识，被注册的对象本身不需要可比较
+type registration struct {
+	closer io.Closer
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []*registration
+)
+
+// Register 注册进程退出前需要关闭的对象，CloseAll时关闭，返回的函数用于取消注册
+// 开启WithWriteBehind或WithWriteCoalescing的缓存会自动注册，Close后自动取消注册；自定义的缓冲写入也可以注册
+func Register(c io.Closer) (unregister func()) {
+	r := &registration{closer: c}
+	registryMu.Lock()
+	registry = append(registry, r)
+	registryMu.Unlock()
+	return func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		if i := slices.Index(registry, r); i >= 0 {
+			registry = slices.Delete(registry, i, i+1)
+		}
+	}
+}
+
+// CloseAll 按注册的相反顺序关闭所有注册的对象，刷新异步写入中尚未写出的数据，应当在进程退出前调用
+// 外层的装饰器后注册，因此先于内层关闭，合并写入刷新的值仍能进入异步持久化的队列
+// ctx结束时不再等待，返回ctx.Err()，剩余的对象继续在后台关闭；关闭后取消注册
+func CloseAll(ctx context.Context) error {
+	registryMu.Lock()
+	closers := make([]io.Closer, len(registry))
+	for i, r := range registry {
+		closers[len(registry)-1-i] = r.closer
+	}
+	registry = nil
+	registryMu.Unlock()
+
+	done := make(chan error, 1)
+	gkit_zerolog.Go(log.Logger, func() {
+		var errs error
+		for _, c := range closers {
+			errs = errors.CombineErrors(errs, c.Close())
+		}
+		done <- errs
+	})
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "cache: close all")
+	}
+}
@@ -0,0 +1,92 @@
+package cache_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// bufferedWriter 模拟自定义的缓冲写入，Close时写出缓冲区中的数据并记录关闭顺序
+type bufferedWriter struct {
+	name    string
+	mu      sync.Mutex
+	pending []string
+	flushed []string
+	order   *[]string
+}
+
+func (w *bufferedWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushed = append(w.flushed, w.pending...)
+	w.pending = nil
+	*w.order = append(*w.order, w.name)
+	return nil
+}
+
+func TestCloseAllDrainsRegistered(t *testing.T) {
+	var order []string
+	a := &bufferedWriter{name: "a", pending: []string{"x", "y"}, order: &order}
+	b := &bufferedWriter{name: "b", pending: []string{"z"}, order: &order}
+	c := &bufferedWriter{name: "c", order: &order}
+	cache.Register(a)
+	unregister := cache.Register(b)
+	cache.Register(c)
+	unregister()
+
+	if err := cache.CloseAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a.flushed, []string{"x", "y"}) {
+		t.Errorf("a flushed = %v, want [x y]", a.flushed)
+	}
+	if len(b.flushed) != 0 {
+		t.Errorf("unregistered b flushed = %v", b.flushed)
+	}
+	// 按注册的相反顺序关闭
+	if !reflect.DeepEqual(order, []string{"c", "a"}) {
+		t.Errorf("close order = %v, want [c a]", order)
+	}
+
+	// 关闭后已取消注册，再次调用不会重复关闭
+	if err := cache.CloseAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 {
+		t.Errorf("second CloseAll closed again: %v", order)
+	}
+}
+
+func TestCloseAllFlushesBufferedCache(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	persisted := map[string]string{}
+	persist := func(ctx context.Context, key string, value []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		persisted[key] = string(value)
+		return nil
+	}
+	c := newTestMemory(t, cache.WithWriteBehind(persist, time.Hour, 100), cache.WithWriteCoalescing(time.Hour))
+
+	if err := c.Set(ctx, "k", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetCoalesced(ctx, "m", "n", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.CloseAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 合并写入先关闭，刷新的值仍进入异步持久化的队列
+	mu.Lock()
+	defer mu.Unlock()
+	if want := map[string]string{"k": `"v"`, "m": `"n"`}; !reflect.DeepEqual(persisted, want) {
+		t.Errorf("persisted = %v, want %v", persisted, want)
+	}
+}
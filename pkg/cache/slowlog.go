@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// slowLogCache 记录耗时超过阈值的后端操作，包装在其他装饰器之内，只统计后端本身的耗时
+type slowLogCache struct {
+	Cache
+	threshold time.Duration
+	logger    zerolog.Logger
+}
+
+func newSlowLogCache(c Cache, threshold time.Duration, logger zerolog.Logger) Cache {
+	return &slowLogCache{Cache: c, threshold: threshold, logger: logger}
+}
+
+// observe 耗时超过阈值时输出警告
+func (c *slowLogCache) observe(ctx context.Context, method, key string, begin time.Time, exclude time.Duration, err error) {
+	elapsed := time.Since(begin) - exclude
+	if elapsed < c.threshold {
+		return
+	}
+	event := c.logger.Warn().Ctx(ctx).
+		Str("backend", c.Cache.Backend()).
+		Str("method", method).
+		Str("key", key).
+		Dur("elapsed", elapsed).
+		Dur("threshold", c.threshold)
+	if err != nil {
+		event = event.Err(err)
+	}
+	event.Msg("cache: slow operation")
+}
+
+// firstKey 批量操作的日志只记录第一个key
+func firstKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+func (c *slowLogCache) Set(ctx context.Context, key string, value any, expiration time.Duration) (err error) {
+	defer func(begin time.Time) { c.observe(ctx, "Set", key, begin, 0, err) }(time.Now())
+	return c.Cache.Set(ctx, key, value, expiration)
+}
+
+func (c *slowLogCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) (err error) {
+	defer func(begin time.Time) { c.observe(ctx, "SetCoalesced", key, begin, 0, err) }(time.Now())
+	return c.Cache.SetCoalesced(ctx, key, value, expiration)
+}
+
+func (c *slowLogCache) GetRaw(ctx context.Context, key string) (data []byte, err error) {
+	defer func(begin time.Time) { c.observe(ctx, "GetRaw", key, begin, 0, err) }(time.Now())
+	return c.Cache.GetRaw(ctx, key)
+}
+
+func (c *slowLogCache) MGetRaw(ctx context.Context, keys []string) (result map[string][]byte, err error) {
+	defer func(begin time.Time) { c.observe(ctx, "MGetRaw", firstKey(keys), begin, 0, err) }(time.Now())
+	return c.Cache.MGetRaw(ctx, keys)
+}
+
+func (c *slowLogCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) (err error) {
+	var key string
+	for k := range values {
+		key = k
+		break
+	}
+	defer func(begin time.Time) { c.observe(ctx, "MSet", key, begin, 0, err) }(time.Now())
+	return c.Cache.MSet(ctx, values, expiration)
+}
+
+func (c *slowLogCache) Exists(ctx context.Context, key string) (ok bool, err error) {
+	defer func(begin time.Time) { c.observe(ctx, "Exists", key, begin, 0, err) }(time.Now())
+	return c.Cache.Exists(ctx, key)
+}
+
+// SaveRaw 扣除fn的执行时间，加载慢不视为缓存慢
+func (c *slowLogCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) (data []byte, err error) {
+	var loading time.Duration
+	defer func(begin time.Time) { c.observe(ctx, "SaveRaw", key, begin, loading, err) }(time.Now())
+	return c.Cache.SaveRaw(ctx, key, func() ([]byte, error) {
+		defer func(begin time.Time) { loading += time.Since(begin) }(time.Now())
+		return fn()
+	}, expiration, options...)
+}
+
+func (c *slowLogCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (ok bool, err error) {
+	defer func(begin time.Time) { c.observe(ctx, "SetIfNewer", key, begin, 0, err) }(time.Now())
+	return c.Cache.SetIfNewer(ctx, key, value, ts, expiration)
+}
+
+func (c *slowLogCache) GetWithTimestamp(ctx context.Context, key string) (data []byte, ts int64, err error) {
+	defer func(begin time.Time) { c.observe(ctx, "GetWithTimestamp", key, begin, 0, err) }(time.Now())
+	return c.Cache.GetWithTimestamp(ctx, key)
+}
+
+func (c *slowLogCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) (err error) {
+	defer func(begin time.Time) { c.observe(ctx, "Pipeline", "", begin, 0, err) }(time.Now())
+	return c.Cache.Pipeline(ctx, fn)
+}
+
+func (c *slowLogCache) Lock(ctx context.Context, key string, expiration time.Duration) (value string, err error) {
+	defer func(begin time.Time) { c.observe(ctx, "Lock", key, begin, 0, err) }(time.Now())
+	return c.Cache.Lock(ctx, key, expiration)
+}
+
+func (c *slowLogCache) Unlock(ctx context.Context, key string, value string) (err error) {
+	defer func(begin time.Time) { c.observe(ctx, "Unlock", key, begin, 0, err) }(time.Now())
+	return c.Cache.Unlock(ctx, key, value)
+}
+
+func (c *slowLogCache) Publish(ctx context.Context, channel string, message []byte) (err error) {
+	defer func(begin time.Time) { c.observe(ctx, "Publish", channel, begin, 0, err) }(time.Now())
+	return c.Cache.Publish(ctx, channel, message)
+}
+
+// RegisterRefresher 刷新时的写入同样经过当前装饰器
+func (c *slowLogCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.registerRefresher(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *slowLogCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		return r.registerRefresher(target, key, loader, ttl, refreshBefore)
+	}
+	return c.Cache.RegisterRefresher(key, loader, ttl, refreshBefore)
+}
+
+func (c *slowLogCache) stopRefreshers() {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		r.stopRefreshers()
+	}
+}
@@ -0,0 +1,49 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestSlowLogWarns(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	c, _ := newHookedRedis(t, sleepHook{delay: 30 * time.Millisecond}, cache.WithSlowLog(20*time.Millisecond, zerolog.New(&buf)))
+	buf.Reset()
+
+	if _, err := c.GetRaw(ctx, "slow"); err == nil {
+		t.Fatal("GetRaw on missing key succeeded")
+	}
+	out := buf.String()
+	for _, want := range []string{`"level":"warn"`, `"method":"GetRaw"`, `"key":"slow"`, `"elapsed"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("日志缺少 %s: %s", want, out)
+		}
+	}
+}
+
+func TestSlowLogSkipsFast(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	c := newTestMemory(t, cache.WithSlowLog(20*time.Millisecond, zerolog.New(&buf)))
+	if err := c.Set(ctx, "fast", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// 只统计后端操作的耗时，不包括fn
+	_, err := c.SaveRaw(ctx, "k", func() ([]byte, error) {
+		time.Sleep(40 * time.Millisecond)
+		return []byte("x"), nil
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("快速操作不应输出警告: %s", buf.String())
+	}
+}
@@ -0,0 +1,78 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// sleepHook 每条命令执行前等待delay，模拟卡住的redis；ctx先结束时返回ctx的错误
+type sleepHook struct {
+	delay time.Duration
+}
+
+func (h sleepHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h sleepHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		select {
+		case <-time.After(h.delay):
+		case <-ctx.Done():
+			cmd.SetErr(ctx.Err())
+			return ctx.Err()
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h sleepHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestOperationTimeout(t *testing.T) {
+	c, _ := newHookedRedis(t, sleepHook{delay: time.Second}, cache.WithOperationTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	_, err := c.GetRaw(context.Background(), "k")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望DeadlineExceeded，实际%v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("超时未生效，耗时%s", elapsed)
+	}
+}
+
+func TestOperationTimeoutKeepsCallerDeadline(t *testing.T) {
+	// 调用方更早的截止时间不会被延长
+	c, _ := newHookedRedis(t, sleepHook{delay: time.Second}, cache.WithOperationTimeout(time.Minute), cache.WithOperationTimeoutAlways())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := c.Set(ctx, "k", "v", time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望DeadlineExceeded，实际%v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("调用方的截止时间未生效，耗时%s", elapsed)
+	}
+}
+
+func TestOperationTimeoutSkippedWithCallerDeadline(t *testing.T) {
+	// 默认调用方已设置截止时间时不再应用OperationTimeout
+	c, _ := newHookedRedis(t, sleepHook{delay: 100 * time.Millisecond}, cache.WithOperationTimeout(20*time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := c.Set(ctx, "k", "v", time.Minute); err != nil {
+		t.Fatalf("调用方截止时间内应成功，实际%v", err)
+	}
+}
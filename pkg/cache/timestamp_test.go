@@ -0,0 +1,53 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestSetIfNewer(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, c cache.Cache) {
+		ctx := context.Background()
+		const base = int64(1700000000000000000)
+
+		ok, err := c.SetIfNewer(ctx, "k", []byte("b"), base+2, time.Minute)
+		if !ok || err != nil {
+			t.Fatalf("首次写入应当成功: %v %v", ok, err)
+		}
+		// 更旧的时间戳被拒绝
+		ok, err = c.SetIfNewer(ctx, "k", []byte("a"), base+1, time.Minute)
+		if ok || err != nil {
+			t.Fatalf("旧时间戳应当被拒绝: %v %v", ok, err)
+		}
+		// 相同的时间戳同样被拒绝
+		ok, err = c.SetIfNewer(ctx, "k", []byte("x"), base+2, time.Minute)
+		if ok || err != nil {
+			t.Fatalf("相同时间戳应当被拒绝: %v %v", ok, err)
+		}
+		data, ts, err := c.GetWithTimestamp(ctx, "k")
+		if err != nil || string(data) != "b" || ts != base+2 {
+			t.Fatalf("got %q %d %v", data, ts, err)
+		}
+
+		// 更新的时间戳被应用，纳秒级时间戳不丢失精度
+		ok, err = c.SetIfNewer(ctx, "k", []byte("c"), base+3, time.Minute)
+		if !ok || err != nil {
+			t.Fatalf("新时间戳应当被应用: %v %v", ok, err)
+		}
+		data, ts, err = c.GetWithTimestamp(ctx, "k")
+		if err != nil || string(data) != "c" || ts != base+3 {
+			t.Fatalf("got %q %d %v", data, ts, err)
+		}
+
+		if _, _, err := c.GetWithTimestamp(ctx, "missing"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("期望ErrNotFound，实际%v", err)
+		}
+		if _, err := c.SetIfNewer(ctx, "k", nil, -1, time.Minute); !errors.Is(err, cache.ErrInvalidParams) {
+			t.Fatalf("负数时间戳应当返回ErrInvalidParams，实际%v", err)
+		}
+	})
+}
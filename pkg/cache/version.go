@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// versionMagic 版本标记的开头，0xff不会出现在合法的UTF-8和JSON中
+var versionMagic = []byte{0xff, 'v'}
+
+// versionedCache 写入时在值前添加结构版本标记，读取到其他版本的值时视为未命中
+// 除空值外的所有写入都添加标记，十进制整数同样带有标记，旧版本写入的整数在新版本中同样未命中；
+// 空值不添加标记，以便防穿透的空值正常工作
+// Pipeline的Incr直接作用于后端，写入的是没有标记的十进制整数，读取时没有标记的整数视为Incr计数，不区分版本；
+// 计数的初始值应通过Incr写入(键不存在时视为0)，通过Set写入的整数带有标记，之后无法Incr
+type versionedCache struct {
+	Cache
+	header []byte
+}
+
+func newVersionedCache(c Cache, version int) Cache {
+	header := binary.BigEndian.AppendUint32(bytes.Clone(versionMagic), uint32(version))
+	return &versionedCache{Cache: c, header: header}
+}
+
+// stamp 添加版本标记
+func (c *versionedCache) stamp(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+	stamped := make([]byte, 0, len(c.header)+len(data))
+	stamped = append(stamped, c.header...)
+	return append(stamped, data...)
+}
+
+// unstamp 去掉版本标记，版本不一致时返回false，没有标记的十进制整数为Incr计数，原样返回
+func (c *versionedCache) unstamp(data []byte) ([]byte, bool) {
+	if len(data) == 0 {
+		return data, true
+	}
+	if bytes.HasPrefix(data, c.header) {
+		return data[len(c.header):], true
+	}
+	if isIntegerValue(data) {
+		return data, true
+	}
+	return nil, false
+}
+
+// isIntegerValue 判断是否为Incr使用的十进制整数
+func isIntegerValue(data []byte) bool {
+	if len(data) > 20 {
+		return false
+	}
+	digits := data
+	if digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 {
+		return false
+	}
+	for _, ch := range digits {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// encode 按Set的规则序列化value并添加版本标记
+func (c *versionedCache) encode(value any) ([]byte, error) {
+	data, ok := value.([]byte)
+	if !ok && value != nil {
+		var err error
+		data, err = Marshal(value)
+		if err != nil {
+			return nil, wrapSerializationError(err, "cache: failed to marshal value")
+		}
+	}
+	return c.stamp(data), nil
+}
+
+func (c *versionedCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	data, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	return c.Cache.Set(ctx, key, data, expiration)
+}
+
+func (c *versionedCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.Set(ctx, key, value, expiration)
+}
+
+func (c *versionedCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	stamped := make(map[string]any, len(values))
+	for key, value := range values {
+		data, err := c.encode(value)
+		if err != nil {
+			return err
+		}
+		stamped[key] = data
+	}
+	return c.Cache.MSet(ctx, stamped, expiration)
+}
+
+func (c *versionedCache) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.Cache.GetRaw(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := c.unstamp(data)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (c *versionedCache) MGetRaw(ctx context.Context, keys []string) (map[string][]byte, error) {
+	result, err := c.Cache.MGetRaw(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	for key, data := range result {
+		if data, ok := c.unstamp(data); ok {
+			result[key] = data
+		} else {
+			delete(result, key)
+		}
+	}
+	return result, nil
+}
+
+// Exists 其他版本的值视为不存在
+func (c *versionedCache) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := c.GetRaw(ctx, key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// SaveRaw 命中其他版本的值时强制重新加载
+func (c *versionedCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	stampedFn := func() ([]byte, error) {
+		data, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		return c.stamp(data), nil
+	}
+
+	data, err := c.Cache.SaveRaw(ctx, key, stampedFn, expiration, decodeDynamicTTL(options, func(data []byte) ([]byte, error) {
+		if data, ok := c.unstamp(data); ok {
+			return data, nil
+		}
+		return nil, errors.New("cache: value has no version stamp")
+	})...)
+	var fe *FallbackError
+	if errors.As(err, &fe) {
+		// 兜底值没有版本标记，旧值属于其他版本时改为返回兜底值
+		if !fe.Stale {
+			return data, err
+		}
+		if stale, ok := c.unstamp(data); ok {
+			return stale, err
+		}
+		if opts := newSaveOptions(ctx, options); opts.Fallback != nil {
+			return bytes.Clone(opts.Fallback), &FallbackError{Err: fe.Err}
+		}
+		return nil, fe.Err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data, ok := c.unstamp(data); ok {
+		return data, nil
+	}
+
+	data, err = c.Cache.SaveRaw(ctx, key, stampedFn, expiration, append(options, WithForceRefresh())...)
+	if err != nil {
+		return nil, err
+	}
+	data, _ = c.unstamp(data)
+	return data, nil
+}
+
+func (c *versionedCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	return c.Cache.SetIfNewer(ctx, key, c.stamp(value), ts, expiration)
+}
+
+func (c *versionedCache) GetWithTimestamp(ctx context.Context, key string) ([]byte, int64, error) {
+	data, ts, err := c.Cache.GetWithTimestamp(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, ok := c.unstamp(data)
+	if !ok {
+		return nil, 0, ErrNotFound
+	}
+	return data, ts, nil
+}
+
+// Pipeline Set的值同样添加版本标记
+func (c *versionedCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	return c.Cache.Pipeline(ctx, func(p Pipeliner) error {
+		return fn(&versionedPipeliner{Pipeliner: p, c: c})
+	})
+}
+
+// versionedPipeliner 为Set的值添加版本标记
+type versionedPipeliner struct {
+	Pipeliner
+	c *versionedCache
+}
+
+func (p *versionedPipeliner) Set(key string, value any, expiration time.Duration) {
+	if data, err := p.c.encode(value); err == nil {
+		value = data
+	}
+	p.Pipeliner.Set(key, value, expiration)
+}
+
+// RegisterRefresher 刷新结果同样添加版本标记
+func (c *versionedCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.registerRefresher(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *versionedCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		return r.registerRefresher(target, key, loader, ttl, refreshBefore)
+	}
+	return c.Cache.RegisterRefresher(key, loader, ttl, refreshBefore)
+}
+
+func (c *versionedCache) stopRefreshers() {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		r.stopRefreshers()
+	}
+}
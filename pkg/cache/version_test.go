@@ -0,0 +1,86 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cockroachdb/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+// newVersionedRedis 创建连接到mr、使用指定结构版本的缓存，多个版本共享同一份数据
+func newVersionedRedis(t *testing.T, mr *miniredis.Miniredis, version int) cache.Cache {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	c, err := cache.New(cache.WithRedis(client), cache.WithSchemaVersion(version))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSchemaVersionMissesOtherVersions(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	v1 := newVersionedRedis(t, mr, 1)
+	v2 := newVersionedRedis(t, mr, 2)
+
+	// 整数和其他值一样带有版本标记
+	for key, value := range map[string]any{"int": 5, "struct": struct{ Name string }{"a"}} {
+		if err := v1.Set(ctx, key, value, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := v1.GetRaw(ctx, key); err != nil {
+			t.Errorf("%s: v1 GetRaw = %v, want hit", key, err)
+		}
+		if _, err := v2.GetRaw(ctx, key); !errors.Is(err, cache.ErrNotFound) {
+			t.Errorf("%s: v2 GetRaw = %v, want ErrNotFound", key, err)
+		}
+	}
+
+	// 其他版本的值由SaveRaw重新加载
+	data, err := v2.SaveRaw(ctx, "int", func() ([]byte, error) { return []byte("7"), nil }, time.Minute)
+	if err != nil || string(data) != "7" {
+		t.Fatalf("v2 SaveRaw = %q, %v", data, err)
+	}
+	if got, err := cache.Get[int](ctx, v2, "int"); err != nil || got != 7 {
+		t.Fatalf("v2 Get = %d, %v", got, err)
+	}
+}
+
+func TestSchemaVersionIncrCounter(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	v1 := newVersionedRedis(t, mr, 1)
+	v2 := newVersionedRedis(t, mr, 2)
+
+	// Incr写入没有标记的整数，不区分版本
+	err := v1.Pipeline(ctx, func(p cache.Pipeliner) error {
+		p.Incr("hits", 3)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []cache.Cache{v1, v2} {
+		if got, err := cache.Get[int64](ctx, c, "hits"); err != nil || got != 3 {
+			t.Errorf("Get(hits) = %d, %v, want 3", got, err)
+		}
+	}
+
+	// Set写入的整数带有标记，之后无法Incr
+	if err := v1.Set(ctx, "set", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	err = v1.Pipeline(ctx, func(p cache.Pipeliner) error {
+		p.Incr("set", 1)
+		return nil
+	})
+	if err == nil {
+		t.Error("Incr on a stamped value succeeded, want error")
+	}
+}
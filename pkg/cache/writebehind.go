@@ -0,0 +1,308 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/rs/zerolog"
+	gkit_zerolog "github.com/shaco-go/gkit-layout/pkg/zerolog"
+)
+
+const (
+	// writeBehindMaxPending 等待持久化的key数量上限
+	writeBehindMaxPending = 10000
+	// writeBehindMaxAttempts 单个值持久化失败后的最大尝试次数
+	writeBehindMaxAttempts = 3
+)
+
+// WriteBehindFunc 将缓存写入持久化到二级存储，key不包含KeyPrefix
+type WriteBehindFunc func(ctx context.Context, key string, value []byte) error
+
+// writeBehindEntry 等待持久化的值
+type writeBehindEntry struct {
+	data     []byte
+	attempts int
+}
+
+// writeBehindCache 写入缓存后异步持久化到二级存储，同一个key只保留最新值
+type writeBehindCache struct {
+	Cache
+	fn     WriteBehindFunc
+	batch  int
+	logger zerolog.Logger
+
+	mu      sync.Mutex
+	pending map[string]*writeBehindEntry
+	order   []string // 按首次写入顺序记录待持久化的key
+	full    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	unregister func()
+	closeOnce  sync.Once
+	closeErr   error
+}
+
+func newWriteBehindCache(c Cache, fn WriteBehindFunc, interval time.Duration, batch int, logger zerolog.Logger) Cache {
+	if batch <= 0 {
+		batch = 100
+	}
+	wc := &writeBehindCache{
+		Cache:   c,
+		fn:      fn,
+		batch:   batch,
+		logger:  logger,
+		pending: make(map[string]*writeBehindEntry),
+		full:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	// 进程退出前通过CloseAll刷新剩余的值
+	wc.unregister = Register(wc)
+
+	gkit_zerolog.Go(logger, func() {
+		defer close(wc.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = wc.flush(context.Background())
+			case <-wc.full:
+				_ = wc.flush(context.Background())
+			case <-wc.stop:
+				return
+			}
+		}
+	})
+
+	return wc
+}
+
+// enqueue 记录待持久化的值，队列已满时返回ErrWriteBehindFull
+func (c *writeBehindCache) enqueue(values map[string][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var dropped int
+	for key, data := range values {
+		if e, ok := c.pending[key]; ok {
+			e.data, e.attempts = data, 0
+			continue
+		}
+		if len(c.order) >= writeBehindMaxPending {
+			dropped++
+			continue
+		}
+		c.pending[key] = &writeBehindEntry{data: data}
+		c.order = append(c.order, key)
+	}
+
+	if len(c.order) >= c.batch {
+		select {
+		case c.full <- struct{}{}:
+		default:
+		}
+	}
+	if dropped > 0 {
+		return errors.Wrapf(ErrWriteBehindFull, "%d values not persisted", dropped)
+	}
+	return nil
+}
+
+// encode 按Set的规则序列化value
+func (c *writeBehindCache) encode(value any) ([]byte, error) {
+	if data, ok := value.([]byte); ok {
+		return data, nil
+	}
+	data, err := Marshal(value)
+	if err != nil {
+		return nil, wrapSerializationError(err, "cache: failed to marshal value")
+	}
+	return data, nil
+}
+
+// Set 同步写入缓存，成功后将值加入持久化队列
+func (c *writeBehindCache) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	data, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+	if err := c.Cache.Set(ctx, key, data, expiration); err != nil {
+		return err
+	}
+	return c.enqueue(map[string][]byte{key: data})
+}
+
+// SetCoalesced 未开启合并写入时等同于Set，确保值同样被持久化
+func (c *writeBehindCache) SetCoalesced(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return c.Set(ctx, key, value, expiration)
+}
+
+func (c *writeBehindCache) MSet(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	encoded := make(map[string][]byte, len(values))
+	raw := make(map[string]any, len(values))
+	for key, value := range values {
+		data, err := c.encode(value)
+		if err != nil {
+			return err
+		}
+		encoded[key] = data
+		raw[key] = data
+	}
+	if err := c.Cache.MSet(ctx, raw, expiration); err != nil {
+		return err
+	}
+	return c.enqueue(encoded)
+}
+
+// SetIfNewer 只有实际写入时才加入持久化队列
+func (c *writeBehindCache) SetIfNewer(ctx context.Context, key string, value []byte, ts int64, expiration time.Duration) (bool, error) {
+	ok, err := c.Cache.SetIfNewer(ctx, key, value, ts, expiration)
+	if err != nil || !ok {
+		return ok, err
+	}
+	return true, c.enqueue(map[string][]byte{key: value})
+}
+
+// SaveRaw 加载函数的结果写入缓存后同样会持久化
+func (c *writeBehindCache) SaveRaw(ctx context.Context, key string, fn func() ([]byte, error), expiration time.Duration, options ...SaveOption) ([]byte, error) {
+	var loaded bool
+	data, err := c.Cache.SaveRaw(ctx, key, func() ([]byte, error) {
+		result, err := fn()
+		loaded = err == nil
+		return result, err
+	}, expiration, options...)
+	if err != nil || !loaded || len(data) == 0 {
+		return data, err
+	}
+	return data, c.enqueue(map[string][]byte{key: data})
+}
+
+// Pipeline 提交成功后持久化管道中Set的值，Delete和Incr只作用于缓存
+func (c *writeBehindCache) Pipeline(ctx context.Context, fn func(p Pipeliner) error) error {
+	values := make(map[string][]byte)
+	err := c.Cache.Pipeline(ctx, func(p Pipeliner) error {
+		return fn(&writeBehindPipeliner{Pipeliner: p, c: c, values: values})
+	})
+	if err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return c.enqueue(values)
+}
+
+// writeBehindPipeliner 记录管道中Set的值
+type writeBehindPipeliner struct {
+	Pipeliner
+	c      *writeBehindCache
+	values map[string][]byte
+}
+
+func (p *writeBehindPipeliner) Set(key string, value any, expiration time.Duration) {
+	if data, err := p.c.encode(value); err == nil {
+		p.values[key] = data
+		value = data
+	}
+	p.Pipeliner.Set(key, value, expiration)
+}
+
+// take 取出最多batch个待持久化的值
+func (c *writeBehindCache) take() map[string]*writeBehindEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := min(c.batch, len(c.order))
+	batch := make(map[string]*writeBehindEntry, n)
+	for _, key := range c.order[:n] {
+		batch[key] = c.pending[key]
+		delete(c.pending, key)
+	}
+	c.order = c.order[n:]
+	return batch
+}
+
+// flush 分批持久化当前队列中的值，失败的值在没有被更新时放回队列等待下次重试，超过最大尝试次数后丢弃
+func (c *writeBehindCache) flush(ctx context.Context) error {
+	c.mu.Lock()
+	remaining := len(c.order)
+	c.mu.Unlock()
+
+	var errs error
+	for remaining > 0 {
+		batch := c.take()
+		if len(batch) == 0 {
+			break
+		}
+		remaining -= len(batch)
+
+		for key, e := range batch {
+			err := c.fn(ctx, key, e.data)
+			if err == nil {
+				continue
+			}
+
+			e.attempts++
+			if e.attempts >= writeBehindMaxAttempts {
+				errs = errors.CombineErrors(errs, errors.Wrapf(err, "cache: write-behind for key %s", key))
+				c.logger.Error().Err(err).Str("key", key).Msg("cache: write-behind flush failed, value dropped")
+				continue
+			}
+			c.mu.Lock()
+			if _, ok := c.pending[key]; !ok {
+				c.pending[key] = e
+				c.order = append(c.order, key)
+			}
+			c.mu.Unlock()
+		}
+	}
+	return errs
+}
+
+// RegisterRefresher 刷新结果同样经过当前装饰器写入
+func (c *writeBehindCache) RegisterRefresher(key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	return c.registerRefresher(c, key, loader, ttl, refreshBefore)
+}
+
+func (c *writeBehindCache) registerRefresher(target Cache, key string, loader RefreshLoader, ttl, refreshBefore time.Duration) error {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		return r.registerRefresher(target, key, loader, ttl, refreshBefore)
+	}
+	return c.Cache.RegisterRefresher(key, loader, ttl, refreshBefore)
+}
+
+func (c *writeBehindCache) stopRefreshers() {
+	if r, ok := c.Cache.(refreshRegistrar); ok {
+		r.stopRefreshers()
+	}
+}
+
+// Close 停止主动刷新和持久化协程，持久化剩余的值后关闭后端，失败的值会重试到最大尝试次数
+// Close 可以重复调用，CloseAll已经关闭时返回第一次关闭的结果
+func (c *writeBehindCache) Close() error {
+	c.closeOnce.Do(func() {
+		c.unregister()
+		c.stopRefreshers()
+		close(c.stop)
+		<-c.done
+
+		var errs error
+		for c.queued() > 0 {
+			errs = errors.CombineErrors(errs, c.flush(context.Background()))
+		}
+		c.closeErr = errors.CombineErrors(errs, c.Cache.Close())
+	})
+	return c.closeErr
+}
+
+// queued 返回待持久化的key数量
+func (c *writeBehindCache) queued() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.order)
+}
@@ -0,0 +1,56 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+)
+
+func TestWriteBehindFlushOnClose(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string]string{}
+	flush := func(ctx context.Context, key string, value []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[key] = string(value)
+		return nil
+	}
+	c, err := cache.New(cache.WithWriteBehind(flush, time.Hour, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := c.Set(ctx, "a", []byte("1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "a", []byte("2"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 || got["a"] != "2" {
+		t.Fatalf("flushed = %v, want only the latest value of a", got)
+	}
+}
+
+func TestWriteBehindInvalidInterval(t *testing.T) {
+	flush := func(ctx context.Context, key string, value []byte) error { return nil }
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := cache.New(cache.WithWriteBehind(flush, interval, 10)); !errors.Is(err, cache.ErrInvalidParams) {
+			t.Errorf("New with interval %v: err = %v, want ErrInvalidParams", interval, err)
+		}
+		clients := []redis.UniversalClient{redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})}
+		if _, err := cache.NewShardedCache(clients, cache.WithWriteBehind(flush, interval, 10)); !errors.Is(err, cache.ErrInvalidParams) {
+			t.Errorf("NewShardedCache with interval %v: err = %v, want ErrInvalidParams", interval, err)
+		}
+		_ = clients[0].Close()
+	}
+}
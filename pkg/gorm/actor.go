@@ -0,0 +1,149 @@
+package gkit_gorm
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type actorKey struct{}
+
+// WithActor 返回携带当前操作人ID的ctx，配合ActorPlugin和db.WithContext使用
+func WithActor(ctx context.Context, id any) context.Context {
+	return context.WithValue(ctx, actorKey{}, id)
+}
+
+// ActorFromContext 获取ctx中的操作人ID
+func ActorFromContext(ctx context.Context) (any, bool) {
+	id := ctx.Value(actorKey{})
+	return id, id != nil
+}
+
+// ActorPlugin 操作人插件，创建时填充created_by和updated_by，更新时填充updated_by
+// 通过db.Use(&ActorPlugin{})注册，ctx中没有操作人或模型没有对应字段时跳过；BatchSave的创建和更新同样生效
+type ActorPlugin struct {
+	// CreatedBy 创建人字段名，默认created_by
+	CreatedBy string
+	// UpdatedBy 更新人字段名，默认updated_by
+	UpdatedBy string
+}
+
+// Name 插件名称
+func (p *ActorPlugin) Name() string {
+	return "gkit:actor"
+}
+
+// Initialize 注册创建和更新的回调
+func (p *ActorPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("gkit:actor_create", p.create); err != nil {
+		return err
+	}
+	return cb.Update().Before("gorm:update").Register("gkit:actor_update", p.update)
+}
+
+// columns 返回创建人和更新人字段名
+func (p *ActorPlugin) columns() (string, string) {
+	createdBy, updatedBy := p.CreatedBy, p.UpdatedBy
+	if createdBy == "" {
+		createdBy = "created_by"
+	}
+	if updatedBy == "" {
+		updatedBy = "updated_by"
+	}
+	return createdBy, updatedBy
+}
+
+// field 返回需要填充的字段，模型没有该字段或被Omit时返回nil
+// 指定了Select时追加该字段，避免BatchSave等只更新部分字段的场景漏掉操作人
+// 参数:
+//   - stmt: 当前语句
+//   - column: 数据库字段名
+//
+// 返回:
+//   - *schema.Field: 需要填充的字段
+func (p *ActorPlugin) field(stmt *gorm.Statement, column string) *schema.Field {
+	field := stmt.Schema.LookUpField(column)
+	if field == nil || slice.Contain(stmt.Omits, field.DBName) || slice.Contain(stmt.Omits, field.Name) {
+		return nil
+	}
+	if len(stmt.Selects) > 0 && !slice.Contain(stmt.Selects, "*") &&
+		!slice.Contain(stmt.Selects, field.DBName) && !slice.Contain(stmt.Selects, field.Name) {
+		stmt.Selects = append(stmt.Selects, field.DBName)
+	}
+	return field
+}
+
+// actor 返回当前语句的操作人ID，不需要处理时返回false
+func (p *ActorPlugin) actor(db *gorm.DB) (any, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return nil, false
+	}
+	return ActorFromContext(stmt.Context)
+}
+
+// create 填充创建人和更新人，已经设置的值不会被覆盖
+func (p *ActorPlugin) create(db *gorm.DB) {
+	actor, ok := p.actor(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	createdBy, updatedBy := p.columns()
+	for _, column := range []string{createdBy, updatedBy} {
+		field := p.field(stmt, column)
+		if field == nil {
+			continue
+		}
+
+		switch dest := stmt.Dest.(type) {
+		case map[string]any:
+			if _, exists := dest[field.DBName]; !exists {
+				dest[field.DBName] = actor
+			}
+			continue
+		case []map[string]any:
+			for _, m := range dest {
+				if _, exists := m[field.DBName]; !exists {
+					m[field.DBName] = actor
+				}
+			}
+			continue
+		}
+
+		switch stmt.ReflectValue.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < stmt.ReflectValue.Len(); i++ {
+				p.setIfZero(db, field, reflect.Indirect(stmt.ReflectValue.Index(i)), actor)
+			}
+		case reflect.Struct:
+			p.setIfZero(db, field, stmt.ReflectValue, actor)
+		}
+	}
+}
+
+// setIfZero 字段为零值时设置为操作人
+func (p *ActorPlugin) setIfZero(db *gorm.DB, field *schema.Field, rv reflect.Value, actor any) {
+	if !rv.CanAddr() {
+		return
+	}
+	if _, zero := field.ValueOf(db.Statement.Context, rv); zero {
+		_ = db.AddError(field.Set(db.Statement.Context, rv, actor))
+	}
+}
+
+// update 填充更新人
+func (p *ActorPlugin) update(db *gorm.DB) {
+	actor, ok := p.actor(db)
+	if !ok {
+		return
+	}
+	_, updatedBy := p.columns()
+	if field := p.field(db.Statement, updatedBy); field != nil {
+		db.Statement.SetColumn(field.DBName, actor, true)
+	}
+}
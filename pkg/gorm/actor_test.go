@@ -0,0 +1,81 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type actorDoc struct {
+	ID        int64
+	Title     string
+	CreatedBy int64
+	UpdatedBy int64
+}
+
+// mockActorDB 返回注册了ActorPlugin的sqlmock连接
+func mockActorDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := mockDB(t)
+	if err := db.Use(&gkit_gorm.ActorPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestActorPluginCreate(t *testing.T) {
+	db, mock := mockActorDB(t)
+	ctx := gkit_gorm.WithActor(context.Background(), int64(42))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `actor_docs` \\(`title`,`created_by`,`updated_by`,`id`\\)").
+		WithArgs("a", 42, 42, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := db.WithContext(ctx).Create(&actorDoc{ID: 1, Title: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 批量创建时已经设置的创建人保持不变
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `actor_docs`").
+		WithArgs("b", 42, 42, 2, "c", 7, 42, 3).WillReturnResult(sqlmock.NewResult(3, 2))
+	mock.ExpectCommit()
+	if err := db.WithContext(ctx).Create([]*actorDoc{{ID: 2, Title: "b"}, {ID: 3, Title: "c", CreatedBy: 7}}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestActorPluginUpdate(t *testing.T) {
+	db, mock := mockActorDB(t)
+	ctx := gkit_gorm.WithActor(context.Background(), int64(42))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `actor_docs` SET `title`=\\?,`updated_by`=\\? WHERE `id` = \\?").
+		WithArgs("x", 42, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := db.WithContext(ctx).Model(&actorDoc{ID: 1}).Update("title", "x").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Select只选择了title时同样填充updated_by
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `actor_docs` SET `title`=\\?,`updated_by`=\\? WHERE `id` = \\?").
+		WithArgs("y", 42, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	doc := &actorDoc{ID: 1, Title: "y", UpdatedBy: 3}
+	if err := db.WithContext(ctx).Model(doc).Select("title").Updates(doc).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestActorPluginWithoutActor(t *testing.T) {
+	db, mock := mockActorDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `actor_docs` SET `title`=\\? WHERE `id` = \\?$").
+		WithArgs("z", 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := db.Model(&actorDoc{ID: 1}).Update("title", "z").Error; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,174 @@
+package gkit_gorm
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrEncryptionKeyMissing 使用aesgcm序列化的字段在调用SetEncryptionKey之前读写
+var ErrEncryptionKeyMissing = errors.New("gorm: aesgcm encryption key is not configured")
+
+// aesgcmKeyID 当前密钥的编号，写在密文开头，为以后的密钥轮换预留
+const aesgcmKeyID byte = 0
+
+// aesgcmAEAD 通过SetEncryptionKey配置的加密器
+var aesgcmAEAD atomic.Pointer[cipher.AEAD]
+
+func init() {
+	schema.RegisterSerializer("aesgcm", AESGCMSerializer{})
+}
+
+// SetEncryptionKey 配置aesgcm序列化使用的密钥，需要在读写加密字段之前调用
+// 参数:
+//   - key: AES密钥，长度为16、24或32字节，分别对应AES-128、AES-192、AES-256
+//
+// 返回:
+//   - error: 密钥长度不正确时返回错误
+func SetEncryptionKey(key []byte) error {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("aesgcm密钥无效: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("创建AES-GCM失败: %w", err)
+	}
+	aesgcmAEAD.Store(&aead)
+	return nil
+}
+
+// AESGCMSerializer 使用AES-GCM加密字段的序列化器，字段通过 gorm:"serializer:aesgcm" 使用，适用于证件号、银行卡号等敏感字段
+// 写入时加密为 base64(密钥编号 + 随机nonce + 密文)，列类型需要是足够长的字符串或二进制类型；读取时解密
+// 字段名作为附加数据，密文被复制到其他字段后无法解密；string和[]byte直接加密，其他类型按JSON编码后加密
+// nil写入为NULL，空值写入为空字符串且不加密；加密后无法按明文查询或建立有意义的索引
+type AESGCMSerializer struct{}
+
+// Scan 解密数据库中的值并写入字段，NULL和空字符串得到零值
+func (AESGCMSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	fieldValue := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var text []byte
+		switch v := dbValue.(type) {
+		case []byte:
+			text = v
+		case string:
+			text = []byte(v)
+		default:
+			return fmt.Errorf("字段 %s 不支持解密 %T 类型的值", field.Name, dbValue)
+		}
+		if len(text) > 0 {
+			plain, err := aesgcmOpen(field.DBName, text)
+			if err != nil {
+				return fmt.Errorf("字段 %s 解密失败: %w", field.Name, err)
+			}
+			if err := aesgcmDecode(fieldValue.Elem(), plain); err != nil {
+				return fmt.Errorf("字段 %s 解码失败: %w", field.Name, err)
+			}
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value 加密字段的值，nil返回NULL，空值返回空字符串
+func (AESGCMSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	rv := reflect.ValueOf(fieldValue)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	var plain []byte
+	switch {
+	case rv.Kind() == reflect.String:
+		plain = []byte(rv.String())
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		plain = rv.Bytes()
+	default:
+		data, err := json.Marshal(rv.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("字段 %s 编码失败: %w", field.Name, err)
+		}
+		plain = data
+	}
+	if len(plain) == 0 {
+		return "", nil
+	}
+	text, err := aesgcmSeal(field.DBName, plain)
+	if err != nil {
+		return nil, fmt.Errorf("字段 %s 加密失败: %w", field.Name, err)
+	}
+	return text, nil
+}
+
+// aesgcmSeal 加密plain，column作为附加数据
+func aesgcmSeal(column string, plain []byte) (string, error) {
+	p := aesgcmAEAD.Load()
+	if p == nil {
+		return "", ErrEncryptionKeyMissing
+	}
+	aead := *p
+	nonceSize := aead.NonceSize()
+	sealed := make([]byte, 1+nonceSize, 1+nonceSize+len(plain)+aead.Overhead())
+	sealed[0] = aesgcmKeyID
+	nonce := sealed[1:]
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成nonce失败: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(sealed, nonce, plain, []byte(column))), nil
+}
+
+// aesgcmOpen 解密aesgcmSeal生成的密文
+func aesgcmOpen(column string, text []byte) ([]byte, error) {
+	p := aesgcmAEAD.Load()
+	if p == nil {
+		return nil, ErrEncryptionKeyMissing
+	}
+	aead := *p
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(text)))
+	n, err := base64.StdEncoding.Decode(data, text)
+	if err != nil {
+		return nil, fmt.Errorf("密文格式错误: %w", err)
+	}
+	data = data[:n]
+	nonceSize := aead.NonceSize()
+	if len(data) < 1+nonceSize || data[0] != aesgcmKeyID {
+		return nil, errors.New("未知的密文格式或密钥编号")
+	}
+	return aead.Open(nil, data[1:1+nonceSize], data[1+nonceSize:], []byte(column))
+}
+
+// aesgcmDecode 将明文写入v，v为指针类型时分配新值
+func aesgcmDecode(v reflect.Value, plain []byte) error {
+	for v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(plain))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(plain)
+	default:
+		return json.Unmarshal(plain, v.Addr().Interface())
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package gkit_gorm_test
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type secretAccount struct {
+	ID    int64
+	SSN   string            `gorm:"serializer:aesgcm"`
+	Bank  *string           `gorm:"serializer:aesgcm"`
+	Extra map[string]string `gorm:"serializer:aesgcm"`
+	Note  string            `gorm:"serializer:aesgcm"`
+}
+
+// captureArg 匹配任意字符串参数并保存，用于检查写入数据库的密文
+type captureArg struct {
+	value *string
+}
+
+func (a captureArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if ok {
+		*a.value = s
+	}
+	return ok
+}
+
+func TestAESGCMSerializer(t *testing.T) {
+	db, mock := mockDB(t)
+	// 密钥是全局配置，未配置密钥的检查必须在SetEncryptionKey之前
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	if err := db.Create(&secretAccount{SSN: "123"}).Error; !errors.Is(err, gkit_gorm.ErrEncryptionKeyMissing) {
+		t.Fatalf("未配置密钥应返回ErrEncryptionKeyMissing，实际 %v", err)
+	}
+	if err := gkit_gorm.SetEncryptionKey([]byte("short")); err == nil {
+		t.Fatal("长度不正确的密钥应返回错误")
+	}
+	if err := gkit_gorm.SetEncryptionKey(bytes.Repeat([]byte{7}, 32)); err != nil {
+		t.Fatal(err)
+	}
+
+	const ssn = "110101199001011234"
+	var storedSSN, storedExtra string
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `secret_accounts`").
+		WithArgs(captureArg{&storedSSN}, nil, captureArg{&storedExtra}, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	account := &secretAccount{SSN: ssn, Extra: map[string]string{"k": "v"}}
+	if err := db.Create(account).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 数据库中是密文，结构体中仍是明文
+	if storedSSN == "" || strings.Contains(storedSSN, ssn) || storedSSN == storedExtra {
+		t.Fatalf("写入的值应为密文，实际 %q", storedSSN)
+	}
+	if account.SSN != ssn {
+		t.Fatalf("写入后结构体的值被修改为 %q", account.SSN)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `secret_accounts`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ssn", "bank", "extra", "note"}).AddRow(1, storedSSN, nil, storedExtra, ""))
+	var got secretAccount
+	if err := db.First(&got, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.SSN != ssn || got.Bank != nil || got.Extra["k"] != "v" || got.Note != "" {
+		t.Fatalf("解密结果不正确: %+v", got)
+	}
+
+	// 列名作为附加数据，密文复制到其他列后无法解密
+	mock.ExpectQuery("SELECT \\* FROM `secret_accounts`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ssn", "bank"}).AddRow(1, storedSSN, storedSSN))
+	if err := db.First(&got, 1).Error; err == nil {
+		t.Fatal("复制到其他列的密文应解密失败")
+	}
+}
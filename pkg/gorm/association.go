@@ -0,0 +1,139 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SyncAssociation 将parent的关联记录同步为desired，只追加新增的关联、删除多余的关联
+// 在事务中先读取当前关联，按关联模型的主键计算差异，再通过GORM关联模式的Append/Delete写入
+// many2many只增删中间表记录，has_many删除时会将外键置空而不是删除关联记录
+// 参数:
+//   - db: GORM数据库连接
+//   - parent: 拥有关联的记录，必须是结构体指针且主键不为零值
+//   - association: 关联字段名，例如"Roles"
+//   - desired: 期望的关联记录，可以是结构体或结构体指针，必须包含主键
+//
+// 返回:
+//   - int: 新增的关联数量
+//   - int: 删除的关联数量
+//   - error: 同步过程中发生的错误，如果成功则返回nil
+func SyncAssociation(db *gorm.DB, parent any, association string, desired []any) (int, int, error) {
+	var added, removed int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		assoc := tx.Model(parent).Association(association)
+		if assoc.Error != nil {
+			return assoc.Error
+		}
+		rel := assoc.Relationship
+		if len(rel.FieldSchema.PrimaryFields) == 0 {
+			return fmt.Errorf("关联模型 %s 没有主键", rel.FieldSchema.Name)
+		}
+
+		// 1.读取当前的关联记录
+		current := reflect.New(reflect.SliceOf(rel.FieldSchema.ModelType))
+		if err := assoc.Find(current.Interface()); err != nil {
+			return err
+		}
+		currentKeys := make(map[string]any, current.Elem().Len())
+		for i := 0; i < current.Elem().Len(); i++ {
+			item := current.Elem().Index(i).Addr()
+			key, err := associationKey(tx, rel.FieldSchema, item)
+			if err != nil {
+				return err
+			}
+			currentKeys[key] = item.Interface()
+		}
+
+		// 2.计算新增和删除的记录
+		toAppend := make([]any, 0)
+		desiredKeys := make(map[string]struct{}, len(desired))
+		for _, item := range desired {
+			ptr, err := associationPointer(item, rel.FieldSchema)
+			if err != nil {
+				return err
+			}
+			key, err := associationKey(tx, rel.FieldSchema, ptr)
+			if err != nil {
+				return err
+			}
+			if _, ok := desiredKeys[key]; ok {
+				continue
+			}
+			desiredKeys[key] = struct{}{}
+			if _, ok := currentKeys[key]; !ok {
+				toAppend = append(toAppend, ptr.Interface())
+			}
+		}
+		toDelete := make([]any, 0)
+		for key, item := range currentKeys {
+			if _, ok := desiredKeys[key]; !ok {
+				toDelete = append(toDelete, item)
+			}
+		}
+
+		// 3.写入差异
+		if len(toDelete) > 0 {
+			if err := tx.Model(parent).Association(association).Delete(toDelete...); err != nil {
+				return err
+			}
+		}
+		if len(toAppend) > 0 {
+			if err := tx.Model(parent).Association(association).Append(toAppend...); err != nil {
+				return err
+			}
+		}
+		added, removed = len(toAppend), len(toDelete)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return added, removed, nil
+}
+
+// associationPointer 将关联记录统一转换为结构体指针，便于Append回写自增主键
+// 参数:
+//   - item: 结构体或结构体指针
+//   - s: 关联模型的schema
+//
+// 返回:
+//   - reflect.Value: 指向记录的指针
+//   - error: 类型与关联模型不一致时返回错误
+func associationPointer(item any, s *schema.Schema) (reflect.Value, error) {
+	val := reflect.ValueOf(item)
+	if val.Kind() == reflect.Ptr && !val.IsNil() && val.Elem().Type() == s.ModelType {
+		return val, nil
+	}
+	if val.IsValid() && val.Type() == s.ModelType {
+		ptr := reflect.New(s.ModelType)
+		ptr.Elem().Set(val)
+		return ptr, nil
+	}
+	return reflect.Value{}, fmt.Errorf("关联记录类型 %T 与模型 %s 不一致", item, s.Name)
+}
+
+// associationKey 根据主键生成记录的唯一标识
+// 参数:
+//   - db: GORM数据库连接
+//   - s: 关联模型的schema
+//   - ptr: 指向记录的指针
+//
+// 返回:
+//   - string: 主键值拼接的标识
+//   - error: 主键为零值时返回错误
+func associationKey(db *gorm.DB, s *schema.Schema, ptr reflect.Value) (string, error) {
+	parts := make([]string, 0, len(s.PrimaryFields))
+	for _, field := range s.PrimaryFields {
+		val, zero := field.ValueOf(db.Statement.Context, ptr.Elem())
+		if zero {
+			return "", fmt.Errorf("关联记录 %s 的主键 %s 为零值", s.Name, field.Name)
+		}
+		parts = append(parts, fmt.Sprintf("%v", val))
+	}
+	return strings.Join(parts, "\x00"), nil
+}
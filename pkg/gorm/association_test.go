@@ -0,0 +1,56 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type assocRole struct {
+	ID   int64
+	Name string
+}
+
+type assocUser struct {
+	ID    int64
+	Roles []assocRole `gorm:"many2many:assoc_user_roles"`
+}
+
+func TestSyncAssociationMany2ManyReplace(t *testing.T) {
+	db, mock := mockDB(t)
+	// 当前关联为1、2，期望为2、3：删除1，追加3，保留2
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `assoc_roles`.`id`,`assoc_roles`.`name` FROM `assoc_roles` JOIN `assoc_user_roles`").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	mock.ExpectExec("DELETE FROM `assoc_user_roles` WHERE `assoc_user_roles`.`assoc_user_id` = \\? AND `assoc_user_roles`.`assoc_role_id` = \\?$").
+		WithArgs(7, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `assoc_roles` \\(`name`,`id`\\) VALUES \\(\\?,\\?\\)").
+		WithArgs("c", 3).WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("INSERT INTO `assoc_user_roles` \\(`assoc_user_id`,`assoc_role_id`\\) VALUES \\(\\?,\\?\\)").
+		WithArgs(7, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	added, removed, err := gkit_gorm.SyncAssociation(db, &assocUser{ID: 7}, "Roles",
+		[]any{assocRole{ID: 2}, &assocRole{ID: 3, Name: "c"}, assocRole{ID: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 1 || removed != 1 {
+		t.Fatalf("应新增1条、删除1条，实际新增%d条、删除%d条", added, removed)
+	}
+}
+
+func TestSyncAssociationUnchanged(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `assoc_roles` JOIN `assoc_user_roles`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+	mock.ExpectCommit()
+
+	added, removed, err := gkit_gorm.SyncAssociation(db, &assocUser{ID: 7}, "Roles", []any{assocRole{ID: 1}})
+	if err != nil || added != 0 || removed != 0 {
+		t.Fatalf("关联一致时不应写入，实际新增%d条、删除%d条，%v", added, removed, err)
+	}
+}
@@ -0,0 +1,104 @@
+package gkit_gorm
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuditEvent 一次被审计的查询
+type AuditEvent struct {
+	// Model 模型名称
+	Model string
+	// Table 表名
+	Table string
+	// Where 查询条件的SQL，不包含WHERE关键字，可能为空
+	Where string
+	// Vars Where中占位符对应的参数
+	Vars []any
+	// Rows 查询返回的行数
+	Rows int64
+	// Error 查询失败时的错误
+	Error error
+}
+
+// AuditPlugin 查询审计插件，对指定模型的SELECT在执行后调用Handler，用于记录谁读取了哪些数据
+// 通过db.Use注册，调用方通过db.WithContext传入携带用户信息的ctx；Row/Rows以及Raw执行的SQL不会被审计
+type AuditPlugin struct {
+	// Models 需要审计的模型，例如 []any{&User{}, &Order{}}
+	Models []any
+	// Handler 审计回调，ctx为查询时的ctx
+	Handler func(ctx context.Context, event AuditEvent)
+
+	types map[reflect.Type]struct{}
+}
+
+// Name 插件名称
+func (p *AuditPlugin) Name() string {
+	return "gkit:audit"
+}
+
+// Initialize 解析需要审计的模型并注册查询后的回调
+func (p *AuditPlugin) Initialize(db *gorm.DB) error {
+	if p.Handler == nil {
+		return errors.New("gorm: AuditPlugin.Handler不能为空")
+	}
+
+	p.types = make(map[reflect.Type]struct{}, len(p.Models))
+	for _, model := range p.Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return errors.Wrap(err, "gorm: 解析审计模型失败")
+		}
+		p.types[stmt.Schema.ModelType] = struct{}{}
+	}
+
+	return db.Callback().Query().After("gorm:query").Register("gkit:audit_query", p.audit)
+}
+
+// audit 查询执行后触发审计回调
+func (p *AuditPlugin) audit(db *gorm.DB) {
+	stmt := db.Statement
+	if stmt.Schema == nil {
+		return
+	}
+	if _, ok := p.types[stmt.Schema.ModelType]; !ok {
+		return
+	}
+
+	where, vars := buildWhere(db)
+	p.Handler(stmt.Context, AuditEvent{
+		Model: stmt.Schema.Name,
+		Table: stmt.Table,
+		Where: where,
+		Vars:  vars,
+		Rows:  db.RowsAffected,
+		Error: db.Error,
+	})
+}
+
+// buildWhere 单独构建语句的WHERE条件
+// 参数:
+//   - db: 当前会话
+//
+// 返回:
+//   - string: 条件SQL，没有条件时为空
+//   - []any: 条件中的参数
+func buildWhere(db *gorm.DB) (string, []any) {
+	c, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return "", nil
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok {
+		return "", nil
+	}
+
+	// 使用独立的Statement构建，避免写入当前语句的SQL
+	stmt := &gorm.Statement{DB: db, Table: db.Statement.Table, Schema: db.Statement.Schema, Clauses: map[string]clause.Clause{}}
+	where.Build(stmt)
+	return stmt.SQL.String(), stmt.Vars
+}
@@ -0,0 +1,66 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type auditSecret struct {
+	ID   int64
+	Name string
+}
+
+type auditPublic struct {
+	ID int64
+}
+
+type auditUserKey struct{}
+
+func TestAuditPlugin(t *testing.T) {
+	db, mock := mockDB(t)
+	var events []gkit_gorm.AuditEvent
+	var users []any
+	err := db.Use(&gkit_gorm.AuditPlugin{
+		Models: []any{&auditSecret{}},
+		Handler: func(ctx context.Context, event gkit_gorm.AuditEvent) {
+			events = append(events, event)
+			users = append(users, ctx.Value(auditUserKey{}))
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `audit_secrets` WHERE name = \\? AND id > \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	ctx := context.WithValue(context.Background(), auditUserKey{}, "alice")
+	var secrets []auditSecret
+	if err := db.WithContext(ctx).Where("name = ?", "x").Where("id > ?", 0).Find(&secrets).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 未登记的模型不触发审计
+	mock.ExpectQuery("SELECT \\* FROM `audit_publics`").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	var publics []auditPublic
+	if err := db.Find(&publics).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("期望1次审计，实际%d次", len(events))
+	}
+	event := events[0]
+	if event.Table != "audit_secrets" || event.Model != "auditSecret" || event.Rows != 2 || event.Error != nil {
+		t.Fatalf("审计事件不正确: %+v", event)
+	}
+	if event.Where != "name = ? AND id > ?" || !reflect.DeepEqual(event.Vars, []any{"x", 0}) {
+		t.Fatalf("审计条件不正确: %q %v", event.Where, event.Vars)
+	}
+	if users[0] != "alice" {
+		t.Fatalf("Handler应收到查询时的ctx，实际%v", users[0])
+	}
+}
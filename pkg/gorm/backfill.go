@@ -0,0 +1,65 @@
+package gkit_gorm
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+)
+
+// ForEachBatch 按主键分批遍历记录，对每条记录调用mutate，每批只把发生变化的记录通过BatchSave写回，适用于数据修复和回填任务
+// 每批的写入在各自的事务中提交，中途失败时已处理的批次保持提交；mutate应当是幂等的，重新执行时已修复的记录返回false即可跳过
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where条件限定需要处理的记录
+//   - batchSize: 每批读取的记录数，必须大于0
+//   - mutate: 修改记录，返回记录是否发生变化；返回错误时停止遍历
+//   - options: 写回时传给BatchSave的选项，例如WithUpdateSelect只更新修改的字段
+//
+// 返回:
+//   - error: 遍历、mutate或写回过程中发生的错误，如果成功则返回nil
+func ForEachBatch[T any](db *gorm.DB, batchSize int, mutate func(*T) (changed bool, err error), options ...BatchSaveOption) error {
+	if batchSize <= 0 {
+		return errors.New("batchSize必须大于0")
+	}
+	modelSchema, err := ParseSchema(db, new(T))
+	if err != nil {
+		return fmt.Errorf("解析模型失败: %w", err)
+	}
+	if modelSchema.PrioritizedPrimaryField == nil {
+		return fmt.Errorf("模型 %s 没有唯一主键", modelSchema.Name)
+	}
+	order := []OrderCol{{Column: modelSchema.PrioritizedPrimaryField.DBName}}
+
+	// 读取使用可复用的会话，写回使用不带预设条件的会话
+	query := db.Session(&gorm.Session{})
+	writer := db.Session(&gorm.Session{NewDB: true})
+
+	var cursor Cursor
+	for {
+		rows, next, err := SeekPaginate[T](query, cursor, batchSize, order)
+		if err != nil {
+			return err
+		}
+
+		var changed []*T
+		for i := range rows {
+			ok, err := mutate(&rows[i])
+			if err != nil {
+				return err
+			}
+			if ok {
+				changed = append(changed, &rows[i])
+			}
+		}
+		if len(changed) > 0 {
+			if err := BatchSave(writer, changed, options...); err != nil {
+				return err
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
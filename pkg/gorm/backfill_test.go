@@ -0,0 +1,66 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type backfillRow struct {
+	ID   uint
+	Name string
+}
+
+func TestForEachBatch(t *testing.T) {
+	db, mock := mockDB(t)
+	// 第1批3行中只有第2行变化，第2批只写回变化的第3行
+	mock.ExpectQuery("SELECT \\* FROM `backfill_rows` WHERE kind = \\? ORDER BY `id` LIMIT \\?$").WithArgs("a", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "x").AddRow(2, "FIX").AddRow(3, "y"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `backfill_rows` WHERE id IN \\(\\?\\)").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec("UPDATE `backfill_rows`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM `backfill_rows` WHERE kind = \\? AND \\(`id`\\) > \\(\\?\\) ORDER BY `id` LIMIT \\?$").WithArgs("a", 2, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "FIX"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT .* FROM `backfill_rows` WHERE id IN \\(\\?\\)").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec("UPDATE `backfill_rows`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var visited, changed int
+	err := gkit_gorm.ForEachBatch(db.Where("kind = ?", "a"), 2, func(r *backfillRow) (bool, error) {
+		visited++
+		if r.Name != "FIX" {
+			return false, nil
+		}
+		changed++
+		r.Name = strings.ToLower(r.Name)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if visited != 3 || changed != 2 {
+		t.Errorf("遍历%d行、修改%d行，期望3行、2行", visited, changed)
+	}
+}
+
+func TestForEachBatchStopsOnError(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `backfill_rows` ORDER BY `id` LIMIT \\?$").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "x").AddRow(2, "y").AddRow(3, "z"))
+
+	stop := errors.New("stop")
+	err := gkit_gorm.ForEachBatch(db, 2, func(r *backfillRow) (bool, error) { return false, stop })
+	if !errors.Is(err, stop) {
+		t.Fatalf("期望返回mutate的错误，实际 %v", err)
+	}
+	if err := gkit_gorm.ForEachBatch(db, 0, func(r *backfillRow) (bool, error) { return false, nil }); err == nil {
+		t.Fatal("batchSize为0应返回错误")
+	}
+}
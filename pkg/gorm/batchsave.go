@@ -7,13 +7,15 @@ import (
 	"github.com/duke-git/lancet/v2/slice"
 	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
 	"reflect"
+	"sort"
 	"strings"
-	"sync"
 )
 
 // BatchSave 提供了一个便捷的批量保存数据的方法，支持自动区分新增和更新操作
+// 新建记录的自增主键会回写到data的元素上(按值传入的数组除外)
 // 参数:
 //   - db: GORM数据库连接
 //   - data: 需要保存的数据集合，必须是切片或数组类型
@@ -27,6 +29,9 @@ func BatchSave(db *gorm.DB, data any, options ...BatchSaveOption) error {
 	if err != nil {
 		return err
 	}
+	if tool.OnConflict != nil {
+		return errOnConflictWithoutUpsert
+	}
 	// 调用Save方法执行实际的保存操作
 	return tool.Save()
 }
@@ -34,11 +39,13 @@ func BatchSave(db *gorm.DB, data any, options ...BatchSaveOption) error {
 // BatchSaveOption 定义了批量保存工具的函数式选项类型
 // 支持的配置选项包括:
 //   - BatchSize: 每批处理的数据量
+//   - LookupBatchSize: 查询已存在记录时每次查询的数据量
 //   - DuplicatedKey: 用于判断记录是否存在的键
 //   - UpdateSelect: 更新时选择的字段
 //   - CreateSelect: 创建时选择的字段
 //   - Transaction: 是否使用事务
 //   - 以及创建和更新时的字段忽略选项
+//   - OnConflict: upsert时使用的冲突处理子句
 type BatchSaveOption func(*batchSave)
 
 // WithBatchSize 设置批量保存的批次大小，用于控制每次数据库操作的数据量
@@ -55,6 +62,21 @@ func WithBatchSize(size int) BatchSaveOption {
 	}
 }
 
+// WithLookupBatchSize 设置查询已存在记录时每次IN查询的数据量，与写入的BatchSize相互独立
+// 过大的IN列表可能超过MySQL的max_allowed_packet或占位符数量限制
+// 参数:
+//   - size: 每次查询的记录数，必须大于0才会生效，否则与BatchSize保持一致
+//
+// 返回:
+//   - BatchSaveOption: 返回一个可应用于BatchSaveTool的选项函数
+func WithLookupBatchSize(size int) BatchSaveOption {
+	return func(tool *batchSave) {
+		if size > 0 {
+			tool.LookupBatchSize = size
+		}
+	}
+}
+
 // WithDuplicatedKey 设置用于判断数据库中记录是否已存在的字段
 // 这些字段将用于构建查询条件，以确定记录应该被更新还是新建
 // 参数:
@@ -162,17 +184,56 @@ func WithCreateOmit(fields ...string) BatchSaveOption {
 	}
 }
 
+// WithOnConflict 指定upsert时使用的冲突处理子句，覆盖按DuplicatedKey和UpdateSelect生成的子句
+// 用于按命名约束定位冲突、冲突时不操作或按表达式更新，例如累加计数:
+//
+//	WithOnConflict(clause.OnConflict{
+//		Columns:   []clause.Column{{Name: "day"}},
+//		DoUpdates: clause.Assignments(map[string]any{"count": gorm.Expr("count + VALUES(count)")}),
+//	})
+//
+// 只能用于UpsertWithActions，BatchSave和BatchUpdateValues不执行upsert，传入时会返回错误
+// 参数:
+//   - onConflict: 冲突处理子句
+//
+// 返回:
+//   - BatchSaveOption: 返回一个可应用于BatchSaveTool的选项函数
+func WithOnConflict(onConflict clause.OnConflict) BatchSaveOption {
+	return func(tool *batchSave) {
+		tool.OnConflict = &onConflict
+	}
+}
+
+// WithPartitionKey 分批前先按分区键分组，每个批次只包含同一分区的记录
+// 用于按天分区的时序表等场景，使每次CreateInBatches只写入一个分区，减少跨分区的开销；分区之间按键排序，分区内保持原有顺序
+// 参数:
+//   - fn: 返回记录所属分区的函数，参数通常为data中元素的指针，例如按created_at的日期返回"2006-01-02"
+//
+// 返回:
+//   - BatchSaveOption: 返回一个可应用于BatchSaveTool的选项函数
+func WithPartitionKey(fn func(entity any) string) BatchSaveOption {
+	return func(tool *batchSave) {
+		tool.PartitionKey = fn
+	}
+}
+
+// errOnConflictWithoutUpsert 在非upsert的批量操作中使用了WithOnConflict
+var errOnConflictWithoutUpsert = errors.New("WithOnConflict只能用于UpsertWithActions")
+
 // batchSave 批量保存工具结构体，用于执行批量保存操作
 type batchSave struct {
-	Database      *gorm.DB       // GORM数据库连接
-	BatchSize     int            // 每个批次的大小，默认100
-	ModelSchema   *schema.Schema // 模型的Schema信息
-	Entities      []any          // 需要保存的实体集合
-	DuplicatedKey []string       // 用于判断数据库中记录是否存在的键，用来决定执行更新还是创建操作
-	UpdateSelect  []string       // 更新操作时包含的字段列表，默认是所有字段
-	CreateSelect  []string       // 创建操作时包含的字段列表，默认是所有字段
-	Transaction   bool           // 是否在事务中执行操作，默认为true
-	MaxRetryCount int            // 处理重复键错误时的最大重试次数，默认为3次
+	Database        *gorm.DB           // GORM数据库连接
+	BatchSize       int                // 每个批次的大小，默认100
+	LookupBatchSize int                // 查询已存在记录时每次查询的数据量，默认与BatchSize一致
+	ModelSchema     *schema.Schema     // 模型的Schema信息
+	Entities        []any              // 需要保存的实体集合
+	DuplicatedKey   []string           // 用于判断数据库中记录是否存在的键，用来决定执行更新还是创建操作
+	UpdateSelect    []string           // 更新操作时包含的字段列表，默认是所有字段
+	CreateSelect    []string           // 创建操作时包含的字段列表，默认是所有字段
+	Transaction     bool               // 是否在事务中执行操作，默认为true
+	MaxRetryCount   int                // 处理重复键错误时的最大重试次数，默认为3次
+	OnConflict      *clause.OnConflict // upsert时使用的冲突处理子句，nil表示按DuplicatedKey生成
+	PartitionKey    func(any) string   // 分批前按分区键分组，nil表示不分组
 }
 
 // getModelFields 获取模型的所有数据库字段名
@@ -218,7 +279,7 @@ func newBatchSave(db *gorm.DB, data any, options ...BatchSaveOption) (*batchSave
 	// 3.使用GORM的schema包解析模型结构
 	// 创建modelType的实例，因为schema.Parse需要的是实例而不是类型
 	modelInstance := reflect.New(modelType).Interface()
-	modelSchema, err := schema.Parse(modelInstance, &sync.Map{}, db.NamingStrategy)
+	modelSchema, err := ParseSchema(db, modelInstance)
 	if err != nil {
 		return nil, fmt.Errorf("解析模型失败: %w", err)
 	}
@@ -260,7 +321,7 @@ func (b *batchSave) Save() error {
 	}
 
 	// 2.将实体列表按照批次大小进行分组
-	batches := slice.Chunk(b.Entities, b.BatchSize)
+	batches := b.chunk(b.BatchSize)
 
 	// 3.根据Transaction属性决定是否在事务中执行
 	if b.Transaction {
@@ -274,6 +335,35 @@ func (b *batchSave) Save() error {
 	return b.processBatches(b.Database, batches)
 }
 
+// chunk 将实体按批次大小分组，设置了PartitionKey时先按分区分组，批次不会跨分区
+// 参数:
+//   - size: 每个批次的大小
+//
+// 返回:
+//   - [][]any: 分组后的实体
+func (b *batchSave) chunk(size int) [][]any {
+	if b.PartitionKey == nil {
+		return slice.Chunk(b.Entities, size)
+	}
+
+	groups := make(map[string][]any)
+	for _, entity := range b.Entities {
+		key := b.PartitionKey(entity)
+		groups[key] = append(groups[key], entity)
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var batches [][]any
+	for _, key := range keys {
+		batches = append(batches, slice.Chunk(groups[key], size)...)
+	}
+	return batches
+}
+
 // processBatches 处理分批的数据，执行查询、更新和创建操作
 // 参数:
 //   - tx: GORM数据库连接或事务
@@ -378,6 +468,36 @@ func (b *batchSave) findExistingEntities(tx *gorm.DB, entities []any) (map[strin
 		return make(map[string]any), nil
 	}
 
+	// 按LookupBatchSize分块查询，避免IN列表过大
+	lookupSize := b.LookupBatchSize
+	if lookupSize <= 0 {
+		lookupSize = b.BatchSize
+	}
+
+	existMap := make(map[string]any)
+	for _, chunk := range slice.Chunk(entities, lookupSize) {
+		chunkMap, err := b.findExistingChunk(tx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		for key, entity := range chunkMap {
+			existMap[key] = entity
+		}
+	}
+
+	return existMap, nil
+}
+
+// findExistingChunk 对单个分块执行一次查询，返回已存在的实体
+// 参数:
+//   - tx: GORM数据库连接或事务
+//   - entities: 需要检查的实体列表，数量不超过LookupBatchSize
+//
+// 返回:
+//   - map[string]any: 以重复键生成的唯一标识为键，实体数据为值的映射
+//   - error: 查询过程中发生的错误，如果成功则返回nil
+func (b *batchSave) findExistingChunk(tx *gorm.DB, entities []any) (map[string]any, error) {
+
 	// 1.从实体中提取重复键的值
 	keyValues := make([]map[string]any, 0, len(entities))
 	for _, entity := range entities {
@@ -392,11 +512,38 @@ func (b *batchSave) findExistingEntities(tx *gorm.DB, entities []any) (map[strin
 		keyValues = append(keyValues, keyValue)
 	}
 
-	// 2.构建查询条件
-	query := tx.Model(reflect.New(b.ModelSchema.ModelType).Interface())
-	if len(b.DuplicatedKey) == 1 {
+	// 2.查询已存在的记录
+	existingEntities, err := findExistingRows(tx, reflect.New(b.ModelSchema.ModelType).Interface(), b.DuplicatedKey, keyValues)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3.构建以重复键为索引的映射，方便快速查找
+	existMap := make(map[string]any)
+	for _, entity := range existingEntities {
+		key := generateKey(entity, b.DuplicatedKey)
+		existMap[key] = entity
+	}
+
+	return existMap, nil
+}
+
+// findExistingRows 查询键值已存在的记录，只返回键字段
+// 单个键使用IN查询，多个键使用 (key1 = ? AND key2 = ?) OR ... 查询
+// 参数:
+//   - tx: GORM数据库连接或事务
+//   - model: 模型实例
+//   - columns: 键字段名
+//   - keyValues: 每条记录的键值
+//
+// 返回:
+//   - []map[string]any: 已存在记录的键字段
+//   - error: 查询过程中发生的错误，如果成功则返回nil
+func findExistingRows(tx *gorm.DB, model any, columns []string, keyValues []map[string]any) ([]map[string]any, error) {
+	query := tx.Model(model).Select(columns)
+	if len(columns) == 1 {
 		// 单个键的情况，使用IN查询（更高效）
-		key := b.DuplicatedKey[0]
+		key := columns[0]
 		values := make([]any, 0, len(keyValues))
 		for _, kv := range keyValues {
 			values = append(values, kv[key])
@@ -408,8 +555,8 @@ func (b *batchSave) findExistingEntities(tx *gorm.DB, entities []any) (map[strin
 		var conditions []string
 		var values []any
 		for _, kv := range keyValues {
-			condition := make([]string, 0, len(b.DuplicatedKey))
-			for _, key := range b.DuplicatedKey {
+			condition := make([]string, 0, len(columns))
+			for _, key := range columns {
 				condition = append(condition, fmt.Sprintf("%s = ?", key))
 				values = append(values, kv[key])
 			}
@@ -418,20 +565,11 @@ func (b *batchSave) findExistingEntities(tx *gorm.DB, entities []any) (map[strin
 		query = query.Where(strings.Join(conditions, " OR "), values...)
 	}
 
-	// 3.执行查询获取已存在的实体
-	var existingEntities []map[string]any
-	if err := query.Find(&existingEntities).Error; err != nil {
+	var rows []map[string]any
+	if err := query.Find(&rows).Error; err != nil {
 		return nil, err
 	}
-
-	// 4.构建以重复键为索引的映射，方便快速查找
-	existMap := make(map[string]any)
-	for _, entity := range existingEntities {
-		key := generateKey(entity, b.DuplicatedKey)
-		existMap[key] = entity
-	}
-
-	return existMap, nil
+	return rows, nil
 }
 
 // separateEntities 将实体分为需要更新和需要创建的两组
@@ -628,9 +766,21 @@ func extractEntities(data any) ([]any, reflect.Type, error) {
 	}
 
 	// 提取所有实体到一个统一的切片中
+	// 统一转换为指针，使创建后数据库生成的主键能回写到调用方的原始元素上
 	entities := make([]any, val.Len())
 	for i := 0; i < val.Len(); i++ {
-		entities[i] = val.Index(i).Interface()
+		elem := val.Index(i)
+		switch {
+		case elem.Kind() == reflect.Ptr:
+			entities[i] = elem.Interface()
+		case elem.CanAddr():
+			entities[i] = elem.Addr().Interface()
+		default:
+			// 按值传入的数组元素不可寻址，只能复制一份，主键无法回写
+			ptr := reflect.New(elem.Type())
+			ptr.Elem().Set(elem)
+			entities[i] = ptr.Interface()
+		}
 	}
 
 	return entities, elemType, nil
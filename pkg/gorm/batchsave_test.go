@@ -0,0 +1,113 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type batchUser struct {
+	ID   uint
+	Code string
+	Name string
+}
+
+func TestBatchSaveLookupBatchSize(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	// 查询按LookupBatchSize分为3次，写入按BatchSize只有1次
+	mock.ExpectQuery("SELECT `code` FROM `batch_users` WHERE code IN \\(\\?,\\?\\)").WithArgs("a", "b").
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("b"))
+	mock.ExpectQuery("SELECT `code` FROM `batch_users` WHERE code IN \\(\\?,\\?\\)").WithArgs("c", "d").
+		WillReturnRows(sqlmock.NewRows([]string{"code"}))
+	mock.ExpectQuery("SELECT `code` FROM `batch_users` WHERE code IN \\(\\?\\)").WithArgs("e").
+		WillReturnRows(sqlmock.NewRows([]string{"code"}))
+	mock.ExpectExec("UPDATE `batch_users` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `batch_users` .* VALUES \\(.*\\),\\(.*\\),\\(.*\\),\\(.*\\)$").
+		WillReturnResult(sqlmock.NewResult(1, 4))
+	mock.ExpectCommit()
+
+	users := []batchUser{{Code: "a"}, {Code: "b"}, {Code: "c"}, {Code: "d"}, {Code: "e"}}
+	err := gkit_gorm.BatchSave(db, users,
+		gkit_gorm.WithDuplicatedKey("code"),
+		gkit_gorm.WithBatchSize(5),
+		gkit_gorm.WithLookupBatchSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchSaveWritesBackIDs(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `code` FROM `batch_users`").WillReturnRows(sqlmock.NewRows([]string{"code"}))
+	mock.ExpectExec("INSERT INTO `batch_users`").WillReturnResult(sqlmock.NewResult(10, 2))
+	mock.ExpectCommit()
+
+	users := []batchUser{{Code: "a"}, {Code: "b"}}
+	if err := gkit_gorm.BatchSave(db, users, gkit_gorm.WithDuplicatedKey("code")); err != nil {
+		t.Fatal(err)
+	}
+	if users[0].ID != 10 || users[1].ID != 11 {
+		t.Fatalf("主键没有写回调用方的切片: %+v", users)
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `batch_users`").WillReturnResult(sqlmock.NewResult(42, 1))
+	mock.ExpectCommit()
+
+	user := &batchUser{Code: "a"}
+	if err := gkit_gorm.InsertReturning(db, user); err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != 42 {
+		t.Fatalf("期望主键42，实际%d", user.ID)
+	}
+	if err := gkit_gorm.InsertReturning[batchUser](db, nil); err == nil {
+		t.Fatal("entity为nil时应当返回错误")
+	}
+}
+
+type partitionEvent struct {
+	ID  uint
+	Day string
+}
+
+func TestBatchSavePartitionKey(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	// d1有3行，按BatchSize分为2批；d2有2行为1批；每批只包含同一分区
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO `partition_events` .*VALUES \\(\\?\\),\\(\\?\\)$").WithArgs("d1", "d1").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO `partition_events` .*VALUES \\(\\?\\)$").WithArgs("d1").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO `partition_events` .*VALUES \\(\\?\\),\\(\\?\\)$").WithArgs("d2", "d2").
+		WillReturnResult(sqlmock.NewResult(4, 2))
+	mock.ExpectCommit()
+
+	events := []partitionEvent{{Day: "d2"}, {Day: "d1"}, {Day: "d2"}, {Day: "d1"}, {Day: "d1"}}
+	err := gkit_gorm.BatchSave(db, events,
+		gkit_gorm.WithBatchSize(2),
+		gkit_gorm.WithPartitionKey(func(e any) string { return e.(*partitionEvent).Day }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 分组不改变写入的总数，所有记录都写回了主键
+	ids := make(map[uint]bool, len(events))
+	for _, e := range events {
+		if e.ID == 0 {
+			t.Fatalf("记录没有写回主键: %+v", events)
+		}
+		ids[e.ID] = true
+	}
+	if len(ids) != len(events) {
+		t.Fatalf("期望%d个不同的主键，实际%d: %+v", len(events), len(ids), events)
+	}
+}
@@ -0,0 +1,181 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxPlaceholders 单条语句允许的最大占位符数量，MySQL的上限为65535
+const maxPlaceholders = 65535
+
+// BatchUpdateValues 使用 CASE WHEN 在一条UPDATE中把多行更新为各自不同的值
+// 生成的语句形如 UPDATE t SET a = CASE id WHEN ? THEN ? ... ELSE a END WHERE id IN (...)
+// 每批一条语句，批次大小会根据占位符上限自动缩小；keyColumn重复时以第一条记录的值为准
+// 参数:
+//   - db: GORM数据库连接
+//   - data: 需要更新的数据集合，必须是切片或数组类型
+//   - keyColumn: 用于定位记录的数据库字段名，通常是主键
+//   - valueColumns: 需要更新的数据库字段名
+//   - options: 可选的配置选项，支持WithBatchSize和WithTransaction
+//
+// 返回:
+//   - error: 更新过程中发生的错误，如果成功则返回nil
+func BatchUpdateValues(db *gorm.DB, data any, keyColumn string, valueColumns []string, options ...BatchSaveOption) error {
+	if len(valueColumns) == 0 {
+		return errors.New("valueColumns不能为空")
+	}
+	tool, err := newBatchSave(db, data, append([]BatchSaveOption{WithDuplicatedKey(keyColumn)}, options...)...)
+	if err != nil {
+		return err
+	}
+	if tool.OnConflict != nil {
+		return errOnConflictWithoutUpsert
+	}
+	for _, column := range append([]string{keyColumn}, valueColumns...) {
+		if _, ok := tool.ModelSchema.FieldsByDBName[column]; !ok {
+			return fmt.Errorf("模型 %s 不存在字段 %s", tool.ModelSchema.Name, column)
+		}
+	}
+	if len(tool.Entities) == 0 {
+		return nil
+	}
+
+	// 每行在每个字段的CASE中占用2个占位符，在IN中占用1个
+	batchSize := tool.BatchSize
+	if limit := maxPlaceholders / (2*len(valueColumns) + 1); batchSize > limit {
+		batchSize = limit
+	}
+	batches := tool.chunk(batchSize)
+
+	update := func(tx *gorm.DB) error {
+		for _, batch := range batches {
+			if err := updateValuesBatch(tx, tool, batch, keyColumn, valueColumns); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if tool.Transaction && len(batches) > 1 {
+		return db.Transaction(update)
+	}
+	return update(db)
+}
+
+// updateValuesBatch 为一批实体执行一条CASE WHEN更新
+// 参数:
+//   - tx: GORM数据库连接或事务
+//   - tool: 解析好的实体和模型信息
+//   - batch: 本批实体
+//   - keyColumn: 定位字段
+//   - valueColumns: 更新的字段
+//
+// 返回:
+//   - error: 更新过程中发生的错误，如果成功则返回nil
+func updateValuesBatch(tx *gorm.DB, tool *batchSave, batch []any, keyColumn string, valueColumns []string) error {
+	keys := make([]any, 0, len(batch))
+	for _, entity := range batch {
+		key, err := getFieldValue(entity, tool.ModelSchema, keyColumn)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	values := make(map[string][]any, len(valueColumns))
+	for _, column := range valueColumns {
+		columnValues := make([]any, 0, len(batch))
+		for _, entity := range batch {
+			val, err := getFieldValue(entity, tool.ModelSchema, column)
+			if err != nil {
+				return err
+			}
+			columnValues = append(columnValues, val)
+		}
+		values[column] = columnValues
+	}
+
+	modelInstance := reflect.New(tool.ModelSchema.ModelType).Interface()
+	return updateCaseWhen(tx, modelInstance, keyColumn, keys, values)
+}
+
+// updateCaseWhen 执行一条CASE WHEN更新，keys[i]对应的行的每个字段更新为values[字段][i]
+// 参数:
+//   - tx: GORM数据库连接或事务
+//   - model: 模型实例，用于确定表名
+//   - keyColumn: 定位字段
+//   - keys: 需要更新的行的定位字段值，不存在的行会被跳过
+//   - values: 每个字段按keys顺序排列的新值
+//
+// 返回:
+//   - error: 更新过程中发生的错误，如果成功则返回nil
+func updateCaseWhen(tx *gorm.DB, model any, keyColumn string, keys []any, values map[string][]any) error {
+	quotedKey := tx.Statement.Quote(keyColumn)
+	updates := make(map[string]any, len(values))
+	for column, columnValues := range values {
+		var sql strings.Builder
+		vars := make([]any, 0, 2*len(keys))
+		sql.WriteString("CASE ")
+		sql.WriteString(quotedKey)
+		for i, key := range keys {
+			sql.WriteString(" WHEN ? THEN ?")
+			vars = append(vars, key, columnValues[i])
+		}
+		sql.WriteString(" ELSE ")
+		sql.WriteString(tx.Statement.Quote(column))
+		sql.WriteString(" END")
+		updates[column] = gorm.Expr(sql.String(), vars...)
+	}
+
+	return tx.Model(model).
+		Where(clause.IN{Column: clause.Column{Name: keyColumn}, Values: keys}).
+		Updates(updates).Error
+}
+
+// Reorder 按orderedKeys的顺序把orderColumn更新为每个键的下标(从0开始)，用于拖拽排序后保存顺序
+// 在事务中按批执行CASE WHEN更新，每批一条语句；表中不存在的键被跳过，键重复时以第一次出现的位置为准
+// 参数:
+//   - db: GORM数据库连接
+//   - model: 模型实例，例如 &Menu{}
+//   - keyColumn: 用于定位记录的数据库字段名，通常是主键
+//   - orderColumn: 保存顺序的数据库字段名，例如"sort_order"
+//   - orderedKeys: 按新顺序排列的键
+//
+// 返回:
+//   - error: 更新过程中发生的错误，如果成功则返回nil
+func Reorder(db *gorm.DB, model any, keyColumn, orderColumn string, orderedKeys []any) error {
+	s, err := ParseSchema(db, model)
+	if err != nil {
+		return fmt.Errorf("解析模型失败: %w", err)
+	}
+	for _, column := range []string{keyColumn, orderColumn} {
+		if _, ok := s.FieldsByDBName[column]; !ok {
+			return fmt.Errorf("模型 %s 不存在字段 %s", s.Name, column)
+		}
+	}
+	if len(orderedKeys) == 0 {
+		return nil
+	}
+
+	// 每个键在CASE中占用2个占位符，在IN中占用1个
+	batchSize := maxPlaceholders / 3
+	return db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(orderedKeys); start += batchSize {
+			end := min(start+batchSize, len(orderedKeys))
+			keys := orderedKeys[start:end]
+			orders := make([]any, len(keys))
+			for i := range keys {
+				orders[i] = start + i
+			}
+			modelInstance := reflect.New(s.ModelType).Interface()
+			if err := updateCaseWhen(tx, modelInstance, keyColumn, keys, map[string][]any{orderColumn: orders}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,155 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type batchItem struct {
+	ID    int64
+	Name  string
+	Price int
+}
+
+func TestBatchUpdateValues(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `batch_items` SET "+
+		"`name`=CASE `id` WHEN \\? THEN \\? WHEN \\? THEN \\? ELSE `name` END,"+
+		"`price`=CASE `id` WHEN \\? THEN \\? WHEN \\? THEN \\? ELSE `price` END "+
+		"WHERE `id` IN \\(\\?,\\?\\)$").
+		WithArgs(1, "a", 2, "b", 1, 10, 2, 20, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	items := []batchItem{{ID: 1, Name: "a", Price: 10}, {ID: 2, Name: "b", Price: 20}}
+	if err := gkit_gorm.BatchUpdateValues(db, items, "id", []string{"name", "price"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchUpdateValuesBatches(t *testing.T) {
+	db, mock := mockDB(t)
+	// 多个批次在同一个事务中执行
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `batch_items` SET `name`=CASE `id` WHEN \\? THEN \\? WHEN \\? THEN \\? ELSE `name` END WHERE `id` IN \\(\\?,\\?\\)$").
+		WithArgs(1, "a", 2, "b", 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE `batch_items` SET `name`=CASE `id` WHEN \\? THEN \\? ELSE `name` END WHERE `id` = \\?$").
+		WithArgs(3, "c", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	items := []batchItem{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}
+	err := gkit_gorm.BatchUpdateValues(db, items, "id", []string{"name"},
+		gkit_gorm.WithBatchSize(2), gkit_gorm.WithTransaction(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatchUpdateValuesUnknownColumn(t *testing.T) {
+	db, _ := mockDB(t)
+	if err := gkit_gorm.BatchUpdateValues(db, []batchItem{{ID: 1}}, "id", []string{"missing"}); err == nil {
+		t.Fatal("不存在的字段应返回错误")
+	}
+	if err := gkit_gorm.BatchUpdateValues(db, []batchItem{{ID: 1}}, "id", nil); err == nil {
+		t.Fatal("valueColumns为空时应返回错误")
+	}
+}
+
+// benchmarkRoundTrip 模拟每条语句的网络往返时间
+const benchmarkRoundTrip = 100 * time.Microsecond
+
+// benchmarkDB 返回不使用默认事务的sqlmock连接，用于基准测试
+func benchmarkDB(b *testing.B) (*gorm.DB, sqlmock.Sqlmock) {
+	b.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard, SkipDefaultTransaction: true})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db, mock
+}
+
+func BenchmarkBatchUpdateValues(b *testing.B) {
+	items := make([]batchItem, 100)
+	for i := range items {
+		items[i] = batchItem{ID: int64(i + 1), Name: "name", Price: i}
+	}
+
+	b.Run("case_when", func(b *testing.B) {
+		db, mock := benchmarkDB(b)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mock.ExpectExec("UPDATE").WillDelayFor(benchmarkRoundTrip).WillReturnResult(sqlmock.NewResult(0, int64(len(items))))
+			if err := gkit_gorm.BatchUpdateValues(db, items, "id", []string{"name", "price"}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("per_row", func(b *testing.B) {
+		db, mock := benchmarkDB(b)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for range items {
+				mock.ExpectExec("UPDATE").WillDelayFor(benchmarkRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			for _, item := range items {
+				err := db.Model(&batchItem{ID: item.ID}).
+					Updates(map[string]any{"name": item.Name, "price": item.Price}).Error
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+type reorderMenu struct {
+	ID        uint
+	SortOrder int
+}
+
+func TestReorder(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	// 5行按新顺序依次得到0到4
+	mock.ExpectExec("^UPDATE `reorder_menus` SET `sort_order`=CASE `id` WHEN \\? THEN \\? WHEN \\? THEN \\? WHEN \\? THEN \\? "+
+		"WHEN \\? THEN \\? WHEN \\? THEN \\? ELSE `sort_order` END WHERE `id` IN \\(\\?,\\?,\\?,\\?,\\?\\)$").
+		WithArgs(5, 0, 3, 1, 1, 2, 4, 3, 2, 4, 5, 3, 1, 4, 2).WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectCommit()
+
+	if err := gkit_gorm.Reorder(db, &reorderMenu{}, "id", "sort_order", []any{5, 3, 1, 4, 2}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReorderDuplicateKeys(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	// 重复的键保留在CASE中，CASE取第一个匹配的WHEN，因此以第一次出现的位置为准
+	mock.ExpectExec("^UPDATE `reorder_menus` SET `sort_order`=CASE `id` WHEN \\? THEN \\? WHEN \\? THEN \\? WHEN \\? THEN \\? "+
+		"ELSE `sort_order` END WHERE `id` IN \\(\\?,\\?,\\?\\)$").
+		WithArgs(2, 0, 1, 1, 2, 2, 2, 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	if err := gkit_gorm.Reorder(db, &reorderMenu{}, "id", "sort_order", []any{2, 1, 2}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReorderUnknownColumn(t *testing.T) {
+	db, _ := mockDB(t)
+	if err := gkit_gorm.Reorder(db, &reorderMenu{}, "id", "nope", []any{1}); err == nil {
+		t.Fatal("字段不存在时应返回错误")
+	}
+}
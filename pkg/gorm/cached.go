@@ -0,0 +1,90 @@
+package gkit_gorm
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+	"gorm.io/gorm"
+)
+
+// CachedFirst 优先从缓存读取记录，未命中时通过First查询并写入缓存
+// 记录不存在时同样缓存一个空值，过期前的查询直接返回ErrNotFound，防止缓存穿透
+// 参数:
+//   - ctx: 上下文，同时用于缓存和数据库查询
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件
+//   - c: 缓存实例
+//   - key: 缓存键
+//   - ttl: 缓存过期时间，不存在的记录使用相同的过期时间
+//   - conds: 查询条件，与db.First的conds一致
+//
+// 返回:
+//   - T: 查询到的记录，未找到时为零值
+//   - error: 未找到时返回ErrNotFound，其他错误原样返回
+func CachedFirst[T any](ctx context.Context, db *gorm.DB, c cache.Cache, key string, ttl time.Duration, conds ...any) (T, error) {
+	var value T
+
+	data, err := c.SaveRaw(ctx, key, func() ([]byte, error) {
+		record, err := First[T](db.WithContext(ctx), conds...)
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return cache.Marshal(record)
+	}, ttl, cache.WithPreventCacheMiss(ttl))
+	if err != nil {
+		return value, err
+	}
+
+	// 空值表示记录不存在
+	if len(data) == 0 {
+		return value, markNotFound(gorm.ErrRecordNotFound)
+	}
+	if err := cache.Unmarshal(data, &value); err != nil {
+		return value, errors.Wrap(err, "gorm: failed to unmarshal cached value")
+	}
+	return value, nil
+}
+
+// CachedFind 优先从缓存读取记录列表，未命中时通过Find查询并写入缓存，空列表同样会被缓存
+// 参数:
+//   - ctx: 上下文，同时用于缓存和数据库查询
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件
+//   - c: 缓存实例
+//   - key: 缓存键
+//   - ttl: 缓存过期时间
+//   - conds: 查询条件，与db.Find的conds一致
+//
+// 返回:
+//   - []T: 查询到的记录，没有数据时为空切片
+//   - error: 查询过程中发生的错误，如果成功则返回nil
+func CachedFind[T any](ctx context.Context, db *gorm.DB, c cache.Cache, key string, ttl time.Duration, conds ...any) ([]T, error) {
+	return cache.Save(ctx, c, key, func() ([]T, error) {
+		records := make([]T, 0)
+		if err := db.WithContext(ctx).Find(&records, conds...).Error; err != nil {
+			return nil, err
+		}
+		return records, nil
+	}, ttl)
+}
+
+// InvalidateCached 删除CachedFirst、CachedFind写入的缓存，记录更新后调用
+// 参数:
+//   - ctx: 上下文
+//   - c: 缓存实例
+//   - keys: 需要删除的缓存键
+//
+// 返回:
+//   - error: 删除过程中发生的错误，如果成功则返回nil
+func InvalidateCached(ctx context.Context, c cache.Cache, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.Pipeline(ctx, func(p cache.Pipeliner) error {
+		p.Delete(keys...)
+		return nil
+	})
+}
@@ -0,0 +1,86 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cockroachdb/errors"
+	"github.com/shaco-go/gkit-layout/pkg/cache"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type cachedThing struct {
+	ID   int64
+	Name string
+}
+
+// newCachedTestCache 返回测试结束时关闭的内存缓存
+func newCachedTestCache(t *testing.T) cache.Cache {
+	t.Helper()
+	c, err := cache.New(cache.WithMemory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestCachedFirstMissThenHit(t *testing.T) {
+	db, mock := mockDB(t)
+	c := newCachedTestCache(t)
+	ctx := context.Background()
+	// 只有第一次查询数据库，第二次命中缓存
+	mock.ExpectQuery("SELECT \\* FROM `cached_things` WHERE `cached_things`.`id` = \\? ORDER BY `cached_things`.`id` LIMIT \\?").
+		WithArgs(1, 1).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+
+	for i := 0; i < 2; i++ {
+		thing, err := gkit_gorm.CachedFirst[cachedThing](ctx, db, c, "thing:1", time.Minute, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if thing.ID != 1 || thing.Name != "a" {
+			t.Fatalf("第%d次读取结果不正确: %+v", i+1, thing)
+		}
+	}
+}
+
+func TestCachedFirstCachesNotFound(t *testing.T) {
+	db, mock := mockDB(t)
+	c := newCachedTestCache(t)
+	ctx := context.Background()
+	mock.ExpectQuery("SELECT \\* FROM `cached_things`").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	for i := 0; i < 2; i++ {
+		_, err := gkit_gorm.CachedFirst[cachedThing](ctx, db, c, "thing:2", time.Minute, 2)
+		if !errors.Is(err, gkit_gorm.ErrNotFound) {
+			t.Fatalf("第%d次读取应返回ErrNotFound，实际 %v", i+1, err)
+		}
+	}
+}
+
+func TestCachedFindAndInvalidate(t *testing.T) {
+	db, mock := mockDB(t)
+	c := newCachedTestCache(t)
+	ctx := context.Background()
+	mock.ExpectQuery("SELECT \\* FROM `cached_things`$").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+
+	for i := 0; i < 2; i++ {
+		things, err := gkit_gorm.CachedFind[cachedThing](ctx, db, c, "things", time.Minute)
+		if err != nil || len(things) != 2 {
+			t.Fatalf("第%d次读取结果不正确: %+v %v", i+1, things, err)
+		}
+	}
+
+	// 删除缓存后重新查询
+	if err := gkit_gorm.InvalidateCached(ctx, c, "things"); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery("SELECT \\* FROM `cached_things`$").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	things, err := gkit_gorm.CachedFind[cachedThing](ctx, db, c, "things", time.Minute)
+	if err != nil || len(things) != 0 {
+		t.Fatalf("删除缓存后应重新查询，实际 %+v %v", things, err)
+	}
+}
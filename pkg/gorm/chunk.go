@@ -0,0 +1,126 @@
+package gkit_gorm
+
+import (
+	"fmt"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// defaultInChunkSize WhereInChunked默认每次IN查询的值数量
+const defaultInChunkSize = 1000
+
+// WhereInChunked 将超长的IN列表按chunk分块，每块执行一次fn，避免单条SQL过大被数据库拒绝
+// fn收到的会话已添加 column IN (...) 条件，结果的合并由调用方在fn中完成
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Model、Where等条件
+//   - column: 字段名，会按数据库方言加引号
+//   - values: IN列表的值，为空时不会调用fn
+//   - chunk: 每块的值数量，小于等于0时默认1000
+//   - fn: 每块执行的查询
+//
+// 返回:
+//   - error: ctx取消或fn返回的第一个错误，如果成功则返回nil
+func WhereInChunked[T any](db *gorm.DB, column string, values []T, chunk int, fn func(*gorm.DB) error) error {
+	if chunk <= 0 {
+		chunk = defaultInChunkSize
+	}
+
+	// Session保证每块的条件互不影响
+	session := db.Session(&gorm.Session{})
+	for _, part := range slice.Chunk(values, chunk) {
+		if ctx := session.Statement.Context; ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		in := make([]any, len(part))
+		for i, v := range part {
+			in[i] = v
+		}
+		if err := fn(session.Where(clause.IN{Column: clause.Column{Name: column}, Values: in})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByIDs 按主键批量查询记录，内部按1000个ID分块查询后合并结果
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件
+//   - ids: 主键列表
+//
+// 返回:
+//   - []Model: 查询到的记录，分块之间不保证顺序，不存在的ID会被忽略
+//   - error: 查询过程中发生的错误，如果成功则返回nil
+func FindByIDs[Model, ID any](db *gorm.DB, ids []ID) ([]Model, error) {
+	var model Model
+	modelSchema, err := ParseSchema(db, &model)
+	if err != nil {
+		return nil, fmt.Errorf("解析模型失败: %w", err)
+	}
+	if modelSchema.PrioritizedPrimaryField == nil {
+		return nil, fmt.Errorf("模型 %s 没有唯一主键", modelSchema.Name)
+	}
+
+	results := make([]Model, 0, len(ids))
+	err = WhereInChunked(db.Model(&model), modelSchema.PrioritizedPrimaryField.DBName, ids, defaultInChunkSize, func(tx *gorm.DB) error {
+		var part []Model
+		if err := tx.Find(&part).Error; err != nil {
+			return err
+		}
+		results = append(results, part...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ExistingKeys 查询keys中哪些值已经存在于表中，按1000个值分块执行 SELECT column WHERE column IN (...)
+// 与BatchSave使用相同的存在性查询，适合在BatchSave之外插入前去重
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where等条件
+//   - model: 模型实例，用于确定表名
+//   - column: 数据库字段名
+//   - keys: 需要检查的值
+//
+// 返回:
+//   - map[K]bool: 每个输入值是否存在
+//   - error: 查询过程中发生的错误，如果成功则返回nil
+func ExistingKeys[K comparable](db *gorm.DB, model any, column string, keys []K) (map[K]bool, error) {
+	result := make(map[K]bool, len(keys))
+	index := make(map[string]K, len(keys))
+	for _, key := range keys {
+		result[key] = false
+		index[fmt.Sprintf("%v", key)] = key
+	}
+
+	columns := []string{column}
+	session := db.Session(&gorm.Session{})
+	for _, part := range slice.Chunk(keys, defaultInChunkSize) {
+		keyValues := make([]map[string]any, len(part))
+		for i, key := range part {
+			keyValues[i] = map[string]any{column: key}
+		}
+		rows, err := findExistingRows(session, model, columns, keyValues)
+		if err != nil {
+			return nil, err
+		}
+
+		// 驱动返回的类型可能与K不同(如int64与int)，按格式化结果匹配
+		for _, row := range rows {
+			val := row[column]
+			if b, ok := val.([]byte); ok {
+				val = string(b)
+			}
+			if key, ok := index[fmt.Sprintf("%v", val)]; ok {
+				result[key] = true
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,92 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type chunkThing struct {
+	ID   int64
+	Name string
+}
+
+func TestFindByIDsChunks(t *testing.T) {
+	db, mock := mockDB(t)
+	ids := make([]int64, 1500)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	// 1500个ID分为1000和500两次查询
+	mock.ExpectQuery("SELECT \\* FROM `chunk_things` WHERE `id` IN \\((\\?,){999}\\?\\)$").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(1000, "b"))
+	mock.ExpectQuery("SELECT \\* FROM `chunk_things` WHERE `id` IN \\((\\?,){499}\\?\\)$").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1001, "c"))
+
+	things, err := gkit_gorm.FindByIDs[chunkThing](db, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(things) != 3 || things[0].ID != 1 || things[1].ID != 1000 || things[2].ID != 1001 {
+		t.Fatalf("合并结果不正确: %+v", things)
+	}
+}
+
+func TestWhereInChunked(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `chunk_things` WHERE name = \\? AND `id` IN \\(\\?,\\?\\)$").
+		WithArgs("x", 1, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `chunk_things` WHERE name = \\? AND `id` = \\?$").
+		WithArgs("x", 3).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	var all []chunkThing
+	calls := 0
+	err := gkit_gorm.WhereInChunked(db.Model(&chunkThing{}).Where("name = ?", "x"), "id", []int{1, 2, 3}, 2, func(tx *gorm.DB) error {
+		calls++
+		var part []chunkThing
+		if err := tx.Find(&part).Error; err != nil {
+			return err
+		}
+		all = append(all, part...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || len(all) != 2 {
+		t.Fatalf("应分为2块并合并结果，实际%d块 %+v", calls, all)
+	}
+}
+
+func TestExistingKeys(t *testing.T) {
+	db, mock := mockDB(t)
+	// 驱动可能以[]byte返回字符串
+	mock.ExpectQuery("SELECT `name` FROM `chunk_things` WHERE name IN \\(\\?,\\?,\\?\\)").WithArgs("a", "b", "c").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow([]byte("a")).AddRow("c"))
+
+	existing, err := gkit_gorm.ExistingKeys(db, &chunkThing{}, "name", []string{"a", "b", "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(existing) != 3 || !existing["a"] || existing["b"] || !existing["c"] {
+		t.Fatalf("存在性结果不正确: %v", existing)
+	}
+}
+
+func TestExistingKeysDriverType(t *testing.T) {
+	db, mock := mockDB(t)
+	// 驱动返回int64，按格式化结果与int匹配
+	mock.ExpectQuery("SELECT `id` FROM `chunk_things` WHERE").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(2)))
+
+	existing, err := gkit_gorm.ExistingKeys(db, &chunkThing{}, "id", []int{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing[1] || !existing[2] || existing[3] {
+		t.Fatalf("存在性结果不正确: %v", existing)
+	}
+}
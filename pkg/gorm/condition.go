@@ -0,0 +1,79 @@
+package gkit_gorm
+
+import (
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// conditionGroup 用AND或OR连接的一组条件，生成时总是加括号，不受db.Or等链式调用顺序的影响
+type conditionGroup struct {
+	op    string
+	empty string
+	conds []clause.Expression
+}
+
+// AndGroup 返回用AND连接conds并加括号的条件，可以通过db.Where或db.Clauses使用，也可以作为OrGroup的成员
+// 例如 OrGroup(AndGroup(a, b), AndGroup(c, d)) 生成 ((a AND b) OR (c AND d))；nil条件被忽略，没有条件时为 1 = 1
+// 参数:
+//   - conds: 条件，例如 clause.Eq{Column: "status", Value: 1} 或 clause.Expr{SQL: "age > ?", Vars: []any{18}}
+//
+// 返回:
+//   - clause.Expression: 分组后的条件
+func AndGroup(conds ...clause.Expression) clause.Expression {
+	return conditionGroup{op: " AND ", empty: "1 = 1", conds: conds}
+}
+
+// OrGroup 返回用OR连接conds并加括号的条件，用法与AndGroup一致，没有条件时为 1 = 0
+// 搜索表单没有任何可选条件时不希望过滤数据，应当不添加该条件
+// 参数:
+//   - conds: 条件
+//
+// 返回:
+//   - clause.Expression: 分组后的条件
+func OrGroup(conds ...clause.Expression) clause.Expression {
+	return conditionGroup{op: " OR ", empty: "1 = 0", conds: conds}
+}
+
+// Build 生成带括号的条件，包含OR的原始SQL条件同样加括号
+func (g conditionGroup) Build(builder clause.Builder) {
+	conds := make([]clause.Expression, 0, len(g.conds))
+	for _, cond := range g.conds {
+		if cond != nil {
+			conds = append(conds, cond)
+		}
+	}
+	if len(conds) == 0 {
+		_, _ = builder.WriteString(g.empty)
+		return
+	}
+
+	_ = builder.WriteByte('(')
+	for i, cond := range conds {
+		if i > 0 {
+			_, _ = builder.WriteString(g.op)
+		}
+		if needsParentheses(cond) {
+			_ = builder.WriteByte('(')
+			cond.Build(builder)
+			_ = builder.WriteByte(')')
+		} else {
+			cond.Build(builder)
+		}
+	}
+	_ = builder.WriteByte(')')
+}
+
+// needsParentheses 判断原始SQL条件是否包含OR，与GORM处理字符串条件的方式一致
+func needsParentheses(cond clause.Expression) bool {
+	var sql string
+	switch c := cond.(type) {
+	case clause.Expr:
+		sql = c.SQL
+	case clause.NamedExpr:
+		sql = c.SQL
+	default:
+		return false
+	}
+	return strings.Contains(strings.ToUpper(sql), " OR ")
+}
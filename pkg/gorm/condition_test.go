@@ -0,0 +1,66 @@
+package gkit_gorm_test
+
+import (
+	"reflect"
+	"testing"
+
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type condUser struct {
+	ID int64
+}
+
+func TestConditionGroupNested(t *testing.T) {
+	db, _ := mockDB(t)
+	dry := db.Session(&gorm.Session{DryRun: true})
+	a := clause.Eq{Column: "a", Value: 1}
+	b := clause.Expr{SQL: "b > ?", Vars: []any{2}}
+	c := clause.Eq{Column: "c", Value: 3}
+	d := clause.Expr{SQL: "d = ? OR e = ?", Vars: []any{4, 5}}
+
+	tests := []struct {
+		name  string
+		query func(tx *gorm.DB) *gorm.DB
+		sql   string
+		vars  []any
+	}{
+		{
+			name: "or of and groups",
+			query: func(tx *gorm.DB) *gorm.DB {
+				return tx.Where("tenant_id = ?", 9).Where(gkit_gorm.OrGroup(gkit_gorm.AndGroup(a, b), gkit_gorm.AndGroup(c, d)))
+			},
+			sql:  "SELECT * FROM `cond_users` WHERE tenant_id = ? AND ((`a` = ? AND b > ?) OR (`c` = ? AND (d = ? OR e = ?)))",
+			vars: []any{9, 1, 2, 3, 4, 5},
+		},
+		{
+			name: "three levels with nil",
+			query: func(tx *gorm.DB) *gorm.DB {
+				return tx.Clauses(gkit_gorm.OrGroup(a, gkit_gorm.AndGroup(c, gkit_gorm.OrGroup(b, nil)))).Where("x = 1")
+			},
+			sql:  "SELECT * FROM `cond_users` WHERE (`a` = ? OR (`c` = ? AND (b > ?))) AND x = 1",
+			vars: []any{1, 3, 2},
+		},
+		{
+			name: "empty groups",
+			query: func(tx *gorm.DB) *gorm.DB {
+				return tx.Where(gkit_gorm.OrGroup()).Or(gkit_gorm.AndGroup())
+			},
+			sql: "SELECT * FROM `cond_users` WHERE 1 = 0 OR 1 = 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []condUser
+			stmt := tt.query(dry).Find(&users).Statement
+			if got := stmt.SQL.String(); got != tt.sql {
+				t.Errorf("SQL不正确\n实际 %s\n期望 %s", got, tt.sql)
+			}
+			if len(tt.vars) > 0 && !reflect.DeepEqual(stmt.Vars, tt.vars) {
+				t.Errorf("参数不正确，实际 %v，期望 %v", stmt.Vars, tt.vars)
+			}
+		})
+	}
+}
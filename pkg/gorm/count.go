@@ -0,0 +1,45 @@
+package gkit_gorm
+
+import (
+	"database/sql"
+
+	"gorm.io/gorm"
+)
+
+// estimateCountThreshold 估算行数低于该值时改用精确计数，小表的估算误差相对较大且精确计数很快
+const estimateCountThreshold = 10000
+
+// EstimateCount 获取表的估算行数，用于分页时展示"约N条结果"，避免大表上SELECT COUNT(*)的全表扫描
+// MySQL读取information_schema.TABLES的TABLE_ROWS，Postgres读取pg_class.reltuples，两者都依赖统计信息，可能与实际行数有较大偏差
+// 其他数据库、估算不可用或估算行数较小时回退为精确计数
+// 参数:
+//   - db: GORM数据库连接，db上已设置的查询条件会被忽略
+//   - table: 表名，Postgres可以带schema前缀，例如"public.users"
+//
+// 返回:
+//   - int64: 估算或精确的行数
+//   - error: 精确计数失败时返回错误，估算失败不会返回错误
+func EstimateCount(db *gorm.DB, table string) (int64, error) {
+	db = db.Session(&gorm.Session{NewDB: true})
+
+	var query string
+	switch db.Dialector.Name() {
+	case "mysql":
+		query = "SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"
+	case "postgres":
+		query = "SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass(?)"
+	}
+	if query != "" {
+		// 表不存在或从未ANALYZE时估算为空或-1，回退为精确计数
+		var estimate sql.NullInt64
+		if err := db.Raw(query, table).Row().Scan(&estimate); err == nil && estimate.Valid && estimate.Int64 >= estimateCountThreshold {
+			return estimate.Int64, nil
+		}
+	}
+
+	var count int64
+	if err := db.Table(table).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
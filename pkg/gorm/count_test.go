@@ -0,0 +1,68 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+func TestEstimateCountMySQL(t *testing.T) {
+	estimate := "SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE\\(\\) AND TABLE_NAME = \\?"
+	tests := []struct {
+		name     string
+		estimate *sqlmock.Rows
+		exact    *sqlmock.Rows
+		want     int64
+	}{
+		{name: "large table", estimate: sqlmock.NewRows([]string{"rows"}).AddRow(500000), want: 500000},
+		{name: "small table", estimate: sqlmock.NewRows([]string{"rows"}).AddRow(12), exact: sqlmock.NewRows([]string{"count"}).AddRow(15), want: 15},
+		{name: "missing table", estimate: sqlmock.NewRows([]string{"rows"}), exact: sqlmock.NewRows([]string{"count"}).AddRow(3), want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := mockDB(t)
+			mock.ExpectQuery(estimate).WithArgs("users").WillReturnRows(tt.estimate)
+			if tt.exact != nil {
+				// 精确计数不受db上已有条件的影响
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users`$").WillReturnRows(tt.exact)
+			}
+			n, err := gkit_gorm.EstimateCount(db.Where("deleted = 0"), "users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want {
+				t.Fatalf("行数应为%d，实际%d", tt.want, n)
+			}
+		})
+	}
+}
+
+func TestEstimateCountPostgres(t *testing.T) {
+	estimate := "SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass\\(\\?\\)"
+	tests := []struct {
+		name     string
+		estimate *sqlmock.Rows
+		exact    *sqlmock.Rows
+		want     int64
+	}{
+		{name: "large table", estimate: sqlmock.NewRows([]string{"reltuples"}).AddRow(2000000), want: 2000000},
+		{name: "never analyzed", estimate: sqlmock.NewRows([]string{"reltuples"}).AddRow(-1), exact: sqlmock.NewRows([]string{"count"}).AddRow(7), want: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := mockPostgres(t)
+			mock.ExpectQuery(estimate).WithArgs("public.users").WillReturnRows(tt.estimate)
+			if tt.exact != nil {
+				mock.ExpectQuery("SELECT count\\(\\*\\) FROM `public`.`users`$").WillReturnRows(tt.exact)
+			}
+			n, err := gkit_gorm.EstimateCount(db, "public.users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != tt.want {
+				t.Fatalf("行数应为%d，实际%d", tt.want, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package gkit_gorm
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeleteReturning 删除满足条件的记录并返回被删除的记录，适用于审计和归档
+// Postgres使用一条 DELETE ... RETURNING * 语句，返回的就是实际删除的行；
+// MySQL不支持RETURNING，在事务中先 SELECT ... FOR UPDATE 锁定满足条件的行，再按主键删除这些行，
+// 锁定期间其他事务无法修改或删除这些行，返回的记录与删除的记录一致；READ COMMITTED隔离级别下，
+// 查询之后新插入的满足条件的行不会被删除，模型没有主键时按conds删除，这些行会被删除但不会返回
+// 与db.Delete一样要求有WHERE条件，软删除模型执行软删除；不执行BeforeDelete和AfterDelete钩子
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where等条件
+//   - conds: 删除条件，与db.Delete的conds一致，例如 "created_at < ?", before
+//
+// 返回:
+//   - []T: 被删除的记录，没有满足条件的记录时为空
+//   - error: 缺少条件或执行失败时返回错误
+func DeleteReturning[T any](db *gorm.DB, conds ...any) ([]T, error) {
+	tx := db.Session(&gorm.Session{SkipHooks: true})
+
+	var rows []T
+	if db.Dialector.Name() == "postgres" {
+		// 只生成SQL，RETURNING的结果由下面的Raw读取；同时沿用GORM对缺少WHERE条件的检查
+		stmt := tx.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true}).
+			Clauses(clause.Returning{}).Delete(new(T), conds...).Statement
+		if stmt.Error != nil {
+			return nil, stmt.Error
+		}
+		if err := tx.Raw(stmt.SQL.String(), stmt.Vars...).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+
+	modelSchema, err := ParseSchema(db, new(T))
+	if err != nil {
+		return nil, fmt.Errorf("解析模型失败: %w", err)
+	}
+	// 查询之前检查条件，避免没有条件时锁定并删除整张表
+	if err := tx.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true}).Delete(new(T), conds...).Error; err != nil {
+		return nil, err
+	}
+
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		if err := ForUpdate(tx).Find(&rows, conds...).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if len(modelSchema.PrimaryFields) == 0 {
+			return tx.Delete(new(T), conds...).Error
+		}
+		return tx.Delete(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
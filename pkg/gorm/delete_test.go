@@ -0,0 +1,74 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type closedTicket struct {
+	ID     int64
+	Status string
+}
+
+// BeforeDelete DeleteReturning不执行删除钩子，执行时测试失败
+func (closedTicket) BeforeDelete(tx *gorm.DB) error {
+	return errors.New("BeforeDelete不应被调用")
+}
+
+func TestDeleteReturningMySQL(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("^SELECT \\* FROM `closed_tickets` WHERE status = \\? FOR UPDATE$").WithArgs("closed").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(3, "closed").AddRow(5, "closed"))
+	mock.ExpectExec("^DELETE FROM `closed_tickets` WHERE `closed_tickets`.`id` IN \\(\\?,\\?\\)$").WithArgs(3, 5).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	rows, err := gkit_gorm.DeleteReturning[closedTicket](db, "status = ?", "closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].ID != 3 || rows[1].ID != 5 {
+		t.Fatalf("应返回被删除的记录，实际 %+v", rows)
+	}
+
+	// 没有满足条件的记录时不执行删除
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE$").WithArgs("open").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}))
+	mock.ExpectCommit()
+	rows, err = gkit_gorm.DeleteReturning[closedTicket](db.Where("status = ?", "open"))
+	if err != nil || len(rows) != 0 {
+		t.Fatalf("got %+v %v", rows, err)
+	}
+}
+
+func TestDeleteReturningPostgres(t *testing.T) {
+	db, mock := mockPostgres(t)
+	db.Callback().Delete().Clauses = append(db.Callback().Delete().Clauses, "RETURNING")
+	mock.ExpectQuery("^DELETE FROM `closed_tickets` WHERE status = \\? RETURNING \\*$").WithArgs("closed").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(7, "closed"))
+
+	rows, err := gkit_gorm.DeleteReturning[closedTicket](db, "status = ?", "closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].ID != 7 || rows[0].Status != "closed" {
+		t.Fatalf("应返回RETURNING的记录，实际 %+v", rows)
+	}
+}
+
+func TestDeleteReturningMissingWhere(t *testing.T) {
+	for name, open := range map[string]func(t *testing.T) (*gorm.DB, sqlmock.Sqlmock){"mysql": mockDB, "postgres": mockPostgres} {
+		t.Run(name, func(t *testing.T) {
+			db, _ := open(t)
+			// 没有条件时不执行任何语句
+			if _, err := gkit_gorm.DeleteReturning[closedTicket](db); !errors.Is(err, gorm.ErrMissingWhereClause) {
+				t.Fatalf("应返回ErrMissingWhereClause，实际 %v", err)
+			}
+		})
+	}
+}
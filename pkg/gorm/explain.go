@@ -0,0 +1,64 @@
+package gkit_gorm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+)
+
+// Explain 执行EXPLAIN并返回执行计划的每一行
+// 参数:
+//   - db: GORM数据库连接
+//   - query: 需要分析的SQL语句，不包含EXPLAIN前缀
+//   - args: SQL语句的参数
+//
+// 返回:
+//   - []map[string]any: 执行计划，每行的键为列名
+//   - error: 执行过程中发生的错误，如果成功则返回nil
+func Explain(db *gorm.DB, query string, args ...any) ([]map[string]any, error) {
+	var plan []map[string]any
+	if err := db.Raw("EXPLAIN "+query, args...).Scan(&plan).Error; err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// ExplainJSON 执行MySQL的EXPLAIN FORMAT=JSON并返回JSON格式的执行计划
+// 参数:
+//   - db: GORM数据库连接
+//   - query: 需要分析的SQL语句，不包含EXPLAIN前缀
+//   - args: SQL语句的参数
+//
+// 返回:
+//   - string: JSON格式的执行计划
+//   - error: 执行过程中发生的错误，如果成功则返回nil
+func ExplainJSON(db *gorm.DB, query string, args ...any) (string, error) {
+	var plan string
+	if err := db.Raw("EXPLAIN FORMAT=JSON "+query, args...).Row().Scan(&plan); err != nil {
+		return "", err
+	}
+	return plan, nil
+}
+
+// NewExplainHook 创建慢查询时自动执行EXPLAIN的钩子，配合gkit_zerolog.WithSlowQueryHook使用
+// 日志需要在gorm.Open之前创建，所以通过函数延迟获取数据库连接
+// EXPLAIN会再次消耗数据库资源，因此只分析SELECT语句，且要求gorm日志未开启ParameterizedQueries
+// 参数:
+//   - db: 返回数据库连接的函数，返回nil时跳过
+//
+// 返回:
+//   - func(ctx context.Context, sql string) (any, error): 返回执行计划的钩子函数
+func NewExplainHook(db func() *gorm.DB) func(ctx context.Context, sql string) (any, error) {
+	return func(ctx context.Context, sql string) (any, error) {
+		conn := db()
+		if conn == nil {
+			return nil, errors.New("数据库连接未初始化")
+		}
+		if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sql)), "SELECT") {
+			return nil, nil
+		}
+		return Explain(conn.WithContext(ctx), sql)
+	}
+}
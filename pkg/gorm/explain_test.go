@@ -0,0 +1,67 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+func TestExplain(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT * FROM users WHERE id = ?")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "select_type", "table", "type", "key", "rows"}).
+			AddRow(1, "SIMPLE", "users", "const", "PRIMARY", 1))
+
+	plan, err := gkit_gorm.Explain(db, "SELECT * FROM users WHERE id = ?", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 1 {
+		t.Fatalf("期望1行执行计划，实际%d行", len(plan))
+	}
+	if plan[0]["table"] != "users" || plan[0]["type"] != "const" || plan[0]["key"] != "PRIMARY" {
+		t.Fatalf("执行计划不正确: %v", plan[0])
+	}
+}
+
+func TestExplainJSON(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN FORMAT=JSON SELECT * FROM users")).
+		WillReturnRows(sqlmock.NewRows([]string{"EXPLAIN"}).AddRow(`{"query_block":{"select_id":1}}`))
+
+	plan, err := gkit_gorm.ExplainJSON(db, "SELECT * FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan != `{"query_block":{"select_id":1}}` {
+		t.Fatalf("执行计划不正确: %s", plan)
+	}
+}
+
+func TestExplainHook(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery(regexp.QuoteMeta("EXPLAIN SELECT * FROM users")).
+		WillReturnRows(sqlmock.NewRows([]string{"table", "type"}).AddRow("users", "ALL"))
+
+	hook := gkit_gorm.NewExplainHook(func() *gorm.DB { return db })
+	plan, err := hook(context.Background(), "SELECT * FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, ok := plan.([]map[string]any); !ok || len(rows) != 1 || rows[0]["type"] != "ALL" {
+		t.Fatalf("执行计划不正确: %v", plan)
+	}
+
+	// 非SELECT语句不执行EXPLAIN
+	if plan, err := hook(context.Background(), "UPDATE users SET name = 'a'"); plan != nil || err != nil {
+		t.Fatalf("非SELECT语句应跳过，实际%v %v", plan, err)
+	}
+	if _, err := gkit_gorm.NewExplainHook(func() *gorm.DB { return nil })(context.Background(), "SELECT 1"); err == nil {
+		t.Fatal("连接未初始化时应返回错误")
+	}
+}
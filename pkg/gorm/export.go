@@ -0,0 +1,49 @@
+package gkit_gorm
+
+import (
+	"encoding/csv"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// exportFlushRows 导出CSV时每写入多少行刷新一次
+const exportFlushRows = 1000
+
+// ExportCSV 逐行读取查询结果并以CSV格式写入w，不会把所有记录加载到内存，适合后台导出
+// 基于StreamRows实现，每1000行刷新一次，HTTP响应等场景下客户端可以尽早收到数据；引号和换行由encoding/csv转义
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件，通过WithContext传入的ctx取消时停止导出
+//   - w: 写入CSV的目标
+//   - headers: 表头，为空时不写入表头
+//   - row: 将一条记录转换为一行CSV的函数，字段顺序与headers一致
+//
+// 返回:
+//   - error: 查询、扫描或写入过程中的第一个错误，ctx取消时返回ctx.Err()
+func ExportCSV[T any](db *gorm.DB, w io.Writer, headers []string, row func(T) []string) error {
+	cw := csv.NewWriter(w)
+	if len(headers) > 0 {
+		if err := cw.Write(headers); err != nil {
+			return err
+		}
+	}
+
+	var n int
+	err := StreamRows(db, func(value T) error {
+		if err := cw.Write(row(value)); err != nil {
+			return err
+		}
+		n++
+		if n%exportFlushRows == 0 {
+			cw.Flush()
+			return cw.Error()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
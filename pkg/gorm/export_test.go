@@ -0,0 +1,74 @@
+package gkit_gorm_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type exportUser struct {
+	ID   uint
+	Name string
+}
+
+func exportUserRow(u exportUser) []string {
+	return []string{strconv.FormatUint(uint64(u.ID), 10), u.Name}
+}
+
+func TestExportCSVRoundTrip(t *testing.T) {
+	db, mock := mockDB(t)
+	names := []string{"alice", `bob, "jr"`, "multi\nline"}
+	rows := sqlmock.NewRows([]string{"id", "name"})
+	for i, name := range names {
+		rows.AddRow(i+1, name)
+	}
+	mock.ExpectQuery("^SELECT \\* FROM `export_users`$").WillReturnRows(rows)
+
+	var buf bytes.Buffer
+	if err := gkit_gorm.ExportCSV(db, &buf, []string{"id", "name"}, exportUserRow); err != nil {
+		t.Fatal(err)
+	}
+
+	// 逗号、引号和换行读回后与原值一致
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(names)+1 || records[0][0] != "id" || records[0][1] != "name" {
+		t.Fatalf("表头或行数不正确: %q", records)
+	}
+	for i, name := range names {
+		if got := records[i+1]; got[0] != strconv.Itoa(i+1) || got[1] != name {
+			t.Errorf("第%d行期望%q，实际%q", i+1, name, got)
+		}
+	}
+}
+
+func TestExportCSVContextCanceled(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "alice").AddRow(2, "bob").AddRow(3, "carol"))
+
+	// 导出第一行后取消，停止读取剩余的行
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	exported := 0
+	var buf bytes.Buffer
+	err := gkit_gorm.ExportCSV(db.WithContext(ctx), &buf, nil, func(u exportUser) []string {
+		exported++
+		cancel()
+		return exportUserRow(u)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("期望context.Canceled，实际%v", err)
+	}
+	if exported != 1 {
+		t.Errorf("取消后仍导出了%d行", exported)
+	}
+}
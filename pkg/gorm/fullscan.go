@@ -0,0 +1,172 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const fullScanStartKey = "gkit:full_scan_start"
+
+// postgresSeqScan 匹配Postgres执行计划中的全表扫描节点
+var postgresSeqScan = regexp.MustCompile(`(?:Parallel )?Seq Scan on (\S+)`)
+
+// FullScanPlugin 全表扫描检测插件，查询返回的行数或耗时超过阈值时执行EXPLAIN，执行计划包含全表扫描时通过zerolog输出警告
+// 支持MySQL(type=ALL)和Postgres(Seq Scan)，EXPLAIN会再次消耗数据库资源，只应在开发环境启用
+// 只检查Find、First等查询，Raw、Row和Rows不会触发
+type FullScanPlugin struct {
+	// Enabled 是否启用，为false时注册后不生效，便于按环境配置
+	Enabled bool
+	// MinRows 返回行数达到该值时检查，默认1000
+	MinRows int64
+	// SlowThreshold 耗时达到该值时检查，0表示只按行数判断
+	SlowThreshold time.Duration
+	// Logger 输出警告的日志，默认使用全局日志
+	Logger *zerolog.Logger
+}
+
+// Name 插件名称
+func (p *FullScanPlugin) Name() string {
+	return "gkit:full_scan"
+}
+
+// Initialize 注册查询前后的回调
+func (p *FullScanPlugin) Initialize(db *gorm.DB) error {
+	if !p.Enabled {
+		return nil
+	}
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("gkit:full_scan_before", p.before); err != nil {
+		return err
+	}
+	return cb.Query().After("gorm:query").Register("gkit:full_scan_after", p.after)
+}
+
+// minRows 返回触发检查的行数
+func (p *FullScanPlugin) minRows() int64 {
+	if p.MinRows <= 0 {
+		return 1000
+	}
+	return p.MinRows
+}
+
+// logger 返回输出警告的日志
+func (p *FullScanPlugin) logger() *zerolog.Logger {
+	if p.Logger != nil {
+		return p.Logger
+	}
+	return &log.Logger
+}
+
+// before 记录查询开始的时间
+func (p *FullScanPlugin) before(db *gorm.DB) {
+	db.InstanceSet(fullScanStartKey, time.Now())
+}
+
+// after 超过阈值时分析执行计划
+func (p *FullScanPlugin) after(db *gorm.DB) {
+	if db.Error != nil || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	dialect := db.Dialector.Name()
+	if dialect != "mysql" && dialect != "postgres" {
+		return
+	}
+
+	var elapsed time.Duration
+	if v, ok := db.InstanceGet(fullScanStartKey); ok {
+		if start, ok := v.(time.Time); ok {
+			elapsed = time.Since(start)
+		}
+	}
+	slow := p.SlowThreshold > 0 && elapsed >= p.SlowThreshold
+	if db.RowsAffected < p.minRows() && !slow {
+		return
+	}
+
+	sql := db.Statement.SQL.String()
+	tables, err := fullScanTables(db, dialect, sql, db.Statement.Vars)
+	if err != nil {
+		p.logger().Debug().Err(err).Str("sql", sql).Msg("全表扫描检测: 执行EXPLAIN失败")
+		return
+	}
+	if len(tables) == 0 {
+		return
+	}
+	p.logger().Warn().
+		Str("sql", db.Dialector.Explain(sql, db.Statement.Vars...)).
+		Strs("tables", tables).
+		Int64("rows", db.RowsAffected).
+		Dur("elapsed", elapsed).
+		Msg("查询使用了全表扫描，可能缺少索引")
+}
+
+// fullScanTables 执行EXPLAIN并返回执行计划中全表扫描的表
+// EXPLAIN直接通过连接池执行，不会再次触发回调，在事务中时使用同一个事务
+// 参数:
+//   - db: 当前语句的数据库连接
+//   - dialect: 数据库类型，mysql或postgres
+//   - sql: 已生成的SQL
+//   - vars: SQL的参数
+//
+// 返回:
+//   - []string: 全表扫描的表名
+//   - error: 执行过程中发生的错误
+func fullScanTables(db *gorm.DB, dialect string, sql string, vars []any) ([]string, error) {
+	rows, err := db.Statement.ConnPool.QueryContext(db.Statement.Context, "EXPLAIN "+sql, vars...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[strings.ToLower(column)] = explainString(values[i])
+		}
+
+		switch dialect {
+		case "mysql":
+			if row["type"] == "ALL" {
+				tables = append(tables, row["table"])
+			}
+		case "postgres":
+			for _, m := range postgresSeqScan.FindAllStringSubmatch(row["query plan"], -1) {
+				tables = append(tables, m[1])
+			}
+		}
+	}
+	return tables, rows.Err()
+}
+
+// explainString 将执行计划中的值转换为字符串
+func explainString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
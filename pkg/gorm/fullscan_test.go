@@ -0,0 +1,89 @@
+package gkit_gorm_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type scanRow struct {
+	ID   int
+	Name string
+}
+
+// mockFullScanDB 创建注册了FullScanPlugin的mock数据库，警告写入返回的buffer
+func mockFullScanDB(t *testing.T, plugin gkit_gorm.FullScanPlugin) (*gorm.DB, sqlmock.Sqlmock, *bytes.Buffer) {
+	t.Helper()
+	db, mock := mockDB(t)
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	plugin.Logger = &logger
+	if err := db.Use(&plugin); err != nil {
+		t.Fatal(err)
+	}
+	return db, mock, &buf
+}
+
+func TestFullScanWarns(t *testing.T) {
+	db, mock, buf := mockFullScanDB(t, gkit_gorm.FullScanPlugin{Enabled: true, MinRows: 2})
+	mock.ExpectQuery("SELECT \\* FROM `scan_rows` WHERE name = \\?$").WithArgs("x").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "x").AddRow(2, "x"))
+	mock.ExpectQuery("EXPLAIN SELECT \\* FROM `scan_rows` WHERE name = \\?$").WithArgs("x").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "select_type", "table", "type", "key"}).AddRow(1, "SIMPLE", "scan_rows", "ALL", nil))
+
+	var rows []scanRow
+	if err := db.Where("name = ?", "x").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{`"level":"warn"`, `"tables":["scan_rows"]`, `name = 'x'`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("日志缺少 %s: %s", want, out)
+		}
+	}
+}
+
+func TestFullScanSkips(t *testing.T) {
+	t.Run("below MinRows", func(t *testing.T) {
+		db, mock, buf := mockFullScanDB(t, gkit_gorm.FullScanPlugin{Enabled: true, MinRows: 2})
+		// 行数不足时不执行EXPLAIN，多出的查询会被sqlmock拒绝
+		mock.ExpectQuery("SELECT \\* FROM `scan_rows`$").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "x"))
+		var rows []scanRow
+		if err := db.Find(&rows).Error; err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("不应输出警告: %s", buf)
+		}
+	})
+
+	t.Run("index used", func(t *testing.T) {
+		db, mock, buf := mockFullScanDB(t, gkit_gorm.FullScanPlugin{Enabled: true, MinRows: 2})
+		mock.ExpectQuery("SELECT \\* FROM `scan_rows`$").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "x").AddRow(2, "y"))
+		mock.ExpectQuery("EXPLAIN SELECT").WillReturnRows(sqlmock.NewRows([]string{"table", "type"}).AddRow("scan_rows", "ref"))
+		var rows []scanRow
+		if err := db.Find(&rows).Error; err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("使用索引时不应输出警告: %s", buf)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		db, mock, buf := mockFullScanDB(t, gkit_gorm.FullScanPlugin{MinRows: 1})
+		mock.ExpectQuery("SELECT \\* FROM `scan_rows`$").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "x"))
+		var rows []scanRow
+		if err := db.Find(&rows).Error; err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("未启用时不应输出警告: %s", buf)
+		}
+	})
+}
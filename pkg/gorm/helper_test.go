@@ -0,0 +1,59 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// mockDB 返回使用sqlmock的MySQL连接，测试结束时检查所有预期的语句都已执行
+func mockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return db, mock
+}
+
+// fakePostgres 名称为postgres的MySQL方言，用于测试按方言选择的分支，生成的SQL仍使用MySQL的引号
+type fakePostgres struct {
+	*mysql.Dialector
+}
+
+func (fakePostgres) Name() string {
+	return "postgres"
+}
+
+// mockPostgres 返回方言名称为postgres、使用sqlmock的连接
+func mockPostgres(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialector := mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}).(*mysql.Dialector)
+	db, err := gorm.Open(fakePostgres{dialector}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+	return db, mock
+}
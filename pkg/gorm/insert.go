@@ -0,0 +1,165 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InsertReturning 插入单条记录，并将数据库生成的主键回写到entity上
+// 参数:
+//   - db: GORM数据库连接
+//   - entity: 需要插入的记录指针
+//
+// 返回:
+//   - error: 插入过程中发生的错误，如果成功则返回nil
+func InsertReturning[T any](db *gorm.DB, entity *T) error {
+	if entity == nil {
+		return errors.New("entity不能为nil")
+	}
+	return db.Create(entity).Error
+}
+
+// IdempotencyKeyColumn IdempotentInsert使用的幂等键字段，需要在表上建立唯一索引
+const IdempotencyKeyColumn = "idempotency_key"
+
+// IdempotentInsert 按幂等键插入记录，重复执行时不会产生重复数据，适用于至少一次投递的任务
+// 使用 INSERT ... ON CONFLICT DO NOTHING(MySQL为ON DUPLICATE KEY UPDATE空操作)，
+// 冲突时查询已存在记录的主键并回写到entity
+// 参数:
+//   - db: GORM数据库连接
+//   - entity: 需要插入的记录，必须是结构体指针，模型需要包含idempotency_key字段
+//   - idempotencyKey: 幂等键，会写入entity的idempotency_key字段
+//
+// 返回:
+//   - bool: 本次是否实际插入了记录
+//   - error: 操作过程中发生的错误，如果成功则返回nil
+func IdempotentInsert(db *gorm.DB, entity any, idempotencyKey string) (bool, error) {
+	modelSchema, err := ParseSchema(db, entity)
+	if err != nil {
+		return false, fmt.Errorf("解析模型失败: %w", err)
+	}
+	field, ok := modelSchema.FieldsByDBName[IdempotencyKeyColumn]
+	if !ok {
+		return false, fmt.Errorf("模型 %s 没有 %s 字段", modelSchema.Name, IdempotencyKeyColumn)
+	}
+
+	entityValue := reflect.ValueOf(entity)
+	if entityValue.Kind() != reflect.Ptr || entityValue.Elem().Kind() != reflect.Struct {
+		return false, errors.New("entity必须是结构体指针")
+	}
+	ctx := db.Statement.Context
+	if err := field.Set(ctx, entityValue.Elem(), idempotencyKey); err != nil {
+		return false, fmt.Errorf("设置幂等键失败: %w", err)
+	}
+
+	// 1.冲突时不做任何修改，通过影响行数判断是否插入
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: IdempotencyKeyColumn}},
+		DoNothing: true,
+	}).Create(entity)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// 2.已存在，查询原记录并回写主键
+	existing := reflect.New(modelSchema.ModelType)
+	err = db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: IdempotencyKeyColumn}, Value: idempotencyKey}).Take(existing.Interface()).Error
+	if err != nil {
+		return false, fmt.Errorf("查询已存在的记录失败: %w", err)
+	}
+	for _, pk := range modelSchema.PrimaryFields {
+		val, _ := pk.ValueOf(ctx, existing.Elem())
+		if err := pk.Set(ctx, entityValue.Elem(), val); err != nil {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// InsertSelect 在数据库内执行 INSERT INTO dest (cols) SELECT ...，复制或归档数据时不需要把记录读取到内存
+// 参数:
+//   - db: GORM数据库连接
+//   - destTable: 目标表名，可以包含库名或schema，例如"archive.orders"
+//   - destCols: 目标列名，顺序与selectQuery的Select列一一对应
+//   - selectQuery: 使用GORM构建的查询，必须通过Select指定与destCols数量相同的列，例如
+//     db.Model(&Order{}).Select("id", "user_id", "amount").Where("created_at < ?", before)
+//
+// 返回:
+//   - int64: 插入的记录数
+//   - error: 列数不一致或执行失败时返回错误
+func InsertSelect(db *gorm.DB, destTable string, destCols []string, selectQuery *gorm.DB) (int64, error) {
+	if destTable == "" || len(destCols) == 0 || selectQuery == nil {
+		return 0, errors.New("destTable、destCols和selectQuery不能为空")
+	}
+	arity := selectArity(selectQuery.Statement)
+	if arity == 0 {
+		return 0, errors.New("selectQuery必须通过Select指定列")
+	}
+	if arity != len(destCols) {
+		return 0, fmt.Errorf("目标列有%d个，查询列有%d个", len(destCols), arity)
+	}
+
+	columns := make([]any, len(destCols))
+	for i, col := range destCols {
+		columns[i] = clause.Column{Name: col}
+	}
+	result := db.Exec("INSERT INTO ? ? ?", clause.Table{Name: destTable}, columns, selectQuery)
+	return result.RowsAffected, result.Error
+}
+
+// selectArity 返回查询通过Select指定的列数，未指定时返回0
+// 参数:
+//   - stmt: 查询的Statement
+//
+// 返回:
+//   - int: 查询列数
+func selectArity(stmt *gorm.Statement) int {
+	if c, ok := stmt.Clauses["SELECT"]; ok {
+		switch expr := c.Expression.(type) {
+		case clause.Expr:
+			return countSelectList(expr.SQL)
+		case clause.NamedExpr:
+			return countSelectList(expr.SQL)
+		}
+	}
+	n := 0
+	for _, s := range stmt.Selects {
+		n += countSelectList(s)
+	}
+	return n
+}
+
+// countSelectList 统计SELECT列表中的列数，括号和引号中的逗号不计入
+func countSelectList(list string) int {
+	if strings.TrimSpace(list) == "" {
+		return 0
+	}
+	n, depth := 1, 0
+	var quote rune
+	for _, ch := range list {
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ',' && depth == 0:
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type idempotentResult struct {
+	ID             uint
+	IdempotencyKey string
+	Value          int
+}
+
+func TestIdempotentInsert(t *testing.T) {
+	db, mock := mockDB(t)
+
+	// 第一次插入成功
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `idempotent_results` .* ON DUPLICATE KEY UPDATE `id`=`id`").
+		WithArgs("job-1", 1).WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+	first := &idempotentResult{Value: 1}
+	inserted, err := gkit_gorm.IdempotentInsert(db, first, "job-1")
+	if err != nil || !inserted {
+		t.Fatalf("第一次应插入，实际%v %v", inserted, err)
+	}
+	if first.ID != 5 || first.IdempotencyKey != "job-1" {
+		t.Fatalf("记录不正确: %+v", first)
+	}
+
+	// 重试时冲突，不插入并回写已存在记录的主键
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `idempotent_results`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM `idempotent_results` WHERE `idempotent_results`.`idempotency_key` = \\? LIMIT \\?").
+		WithArgs("job-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "idempotency_key", "value"}).AddRow(5, "job-1", 1))
+	retry := &idempotentResult{Value: 1}
+	inserted, err = gkit_gorm.IdempotentInsert(db, retry, "job-1")
+	if err != nil || inserted {
+		t.Fatalf("重试不应插入，实际%v %v", inserted, err)
+	}
+	if retry.ID != 5 {
+		t.Fatalf("应回写已存在记录的主键，实际%d", retry.ID)
+	}
+}
+
+func TestIdempotentInsertWithoutKeyColumn(t *testing.T) {
+	db, _ := mockDB(t)
+	if _, err := gkit_gorm.IdempotentInsert(db, &batchUser{}, "job-1"); err == nil {
+		t.Fatal("模型没有idempotency_key字段时应返回错误")
+	}
+}
+
+type archiveOrder struct {
+	ID        uint
+	UserID    uint
+	Amount    int
+	CreatedAt time.Time
+}
+
+func TestInsertSelect(t *testing.T) {
+	db, mock := mockDB(t)
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec("^INSERT INTO `archive`.`orders` \\(`id`,`user_id`,`amount`\\) "+
+		"SELECT id, user_id, COALESCE\\(amount, 0\\) FROM `archive_orders` WHERE created_at < \\? AND user_id IN \\(\\?,\\?\\)$").
+		WithArgs(before, 1, 2).WillReturnResult(sqlmock.NewResult(0, 7))
+
+	query := db.Model(&archiveOrder{}).Select("id, user_id, COALESCE(amount, 0)").
+		Where("created_at < ?", before).Where("user_id IN ?", []int{1, 2})
+	n, err := gkit_gorm.InsertSelect(db, "archive.orders", []string{"id", "user_id", "amount"}, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 7 {
+		t.Fatalf("期望插入7行，实际%d", n)
+	}
+}
+
+func TestInsertSelectInvalid(t *testing.T) {
+	db, _ := mockDB(t)
+	if _, err := gkit_gorm.InsertSelect(db, "a", []string{"id"}, db.Model(&archiveOrder{}).Select("id", "user_id")); err == nil {
+		t.Error("列数与SELECT不一致时应返回错误")
+	}
+	if _, err := gkit_gorm.InsertSelect(db, "a", []string{"id"}, db.Model(&archiveOrder{})); err == nil {
+		t.Error("没有Select时应返回错误")
+	}
+}
@@ -0,0 +1,169 @@
+package gkit_gorm
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// jsonPathKeyPattern 不需要加引号的JSON路径键
+var jsonPathKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// JSONExtractEqual 返回按JSON字段中的路径等值查询的Scope，配合db.Scopes使用
+// MySQL生成 JSON_UNQUOTE(JSON_EXTRACT(col, ?)) = ?，Postgres生成 col #>> ? = ?，值均以参数传递
+// 参数:
+//   - column: JSON字段名
+//   - path: 路径，例如 "a.b[0]" 或 "$.a.b[0]"
+//   - value: 比较的值，非字符串按JSON编码后比较，例如true、1
+//
+// 返回:
+//   - func(*gorm.DB) *gorm.DB: 添加查询条件的Scope，不支持的数据库会在db上添加错误
+func JSONExtractEqual(column, path string, value any) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		parts, err := parseJSONPath(path)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		text, err := jsonText(value)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+
+		col := db.Statement.Quote(column)
+		switch db.Dialector.Name() {
+		case "mysql":
+			return db.Where(fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, ?)) = ?", col), mysqlJSONPath(parts), text)
+		case "postgres":
+			return db.Where(fmt.Sprintf("(%s #>> ?) = ?", col), postgresJSONPath(parts), text)
+		case "sqlite":
+			return db.Where(fmt.Sprintf("CAST(json_extract(%s, ?) AS TEXT) = ?", col), mysqlJSONPath(parts), text)
+		default:
+			_ = db.AddError(fmt.Errorf("JSONExtractEqual不支持数据库 %s", db.Dialector.Name()))
+			return db
+		}
+	}
+}
+
+// JSONContains 返回查询JSON字段的指定路径包含value的Scope，配合db.Scopes使用
+// MySQL生成 JSON_CONTAINS(col, ?, ?)，Postgres生成 (col #> ?) @> ?::jsonb，Postgres的字段需要是jsonb类型
+// 参数:
+//   - column: JSON字段名
+//   - path: 路径，为空或"$"表示整个文档
+//   - value: 需要包含的值，会按JSON编码，例如数组中的元素或对象的部分键值
+//
+// 返回:
+//   - func(*gorm.DB) *gorm.DB: 添加查询条件的Scope，不支持的数据库会在db上添加错误
+func JSONContains(column, path string, value any) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		parts, err := parseJSONPath(path)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		doc, err := json.Marshal(value)
+		if err != nil {
+			_ = db.AddError(fmt.Errorf("JSON编码失败: %w", err))
+			return db
+		}
+
+		col := db.Statement.Quote(column)
+		switch db.Dialector.Name() {
+		case "mysql":
+			return db.Where(fmt.Sprintf("JSON_CONTAINS(%s, ?, ?)", col), string(doc), mysqlJSONPath(parts))
+		case "postgres":
+			return db.Where(fmt.Sprintf("(%s #> ?) @> ?::jsonb", col), postgresJSONPath(parts), string(doc))
+		default:
+			_ = db.AddError(fmt.Errorf("JSONContains不支持数据库 %s", db.Dialector.Name()))
+			return db
+		}
+	}
+}
+
+// parseJSONPath 将 "$.a.b[0]" 或 "a.b[0]" 拆分为 ["a", "b", 0]
+// 参数:
+//   - path: JSON路径
+//
+// 返回:
+//   - []any: 键为string，下标为int
+//   - error: 路径格式错误时返回错误
+func parseJSONPath(path string) ([]any, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return nil, nil
+	}
+
+	var parts []any
+	for _, segment := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(segment, "[")
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("JSON路径 %q 格式错误", path)
+		}
+		if key != "" {
+			parts = append(parts, key)
+		}
+		for rest != "" {
+			index, after, ok := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(index)
+			if !ok || err != nil || n < 0 {
+				return nil, fmt.Errorf("JSON路径 %q 的数组下标格式错误", path)
+			}
+			parts = append(parts, n)
+			rest = strings.TrimPrefix(after, "[")
+			if after != "" && !strings.HasPrefix(after, "[") {
+				return nil, fmt.Errorf("JSON路径 %q 格式错误", path)
+			}
+		}
+	}
+	return parts, nil
+}
+
+// mysqlJSONPath 生成MySQL/SQLite的路径，例如 $.a."b-c"[0]
+func mysqlJSONPath(parts []any) string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, part := range parts {
+		switch p := part.(type) {
+		case int:
+			b.WriteString("[" + strconv.Itoa(p) + "]")
+		case string:
+			if jsonPathKeyPattern.MatchString(p) {
+				b.WriteString("." + p)
+			} else {
+				b.WriteString("." + strconv.Quote(p))
+			}
+		}
+	}
+	return b.String()
+}
+
+// postgresJSONPath 生成Postgres的text[]路径，例如 {a,"b-c",0}
+func postgresJSONPath(parts []any) string {
+	elems := make([]string, 0, len(parts))
+	for _, part := range parts {
+		switch p := part.(type) {
+		case int:
+			elems = append(elems, strconv.Itoa(p))
+		case string:
+			elems = append(elems, strconv.Quote(p))
+		}
+	}
+	return "{" + strings.Join(elems, ",") + "}"
+}
+
+// jsonText 返回值在JSON中取出文本后的形式，字符串原样返回，其他值按JSON编码
+func jsonText(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("JSON编码失败: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,57 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type jsonDoc struct {
+	ID    int64
+	Attrs string
+	Tags  string
+}
+
+func TestJSONScopesMySQL(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `json_docs` WHERE JSON_UNQUOTE\\(JSON_EXTRACT\\(`attrs`, \\?\\)\\) = \\? "+
+		"AND JSON_CONTAINS\\(`tags`, \\?, \\?\\)$").
+		WithArgs(`$.a."b-c"[0]`, "true", `"x"`, "$").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	var docs []jsonDoc
+	err := db.Scopes(gkit_gorm.JSONExtractEqual("attrs", "a.b-c[0]", true), gkit_gorm.JSONContains("tags", "$", "x")).
+		Find(&docs).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("应返回1条记录，实际 %+v", docs)
+	}
+}
+
+func TestJSONScopesPostgres(t *testing.T) {
+	db, mock := mockPostgres(t)
+	mock.ExpectQuery("SELECT \\* FROM `json_docs` WHERE \\(`attrs` #>> \\?\\) = \\? "+
+		"AND \\(`tags` #> \\?\\) @> \\?::jsonb$").
+		WithArgs(`{"a","b-c",0}`, "1", `{"k"}`, `{"v":1}`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	var docs []jsonDoc
+	err := db.Scopes(gkit_gorm.JSONExtractEqual("attrs", "$.a.b-c[0]", 1), gkit_gorm.JSONContains("tags", "k", map[string]int{"v": 1})).
+		Find(&docs).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONScopesInvalidPath(t *testing.T) {
+	db, _ := mockDB(t)
+	var docs []jsonDoc
+	err := db.Session(&gorm.Session{DryRun: true}).Scopes(gkit_gorm.JSONExtractEqual("attrs", "a[x]", 1)).Find(&docs).Error
+	if err == nil {
+		t.Fatal("无效的数组下标应返回错误")
+	}
+}
@@ -0,0 +1,183 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidListParams 排序或过滤参数不合法，调用方通常应返回400
+var ErrInvalidListParams = errors.New("gorm: invalid list params")
+
+// ListParams 列表接口的排序和过滤参数
+type ListParams struct {
+	// Sort 排序字段，按优先级排列，"-"前缀表示降序，例如 []string{"-created_at", "id"}
+	Sort []string
+	// Filters 过滤条件，多个条件之间为AND
+	Filters []ListFilter
+}
+
+// ListFilter 单个过滤条件
+type ListFilter struct {
+	// Field 请求中的字段名
+	Field string
+	// Op 操作符: eq、ne、gt、gte、lt、lte、like、in，为空时为eq
+	Op string
+	// Value 过滤值，in操作符可以是切片或逗号分隔的字符串
+	Value any
+}
+
+// AllowedFields 允许排序和过滤的字段，键为请求中的字段名，值为数据库列名，为空时与字段名相同
+type AllowedFields map[string]string
+
+// ParseListParams 从查询参数中解析排序和过滤参数
+// 支持 sort=-created_at,id、filter[status]=active 和 filter[age][gte]=18 的形式
+// 参数:
+//   - values: URL查询参数，例如r.URL.Query()
+//
+// 返回:
+//   - ListParams: 解析得到的参数，字段和操作符在ApplyListParams中校验
+func ParseListParams(values url.Values) ListParams {
+	var params ListParams
+	for _, sort := range values["sort"] {
+		for _, field := range strings.Split(sort, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				params.Sort = append(params.Sort, field)
+			}
+		}
+	}
+
+	// 按参数名排序，保证生成的SQL稳定
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]") {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		// filter[field] 或 filter[field][op]
+		parts := strings.Split(key[len("filter["):len(key)-1], "][")
+		filter := ListFilter{Field: parts[0]}
+		if len(parts) > 1 {
+			filter.Op = strings.Join(parts[1:], "][")
+		}
+		for _, v := range values[key] {
+			filter.Value = v
+			params.Filters = append(params.Filters, filter)
+		}
+	}
+	return params
+}
+
+// ApplyListParams 校验并应用排序和过滤参数，字段必须在白名单中，值均以参数形式传入
+// 参数:
+//   - db: GORM数据库连接
+//   - params: 排序和过滤参数
+//   - allowed: 允许的字段
+//
+// 返回:
+//   - *gorm.DB: 应用了条件和排序的连接
+//   - error: 字段不在白名单或操作符不支持时返回包含ErrInvalidListParams的错误
+func ApplyListParams(db *gorm.DB, params ListParams, allowed AllowedFields) (*gorm.DB, error) {
+	for _, filter := range params.Filters {
+		column, ok := allowed.column(filter.Field)
+		if !ok {
+			return nil, fmt.Errorf("%w: 不允许按字段 %s 过滤", ErrInvalidListParams, filter.Field)
+		}
+		expr, err := listFilterExpr(clause.Column{Name: column}, filter)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(expr)
+	}
+
+	for _, sort := range params.Sort {
+		field, desc := strings.CutPrefix(sort, "-")
+		column, ok := allowed.column(field)
+		if !ok {
+			return nil, fmt.Errorf("%w: 不允许按字段 %s 排序", ErrInvalidListParams, field)
+		}
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: desc})
+	}
+	return db, nil
+}
+
+// column 获取字段对应的列名
+func (a AllowedFields) column(field string) (string, bool) {
+	column, ok := a[field]
+	if !ok {
+		return "", false
+	}
+	if column == "" {
+		column = field
+	}
+	return column, true
+}
+
+// listFilterExpr 将过滤条件转换为WHERE表达式
+// 参数:
+//   - column: 列
+//   - filter: 过滤条件
+//
+// 返回:
+//   - clause.Expression: WHERE表达式
+//   - error: 操作符不支持或in的值为空时返回错误
+func listFilterExpr(column clause.Column, filter ListFilter) (clause.Expression, error) {
+	switch filter.Op {
+	case "", "eq":
+		return clause.Eq{Column: column, Value: filter.Value}, nil
+	case "ne":
+		return clause.Neq{Column: column, Value: filter.Value}, nil
+	case "gt":
+		return clause.Gt{Column: column, Value: filter.Value}, nil
+	case "gte":
+		return clause.Gte{Column: column, Value: filter.Value}, nil
+	case "lt":
+		return clause.Lt{Column: column, Value: filter.Value}, nil
+	case "lte":
+		return clause.Lte{Column: column, Value: filter.Value}, nil
+	case "like":
+		// 按包含匹配，转义值中的通配符
+		value := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(fmt.Sprint(filter.Value))
+		return clause.Like{Column: column, Value: "%" + value + "%"}, nil
+	case "in":
+		values := listFilterValues(filter.Value)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%w: 字段 %s 的in条件没有值", ErrInvalidListParams, filter.Field)
+		}
+		return clause.IN{Column: column, Values: values}, nil
+	default:
+		return nil, fmt.Errorf("%w: 不支持的操作符 %s", ErrInvalidListParams, filter.Op)
+	}
+}
+
+// listFilterValues 将in的值转换为切片，字符串按逗号分隔
+func listFilterValues(value any) []any {
+	switch v := value.(type) {
+	case string:
+		var values []any
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	case []string:
+		values := make([]any, len(v))
+		for i, s := range v {
+			values[i] = s
+		}
+		return values
+	case []any:
+		return v
+	default:
+		return []any{v}
+	}
+}
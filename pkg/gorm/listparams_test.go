@@ -0,0 +1,53 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type listUser struct {
+	ID     int64
+	Name   string
+	Status string
+	Age    int
+}
+
+var listAllowed = gkit_gorm.AllowedFields{"status": "", "age": "", "name": "list_users.name", "id": "", "created_at": ""}
+
+func TestApplyListParams(t *testing.T) {
+	db, mock := mockDB(t)
+	query, err := url.ParseQuery("sort=-created_at,name&filter[status]=active&filter[age][gte]=18&filter[name][like]=a%25b&filter[id][in]=1,2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := gkit_gorm.ApplyListParams(db.Model(&listUser{}), gkit_gorm.ParseListParams(query), listAllowed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 过滤条件按参数名排序，like的值转义通配符
+	mock.ExpectQuery("SELECT \\* FROM `list_users` WHERE `age` >= \\? AND `id` IN \\(\\?,\\?\\) AND `list_users`.`name` LIKE \\? "+
+		"AND `status` = \\? ORDER BY `created_at` DESC,`list_users`.`name`$").
+		WithArgs("18", "1", "2", `%a\%b%`, "active").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	var users []listUser
+	if err := tx.Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyListParamsRejects(t *testing.T) {
+	db, _ := mockDB(t)
+	for _, raw := range []string{"filter[password]=x", "sort=secret", "filter[age][regex]=1", "sort=-id%3Bdrop"} {
+		query, err := url.ParseQuery(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gkit_gorm.ApplyListParams(db, gkit_gorm.ParseListParams(query), listAllowed); !errors.Is(err, gkit_gorm.ErrInvalidListParams) {
+			t.Errorf("%s 应返回ErrInvalidListParams，实际 %v", raw, err)
+		}
+	}
+}
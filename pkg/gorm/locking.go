@@ -0,0 +1,63 @@
+package gkit_gorm
+
+import (
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errLockingWithoutTransaction 不在事务中加锁时返回的错误，自动提交模式下锁在语句结束后立即释放
+var errLockingWithoutTransaction = errors.New("gorm: 行锁必须在事务中使用")
+
+// ForUpdate 为查询添加排他锁 SELECT ... FOR UPDATE，可以直接调用或配合db.Scopes使用
+// 必须在事务中使用，否则添加错误；锁在事务提交或回滚时释放，SQLite等不支持行锁的数据库由方言忽略
+// 参数:
+//   - db: 事务中的GORM数据库连接
+//
+// 返回:
+//   - *gorm.DB: 添加了锁定子句的查询
+func ForUpdate(db *gorm.DB) *gorm.DB {
+	return withLocking(db, clause.Locking{Strength: clause.LockingStrengthUpdate})
+}
+
+// ForShare 为查询添加共享锁 SELECT ... FOR SHARE，其他事务可以读取和加共享锁，但不能修改
+// MySQL需要8.0及以上版本；必须在事务中使用
+// 参数:
+//   - db: 事务中的GORM数据库连接
+//
+// 返回:
+//   - *gorm.DB: 添加了锁定子句的查询
+func ForShare(db *gorm.DB) *gorm.DB {
+	return withLocking(db, clause.Locking{Strength: clause.LockingStrengthShare})
+}
+
+// ForUpdateSkipLocked 添加排他锁并跳过已被其他事务锁定的行 FOR UPDATE SKIP LOCKED，适用于多个消费者从表中领取任务
+// MySQL需要8.0及以上版本；必须在事务中使用
+// 参数:
+//   - db: 事务中的GORM数据库连接
+//
+// 返回:
+//   - *gorm.DB: 添加了锁定子句的查询
+func ForUpdateSkipLocked(db *gorm.DB) *gorm.DB {
+	return withLocking(db, clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked})
+}
+
+// ForUpdateNowait 添加排他锁，行已被锁定时立即返回错误而不是等待 FOR UPDATE NOWAIT
+// MySQL需要8.0及以上版本；必须在事务中使用
+// 参数:
+//   - db: 事务中的GORM数据库连接
+//
+// 返回:
+//   - *gorm.DB: 添加了锁定子句的查询
+func ForUpdateNowait(db *gorm.DB) *gorm.DB {
+	return withLocking(db, clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsNoWait})
+}
+
+// withLocking 添加锁定子句，不在事务中时添加错误
+func withLocking(db *gorm.DB, locking clause.Locking) *gorm.DB {
+	tx := db.Clauses(locking)
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		_ = tx.AddError(errLockingWithoutTransaction)
+	}
+	return tx
+}
@@ -0,0 +1,64 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type lockJob struct {
+	ID     uint
+	Status string
+}
+
+func TestLockingClauses(t *testing.T) {
+	dialects := []struct {
+		name string
+		open func(t *testing.T) (*gorm.DB, sqlmock.Sqlmock)
+	}{
+		{"mysql", mockDB},
+		{"postgres", mockPostgres},
+	}
+	variants := []struct {
+		name   string
+		scope  func(*gorm.DB) *gorm.DB
+		suffix string
+	}{
+		{"ForUpdate", gkit_gorm.ForUpdate, "FOR UPDATE$"},
+		{"ForShare", gkit_gorm.ForShare, "FOR SHARE$"},
+		{"ForUpdateSkipLocked", gkit_gorm.ForUpdateSkipLocked, "FOR UPDATE SKIP LOCKED$"},
+		{"ForUpdateNowait", gkit_gorm.ForUpdateNowait, "FOR UPDATE NOWAIT$"},
+	}
+	for _, d := range dialects {
+		for _, v := range variants {
+			t.Run(d.name+"/"+v.name, func(t *testing.T) {
+				db, mock := d.open(t)
+				mock.ExpectBegin()
+				mock.ExpectQuery("SELECT \\* FROM `lock_jobs` WHERE status = \\? ORDER BY `lock_jobs`.`id` LIMIT \\? "+v.suffix).
+					WithArgs("new", 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectCommit()
+
+				err := db.Transaction(func(tx *gorm.DB) error {
+					var job lockJob
+					return tx.Scopes(v.scope).Where("status = ?", "new").First(&job).Error
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
+
+func TestLockingWithoutTransaction(t *testing.T) {
+	db, _ := mockDB(t)
+	var job lockJob
+	if err := gkit_gorm.ForUpdate(db).First(&job).Error; err == nil {
+		t.Fatal("不在事务中加锁应返回错误")
+	}
+	if db.Error != nil {
+		t.Fatalf("错误不应写入原连接: %v", db.Error)
+	}
+}
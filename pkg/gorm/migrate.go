@@ -0,0 +1,130 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration 一次数据库迁移
+type Migration struct {
+	// ID 迁移的唯一标识，建议使用递增的编号或日期前缀，例如"20240101_create_users"
+	ID string
+	// Up 执行迁移
+	Up func(tx *gorm.DB) error
+}
+
+// schemaMigration 已执行的迁移记录
+type schemaMigration struct {
+	ID        string `gorm:"primaryKey;size:191"`
+	AppliedAt time.Time
+}
+
+// TableName 迁移记录表名
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrate 按顺序执行尚未执行的迁移，并将ID记录到schema_migrations表，已执行的迁移会被跳过
+// 支持事务性DDL的数据库(postgres、sqlite)中每个迁移与其记录在同一个事务中执行
+// MySQL的DDL会隐式提交，因此迁移成功后才写入记录，失败的迁移需要保证可以重复执行
+// 参数:
+//   - db: GORM数据库连接
+//   - migrations: 迁移列表，按执行顺序排列
+//
+// 返回:
+//   - error: 第一个失败的迁移的错误，包含迁移ID，之后的迁移不会执行
+func Migrate(db *gorm.DB, migrations []Migration) error {
+	pending, err := pendingMigrations(db, migrations, true)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		run := func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		}
+
+		switch db.Dialector.Name() {
+		case "postgres", "sqlite":
+			err = db.Transaction(run)
+		default:
+			err = run(db)
+		}
+		if err != nil {
+			return fmt.Errorf("执行迁移%s失败: %w", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// PendingMigrations 返回尚未执行的迁移ID，不执行迁移也不创建迁移记录表，用于发布前检查
+// 参数:
+//   - db: GORM数据库连接
+//   - migrations: 迁移列表，按执行顺序排列
+//
+// 返回:
+//   - []string: 尚未执行的迁移ID，按执行顺序排列
+//   - error: 迁移列表无效或查询失败时返回错误
+func PendingMigrations(db *gorm.DB, migrations []Migration) ([]string, error) {
+	pending, err := pendingMigrations(db, migrations, false)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(pending))
+	for i, m := range pending {
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
+
+// pendingMigrations 校验迁移列表并过滤掉已执行的迁移
+// 参数:
+//   - db: GORM数据库连接
+//   - migrations: 迁移列表
+//   - create: 迁移记录表不存在时是否创建，不创建时所有迁移都视为未执行
+//
+// 返回:
+//   - []Migration: 尚未执行的迁移
+//   - error: ID为空、重复或Up为空时返回错误
+func pendingMigrations(db *gorm.DB, migrations []Migration, create bool) ([]Migration, error) {
+	seen := make(map[string]struct{}, len(migrations))
+	for _, m := range migrations {
+		if m.ID == "" || m.Up == nil {
+			return nil, fmt.Errorf("迁移 %q 缺少ID或Up函数", m.ID)
+		}
+		if _, ok := seen[m.ID]; ok {
+			return nil, fmt.Errorf("迁移ID %s 重复", m.ID)
+		}
+		seen[m.ID] = struct{}{}
+	}
+
+	var applied []string
+	switch {
+	case create:
+		if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+			return nil, fmt.Errorf("创建迁移记录表失败: %w", err)
+		}
+		fallthrough
+	case db.Migrator().HasTable(&schemaMigration{}):
+		if err := db.Model(&schemaMigration{}).Pluck("id", &applied).Error; err != nil {
+			return nil, fmt.Errorf("查询已执行的迁移失败: %w", err)
+		}
+	}
+	done := make(map[string]struct{}, len(applied))
+	for _, id := range applied {
+		done[id] = struct{}{}
+	}
+
+	pending := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		if _, ok := done[m.ID]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,54 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+func TestMigrateRunsOnceAndSkipsOnRerun(t *testing.T) {
+	db, mock := mockDB(t)
+	runs := map[string]int{}
+	migrations := []gkit_gorm.Migration{
+		{ID: "1", Up: func(tx *gorm.DB) error { runs["1"]++; return tx.Exec("CREATE TABLE a (id int)").Error }},
+		{ID: "2", Up: func(tx *gorm.DB) error { runs["2"]++; return tx.Exec("CREATE TABLE b (id int)").Error }},
+	}
+
+	// 第一次执行: 创建迁移记录表，依次执行并记录两个迁移
+	mock.ExpectQuery("SELECT DATABASE\\(\\)").WillReturnRows(sqlmock.NewRows([]string{"db"}).AddRow("app"))
+	mock.ExpectQuery("SELECT SCHEMA_NAME from Information_schema.SCHEMATA").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("app"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM information_schema.tables").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("CREATE TABLE `schema_migrations`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT `id` FROM `schema_migrations`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	for _, m := range []struct{ id, table string }{{"1", "a"}, {"2", "b"}} {
+		mock.ExpectExec("CREATE TABLE " + m.table).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `schema_migrations`").WithArgs(m.id, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	if err := gkit_gorm.Migrate(db, migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二次执行: 两个迁移都已记录，不再执行
+	mock.ExpectQuery("SELECT DATABASE\\(\\)").WillReturnRows(sqlmock.NewRows([]string{"db"}).AddRow("app"))
+	mock.ExpectQuery("SELECT SCHEMA_NAME from Information_schema.SCHEMATA").WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("app"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM information_schema.tables").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	// 表已存在时AutoMigrate读取列信息，列与模型一致时不修改表
+	mock.ExpectQuery("SELECT \\* FROM `schema_migrations` LIMIT \\?").WillReturnRows(sqlmock.NewRows([]string{"id", "applied_at"}))
+	mock.ExpectQuery("FROM information_schema.columns").WithArgs(sqlmock.AnyArg(), "schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "column_default", "is_nullable", "data_type", "character_maximum_length",
+			"column_type", "column_key", "extra", "column_comment", "numeric_precision", "numeric_scale", "datetime_precision"}).
+			AddRow("id", nil, false, "varchar", 191, "varchar(191)", "PRI", "", "", nil, nil, nil).
+			AddRow("applied_at", nil, true, "datetime", nil, "datetime(3)", "", "", "", nil, nil, 3))
+	mock.ExpectQuery("SELECT `id` FROM `schema_migrations`").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1").AddRow("2"))
+	if err := gkit_gorm.Migrate(db, migrations); err != nil {
+		t.Fatal(err)
+	}
+
+	if runs["1"] != 1 || runs["2"] != 1 {
+		t.Fatalf("每个迁移应只执行一次，实际 %v", runs)
+	}
+}
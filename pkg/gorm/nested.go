@@ -0,0 +1,52 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+)
+
+// savepointSeq 生成唯一的保存点名称
+var savepointSeq atomic.Uint64
+
+// Nested 在外层事务中通过保存点执行fn，fn失败时只回滚fn内的操作，外层事务可以继续执行
+// tx不在事务中时等同于tx.Transaction(fn)
+// 数据库驱动不支持保存点时直接执行fn并返回其错误，此时fn内的操作无法单独回滚，应由调用方回滚整个事务
+// 参数:
+//   - tx: 外层事务
+//   - fn: 在保存点内执行的操作
+//
+// 返回:
+//   - error: fn返回的错误或保存点操作失败的错误，如果成功则返回nil
+func Nested(tx *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		return tx.Transaction(fn)
+	}
+
+	name := fmt.Sprintf("gkit_sp_%d", savepointSeq.Add(1))
+	session := tx.Session(&gorm.Session{NewDB: true})
+	if err := session.SavePoint(name).Error; err != nil {
+		if errors.Is(err, gorm.ErrUnsupportedDriver) {
+			return fn(session)
+		}
+		return fmt.Errorf("创建保存点失败: %w", err)
+	}
+
+	if err := fn(session); err != nil {
+		if rbErr := session.RollbackTo(name).Error; rbErr != nil {
+			return errors.CombineErrors(err, fmt.Errorf("回滚到保存点失败: %w", rbErr))
+		}
+		return err
+	}
+
+	// SQL Server没有RELEASE SAVEPOINT，保存点在事务结束时自动释放
+	if tx.Dialector.Name() == "sqlserver" {
+		return nil
+	}
+	if err := session.Exec("RELEASE SAVEPOINT " + name).Error; err != nil {
+		return fmt.Errorf("释放保存点失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type nestedThing struct {
+	ID   int64
+	Name string
+}
+
+func TestNestedInnerFailureRollsBackInnerOnly(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `nested_things`").WithArgs("a").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("SAVEPOINT gkit_sp_\\d+").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `nested_things`").WithArgs("b").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("SAVEPOINT gkit_sp_\\d+").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO `nested_things`").WithArgs("c").WillReturnResult(sqlmock.NewResult(3, 1))
+	// 内层失败只回滚到内层保存点，外层保存点正常释放，事务提交
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT gkit_sp_\\d+").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("RELEASE SAVEPOINT gkit_sp_\\d+").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	boom := errors.New("boom")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&nestedThing{Name: "a"}).Error; err != nil {
+			return err
+		}
+		return gkit_gorm.Nested(tx, func(tx *gorm.DB) error {
+			if err := tx.Create(&nestedThing{Name: "b"}).Error; err != nil {
+				return err
+			}
+			err := gkit_gorm.Nested(tx, func(tx *gorm.DB) error {
+				if err := tx.Create(&nestedThing{Name: "c"}).Error; err != nil {
+					return err
+				}
+				return boom
+			})
+			if !errors.Is(err, boom) {
+				t.Errorf("内层应返回fn的错误，实际 %v", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNestedWithoutTransaction(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `nested_things`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectRollback()
+
+	boom := errors.New("boom")
+	err := gkit_gorm.Nested(db, func(tx *gorm.DB) error {
+		if err := tx.Create(&nestedThing{Name: "a"}).Error; err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("应返回fn的错误，实际 %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package gkit_gorm
+
+import (
+	"context"
+	"regexp"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+type queryTrackerKey struct{}
+
+// queryTracker 记录一次请求中各类SQL的执行次数
+type queryTracker struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// WithQueryTracking 返回开启N+1检测的ctx，通常在请求入口调用，配合NPlusOnePlugin和db.WithContext使用
+func WithQueryTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryTrackerKey{}, &queryTracker{counts: make(map[string]int)})
+}
+
+// NPlusOnePlugin N+1查询检测插件，同一请求中相同结构的SQL执行次数超过Threshold时通过GORM的日志输出一次警告
+// 只统计通过WithQueryTracking开启的ctx，建议只在开发环境注册
+type NPlusOnePlugin struct {
+	// Threshold 允许的执行次数，默认10
+	Threshold int
+}
+
+// Name 插件名称
+func (p *NPlusOnePlugin) Name() string {
+	return "gkit:n_plus_one"
+}
+
+// Initialize 注册查询后的回调
+func (p *NPlusOnePlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().After("gorm:query").Register("gkit:n_plus_one_query", p.track); err != nil {
+		return err
+	}
+	return cb.Row().After("gorm:row").Register("gkit:n_plus_one_row", p.track)
+}
+
+// threshold 返回允许的执行次数
+func (p *NPlusOnePlugin) threshold() int {
+	if p.Threshold <= 0 {
+		return 10
+	}
+	return p.Threshold
+}
+
+// track 统计SQL指纹，超过阈值时输出警告
+func (p *NPlusOnePlugin) track(db *gorm.DB) {
+	ctx := db.Statement.Context
+	tracker, ok := ctx.Value(queryTrackerKey{}).(*queryTracker)
+	if !ok || db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	fingerprint := sqlFingerprint(db.Statement.SQL.String())
+	tracker.mu.Lock()
+	tracker.counts[fingerprint]++
+	count := tracker.counts[fingerprint]
+	tracker.mu.Unlock()
+
+	// 只在刚超过阈值时警告一次
+	if count == p.threshold()+1 {
+		db.Logger.Warn(ctx, "检测到可能的N+1查询，相同结构的SQL在一次请求中已执行%d次: %s", count, fingerprint)
+	}
+}
+
+var (
+	fingerprintString = regexp.MustCompile(`'(?:[^'\\]|\\.|'')*'`)
+	fingerprintNumber = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	fingerprintList   = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)*\s*\)`)
+	fingerprintSpace  = regexp.MustCompile(`\s+`)
+)
+
+// sqlFingerprint 将SQL中的字面量替换为?，IN列表合并为一个占位符，得到查询的结构
+// 参数:
+//   - sql: 执行的SQL
+//
+// 返回:
+//   - string: 归一化后的SQL
+func sqlFingerprint(sql string) string {
+	sql = fingerprintString.ReplaceAllString(sql, "?")
+	sql = fingerprintNumber.ReplaceAllString(sql, "?")
+	sql = fingerprintList.ReplaceAllString(sql, "(?)")
+	return fingerprintSpace.ReplaceAllString(sql, " ")
+}
@@ -0,0 +1,88 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// warnLogger 记录Warn级别日志的GORM日志
+type warnLogger struct {
+	logger.Interface
+	mu    sync.Mutex
+	warns []string
+}
+
+func (l *warnLogger) Warn(ctx context.Context, msg string, data ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warns = append(l.warns, fmt.Sprintf(msg, data...))
+}
+
+type nPlusOneItem struct {
+	ID uint
+}
+
+func TestNPlusOneWarnsOnceAfterThreshold(t *testing.T) {
+	db, mock := mockDB(t)
+	log := &warnLogger{Interface: logger.Discard}
+	db.Logger = log
+	if err := db.Use(&gkit_gorm.NPlusOnePlugin{Threshold: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := gkit_gorm.WithQueryTracking(context.Background())
+	for i := 0; i < 6; i++ {
+		mock.ExpectQuery("SELECT \\* FROM `n_plus_one_items` WHERE id = \\?").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		var items []nPlusOneItem
+		if err := db.WithContext(ctx).Where("id = ?", i).Find(&items).Error; err != nil {
+			t.Fatal(err)
+		}
+		// 第4次执行时刚超过阈值，之后不再重复警告
+		want := 0
+		if i >= 3 {
+			want = 1
+		}
+		if len(log.warns) != want {
+			t.Fatalf("第%d次执行后期望%d条警告，实际%v", i+1, want, log.warns)
+		}
+	}
+	if !strings.Contains(log.warns[0], "已执行4次") || !strings.Contains(log.warns[0], "n_plus_one_items") {
+		t.Fatalf("警告内容不正确: %s", log.warns[0])
+	}
+}
+
+func TestNPlusOneRequiresTracking(t *testing.T) {
+	db, mock := mockDB(t)
+	log := &warnLogger{Interface: logger.Discard}
+	db.Logger = log
+	if err := db.Use(&gkit_gorm.NPlusOnePlugin{Threshold: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未开启跟踪的ctx以及不同请求的ctx不会累计
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		var items []nPlusOneItem
+		if err := db.Where("id = ?", i).Find(&items).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		var items []nPlusOneItem
+		if err := db.WithContext(gkit_gorm.WithQueryTracking(context.Background())).Where("id = ?", i).Find(&items).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(log.warns) != 0 {
+		t.Fatalf("不应输出警告: %v", log.warns)
+	}
+}
@@ -0,0 +1,102 @@
+package gkit_gorm
+
+import (
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+)
+
+// errOutboxWithoutTransaction 不在事务中写入发件箱时返回的错误，事件与业务数据无法保证同时提交
+var errOutboxWithoutTransaction = errors.New("gorm: 发件箱事件必须在业务写入的事务中写入")
+
+// OutboxEvent 发件箱中的一条事件，使用前需要 db.AutoMigrate(&OutboxEvent{}) 创建outbox表
+type OutboxEvent struct {
+	// ID 自增主键，DrainOutbox按ID顺序发布
+	ID uint64 `gorm:"primaryKey"`
+	// Topic 事件主题，例如"order.created"
+	Topic string `gorm:"size:191;not null"`
+	// Key 事件的业务键，例如订单号，可用于消息队列的分区
+	Key string `gorm:"size:191"`
+	// Payload 事件内容
+	Payload []byte
+	// CreatedAt 写入时间
+	CreatedAt time.Time
+	// SentAt 发布时间，为空表示尚未发布
+	SentAt *time.Time `gorm:"index"`
+}
+
+// TableName 发件箱表名
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
+
+// WriteOutbox 在业务写入的事务中写入一条待发布的事件，事务提交后由DrainOutbox转发到消息队列
+// 事件与业务数据同时提交或回滚，不会出现数据已修改但事件丢失的情况
+// 参数:
+//   - tx: 业务写入所在的事务
+//   - event: 需要发布的事件，Topic不能为空，ID、CreatedAt和SentAt会被忽略
+//
+// 返回:
+//   - error: 不在事务中或写入失败时返回错误
+func WriteOutbox(tx *gorm.DB, event OutboxEvent) error {
+	if _, ok := tx.Statement.ConnPool.(gorm.TxCommitter); !ok {
+		return errOutboxWithoutTransaction
+	}
+	if event.Topic == "" {
+		return errors.New("gorm: 发件箱事件的Topic不能为空")
+	}
+	event.ID = 0
+	event.CreatedAt = tx.NowFunc()
+	event.SentAt = nil
+	return tx.Create(&event).Error
+}
+
+// DrainOutbox 按写入顺序发布尚未发布的事件，直到没有待发布的事件
+// 每批在一个事务中使用 FOR UPDATE SKIP LOCKED 领取，publish成功后标记为已发布，多个实例可以同时调用而不会重复领取同一批事件
+// publish返回错误时本批事件保持未发布，下次调用时重新发布；publish成功但标记失败时同样会重新发布，因此为至少一次语义，消费者需要按ID去重
+// MySQL需要8.0及以上版本
+// 参数:
+//   - db: GORM数据库连接
+//   - batch: 每批领取的事件数，小于等于0时为100
+//   - publish: 发布一批事件的函数，例如写入Kafka
+//
+// 返回:
+//   - error: 第一个失败的批次的错误，之前的批次已经标记为已发布
+func DrainOutbox(db *gorm.DB, batch int, publish func([]OutboxEvent) error) error {
+	if batch <= 0 {
+		batch = 100
+	}
+	for {
+		var n int
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// 1.领取一批未发布的事件，被其他实例锁定的事件直接跳过
+			var events []OutboxEvent
+			if err := ForUpdateSkipLocked(tx.Where("sent_at IS NULL").Order("id").Limit(batch)).Find(&events).Error; err != nil {
+				return err
+			}
+			n = len(events)
+			if n == 0 {
+				return nil
+			}
+
+			// 2.发布，失败时回滚释放锁
+			if err := publish(events); err != nil {
+				return err
+			}
+
+			// 3.标记为已发布
+			ids := make([]uint64, n)
+			for i, event := range events {
+				ids[i] = event.ID
+			}
+			return tx.Model(&OutboxEvent{}).Where("id IN ?", ids).Update("sent_at", tx.NowFunc()).Error
+		})
+		if err != nil {
+			return err
+		}
+		if n < batch {
+			return nil
+		}
+	}
+}
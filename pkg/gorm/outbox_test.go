@@ -0,0 +1,90 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type outboxUser struct {
+	ID   uint
+	Name string
+}
+
+// outboxRows 返回领取到的事件行
+func outboxRows(ids ...int) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "topic"})
+	for _, id := range ids {
+		rows.AddRow(id, "t")
+	}
+	return rows
+}
+
+func TestWriteOutboxInTransaction(t *testing.T) {
+	db, mock := mockDB(t)
+	// 与业务写入在同一个事务中提交
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `outbox_users`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("^INSERT INTO `outbox` \\(`topic`,`key`,`payload`,`created_at`,`sent_at`\\)").
+		WithArgs("user.created", "1", []byte("{}"), sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&outboxUser{Name: "a"}).Error; err != nil {
+			return err
+		}
+		return gkit_gorm.WriteOutbox(tx, gkit_gorm.OutboxEvent{Topic: "user.created", Key: "1", Payload: []byte("{}")})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteOutboxWithoutTransaction(t *testing.T) {
+	db, _ := mockDB(t)
+	if err := gkit_gorm.WriteOutbox(db, gkit_gorm.OutboxEvent{Topic: "x"}); err == nil {
+		t.Fatal("不在事务中写入应返回错误")
+	}
+}
+
+func TestDrainOutboxAtLeastOnce(t *testing.T) {
+	db, mock := mockDB(t)
+	// 发布失败时回滚，事件保持未发布
+	mock.ExpectBegin()
+	mock.ExpectQuery("^SELECT \\* FROM `outbox` WHERE sent_at IS NULL ORDER BY id LIMIT \\? FOR UPDATE SKIP LOCKED$").WithArgs(2).
+		WillReturnRows(outboxRows(1, 2))
+	mock.ExpectRollback()
+	publishErr := errors.New("publish failed")
+	if err := gkit_gorm.DrainOutbox(db, 2, func([]gkit_gorm.OutboxEvent) error { return publishErr }); !errors.Is(err, publishErr) {
+		t.Fatalf("期望返回publish的错误，实际 %v", err)
+	}
+
+	// 再次调用时重新发布同一批事件，之后继续领取直到不足一批
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE SKIP LOCKED$").WillReturnRows(outboxRows(1, 2))
+	mock.ExpectExec("^UPDATE `outbox` SET `sent_at`=\\? WHERE id IN \\(\\?,\\?\\)$").WithArgs(sqlmock.AnyArg(), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE SKIP LOCKED$").WillReturnRows(outboxRows(3))
+	mock.ExpectExec("^UPDATE `outbox` SET `sent_at`=\\? WHERE id IN \\(\\?\\)$").WithArgs(sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var published []uint64
+	err := gkit_gorm.DrainOutbox(db, 2, func(events []gkit_gorm.OutboxEvent) error {
+		for _, e := range events {
+			published = append(published, e.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 3 || published[0] != 1 || published[1] != 2 || published[2] != 3 {
+		t.Fatalf("发布顺序不正确: %v", published)
+	}
+}
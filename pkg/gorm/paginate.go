@@ -0,0 +1,175 @@
+package gkit_gorm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidCursor 游标无法解析或与排序列不一致
+var ErrInvalidCursor = errors.New("gorm: invalid cursor")
+
+// Cursor 不透明的分页游标，为上一页最后一行排序列的值经JSON编码后的base64，空字符串表示第一页或没有下一页
+type Cursor string
+
+// OrderCol 排序列
+type OrderCol struct {
+	// Column 数据库列名，可以带表名前缀，例如"users.created_at"
+	Column string
+	// Desc 是否降序
+	Desc bool
+}
+
+// SeekPaginate 按排序列的值定位(keyset分页)，页数很深时仍然可以使用索引，代替OFFSET分页
+// 所有排序列方向一致时使用 (col1, col2) > (?, ?)，否则展开为 col1 > ? OR (col1 = ? AND col2 < ?) 的形式
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where等条件，不要设置Order、Limit和Offset
+//   - after: 上一页返回的游标，为空时查询第一页
+//   - limit: 每页数量，必须大于0
+//   - order: 排序列，最后一列必须唯一(通常为主键)以保证顺序稳定；排序列不能为NULL
+//
+// 返回:
+//   - []T: 本页的记录
+//   - Cursor: 下一页的游标，没有下一页时为空
+//   - error: 参数不合法时返回错误，游标不合法时返回包含ErrInvalidCursor的错误
+func SeekPaginate[T any](db *gorm.DB, after Cursor, limit int, order []OrderCol) ([]T, Cursor, error) {
+	if limit <= 0 || len(order) == 0 {
+		return nil, "", errors.New("limit必须大于0且order不能为空")
+	}
+
+	modelSchema, err := ParseSchema(db, new(T))
+	if err != nil {
+		return nil, "", fmt.Errorf("解析模型失败: %w", err)
+	}
+	fields := make([]*schema.Field, len(order))
+	for i, col := range order {
+		name := col.Column[strings.LastIndex(col.Column, ".")+1:]
+		if fields[i] = modelSchema.LookUpField(name); fields[i] == nil {
+			return nil, "", fmt.Errorf("模型 %s 不存在字段 %s", modelSchema.Name, col.Column)
+		}
+	}
+
+	tx := db
+	if after != "" {
+		values, err := decodeCursor(after, fields)
+		if err != nil {
+			return nil, "", err
+		}
+		tx = tx.Where(seekCondition(order, values))
+	}
+	for _, col := range order {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: col.Column}, Desc: col.Desc})
+	}
+
+	// 多查询一行判断是否还有下一页
+	var rows []T
+	if err := tx.Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, "", err
+	}
+	if len(rows) <= limit {
+		return rows, "", nil
+	}
+	rows = rows[:limit]
+
+	next, err := encodeCursor(db, reflect.ValueOf(&rows[limit-1]).Elem(), fields)
+	if err != nil {
+		return nil, "", err
+	}
+	return rows, next, nil
+}
+
+// seekCondition 构建定位到游标之后的条件
+// 参数:
+//   - order: 排序列
+//   - values: 游标中排序列的值
+//
+// 返回:
+//   - clause.Expression: WHERE条件
+func seekCondition(order []OrderCol, values []any) clause.Expression {
+	op := func(desc bool) string {
+		if desc {
+			return "<"
+		}
+		return ">"
+	}
+
+	sameDirection := true
+	for _, col := range order[1:] {
+		sameDirection = sameDirection && col.Desc == order[0].Desc
+	}
+	if sameDirection {
+		columns := make([]any, len(order))
+		for i, col := range order {
+			columns[i] = clause.Column{Name: col.Column}
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(order)), ",")
+		return clause.Expr{
+			SQL:  "(" + placeholders + ") " + op(order[0].Desc) + " (" + placeholders + ")",
+			Vars: append(columns, values...),
+		}
+	}
+
+	// 方向不一致时逐列展开: 前i列相等且第i列越过游标，GORM与其他条件组合时会添加括号
+	var (
+		sql  strings.Builder
+		vars []any
+	)
+	for i, col := range order {
+		if i > 0 {
+			sql.WriteString(" OR ")
+		}
+		sql.WriteString("(")
+		for j := 0; j < i; j++ {
+			sql.WriteString("? = ? AND ")
+			vars = append(vars, clause.Column{Name: order[j].Column}, values[j])
+		}
+		sql.WriteString("? " + op(col.Desc) + " ?)")
+		vars = append(vars, clause.Column{Name: col.Column}, values[i])
+	}
+	return clause.Expr{SQL: sql.String(), Vars: vars}
+}
+
+// encodeCursor 将一行记录的排序列的值编码为游标
+func encodeCursor(db *gorm.DB, row reflect.Value, fields []*schema.Field) (Cursor, error) {
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		values[i], _ = field.ValueOf(db.Statement.Context, row)
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("编码游标失败: %w", err)
+	}
+	return Cursor(base64.RawURLEncoding.EncodeToString(data)), nil
+}
+
+// decodeCursor 按排序列的字段类型解码游标中的值
+func decodeCursor(cursor Cursor, fields []*schema.Field) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(string(cursor))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if len(raw) != len(fields) {
+		return nil, fmt.Errorf("%w: 游标包含%d个值，排序列有%d个", ErrInvalidCursor, len(raw), len(fields))
+	}
+
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		value := reflect.New(field.FieldType)
+		if err := json.Unmarshal(raw[i], value.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: 字段 %s: %v", ErrInvalidCursor, field.Name, err)
+		}
+		values[i] = value.Elem().Interface()
+	}
+	return values, nil
+}
@@ -0,0 +1,88 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type seekPost struct {
+	ID        int64
+	Score     int
+	CreatedAt time.Time
+}
+
+var seekPostColumns = []string{"id", "score", "created_at"}
+
+func TestSeekPaginateCompositeOrder(t *testing.T) {
+	db, mock := mockDB(t)
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	order := []gkit_gorm.OrderCol{{Column: "created_at", Desc: true}, {Column: "id", Desc: true}}
+
+	// 多查一行判断是否有下一页
+	mock.ExpectQuery("SELECT \\* FROM `seek_posts` ORDER BY `created_at` DESC,`id` DESC LIMIT \\?$").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(seekPostColumns).AddRow(9, 1, t0).AddRow(8, 1, t0).AddRow(7, 1, t0))
+	page, cursor, err := gkit_gorm.SeekPaginate[seekPost](db, "", 2, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != 9 || page[1].ID != 8 || cursor == "" {
+		t.Fatalf("第1页不正确: %+v, 游标 %q", page, cursor)
+	}
+
+	// 同一时间的行按id继续定位，不会重复或遗漏
+	mock.ExpectQuery("SELECT \\* FROM `seek_posts` WHERE \\(`created_at`,`id`\\) < \\(\\?,\\?\\) ORDER BY `created_at` DESC,`id` DESC LIMIT \\?$").
+		WithArgs(t0, int64(8), 3).
+		WillReturnRows(sqlmock.NewRows(seekPostColumns).AddRow(7, 1, t0).AddRow(6, 1, t0.Add(-time.Hour)).AddRow(5, 1, t0.Add(-time.Hour)))
+	page, cursor, err = gkit_gorm.SeekPaginate[seekPost](db, cursor, 2, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != 7 || page[1].ID != 6 || cursor == "" {
+		t.Fatalf("第2页不正确: %+v, 游标 %q", page, cursor)
+	}
+
+	mock.ExpectQuery("WHERE \\(`created_at`,`id`\\) < \\(\\?,\\?\\)").WithArgs(t0.Add(-time.Hour), int64(6), 3).
+		WillReturnRows(sqlmock.NewRows(seekPostColumns).AddRow(5, 1, t0.Add(-time.Hour)))
+	page, cursor, err = gkit_gorm.SeekPaginate[seekPost](db, cursor, 2, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].ID != 5 || cursor != "" {
+		t.Fatalf("最后一页不正确: %+v, 游标 %q", page, cursor)
+	}
+}
+
+func TestSeekPaginateMixedDirections(t *testing.T) {
+	db, mock := mockDB(t)
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	order := []gkit_gorm.OrderCol{{Column: "score", Desc: true}, {Column: "seek_posts.id"}}
+
+	mock.ExpectQuery("SELECT \\* FROM `seek_posts` WHERE score > \\? ORDER BY `score` DESC,`seek_posts`.`id` LIMIT \\?$").WithArgs(0, 2).
+		WillReturnRows(sqlmock.NewRows(seekPostColumns).AddRow(1, 5, t0).AddRow(2, 5, t0))
+	_, cursor, err := gkit_gorm.SeekPaginate[seekPost](db.Where("score > ?", 0), "", 1, order)
+	if err != nil || cursor == "" {
+		t.Fatalf("第1页 游标 %q, %v", cursor, err)
+	}
+
+	// 方向不一致时展开为OR条件
+	mock.ExpectQuery("SELECT \\* FROM `seek_posts` WHERE score > \\? AND \\(\\(`score` < \\?\\) OR \\(`score` = \\? AND `seek_posts`.`id` > \\?\\)\\) ORDER BY").
+		WithArgs(0, 5, 5, int64(1), 2).WillReturnRows(sqlmock.NewRows(seekPostColumns))
+	if _, _, err := gkit_gorm.SeekPaginate[seekPost](db.Where("score > ?", 0), cursor, 1, order); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSeekPaginateInvalidCursor(t *testing.T) {
+	db, _ := mockDB(t)
+	order := []gkit_gorm.OrderCol{{Column: "id"}}
+	if _, _, err := gkit_gorm.SeekPaginate[seekPost](db, "!!bad", 2, order); !errors.Is(err, gkit_gorm.ErrInvalidCursor) {
+		t.Errorf("无效游标应返回ErrInvalidCursor，实际 %v", err)
+	}
+	if _, _, err := gkit_gorm.SeekPaginate[seekPost](db, "", 0, order); err == nil {
+		t.Error("limit为0应返回错误")
+	}
+}
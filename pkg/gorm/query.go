@@ -0,0 +1,191 @@
+package gkit_gorm
+
+import (
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound 查询不到记录，返回的错误同时满足errors.Is(err, gorm.ErrRecordNotFound)
+var ErrNotFound = errors.New("gorm: record not found")
+
+// First 按主键升序查询第一条记录
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件
+//   - conds: 查询条件，与db.First的conds一致
+//
+// 返回:
+//   - T: 查询到的记录，未找到时为零值
+//   - error: 未找到时返回ErrNotFound，其他错误原样返回
+func First[T any](db *gorm.DB, conds ...any) (T, error) {
+	var value T
+	err := db.First(&value, conds...).Error
+	return value, markNotFound(err)
+}
+
+// Take 不指定排序查询一条记录
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件
+//   - conds: 查询条件，与db.Take的conds一致
+//
+// 返回:
+//   - T: 查询到的记录，未找到时为零值
+//   - error: 未找到时返回ErrNotFound，其他错误原样返回
+func Take[T any](db *gorm.DB, conds ...any) (T, error) {
+	var value T
+	err := db.Take(&value, conds...).Error
+	return value, markNotFound(err)
+}
+
+// Last 按主键降序查询第一条记录
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件
+//   - conds: 查询条件，与db.Last的conds一致
+//
+// 返回:
+//   - T: 查询到的记录，未找到时为零值
+//   - error: 未找到时返回ErrNotFound，其他错误原样返回
+func Last[T any](db *gorm.DB, conds ...any) (T, error) {
+	var value T
+	err := db.Last(&value, conds...).Error
+	return value, markNotFound(err)
+}
+
+// notFoundError 同时匹配ErrNotFound和原始错误，标准库的errors.Is也能识别
+type notFoundError struct {
+	err error
+}
+
+func (e *notFoundError) Error() string { return e.err.Error() }
+
+func (e *notFoundError) Unwrap() error { return e.err }
+
+func (e *notFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// markNotFound 为gorm.ErrRecordNotFound附加ErrNotFound标记，保留原始错误
+func markNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &notFoundError{err: err}
+	}
+	return err
+}
+
+// ScanRaw 执行原生SQL并扫描到切片，适合不对应模型的统计、报表查询
+// T可以是结构体，也可以是int、string等基础类型(取第一列)
+// 参数:
+//   - db: GORM数据库连接
+//   - sql: 原生SQL
+//   - args: SQL参数
+//
+// 返回:
+//   - []T: 查询结果，没有数据时为空切片
+//   - error: 查询过程中发生的错误，如果成功则返回nil
+func ScanRaw[T any](db *gorm.DB, sql string, args ...any) ([]T, error) {
+	values := make([]T, 0)
+	if err := db.Raw(sql, args...).Scan(&values).Error; err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// ScanRawOne 执行原生SQL并扫描第一行
+// 参数:
+//   - db: GORM数据库连接
+//   - sql: 原生SQL
+//   - args: SQL参数
+//
+// 返回:
+//   - T: 查询结果，没有数据时为零值
+//   - error: 没有数据时返回ErrNotFound，其他错误原样返回
+func ScanRawOne[T any](db *gorm.DB, sql string, args ...any) (T, error) {
+	var value T
+	result := db.Raw(sql, args...).Scan(&value)
+	if result.Error != nil {
+		return value, markNotFound(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return value, markNotFound(gorm.ErrRecordNotFound)
+	}
+	return value, nil
+}
+
+// StreamRows 逐行读取查询结果并调用fn，不会把所有记录加载到内存，适合大批量导出
+// 未设置Model和Table时使用T作为模型；fn返回错误或ctx取消时停止读取，rows在返回或panic时都会关闭
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where、Order、Raw等条件
+//   - fn: 处理每一行的函数
+//
+// 返回:
+//   - error: 查询、扫描或fn返回的第一个错误，ctx取消时返回ctx.Err()
+func StreamRows[T any](db *gorm.DB, fn func(T) error) error {
+	tx := db
+	if tx.Statement.Model == nil && tx.Statement.Table == "" && tx.Statement.SQL.Len() == 0 {
+		tx = tx.Model(new(T))
+	}
+
+	rows, err := tx.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ctx := tx.Statement.Context
+	for rows.Next() {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		var value T
+		if err := tx.ScanRows(rows, &value); err != nil {
+			return err
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// FindMap 查询记录并按keyFn的结果建立索引，key重复时保留后出现的记录
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件
+//   - keyFn: 从记录中取出key的函数，例如 func(u User) int64 { return u.ID }
+//   - conds: 查询条件，与db.Find的conds一致
+//
+// 返回:
+//   - map[K]Model: 以key为索引的记录，没有数据时为空map
+//   - error: 查询过程中发生的错误，如果成功则返回nil
+func FindMap[K comparable, Model any](db *gorm.DB, keyFn func(Model) K, conds ...any) (map[K]Model, error) {
+	var values []Model
+	if err := db.Find(&values, conds...).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[K]Model, len(values))
+	for _, value := range values {
+		result[keyFn(value)] = value
+	}
+	return result, nil
+}
+
+// FindGroup 查询记录并按keyFn的结果分组，适合一对多的关联，同组记录保持查询返回的顺序
+// 参数:
+//   - db: GORM数据库连接，可以预先设置Where、Order等条件
+//   - keyFn: 从记录中取出分组key的函数，例如 func(o Order) int64 { return o.UserID }
+//   - conds: 查询条件，与db.Find的conds一致
+//
+// 返回:
+//   - map[K][]Model: 以key分组的记录，没有数据时为空map
+//   - error: 查询过程中发生的错误，如果成功则返回nil
+func FindGroup[K comparable, Model any](db *gorm.DB, keyFn func(Model) K, conds ...any) (map[K][]Model, error) {
+	var values []Model
+	if err := db.Find(&values, conds...).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[K][]Model)
+	for _, value := range values {
+		key := keyFn(value)
+		result[key] = append(result[key], value)
+	}
+	return result, nil
+}
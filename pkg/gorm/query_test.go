@@ -0,0 +1,232 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	crerrors "github.com/cockroachdb/errors"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type queryUser struct {
+	ID   uint
+	Name string
+}
+
+func TestFirstTakeLast(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		fn    func(db *gorm.DB, conds ...any) (queryUser, error)
+	}{
+		{"First", "SELECT \\* FROM `query_users` WHERE name = \\? ORDER BY `query_users`.`id` LIMIT \\?", gkit_gorm.First[queryUser]},
+		{"Take", "SELECT \\* FROM `query_users` WHERE name = \\? LIMIT \\?", gkit_gorm.Take[queryUser]},
+		{"Last", "SELECT \\* FROM `query_users` WHERE name = \\? ORDER BY `query_users`.`id` DESC LIMIT \\?", gkit_gorm.Last[queryUser]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"/found", func(t *testing.T) {
+			db, mock := mockDB(t)
+			mock.ExpectQuery(tt.query).WithArgs("alice", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(7, "alice"))
+
+			user, err := tt.fn(db, "name = ?", "alice")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.ID != 7 || user.Name != "alice" {
+				t.Fatalf("记录不正确: %+v", user)
+			}
+		})
+		t.Run(tt.name+"/not found", func(t *testing.T) {
+			db, mock := mockDB(t)
+			mock.ExpectQuery(tt.query).WithArgs("bob", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+			user, err := tt.fn(db, "name = ?", "bob")
+			if !errors.Is(err, gkit_gorm.ErrNotFound) || !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Fatalf("期望ErrNotFound，实际%v", err)
+			}
+			if user != (queryUser{}) {
+				t.Fatalf("未找到时应返回零值，实际%+v", user)
+			}
+		})
+	}
+}
+
+func TestNotFoundMatchesBothErrorPackages(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `query_users`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := gkit_gorm.First[queryUser](db)
+	if !crerrors.Is(err, gkit_gorm.ErrNotFound) || !crerrors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("cockroachdb/errors无法识别: %v", err)
+	}
+}
+
+type scanReport struct {
+	Day   string
+	Total int
+}
+
+func TestScanRaw(t *testing.T) {
+	db, mock := mockDB(t)
+
+	// 结构体按列名映射
+	mock.ExpectQuery("SELECT day, total FROM orders GROUP BY day").
+		WillReturnRows(sqlmock.NewRows([]string{"day", "total"}).AddRow("2024-01-01", 1).AddRow("2024-01-02", 2))
+	reports, err := gkit_gorm.ScanRaw[scanReport](db, "SELECT day, total FROM orders GROUP BY day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0] != (scanReport{"2024-01-01", 1}) || reports[1] != (scanReport{"2024-01-02", 2}) {
+		t.Fatalf("结构体结果不正确: %+v", reports)
+	}
+
+	// 基础类型取第一列
+	mock.ExpectQuery("SELECT id FROM orders WHERE user_id = \\?").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	ids, err := gkit_gorm.ScanRaw[int64](db, "SELECT id FROM orders WHERE user_id = ?", 3)
+	if err != nil || len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("标量结果不正确: %v %v", ids, err)
+	}
+
+	// 没有数据时为空切片而不是nil
+	mock.ExpectQuery("SELECT id FROM orders").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	ids, err = gkit_gorm.ScanRaw[int64](db, "SELECT id FROM orders")
+	if err != nil || ids == nil || len(ids) != 0 {
+		t.Fatalf("期望空切片，实际%v %v", ids, err)
+	}
+}
+
+func TestScanRawOne(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM orders").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+	n, err := gkit_gorm.ScanRawOne[int](db, "SELECT count(*) FROM orders")
+	if err != nil || n != 7 {
+		t.Fatalf("期望7，实际%d %v", n, err)
+	}
+
+	mock.ExpectQuery("SELECT day, total FROM orders").WillReturnRows(sqlmock.NewRows([]string{"day", "total"}).AddRow("2024-01-01", 3))
+	report, err := gkit_gorm.ScanRawOne[scanReport](db, "SELECT day, total FROM orders")
+	if err != nil || report != (scanReport{"2024-01-01", 3}) {
+		t.Fatalf("结果不正确: %+v %v", report, err)
+	}
+
+	mock.ExpectQuery("SELECT day, total FROM orders").WillReturnRows(sqlmock.NewRows([]string{"day", "total"}))
+	if _, err := gkit_gorm.ScanRawOne[scanReport](db, "SELECT day, total FROM orders"); !errors.Is(err, gkit_gorm.ErrNotFound) {
+		t.Fatalf("期望ErrNotFound，实际%v", err)
+	}
+}
+
+func TestStreamRows(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `query_users` WHERE id > \\?$").WithArgs(0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b").AddRow(3, "c")).
+		RowsWillBeClosed()
+
+	var got []queryUser
+	err := gkit_gorm.StreamRows(db.Where("id > ?", 0), func(u queryUser) error {
+		got = append(got, u)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Name != "a" || got[2].Name != "c" {
+		t.Fatalf("逐行读取结果不正确: %+v", got)
+	}
+}
+
+func TestStreamRowsStops(t *testing.T) {
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b")
+	}
+
+	t.Run("fn error", func(t *testing.T) {
+		db, mock := mockDB(t)
+		mock.ExpectQuery("SELECT \\* FROM `query_users`").WillReturnRows(rows()).RowsWillBeClosed()
+		stop := errors.New("stop")
+		calls := 0
+		err := gkit_gorm.StreamRows(db, func(queryUser) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Fatalf("fn返回错误后应停止读取，调用%d次，%v", calls, err)
+		}
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		db, mock := mockDB(t)
+		mock.ExpectQuery("SELECT \\* FROM `query_users`").WillReturnRows(rows()).RowsWillBeClosed()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := gkit_gorm.StreamRows(db.WithContext(ctx), func(queryUser) error {
+			cancel()
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("ctx取消后应返回context.Canceled，实际 %v", err)
+		}
+	})
+
+	t.Run("panic closes rows", func(t *testing.T) {
+		db, mock := mockDB(t)
+		mock.ExpectQuery("SELECT \\* FROM `query_users`").WillReturnRows(rows()).RowsWillBeClosed()
+		defer func() {
+			if recover() == nil {
+				t.Error("fn的panic应向上传递")
+			}
+		}()
+		_ = gkit_gorm.StreamRows(db, func(queryUser) error { panic("boom") })
+	})
+}
+
+func TestFindMap(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `query_users` WHERE name <> \\?$").WithArgs("z").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b").AddRow(1, "c"))
+
+	users, err := gkit_gorm.FindMap(db, func(u queryUser) uint { return u.ID }, "name <> ?", "z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// key重复时保留后出现的记录
+	if len(users) != 2 || users[1].Name != "c" || users[2].Name != "b" {
+		t.Fatalf("索引不正确: %+v", users)
+	}
+}
+
+func TestFindGroup(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `query_users`$").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b").AddRow(3, "a"))
+
+	groups, err := gkit_gorm.FindGroup(db, func(u queryUser) string { return u.Name })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || len(groups["a"]) != 2 || len(groups["b"]) != 1 {
+		t.Fatalf("分组不正确: %+v", groups)
+	}
+	// 同组记录保持查询返回的顺序
+	if groups["a"][0].ID != 1 || groups["a"][1].ID != 3 {
+		t.Errorf("分组内顺序不正确: %+v", groups["a"])
+	}
+}
+
+func TestFindMapEmpty(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `query_users`$").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	users, err := gkit_gorm.FindMap(db, func(u queryUser) uint { return u.ID })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users == nil || len(users) != 0 {
+		t.Errorf("没有数据时应返回空map，实际 %#v", users)
+	}
+}
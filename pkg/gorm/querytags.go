@@ -0,0 +1,115 @@
+package gkit_gorm
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type queryTagsKey struct{}
+
+// WithQueryTags 返回携带查询标签的ctx，配合QueryTagsPlugin和db.WithContext使用，与ctx中已有的标签合并
+func WithQueryTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	for k, v := range QueryTagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, queryTagsKey{}, merged)
+}
+
+// QueryTagsFromContext 获取ctx中的查询标签
+func QueryTagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(queryTagsKey{}).(map[string]string)
+	return tags
+}
+
+// QueryTagsPlugin 按sqlcommenter格式在每条语句前添加注释，例如 /*app='api',route='%2Fusers'*/ SELECT ...
+// 便于在慢日志和performance_schema中定位语句来源；通过db.Use注册，ctx中没有标签时不添加
+type QueryTagsPlugin struct{}
+
+// Name 插件名称
+func (p *QueryTagsPlugin) Name() string {
+	return "gkit:query_tags"
+}
+
+// Initialize 注册各类语句执行前的回调
+func (p *QueryTagsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("gkit:query_tags_create", p.comment("INSERT")); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("gkit:query_tags_query", p.comment("SELECT")); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("gkit:query_tags_update", p.comment("UPDATE")); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("gkit:query_tags_delete", p.comment("DELETE")); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("gkit:query_tags_row", p.comment("SELECT")); err != nil {
+		return err
+	}
+	return cb.Raw().Before("gorm:raw").Register("gkit:query_tags_raw", p.comment(""))
+}
+
+// comment 创建添加注释的回调
+// 参数:
+//   - name: 语句的第一个子句名，注释写在该子句之前
+//
+// 返回:
+//   - func(*gorm.DB): GORM回调函数
+func (p *QueryTagsPlugin) comment(name string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		comment := formatQueryTags(QueryTagsFromContext(db.Statement.Context))
+		if comment == "" {
+			return
+		}
+
+		// Raw/Exec以及Raw().Scan()的SQL已经生成，直接添加在开头
+		stmt := db.Statement
+		if stmt.SQL.Len() > 0 || name == "" {
+			sql := comment + " " + stmt.SQL.String()
+			stmt.SQL.Reset()
+			stmt.SQL.WriteString(sql)
+			return
+		}
+
+		c := stmt.Clauses[name]
+		c.BeforeExpression = clause.Expr{SQL: comment}
+		stmt.Clauses[name] = c
+	}
+}
+
+// formatQueryTags 按sqlcommenter格式生成注释，键和值经过百分号编码，不会出现引号、*/和占位符
+// 参数:
+//   - tags: 查询标签
+//
+// 返回:
+//   - string: 按键排序的注释，没有标签时为空
+func formatQueryTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, url.PathEscape(k)+"='"+url.PathEscape(tags[k])+"'")
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
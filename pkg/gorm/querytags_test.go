@@ -0,0 +1,82 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type tagThing struct {
+	ID   int64
+	Name string
+}
+
+// mockQueryTagsDB 返回注册了QueryTagsPlugin的sqlmock连接
+func mockQueryTagsDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := mockDB(t)
+	if err := db.Use(&gkit_gorm.QueryTagsPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestQueryTagsComment(t *testing.T) {
+	db, mock := mockQueryTagsDB(t)
+	// 标签按键排序，值经过URL编码，不能提前结束注释
+	ctx := gkit_gorm.WithQueryTags(context.Background(), map[string]string{"route": "/users*/'; drop?", "app": "api"})
+	comment := "^/\\*app='api',route='%2Fusers%2A%2F%27%3B%20drop%3F'\\*/ "
+
+	mock.ExpectQuery(comment + "SELECT \\* FROM `tag_things`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	var things []tagThing
+	if err := db.WithContext(ctx).Find(&things).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(comment + "INSERT INTO `tag_things`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := db.WithContext(ctx).Create(&tagThing{Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(comment + "UPDATE `tag_things`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := db.WithContext(ctx).Model(&tagThing{ID: 1}).Update("name", "b").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(comment + "DELETE FROM `tag_things`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := db.WithContext(ctx).Delete(&tagThing{ID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectExec(comment + "TRUNCATE tag_things").WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := db.WithContext(ctx).Exec("TRUNCATE tag_things").Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryTagsWithoutTags(t *testing.T) {
+	db, mock := mockQueryTagsDB(t)
+	mock.ExpectQuery("^SELECT \\* FROM `tag_things`").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	var things []tagThing
+	if err := db.Find(&things).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithQueryTagsMerges(t *testing.T) {
+	ctx := gkit_gorm.WithQueryTags(context.Background(), map[string]string{"app": "api", "route": "/a"})
+	ctx = gkit_gorm.WithQueryTags(ctx, map[string]string{"route": "/b"})
+	tags := gkit_gorm.QueryTagsFromContext(ctx)
+	if len(tags) != 2 || tags["app"] != "api" || tags["route"] != "/b" {
+		t.Fatalf("合并后的标签不正确: %v", tags)
+	}
+}
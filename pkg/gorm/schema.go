@@ -0,0 +1,36 @@
+package gkit_gorm
+
+import (
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// schemaCaches 按命名策略区分的schema缓存，值为*sync.Map，由schema.Parse按模型类型缓存
+var schemaCaches sync.Map
+
+// ParseSchema 解析模型的schema，结果按模型类型和命名策略在包内共享缓存，热点路径上重复调用不会再次反射解析
+// 参数:
+//   - db: GORM数据库连接，使用其命名策略解析模型
+//   - model: 模型实例或指针，例如 &User{}
+//
+// 返回:
+//   - *schema.Schema: 模型的Schema信息
+//   - error: 解析失败时返回错误
+func ParseSchema(db *gorm.DB, model any) (*schema.Schema, error) {
+	return schema.Parse(model, schemaCache(db.NamingStrategy), db.NamingStrategy)
+}
+
+// schemaCache 返回命名策略对应的缓存，命名策略不可比较时无法作为键，每次返回新的缓存
+func schemaCache(namer schema.Namer) *sync.Map {
+	if namer == nil || !reflect.ValueOf(namer).Comparable() {
+		return &sync.Map{}
+	}
+	if cache, ok := schemaCaches.Load(namer); ok {
+		return cache.(*sync.Map)
+	}
+	cache, _ := schemaCaches.LoadOrStore(namer, &sync.Map{})
+	return cache.(*sync.Map)
+}
@@ -0,0 +1,62 @@
+package gkit_gorm_test
+
+import (
+	"sync"
+	"testing"
+
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type schemaModel struct {
+	ID            uint
+	Name          string
+	Tags          string
+	A, B, C, D, E int
+}
+
+func TestParseSchemaCached(t *testing.T) {
+	db, _ := mockDB(t)
+	first, err := gkit_gorm.ParseSchema(db, &schemaModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := gkit_gorm.ParseSchema(db, schemaModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("同一模型应返回缓存的schema")
+	}
+
+	// 命名策略不同时分别缓存
+	prefixed, _ := mockDB(t)
+	prefixed.NamingStrategy = schema.NamingStrategy{TablePrefix: "x_"}
+	other, err := gkit_gorm.ParseSchema(prefixed, &schemaModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == first || other.Table != "x_schema_models" {
+		t.Errorf("不同命名策略的表名为 %s", other.Table)
+	}
+}
+
+func BenchmarkParseSchema(b *testing.B) {
+	db, _ := benchmarkDB(b)
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := gkit_gorm.ParseSchema(db, &schemaModel{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := schema.Parse(&schemaModel{}, &sync.Map{}, db.NamingStrategy); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SchemaDiffKind 表结构差异的类型
+type SchemaDiffKind string
+
+const (
+	// SchemaDiffMissing 模型中有、表中没有的列
+	SchemaDiffMissing SchemaDiffKind = "missing"
+	// SchemaDiffExtra 表中有、模型中没有的列
+	SchemaDiffExtra SchemaDiffKind = "extra"
+	// SchemaDiffType 两边都有但类型不一致的列
+	SchemaDiffType SchemaDiffKind = "type_mismatch"
+)
+
+// SchemaDiff 模型与线上表结构的一处差异
+type SchemaDiff struct {
+	Kind      SchemaDiffKind
+	Column    string
+	ModelType string // 模型对应的列类型，SchemaDiffExtra时为空
+	TableType string // 表中的列类型，SchemaDiffMissing时为空
+}
+
+func (d SchemaDiff) String() string {
+	switch d.Kind {
+	case SchemaDiffMissing:
+		return fmt.Sprintf("缺少列 %s %s", d.Column, d.ModelType)
+	case SchemaDiffExtra:
+		return fmt.Sprintf("多出列 %s %s", d.Column, d.TableType)
+	default:
+		return fmt.Sprintf("列 %s 类型不一致，模型为 %s，表中为 %s", d.Column, d.ModelType, d.TableType)
+	}
+}
+
+// tableColumn 从系统表读取的列
+type tableColumn struct {
+	Name string
+	Type string
+}
+
+// DiffSchema 比较模型与线上表结构，不执行任何迁移，用于发布前检查
+// MySQL读取 information_schema.columns，Postgres读取 pg_attribute；类型按数据库的别名归一后比较，
+// 例如MySQL的 int(11) 与 int、Postgres的 varchar(191) 与 character varying(191) 视为一致
+// 表不存在时模型的所有列都报告为缺少，带有 -:migration 标签的字段不检查
+// 参数:
+//   - db: GORM数据库连接，通过db.Table指定表名时比较该表
+//   - model: 模型实例或指针，例如 &User{}
+//
+// 返回:
+//   - []SchemaDiff: 差异，先按模型字段顺序列出缺少和类型不一致的列，再按表中顺序列出多出的列，一致时为空
+//   - error: 解析模型或查询表结构失败，或数据库不支持时返回错误
+func DiffSchema(db *gorm.DB, model any) ([]SchemaDiff, error) {
+	s, err := ParseSchema(db, model)
+	if err != nil {
+		return nil, fmt.Errorf("解析模型 %T 失败: %w", model, err)
+	}
+	table := s.Table
+	if db.Statement.Table != "" {
+		table = db.Statement.Table
+	}
+
+	var normalize func(string) string
+	var columns []tableColumn
+	tx := db.Session(&gorm.Session{NewDB: true})
+	switch db.Dialector.Name() {
+	case "mysql":
+		normalize = normalizeMySQLType
+		err = tx.Raw("SELECT column_name AS name, column_type AS type FROM information_schema.columns "+
+			"WHERE table_schema = DATABASE() AND table_name = ? ORDER BY ordinal_position", table).Scan(&columns).Error
+	case "postgres":
+		normalize = normalizePostgresType
+		err = tx.Raw("SELECT a.attname AS name, format_type(a.atttypid, a.atttypmod) AS type FROM pg_attribute a "+
+			"WHERE a.attrelid = to_regclass(?) AND a.attnum > 0 AND NOT a.attisdropped ORDER BY a.attnum", table).Scan(&columns).Error
+	default:
+		return nil, fmt.Errorf("DiffSchema不支持数据库 %s", db.Dialector.Name())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询表 %s 的结构失败: %w", table, err)
+	}
+
+	existing := make(map[string]string, len(columns))
+	for _, col := range columns {
+		existing[strings.ToLower(col.Name)] = col.Type
+	}
+
+	var diffs []SchemaDiff
+	modeled := make(map[string]bool, len(s.DBNames))
+	for _, name := range s.DBNames {
+		field := s.FieldsByDBName[name]
+		modeled[strings.ToLower(name)] = true
+		if field.IgnoreMigration {
+			continue
+		}
+		modelType := modelColumnType(db, field)
+		tableType, ok := existing[strings.ToLower(name)]
+		if !ok {
+			diffs = append(diffs, SchemaDiff{Kind: SchemaDiffMissing, Column: name, ModelType: modelType})
+			continue
+		}
+		if modelType != "" && normalize(modelType) != normalize(tableType) {
+			diffs = append(diffs, SchemaDiff{Kind: SchemaDiffType, Column: name, ModelType: modelType, TableType: tableType})
+		}
+	}
+	for _, col := range columns {
+		if !modeled[strings.ToLower(col.Name)] {
+			diffs = append(diffs, SchemaDiff{Kind: SchemaDiffExtra, Column: col.Name, TableType: col.Type})
+		}
+	}
+	return diffs, nil
+}
+
+// modelColumnType 模型字段对应的列类型，type标签优先，与AutoMigrate生成的类型一致
+func modelColumnType(db *gorm.DB, field *schema.Field) string {
+	if typ := field.TagSettings["TYPE"]; typ != "" {
+		return typ
+	}
+	return db.Dialector.DataTypeOf(field)
+}
+
+var (
+	// typeSpacePattern 类型中多余的空白和逗号后的空格
+	typeSpacePattern = regexp.MustCompile(`\s+`)
+	// typeModifierPattern 列类型之后的约束，不参与比较
+	typeModifierPattern = regexp.MustCompile(` (not null|null|auto_increment|auto_random|primary key)\b.*$`)
+	// mysqlIntWidthPattern MySQL 5.7返回的整数显示宽度，8.0已不再返回
+	mysqlIntWidthPattern = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+	// postgresTimePattern Postgres的时间类型简写，例如 timestamptz(3)
+	postgresTimePattern = regexp.MustCompile(`^(timestamp|time)(tz)?(\(\d+\))?$`)
+)
+
+// normalizeType 统一大小写和空白，去掉约束
+func normalizeType(typ string) string {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	typ = typeSpacePattern.ReplaceAllString(typ, " ")
+	typ = strings.ReplaceAll(typ, ", ", ",")
+	return typeModifierPattern.ReplaceAllString(typ, "")
+}
+
+// normalizeMySQLType 将MySQL的类型别名转换为information_schema中的写法
+func normalizeMySQLType(typ string) string {
+	typ = normalizeType(typ)
+	switch typ {
+	case "bool", "boolean", "tinyint(1)":
+		return "tinyint(1)"
+	case "integer":
+		return "int"
+	case "real", "double precision":
+		return "double"
+	}
+	typ = strings.Replace(typ, "integer", "int", 1)
+	typ = strings.Replace(typ, "numeric", "decimal", 1)
+	return mysqlIntWidthPattern.ReplaceAllString(typ, "$1")
+}
+
+// postgresTypeAliases Postgres类型简写与format_type返回的名称
+var postgresTypeAliases = map[string]string{
+	"bigserial":   "bigint",
+	"serial8":     "bigint",
+	"int8":        "bigint",
+	"serial":      "integer",
+	"serial4":     "integer",
+	"int":         "integer",
+	"int4":        "integer",
+	"smallserial": "smallint",
+	"serial2":     "smallint",
+	"int2":        "smallint",
+	"bool":        "boolean",
+	"float8":      "double precision",
+	"float4":      "real",
+	"varchar":     "character varying",
+	"char":        "character",
+	"decimal":     "numeric",
+}
+
+// normalizePostgresType 将Postgres的类型简写转换为format_type返回的写法
+func normalizePostgresType(typ string) string {
+	typ = normalizeType(typ)
+	if m := postgresTimePattern.FindStringSubmatch(typ); m != nil {
+		zone := " without time zone"
+		if m[2] != "" {
+			zone = " with time zone"
+		}
+		return m[1] + m[3] + zone
+	}
+	name, args, _ := strings.Cut(typ, "(")
+	if alias, ok := postgresTypeAliases[name]; ok {
+		name = alias
+	}
+	if args != "" {
+		return name + "(" + args
+	}
+	return name
+}
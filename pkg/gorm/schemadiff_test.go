@@ -0,0 +1,98 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type diffProfile struct {
+	ID        uint64
+	Name      string `gorm:"size:64"`
+	Age       int
+	Active    bool
+	Score     float64 `gorm:"type:decimal(10,2)"`
+	CreatedAt time.Time
+	Nick      string `gorm:"-:migration"`
+}
+
+type diffEvent struct {
+	ID   uint64    `gorm:"type:bigserial"`
+	Name string    `gorm:"type:varchar(64)"`
+	At   time.Time `gorm:"type:timestamptz(3)"`
+	Flag bool      `gorm:"type:bool"`
+	N    int       `gorm:"type:int8"`
+}
+
+func TestDiffSchemaMySQL(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("FROM information_schema.columns WHERE table_schema = DATABASE\\(\\) AND table_name = \\?").
+		WithArgs("diff_profiles").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type"}).
+			AddRow("id", "bigint(20) unsigned").
+			AddRow("name", "varchar(32)").
+			AddRow("active", "tinyint(1)").
+			AddRow("score", "decimal(10,2)").
+			AddRow("created_at", "datetime(3)").
+			AddRow("legacy", "int(11)"))
+
+	diffs, err := gkit_gorm.DiffSchema(db, &diffProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gkit_gorm.SchemaDiff{
+		{Kind: gkit_gorm.SchemaDiffType, Column: "name", ModelType: "varchar(64)", TableType: "varchar(32)"},
+		{Kind: gkit_gorm.SchemaDiffMissing, Column: "age", ModelType: "bigint"},
+		{Kind: gkit_gorm.SchemaDiffExtra, Column: "legacy", TableType: "int(11)"},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("应返回%d处差异，实际 %v", len(want), diffs)
+	}
+	for i := range want {
+		if diffs[i] != want[i] {
+			t.Errorf("第%d处差异为 %+v，期望 %+v", i, diffs[i], want[i])
+		}
+	}
+}
+
+func TestDiffSchemaMissingTable(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectQuery("FROM information_schema.columns").WithArgs("diff_profiles").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type"}))
+
+	diffs, err := gkit_gorm.DiffSchema(db, &diffProfile{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 带 -:migration 标签的Nick不报告
+	if len(diffs) != 6 {
+		t.Fatalf("表不存在时应报告6个缺少的列，实际 %v", diffs)
+	}
+	for _, d := range diffs {
+		if d.Kind != gkit_gorm.SchemaDiffMissing {
+			t.Errorf("差异类型应为缺少，实际 %s", d)
+		}
+	}
+}
+
+func TestDiffSchemaPostgres(t *testing.T) {
+	db, mock := mockPostgres(t)
+	mock.ExpectQuery("FROM pg_attribute a WHERE a.attrelid = to_regclass\\(\\?\\)").WithArgs("events").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type"}).
+			AddRow("id", "bigint").
+			AddRow("name", "character varying(64)").
+			AddRow("at", "timestamp(3) with time zone").
+			AddRow("flag", "boolean").
+			AddRow("n", "integer"))
+
+	// 类型简写按format_type的写法比较，只有 int8 与 integer 不一致
+	diffs, err := gkit_gorm.DiffSchema(db.Table("events"), &diffEvent{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 || diffs[0].Kind != gkit_gorm.SchemaDiffType || diffs[0].Column != "n" {
+		t.Fatalf("应只报告列n类型不一致，实际 %v", diffs)
+	}
+}
@@ -0,0 +1,77 @@
+package gkit_gorm
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// ReadOnlyTx 在只读的一致性快照事务中执行fn，fn中的多次查询看到同一时刻的数据，适用于生成报表
+// MySQL使用 START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY，快照在事务开始时建立；
+// Postgres在事务开始后执行 SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY，快照在第一条查询时建立；
+// 其他数据库使用普通事务。事务中执行写入会返回数据库的错误
+// 参数:
+//   - db: GORM数据库连接
+//   - fn: 在事务中执行的查询，返回错误时回滚
+//
+// 返回:
+//   - error: 开启事务、fn或结束事务时发生的错误
+func ReadOnlyTx(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return mysqlSnapshotTx(db, fn)
+	case "postgres":
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY").Error; err != nil {
+				return err
+			}
+			return fn(tx)
+		})
+	default:
+		return db.Transaction(fn)
+	}
+}
+
+// mysqlSnapshotTx 在独占的连接上手动开启事务，database/sql的BeginTx无法生成WITH CONSISTENT SNAPSHOT
+// fn中的连接被包装为事务，嵌套的Transaction使用保存点，ForUpdate等检查也能识别
+func mysqlSnapshotTx(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY").Error; err != nil {
+			return err
+		}
+		// ctx取消后仍然需要结束事务
+		pool := &snapshotTx{ConnPool: conn.Statement.ConnPool, ctx: context.WithoutCancel(conn.Statement.Context)}
+
+		// fn返回错误或panic时回滚，避免连接带着未结束的事务回到连接池
+		committed := false
+		defer func() {
+			if !committed {
+				_ = pool.Rollback()
+			}
+		}()
+
+		tx := conn.Session(&gorm.Session{NewDB: true})
+		tx.Statement.ConnPool = pool
+		if err := fn(tx); err != nil {
+			return err
+		}
+		committed = true
+		return pool.Commit()
+	})
+}
+
+// snapshotTx 手动开启的事务，提交和回滚通过语句执行
+type snapshotTx struct {
+	gorm.ConnPool
+	ctx context.Context
+}
+
+func (t *snapshotTx) Commit() error {
+	_, err := t.ConnPool.ExecContext(t.ctx, "COMMIT")
+	return err
+}
+
+func (t *snapshotTx) Rollback() error {
+	_, err := t.ConnPool.ExecContext(t.ctx, "ROLLBACK")
+	return err
+}
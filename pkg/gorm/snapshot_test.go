@@ -0,0 +1,77 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type reportOrder struct {
+	ID     int64
+	Amount int64
+}
+
+func TestReadOnlyTxMySQL(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectExec("^START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY$").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `report_orders`").WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}).AddRow(1, 10))
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `report_orders`").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec("^COMMIT$").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := gkit_gorm.ReadOnlyTx(db, func(tx *gorm.DB) error {
+		var orders []reportOrder
+		if err := tx.Find(&orders).Error; err != nil {
+			return err
+		}
+		// 嵌套事务使用保存点，不会另开事务
+		return tx.Transaction(func(tx *gorm.DB) error {
+			var n int64
+			return tx.Model(&reportOrder{}).Count(&n).Error
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadOnlyTxMySQLRollback(t *testing.T) {
+	db, mock := mockDB(t)
+	boom := errors.New("boom")
+	mock.ExpectExec("^START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY$").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("^ROLLBACK$").WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := gkit_gorm.ReadOnlyTx(db, func(tx *gorm.DB) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("应返回fn的错误，实际 %v", err)
+	}
+
+	// panic时同样回滚，连接不会带着未结束的事务回到连接池
+	mock.ExpectExec("^START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY$").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("^ROLLBACK$").WillReturnResult(sqlmock.NewResult(0, 0))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic应继续向外传递")
+			}
+		}()
+		_ = gkit_gorm.ReadOnlyTx(db, func(tx *gorm.DB) error { panic("boom") })
+	}()
+}
+
+func TestReadOnlyTxPostgres(t *testing.T) {
+	db, mock := mockPostgres(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("^SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY$").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `report_orders`").WillReturnRows(sqlmock.NewRows([]string{"id", "amount"}))
+	mock.ExpectCommit()
+
+	err := gkit_gorm.ReadOnlyTx(db, func(tx *gorm.DB) error {
+		var orders []reportOrder
+		return tx.Find(&orders).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,171 @@
+package gkit_gorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// SoftDeleteCascade 在事务中软删除父记录以及指定的has one/has many关联记录
+// GORM不会自动级联软删除，关联记录通过schema中的关系元数据按外键定位
+// 参数:
+//   - db: GORM数据库连接
+//   - parent: 需要软删除的父记录，必须是已设置主键的结构体指针
+//   - relations: 需要级联软删除的关联字段名，例如 []string{"Orders", "Profile"}
+//
+// 返回:
+//   - error: 操作过程中发生的错误，如果操作成功则返回nil
+func SoftDeleteCascade(db *gorm.DB, parent any, relations []string) error {
+	// 1.解析父模型的schema
+	parentSchema, err := ParseSchema(db, parent)
+	if err != nil {
+		return fmt.Errorf("解析模型失败: %w", err)
+	}
+	if !isSoftDeleteSchema(parentSchema) {
+		return fmt.Errorf("模型 %s 没有DeletedAt字段，无法软删除", parentSchema.Name)
+	}
+
+	parentValue := reflect.Indirect(reflect.ValueOf(parent))
+	if parentValue.Kind() != reflect.Struct {
+		return errors.New("parent必须是结构体或结构体指针")
+	}
+
+	// 2.校验关联关系，在开启事务前发现配置错误
+	rels := make([]*schema.Relationship, 0, len(relations))
+	for _, name := range relations {
+		rel, ok := parentSchema.Relationships.Relations[name]
+		if !ok {
+			return fmt.Errorf("模型 %s 不存在关联 %s", parentSchema.Name, name)
+		}
+		if rel.Type != schema.HasOne && rel.Type != schema.HasMany {
+			return fmt.Errorf("关联 %s 的类型为 %s，仅支持has one/has many", name, rel.Type)
+		}
+		if !isSoftDeleteSchema(rel.FieldSchema) {
+			return fmt.Errorf("关联 %s 的模型 %s 没有DeletedAt字段，无法软删除", name, rel.FieldSchema.Name)
+		}
+		rels = append(rels, rel)
+	}
+
+	// 3.在事务中依次软删除关联记录和父记录
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, rel := range rels {
+			query := tx.Model(reflect.New(rel.FieldSchema.ModelType).Interface())
+			for _, ref := range rel.References {
+				if ref.OwnPrimaryKey {
+					// 外键指向父记录的主键
+					val, zero := ref.PrimaryKey.ValueOf(context.Background(), parentValue)
+					if zero {
+						return fmt.Errorf("父记录的 %s 为空，无法定位关联 %s", ref.PrimaryKey.DBName, rel.Name)
+					}
+					query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: ref.ForeignKey.DBName}, Value: val})
+				} else {
+					// 多态关联的类型字段
+					query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: ref.ForeignKey.DBName}, Value: ref.PrimaryValue})
+				}
+			}
+			if err := query.Delete(reflect.New(rel.FieldSchema.ModelType).Interface()).Error; err != nil {
+				return fmt.Errorf("软删除关联 %s 失败: %w", rel.Name, err)
+			}
+		}
+
+		return tx.Delete(parent).Error
+	})
+}
+
+// isSoftDeleteSchema 判断模型是否支持软删除
+// 参数:
+//   - s: 模型的Schema信息
+//
+// 返回:
+//   - bool: 模型包含gorm.DeletedAt等软删除字段时返回true
+func isSoftDeleteSchema(s *schema.Schema) bool {
+	return len(s.DeleteClauses) > 0
+}
+
+// UpsertOrRestore 插入记录，唯一键冲突时更新已存在的记录，已被软删除的记录同时恢复
+// 普通的Create会因软删除的记录违反唯一索引而失败，带作用域的更新又匹配不到已删除的记录，适用于"重新添加已删除的条目"
+// 在事务中执行不带软删除作用域的upsert，冲突时额外清空软删除字段(MySQL为ON DUPLICATE KEY UPDATE ..., deleted_at = NULL；
+// Postgres为ON CONFLICT DO UPDATE SET ..., deleted_at = NULL)，再按冲突字段重新读取记录，使entity的主键等字段与数据库一致
+// 参数:
+//   - db: GORM数据库连接
+//   - entity: 需要保存的记录，必须是结构体指针，模型需要有DeletedAt字段
+//   - conflictColumns: 唯一索引包含的字段，可以是字段名或列名
+//   - updateColumns: 冲突时需要更新的字段，为空时只恢复软删除的记录
+//
+// 返回:
+//   - error: 操作过程中发生的错误，如果操作成功则返回nil
+func UpsertOrRestore(db *gorm.DB, entity any, conflictColumns []string, updateColumns []string) error {
+	// 1.解析模型并找到软删除字段
+	s, err := ParseSchema(db, entity)
+	if err != nil {
+		return fmt.Errorf("解析模型失败: %w", err)
+	}
+	value := reflect.ValueOf(entity)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return errors.New("entity必须是结构体指针")
+	}
+	var deletedAt *schema.Field
+	for _, field := range s.Fields {
+		// 与GORM解析模型时一致，按字段类型是否提供删除子句判断
+		if _, ok := reflect.New(field.IndirectFieldType).Interface().(schema.DeleteClausesInterface); ok {
+			deletedAt = field
+			break
+		}
+	}
+	if deletedAt == nil {
+		return fmt.Errorf("模型 %s 没有DeletedAt字段，无法恢复软删除的记录", s.Name)
+	}
+	if len(conflictColumns) == 0 {
+		return errors.New("conflictColumns不能为空")
+	}
+
+	// 2.构建冲突子句
+	conflictFields := make([]*schema.Field, 0, len(conflictColumns))
+	columns := make([]clause.Column, 0, len(conflictColumns))
+	for _, name := range conflictColumns {
+		field := s.LookUpField(name)
+		if field == nil {
+			return fmt.Errorf("模型 %s 不存在字段 %s", s.Name, name)
+		}
+		conflictFields = append(conflictFields, field)
+		columns = append(columns, clause.Column{Name: field.DBName})
+	}
+	updates := make([]string, 0, len(updateColumns))
+	for _, name := range updateColumns {
+		field := s.LookUpField(name)
+		if field == nil {
+			return fmt.Errorf("模型 %s 不存在字段 %s", s.Name, name)
+		}
+		updates = append(updates, field.DBName)
+	}
+	onConflict := clause.OnConflict{
+		Columns: columns,
+		DoUpdates: append(clause.AssignmentColumns(updates),
+			clause.Assignment{Column: clause.Column{Name: deletedAt.DBName}, Value: nil}),
+	}
+
+	// 3.在事务中执行upsert并重新读取记录
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Clauses(onConflict).Create(entity).Error; err != nil {
+			return err
+		}
+
+		query := tx.Unscoped()
+		for _, field := range conflictFields {
+			val, _ := field.ValueOf(tx.Statement.Context, value.Elem())
+			query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: val})
+		}
+		// 读取到新的实例，entity上回填的主键在MySQL更新已存在的记录时不可靠，不能作为查询条件
+		fresh := reflect.New(s.ModelType)
+		if err := query.Take(fresh.Interface()).Error; err != nil {
+			return err
+		}
+		value.Elem().Set(fresh.Elem())
+		return nil
+	})
+}
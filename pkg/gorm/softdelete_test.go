@@ -0,0 +1,117 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type cascadeParent struct {
+	ID        uint
+	Children  []cascadeChild `gorm:"foreignKey:ParentID"`
+	Profile   cascadeProfile `gorm:"foreignKey:ParentID"`
+	DeletedAt gorm.DeletedAt
+}
+
+type cascadeChild struct {
+	ID        uint
+	ParentID  uint
+	DeletedAt gorm.DeletedAt
+}
+
+type cascadeProfile struct {
+	ID        uint
+	ParentID  uint
+	DeletedAt gorm.DeletedAt
+}
+
+func TestSoftDeleteCascade(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `cascade_children` SET `deleted_at`=\\? WHERE `cascade_children`.`parent_id` = \\? AND `cascade_children`.`deleted_at` IS NULL").
+		WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE `cascade_profiles` SET `deleted_at`=\\? WHERE `cascade_profiles`.`parent_id` = \\? AND `cascade_profiles`.`deleted_at` IS NULL").
+		WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `cascade_parents` SET `deleted_at`=\\? WHERE `cascade_parents`.`id` = \\? AND `cascade_parents`.`deleted_at` IS NULL").
+		WithArgs(sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := gkit_gorm.SoftDeleteCascade(db, &cascadeParent{ID: 1}, []string{"Children", "Profile"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSoftDeleteCascadeRollback(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `cascade_children`").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE `cascade_profiles`").WillReturnError(errors.New("boom"))
+	mock.ExpectRollback()
+
+	err := gkit_gorm.SoftDeleteCascade(db, &cascadeParent{ID: 1}, []string{"Children", "Profile"})
+	if err == nil {
+		t.Fatal("期望返回错误")
+	}
+}
+
+func TestSoftDeleteCascadeInvalidRelation(t *testing.T) {
+	db, _ := mockDB(t)
+	if err := gkit_gorm.SoftDeleteCascade(db, &cascadeParent{ID: 1}, []string{"Missing"}); err == nil {
+		t.Fatal("期望返回错误")
+	}
+}
+
+type restoreItem struct {
+	ID        uint
+	SKU       string `gorm:"uniqueIndex"`
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+func TestUpsertOrRestore(t *testing.T) {
+	tests := []struct {
+		name     string
+		insertID int64 // 冲突时MySQL的LastInsertId为0
+		affected int64 // 插入为1，冲突后更新为2
+		rowID    int
+	}{
+		{"insert new", 5, 1, 5},
+		{"update existing", 0, 2, 3},
+		{"restore soft-deleted", 0, 2, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := mockDB(t)
+			mock.ExpectBegin()
+			// 不带软删除作用域，冲突时同时清空deleted_at
+			mock.ExpectExec("^INSERT INTO `restore_items` \\(`sku`,`name`,`deleted_at`\\) VALUES \\(\\?,\\?,\\?\\) "+
+				"ON DUPLICATE KEY UPDATE `name`=VALUES\\(`name`\\),`deleted_at`=\\?$").
+				WithArgs("a", "new", nil, nil).WillReturnResult(sqlmock.NewResult(tt.insertID, tt.affected))
+			mock.ExpectQuery("^SELECT \\* FROM `restore_items` WHERE `restore_items`.`sku` = \\? LIMIT \\?$").WithArgs("a", 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "sku", "name", "deleted_at"}).AddRow(tt.rowID, "a", "new", nil))
+			mock.ExpectCommit()
+
+			item := &restoreItem{SKU: "a", Name: "new"}
+			if err := gkit_gorm.UpsertOrRestore(db, item, []string{"SKU"}, []string{"name"}); err != nil {
+				t.Fatal(err)
+			}
+			if item.ID != uint(tt.rowID) || item.DeletedAt.Valid {
+				t.Fatalf("记录未与数据库一致: %+v", item)
+			}
+		})
+	}
+}
+
+func TestUpsertOrRestoreWithoutDeletedAt(t *testing.T) {
+	db, _ := mockDB(t)
+	type plainItem struct {
+		ID  uint
+		SKU string
+	}
+	if err := gkit_gorm.UpsertOrRestore(db, &plainItem{}, []string{"sku"}, nil); err == nil {
+		t.Fatal("没有DeletedAt字段时应返回错误")
+	}
+}
@@ -0,0 +1,110 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ExecSQLFile 读取SQL文件，按语句拆分后依次执行，遇到第一个错误即停止
+// 支持引号内的分号、"-- "行注释以及DELIMITER块
+// 支持事务性DDL的数据库(postgres、sqlite)会在事务中执行，MySQL的DDL会隐式提交，因此不使用事务
+// 参数:
+//   - db: GORM数据库连接
+//   - path: SQL文件路径
+//
+// 返回:
+//   - error: 执行过程中发生的错误，包含出错语句的序号(从1开始)
+func ExecSQLFile(db *gorm.DB, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取SQL文件失败: %w", err)
+	}
+	statements := splitSQLStatements(string(content))
+
+	exec := func(tx *gorm.DB) error {
+		for i, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("执行第%d条语句失败: %w", i+1, err)
+			}
+		}
+		return nil
+	}
+
+	switch db.Dialector.Name() {
+	case "postgres", "sqlite":
+		return db.Transaction(exec)
+	default:
+		return exec(db)
+	}
+}
+
+// splitSQLStatements 将SQL脚本拆分为单条语句
+// 参数:
+//   - content: SQL脚本内容
+//
+// 返回:
+//   - []string: 去除首尾空白和结束符后的语句列表，不包含空语句
+func splitSQLStatements(content string) []string {
+	var (
+		statements []string
+		buf        strings.Builder
+		delimiter  = ";"
+		quote      byte // 当前所在的引号，0表示不在引号内
+	)
+
+	flush := func() {
+		if stmt := strings.TrimSpace(buf.String()); stmt != "" {
+			statements = append(statements, stmt)
+		}
+		buf.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(content, "\n") {
+		// DELIMITER只在语句开头生效，用于定义存储过程等包含分号的语句
+		if quote == 0 && strings.TrimSpace(buf.String()) == "" {
+			trimmed := strings.TrimSpace(line)
+			if len(trimmed) > len("DELIMITER ") && strings.EqualFold(trimmed[:len("DELIMITER ")], "DELIMITER ") {
+				delimiter = strings.TrimSpace(trimmed[len("DELIMITER "):])
+				buf.Reset()
+				continue
+			}
+		}
+
+		for i := 0; i < len(line); i++ {
+			ch := line[i]
+
+			// 引号内的内容原样保留，反斜杠转义下一个字符
+			if quote != 0 {
+				buf.WriteByte(ch)
+				if ch == '\\' && quote != '`' && i+1 < len(line) {
+					i++
+					buf.WriteByte(line[i])
+				} else if ch == quote {
+					quote = 0
+				}
+				continue
+			}
+
+			switch {
+			case ch == '\'' || ch == '"' || ch == '`':
+				quote = ch
+			case strings.HasPrefix(line[i:], "-- ") || strings.TrimRight(line[i:], "\r\n") == "--":
+				// 跳过行注释，保留换行
+				i = len(line)
+				buf.WriteByte('\n')
+				continue
+			case strings.HasPrefix(line[i:], delimiter):
+				flush()
+				i += len(delimiter) - 1
+				continue
+			}
+			buf.WriteByte(ch)
+		}
+	}
+	flush()
+
+	return statements
+}
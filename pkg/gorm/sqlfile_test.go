@@ -0,0 +1,72 @@
+package gkit_gorm_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+// writeSQLFile 将内容写入临时SQL文件
+func writeSQLFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "script.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const twoStatements = `-- 创建表
+CREATE TABLE notes (id INT, body TEXT);
+INSERT INTO notes VALUES (1, 'a;b -- not a comment');
+`
+
+func TestExecSQLFile(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE notes (id INT, body TEXT)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO notes VALUES (1, 'a;b -- not a comment')")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := gkit_gorm.ExecSQLFile(db, writeSQLFile(t, twoStatements)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecSQLFileStopsAtFirstError(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectExec("CREATE TABLE").WillReturnError(errors.New("boom"))
+
+	err := gkit_gorm.ExecSQLFile(db, writeSQLFile(t, twoStatements))
+	if err == nil || !strings.Contains(err.Error(), "第1条") {
+		t.Fatalf("期望包含语句序号的错误，实际%v", err)
+	}
+}
+
+func TestExecSQLFilePostgresTransaction(t *testing.T) {
+	db, mock := mockPostgres(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO").WillReturnError(errors.New("boom"))
+	mock.ExpectRollback()
+
+	if err := gkit_gorm.ExecSQLFile(db, writeSQLFile(t, twoStatements)); err == nil {
+		t.Fatal("期望返回错误")
+	}
+}
+
+func TestExecSQLFileDelimiter(t *testing.T) {
+	db, mock := mockDB(t)
+	body := "CREATE PROCEDURE p() BEGIN SELECT 1; SELECT 2; END"
+	mock.ExpectExec(regexp.QuoteMeta(body)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CALL p()")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	content := "DELIMITER $$\n" + body + "$$\nDELIMITER ;\nCALL p();\n"
+	if err := gkit_gorm.ExecSQLFile(db, writeSQLFile(t, content)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+package gkit_gorm
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTenantMissing 模型包含租户字段，但ctx中没有租户ID
+var ErrTenantMissing = errors.New("gorm: tenant id is required in context")
+
+type tenantKey struct{}
+
+type skipTenantKey struct{}
+
+// WithTenant 返回携带租户ID的ctx，配合TenantPlugin和db.WithContext使用
+func WithTenant(ctx context.Context, id any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// TenantFromContext 获取ctx中的租户ID
+func TenantFromContext(ctx context.Context) (any, bool) {
+	id := ctx.Value(tenantKey{})
+	return id, id != nil
+}
+
+// WithoutTenant 返回跳过租户过滤的ctx，用于管理后台等需要跨租户的操作
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipTenantKey{}, true)
+}
+
+// TenantPlugin 多租户插件，为包含租户字段的模型的SELECT/UPDATE/DELETE自动添加 tenant_id = ? 条件
+// 通过db.Use(&TenantPlugin{})注册，Raw/Exec执行的原生SQL不会被处理
+type TenantPlugin struct {
+	// Column 租户字段名，默认tenant_id
+	Column string
+	// AllowMissingTenant ctx中没有租户ID时是否放行，默认返回ErrTenantMissing，避免遗漏条件导致数据泄露
+	AllowMissingTenant bool
+}
+
+// Name 插件名称
+func (p *TenantPlugin) Name() string {
+	return "gkit:tenant"
+}
+
+// Initialize 注册查询、更新和删除的回调
+func (p *TenantPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("gkit:tenant_query", p.scope(false)); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("gkit:tenant_row", p.scope(false)); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("gkit:tenant_update", p.scope(true)); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("gkit:tenant_delete", p.scope(true))
+}
+
+// column 返回租户字段名
+func (p *TenantPlugin) column() string {
+	if p.Column == "" {
+		return "tenant_id"
+	}
+	return p.Column
+}
+
+// scope 创建注入租户条件的回调
+// 参数:
+//   - write: 是否为更新或删除操作
+//
+// 返回:
+//   - func(*gorm.DB): GORM回调函数
+func (p *TenantPlugin) scope(write bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		stmt := db.Statement
+		if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+			return
+		}
+		field := stmt.Schema.LookUpField(p.column())
+		if field == nil {
+			return
+		}
+		if skip, _ := stmt.Context.Value(skipTenantKey{}).(bool); skip {
+			return
+		}
+
+		tenant, ok := TenantFromContext(stmt.Context)
+		if !ok {
+			if !p.AllowMissingTenant {
+				_ = db.AddError(ErrTenantMissing)
+			}
+			return
+		}
+
+		// 没有条件的全表更新/删除交给GORM报ErrMissingWhereClause，避免租户条件绕过该保护
+		if write && !db.AllowGlobalUpdate && !hasWhereCondition(stmt) {
+			return
+		}
+
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenant},
+		}})
+	}
+}
+
+// hasWhereCondition 判断语句是否已有条件，或者GORM会根据模型主键自动添加条件
+// 参数:
+//   - stmt: 当前语句
+//
+// 返回:
+//   - bool: 存在条件时返回true
+func hasWhereCondition(stmt *gorm.Statement) bool {
+	if _, ok := stmt.Clauses["WHERE"]; ok {
+		return true
+	}
+
+	val := stmt.ReflectValue
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		return val.Len() > 0
+	case reflect.Struct:
+		for _, field := range stmt.Schema.PrimaryFields {
+			if _, zero := field.ValueOf(stmt.Context, val); !zero {
+				return true
+			}
+		}
+	}
+	return false
+}
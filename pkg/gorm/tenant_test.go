@@ -0,0 +1,93 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type tenantDoc struct {
+	ID       uint
+	TenantID uint
+	Title    string
+}
+
+// mockTenantDB 返回注册了TenantPlugin的sqlmock连接
+func mockTenantDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := mockDB(t)
+	if err := db.Use(&gkit_gorm.TenantPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestTenantQueryInjectsClause(t *testing.T) {
+	db, mock := mockTenantDB(t)
+	ctx := gkit_gorm.WithTenant(context.Background(), 7)
+
+	mock.ExpectQuery("SELECT \\* FROM `tenant_docs` WHERE title = \\? AND `tenant_docs`.`tenant_id` = \\?$").
+		WithArgs("x", 7).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	var docs []tenantDoc
+	if err := db.WithContext(ctx).Where("title = ?", "x").Find(&docs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `tenant_docs` WHERE `tenant_docs`.`tenant_id` = \\?$").
+		WithArgs(7).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	var n int64
+	if err := db.WithContext(ctx).Model(&tenantDoc{}).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTenantWriteInjectsClause(t *testing.T) {
+	db, mock := mockTenantDB(t)
+	ctx := gkit_gorm.WithTenant(context.Background(), 7)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `tenant_docs` SET `title`=\\? WHERE `tenant_docs`.`tenant_id` = \\? AND `id` = \\?").
+		WithArgs("y", 7, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := db.WithContext(ctx).Model(&tenantDoc{ID: 3}).Update("title", "y").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `tenant_docs` WHERE `tenant_docs`.`tenant_id` = \\? AND `tenant_docs`.`id` = \\?").
+		WithArgs(7, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := db.WithContext(ctx).Delete(&tenantDoc{ID: 3}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 租户条件不算作WHERE条件，没有其他条件的更新仍然被拒绝
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	if err := db.WithContext(ctx).Model(&tenantDoc{}).Update("title", "y").Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("期望ErrMissingWhereClause，实际%v", err)
+	}
+}
+
+func TestTenantOptOut(t *testing.T) {
+	db, mock := mockTenantDB(t)
+	ctx := gkit_gorm.WithoutTenant(gkit_gorm.WithTenant(context.Background(), 7))
+
+	mock.ExpectQuery("SELECT \\* FROM `tenant_docs`$").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	var docs []tenantDoc
+	if err := db.WithContext(ctx).Find(&docs).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTenantMissing(t *testing.T) {
+	db, _ := mockTenantDB(t)
+	var docs []tenantDoc
+	if err := db.Find(&docs).Error; !errors.Is(err, gkit_gorm.ErrTenantMissing) {
+		t.Fatalf("期望ErrTenantMissing，实际%v", err)
+	}
+}
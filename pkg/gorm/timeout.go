@@ -0,0 +1,127 @@
+package gkit_gorm
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	queryTimeoutPluginName = "gkit:query_timeout"
+	queryTimeoutKey        = "gkit:query_timeout"
+	queryTimeoutCancelKey  = "gkit:query_timeout_cancel"
+	queryTimeoutCtxKey     = "gkit:query_timeout_ctx"
+)
+
+// WithQueryTimeout 返回单条语句超时时间为d的会话，需要先注册QueryTimeoutPlugin
+// 参数:
+//   - db: GORM数据库连接
+//   - d: 每条语句的超时时间，小于等于0表示不限制
+//
+// 返回:
+//   - *gorm.DB: 带有超时设置的会话，插件未注册时会话带有错误
+func WithQueryTimeout(db *gorm.DB, d time.Duration) *gorm.DB {
+	tx := db.Set(queryTimeoutKey, d)
+	if _, ok := db.Config.Plugins[queryTimeoutPluginName]; !ok {
+		_ = tx.AddError(errors.New("gorm: QueryTimeoutPlugin未注册，WithQueryTimeout不会生效"))
+	}
+	return tx
+}
+
+// QueryTimeoutPlugin 为每条语句设置超时时间，防止失控的查询长期占用连接
+// 通过db.Use注册，Rows/Row返回的结果在回调结束后才会读取，因此不受超时控制
+type QueryTimeoutPlugin struct {
+	// Default 默认超时时间，0表示只对WithQueryTimeout的会话生效
+	Default time.Duration
+	// DisableMySQLHint 是否禁用MySQL的MAX_EXECUTION_TIME优化器提示，默认对SELECT添加，由服务端中止查询
+	DisableMySQLHint bool
+}
+
+// Name 插件名称
+func (p *QueryTimeoutPlugin) Name() string {
+	return queryTimeoutPluginName
+}
+
+// Initialize 注册各类语句执行前后的回调
+// 释放超时ctx的回调注册在gorm:after_*之后，预加载和AfterFind等钩子中的查询同样受超时控制
+func (p *QueryTimeoutPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	registrations := []struct {
+		name   string
+		before func(string, func(*gorm.DB)) error
+		after  func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:after_create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:after_query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:after_update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:after_delete").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+
+	for _, r := range registrations {
+		name := "gkit:query_timeout_" + r.name
+		if err := r.before(name+"_before", p.before(r.name == "query")); err != nil {
+			return err
+		}
+		if err := r.after(name+"_after", p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timeout 返回当前语句的超时时间
+func (p *QueryTimeoutPlugin) timeout(db *gorm.DB) time.Duration {
+	if v, ok := db.Get(queryTimeoutKey); ok {
+		if d, ok := v.(time.Duration); ok {
+			return d
+		}
+	}
+	return p.Default
+}
+
+// before 派生带超时的ctx，SELECT语句在MySQL下额外添加MAX_EXECUTION_TIME提示
+// 参数:
+//   - query: 是否为查询语句
+//
+// 返回:
+//   - func(*gorm.DB): GORM回调函数
+func (p *QueryTimeoutPlugin) before(query bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		d := p.timeout(db)
+		if db.Error != nil || d <= 0 {
+			return
+		}
+
+		// context.WithTimeout会保留调用方更早的截止时间
+		ctx, cancel := context.WithTimeout(db.Statement.Context, d)
+		db.InstanceSet(queryTimeoutCtxKey, db.Statement.Context)
+		db.InstanceSet(queryTimeoutCancelKey, cancel)
+		db.Statement.Context = ctx
+
+		// 提示写在SELECT之后，例如 SELECT /*+ MAX_EXECUTION_TIME(1000) */ ...
+		if query && !p.DisableMySQLHint && db.Dialector.Name() == "mysql" && db.Statement.SQL.Len() == 0 {
+			c := db.Statement.Clauses["SELECT"]
+			c.AfterNameExpression = clause.Expr{SQL: fmt.Sprintf("/*+ MAX_EXECUTION_TIME(%d) */", d.Milliseconds())}
+			db.Statement.Clauses["SELECT"] = c
+		}
+	}
+}
+
+// after 语句执行完后释放超时ctx，并恢复调用方的ctx，避免同一会话的后续语句使用已取消的ctx
+func (p *QueryTimeoutPlugin) after(db *gorm.DB) {
+	if v, ok := db.InstanceGet(queryTimeoutCancelKey); ok {
+		if cancel, ok := v.(context.CancelFunc); ok {
+			cancel()
+		}
+	}
+	if v, ok := db.InstanceGet(queryTimeoutCtxKey); ok {
+		if ctx, ok := v.(context.Context); ok {
+			db.Statement.Context = ctx
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+)
+
+type timeoutOrder struct {
+	ID     uint
+	UserID uint
+}
+
+type timeoutUser struct {
+	ID     uint
+	Orders []timeoutOrder `gorm:"foreignKey:UserID"`
+
+	afterFindErr error `gorm:"-"`
+}
+
+// AfterFind 记录钩子执行时ctx的状态
+func (u *timeoutUser) AfterFind(tx *gorm.DB) error {
+	if _, ok := tx.Statement.Context.Deadline(); !ok {
+		u.afterFindErr = errors.New("AfterFind的ctx没有截止时间")
+	} else {
+		u.afterFindErr = tx.Statement.Context.Err()
+	}
+	return nil
+}
+
+// mockTimeoutDB 返回注册了QueryTimeoutPlugin的sqlmock连接
+func mockTimeoutDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := mockDB(t)
+	if err := db.Use(&gkit_gorm.QueryTimeoutPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db, mock := mockTimeoutDB(t)
+	mock.ExpectQuery("SELECT /\\*\\+ MAX_EXECUTION_TIME\\(50\\) \\*/ \\* FROM `timeout_orders`").
+		WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	var orders []timeoutOrder
+	start := time.Now()
+	err := gkit_gorm.WithQueryTimeout(db, 50*time.Millisecond).Find(&orders).Error
+	if err == nil {
+		t.Fatal("超过超时时间的查询应返回错误")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("超时未生效，耗时%s", elapsed)
+	}
+
+	// 未设置超时的会话不受影响
+	mock.ExpectQuery("SELECT \\* FROM `timeout_orders`$").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if err := db.Find(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryTimeoutCoversPreloadAndHooks(t *testing.T) {
+	db, mock := mockTimeoutDB(t)
+	mock.ExpectQuery("SELECT /\\*\\+ MAX_EXECUTION_TIME\\(1000\\) \\*/ \\* FROM `timeout_users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT /\\*\\+ MAX_EXECUTION_TIME\\(1000\\) \\*/ \\* FROM `timeout_orders` WHERE `timeout_orders`.`user_id` = \\?").
+		WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(10, 1))
+
+	ctx := context.Background()
+	var users []timeoutUser
+	result := gkit_gorm.WithQueryTimeout(db.WithContext(ctx), time.Second).Preload("Orders").Find(&users)
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if len(users) != 1 || len(users[0].Orders) != 1 {
+		t.Fatalf("预加载结果不正确: %+v", users)
+	}
+	if users[0].afterFindErr != nil {
+		t.Fatalf("AfterFind执行时超时ctx应仍然有效: %v", users[0].afterFindErr)
+	}
+	// 语句结束后恢复调用方的ctx
+	if result.Statement.Context != ctx {
+		t.Fatal("语句结束后应恢复调用方的ctx")
+	}
+}
@@ -2,6 +2,15 @@ package gkit_gorm
 
 import (
 	"fmt"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	// ProtocolTCP 通过host:port连接
+	ProtocolTCP = "tcp"
+	// ProtocolUnix 通过unix socket连接
+	ProtocolUnix = "unix"
 )
 
 var DefaultDSN = func() *DSN {
@@ -19,15 +28,57 @@ var DefaultDSN = func() *DSN {
 type DSN struct {
 	Username  string
 	Password  string
+	Protocol  string // 连接协议，tcp或unix，为空时设置了Socket则使用unix
 	Host      string
 	Port      int
+	Socket    string // unix socket路径，使用unix协议时生效
 	DBName    string
 	Char      string
 	ParseTime bool
 	Loc       string
 }
 
+// protocol 返回实际使用的连接协议
+func (d *DSN) protocol() string {
+	if d.Protocol != "" {
+		return d.Protocol
+	}
+	if d.Socket != "" && d.Host == "" {
+		return ProtocolUnix
+	}
+	return ProtocolTCP
+}
+
+// Validate 校验连接地址配置，socket和host必须且只能选择一种
+func (d *DSN) Validate() error {
+	switch d.Protocol {
+	case "":
+		if d.Socket != "" && d.Host != "" {
+			return errors.New("dsn: socket和host不能同时配置，请通过Protocol指定连接方式")
+		}
+		if d.Socket == "" && d.Host == "" {
+			return errors.New("dsn: socket和host必须配置一个")
+		}
+	case ProtocolTCP:
+		if d.Host == "" {
+			return errors.New("dsn: tcp连接必须配置host")
+		}
+	case ProtocolUnix:
+		if d.Socket == "" {
+			return errors.New("dsn: unix连接必须配置socket")
+		}
+	default:
+		return fmt.Errorf("dsn: 不支持的连接协议 %s", d.Protocol)
+	}
+	return nil
+}
+
 func (d *DSN) String() string {
-	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=%s&parseTime=%v&loc=%s",
-		d.Username, d.Password, d.Host, d.Port, d.DBName, d.Char, d.ParseTime, d.Loc)
+	addr := fmt.Sprintf("tcp(%s:%d)", d.Host, d.Port)
+	if d.protocol() == ProtocolUnix {
+		// unix socket连接忽略host和port
+		addr = fmt.Sprintf("unix(%s)", d.Socket)
+	}
+	return fmt.Sprintf("%s:%s@%s/%s?charset=%s&parseTime=%v&loc=%s",
+		d.Username, d.Password, addr, d.DBName, d.Char, d.ParseTime, d.Loc)
 }
@@ -0,0 +1,80 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+func TestDSNString(t *testing.T) {
+	tests := []struct {
+		name     string
+		dsn      func(d *gkit_gorm.DSN)
+		want     string
+		net      string
+		wantAddr string
+	}{
+		{
+			name:     "tcp",
+			dsn:      func(d *gkit_gorm.DSN) { d.Host, d.Port = "db.local", 3307 },
+			want:     "root:123456@tcp(db.local:3307)/app?charset=utf8mb4&parseTime=true&loc=Local",
+			net:      "tcp",
+			wantAddr: "db.local:3307",
+		},
+		{
+			name:     "unix socket",
+			dsn:      func(d *gkit_gorm.DSN) { d.Host, d.Socket = "", "/var/run/mysqld/mysqld.sock" },
+			want:     "root:123456@unix(/var/run/mysqld/mysqld.sock)/app?charset=utf8mb4&parseTime=true&loc=Local",
+			net:      "unix",
+			wantAddr: "/var/run/mysqld/mysqld.sock",
+		},
+		{
+			name: "显式指定unix时忽略host",
+			dsn: func(d *gkit_gorm.DSN) {
+				d.Protocol, d.Socket = gkit_gorm.ProtocolUnix, "/tmp/mysql.sock"
+			},
+			want:     "root:123456@unix(/tmp/mysql.sock)/app?charset=utf8mb4&parseTime=true&loc=Local",
+			net:      "unix",
+			wantAddr: "/tmp/mysql.sock",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := gkit_gorm.DefaultDSN()
+			d.DBName = "app"
+			tt.dsn(d)
+			if err := d.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			if got := d.String(); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+			// 驱动能够解析生成的DSN
+			cfg, err := mysql.ParseDSN(d.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Net != tt.net || cfg.Addr != tt.wantAddr {
+				t.Fatalf("got %s %s", cfg.Net, cfg.Addr)
+			}
+		})
+	}
+}
+
+func TestDSNValidate(t *testing.T) {
+	invalid := map[string]func(d *gkit_gorm.DSN){
+		"同时配置socket和host": func(d *gkit_gorm.DSN) { d.Socket = "/tmp/mysql.sock" },
+		"都没有配置":           func(d *gkit_gorm.DSN) { d.Host = "" },
+		"unix缺少socket":    func(d *gkit_gorm.DSN) { d.Protocol = gkit_gorm.ProtocolUnix },
+		"tcp缺少host":       func(d *gkit_gorm.DSN) { d.Protocol, d.Host = gkit_gorm.ProtocolTCP, "" },
+		"未知协议":            func(d *gkit_gorm.DSN) { d.Protocol = "pipe" },
+	}
+	for name, fn := range invalid {
+		d := gkit_gorm.DefaultDSN()
+		fn(d)
+		if err := d.Validate(); err == nil {
+			t.Errorf("%s: 期望返回错误", name)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package gkit_gorm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TxEvent 一次事务的结束
+type TxEvent struct {
+	// Duration 从开始事务到提交或回滚完成的时间
+	Duration time.Duration
+	// Error 提交或回滚失败时的错误
+	Error error
+}
+
+// TxHooksPlugin 事务钩子插件，在事务开始、提交和回滚时调用回调，用于排查长事务和锁等待
+// 通过包装连接池实现，db.Transaction、db.Begin以及创建、更新时的默认事务都会触发；嵌套事务的保存点不会触发
+// 需要在其他替换连接池的配置(例如PrepareStmt)之后注册
+type TxHooksPlugin struct {
+	// OnBegin 事务开始后调用，开始失败时不调用
+	OnBegin func(ctx context.Context)
+	// OnCommit 提交后调用，提交失败时Error不为空
+	OnCommit func(ctx context.Context, event TxEvent)
+	// OnRollback 回滚后调用
+	OnRollback func(ctx context.Context, event TxEvent)
+	// SlowThreshold 事务持续时间超过该值时通过db.Logger输出警告，0表示不输出
+	SlowThreshold time.Duration
+
+	logger logger.Interface
+}
+
+// Name 插件名称
+func (p *TxHooksPlugin) Name() string {
+	return "gkit:tx_hooks"
+}
+
+// Initialize 包装连接池
+func (p *TxHooksPlugin) Initialize(db *gorm.DB) error {
+	if _, ok := db.ConnPool.(gorm.TxCommitter); ok {
+		return errors.New("gorm: TxHooksPlugin不能在事务中注册")
+	}
+	p.logger = db.Logger
+	pool := &txHookPool{ConnPool: db.ConnPool, plugin: p}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return nil
+}
+
+// finish 事务结束时调用回调并检查是否为慢事务
+func (p *TxHooksPlugin) finish(ctx context.Context, begin time.Time, outcome string, err error, fn func(context.Context, TxEvent)) {
+	event := TxEvent{Duration: time.Since(begin), Error: err}
+	if fn != nil {
+		fn(ctx, event)
+	}
+	if p.SlowThreshold > 0 && event.Duration > p.SlowThreshold && p.logger != nil {
+		p.logger.Warn(ctx, "SLOW TRANSACTION >= %v [%.3fms] %s", p.SlowThreshold, float64(event.Duration.Nanoseconds())/1e6, outcome)
+	}
+}
+
+// txHookPool 在开始事务时记录开始时间并包装事务
+type txHookPool struct {
+	gorm.ConnPool
+	plugin *TxHooksPlugin
+}
+
+func (p *txHookPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var (
+		tx  gorm.ConnPool
+		err error
+	)
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	if err != nil {
+		return nil, err
+	}
+	committer, ok := tx.(gorm.TxCommitter)
+	if !ok {
+		return nil, gorm.ErrInvalidTransaction
+	}
+
+	if p.plugin.OnBegin != nil {
+		p.plugin.OnBegin(ctx)
+	}
+	return &txHookTx{ConnPool: tx, committer: committer, plugin: p.plugin, ctx: ctx, begin: time.Now()}, nil
+}
+
+// GetDBConn 返回底层的*sql.DB，使db.DB()在包装后仍然可用
+func (p *txHookPool) GetDBConn() (*sql.DB, error) {
+	switch pool := p.ConnPool.(type) {
+	case *sql.DB:
+		return pool, nil
+	case gorm.GetDBConnector:
+		return pool.GetDBConn()
+	default:
+		return nil, gorm.ErrInvalidDB
+	}
+}
+
+// txHookTx 提交或回滚时调用回调
+type txHookTx struct {
+	gorm.ConnPool
+	committer gorm.TxCommitter
+	plugin    *TxHooksPlugin
+	ctx       context.Context
+	begin     time.Time
+	finished  bool // 提交失败后GORM会再回滚一次，只在第一次结束时调用回调
+}
+
+func (t *txHookTx) Commit() error {
+	err := t.committer.Commit()
+	if t.finished {
+		return err
+	}
+	t.finished = true
+	t.plugin.finish(t.ctx, t.begin, "COMMIT", err, t.plugin.OnCommit)
+	return err
+}
+
+func (t *txHookTx) Rollback() error {
+	err := t.committer.Rollback()
+	if t.finished {
+		return err
+	}
+	t.finished = true
+	t.plugin.finish(t.ctx, t.begin, "ROLLBACK", err, t.plugin.OnRollback)
+	return err
+}
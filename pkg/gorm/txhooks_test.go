@@ -0,0 +1,106 @@
+package gkit_gorm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type txHookThing struct {
+	ID   int64
+	Name string
+}
+
+// txHookRecorder 记录事务钩子的调用
+type txHookRecorder struct {
+	begins    int
+	commits   []gkit_gorm.TxEvent
+	rollbacks []gkit_gorm.TxEvent
+}
+
+// mockTxHooksDB 返回注册了TxHooksPlugin的sqlmock连接
+func mockTxHooksDB(t *testing.T, slow time.Duration) (*gorm.DB, sqlmock.Sqlmock, *txHookRecorder, *warnLogger) {
+	t.Helper()
+	db, mock := mockDB(t)
+	log := &warnLogger{Interface: logger.Discard}
+	db.Logger = log
+	rec := &txHookRecorder{}
+	err := db.Use(&gkit_gorm.TxHooksPlugin{
+		OnBegin:       func(context.Context) { rec.begins++ },
+		OnCommit:      func(_ context.Context, e gkit_gorm.TxEvent) { rec.commits = append(rec.commits, e) },
+		OnRollback:    func(_ context.Context, e gkit_gorm.TxEvent) { rec.rollbacks = append(rec.rollbacks, e) },
+		SlowThreshold: slow,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock, rec, log
+}
+
+func TestTxHooksBeginCommit(t *testing.T) {
+	db, mock, rec, log := mockTxHooksDB(t, time.Millisecond)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE tx_hook_things SET name = \\?").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		time.Sleep(2 * time.Millisecond)
+		return tx.Exec("UPDATE tx_hook_things SET name = ?", "a").Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.begins != 1 || len(rec.commits) != 1 || len(rec.rollbacks) != 0 {
+		t.Fatalf("应开始和提交各1次，实际 %+v", rec)
+	}
+	if d := rec.commits[0].Duration; d < 2*time.Millisecond {
+		t.Errorf("事务持续时间应为正数且不小于2ms，实际 %v", d)
+	}
+	if len(log.warns) != 1 || !strings.Contains(log.warns[0], "SLOW TRANSACTION") {
+		t.Errorf("超过阈值的事务应输出警告，实际 %v", log.warns)
+	}
+	if _, err := db.DB(); err != nil {
+		t.Errorf("包装连接池后db.DB()应可用: %v", err)
+	}
+}
+
+func TestTxHooksDefaultTransactionAndRollback(t *testing.T) {
+	db, mock, rec, _ := mockTxHooksDB(t, 0)
+	// 创建时的默认事务同样触发
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `tx_hook_things`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := db.Create(&txHookThing{Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	boom := errors.New("boom")
+	if err := db.Transaction(func(*gorm.DB) error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("应返回fn的错误，实际 %v", err)
+	}
+	if rec.begins != 2 || len(rec.commits) != 1 || len(rec.rollbacks) != 1 {
+		t.Fatalf("应开始2次、提交1次、回滚1次，实际 %+v", rec)
+	}
+}
+
+func TestTxHooksCommitFailureReportedOnce(t *testing.T) {
+	db, mock, rec, _ := mockTxHooksDB(t, 0)
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errors.New("commit failed"))
+
+	if err := db.Transaction(func(*gorm.DB) error { return nil }); err == nil {
+		t.Fatal("提交失败应返回错误")
+	}
+	if len(rec.commits) != 1 || rec.commits[0].Error == nil || len(rec.rollbacks) != 0 {
+		t.Fatalf("提交失败应只调用一次OnCommit且带有错误，实际 %+v", rec)
+	}
+}
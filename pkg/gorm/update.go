@@ -0,0 +1,289 @@
+package gkit_gorm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/duke-git/lancet/v2/slice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// UpdateChangedOption 定义了UpdateChanged的函数式选项类型
+type UpdateChangedOption func(*updateChanged)
+
+// WithChangedKey 设置用于定位记录的字段，默认使用主键
+// 参数:
+//   - keys: 一个或多个数据库字段名
+//
+// 返回:
+//   - UpdateChangedOption: 返回一个可应用于UpdateChanged的选项函数
+func WithChangedKey(keys ...string) UpdateChangedOption {
+	return func(u *updateChanged) {
+		if len(keys) > 0 {
+			u.Keys = keys
+		}
+	}
+}
+
+// WithChangedOmit 设置不参与比较和更新的字段
+// 参数:
+//   - fields: 需要忽略的数据库字段名
+//
+// 返回:
+//   - UpdateChangedOption: 返回一个可应用于UpdateChanged的选项函数
+func WithChangedOmit(fields ...string) UpdateChangedOption {
+	return func(u *updateChanged) {
+		u.Omit = append(u.Omit, fields...)
+	}
+}
+
+// updateChanged UpdateChanged的配置
+type updateChanged struct {
+	Keys []string // 用于定位记录的字段，默认是主键
+	Omit []string // 不参与比较的字段
+}
+
+// UpdateChanged 将entity与加载时的快照original逐字段比较，只更新值发生变化的字段
+// 只有original中存在的字段才参与比较，适合编辑表单只提交部分修改的场景
+// 参数:
+//   - db: GORM数据库连接
+//   - entity: 修改后的记录，必须是结构体指针
+//   - original: 修改前的快照，键为数据库字段名
+//   - options: 可选的配置选项
+//
+// 返回:
+//   - []string: 实际更新的字段名，没有变化时为空且不会执行SQL
+//   - error: 更新过程中发生的错误，如果成功则返回nil
+func UpdateChanged(db *gorm.DB, entity any, original map[string]any, options ...UpdateChangedOption) ([]string, error) {
+	modelSchema, err := ParseSchema(db, entity)
+	if err != nil {
+		return nil, fmt.Errorf("解析模型失败: %w", err)
+	}
+
+	u := &updateChanged{}
+	for _, field := range modelSchema.PrimaryFields {
+		u.Keys = append(u.Keys, field.DBName)
+	}
+	for _, option := range options {
+		option(u)
+	}
+	if len(u.Keys) == 0 {
+		return nil, errors.New("模型没有主键，请通过WithChangedKey指定定位字段")
+	}
+
+	// 1.比较快照，收集发生变化的字段
+	changed := make([]string, 0)
+	updates := make(map[string]any)
+	for _, field := range modelSchema.Fields {
+		name := field.DBName
+		if name == "" || slice.Contain(u.Keys, name) || slice.Contain(u.Omit, name) {
+			continue
+		}
+		before, ok := original[name]
+		if !ok {
+			continue
+		}
+		after, err := getFieldValue(entity, modelSchema, name)
+		if err != nil {
+			return nil, err
+		}
+		if valuesEqual(before, after) {
+			continue
+		}
+		changed = append(changed, name)
+		updates[name] = after
+	}
+	if len(changed) == 0 {
+		return changed, nil
+	}
+
+	// 2.构建定位条件
+	conditions := make([]string, 0, len(u.Keys))
+	values := make([]any, 0, len(u.Keys))
+	for _, key := range u.Keys {
+		val, err := getFieldValue(entity, modelSchema, key)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = ?", key))
+		values = append(values, val)
+	}
+
+	// 3.只更新变化的字段
+	err = db.Model(reflect.New(modelSchema.ModelType).Interface()).
+		Where(strings.Join(conditions, " AND "), values...).
+		Updates(updates).Error
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// valuesEqual 比较快照值与当前值是否相等
+// 快照通常来自map扫描，类型可能与结构体字段不同(如int64与uint、[]byte与string)，按值的类别比较:
+// 整数和浮点数按数值比较，字符串与[]byte按字节比较，time.Time使用Equal，实现了driver.Valuer的字段先取Value；
+// 其他类型只有在可以相互转换且类别相同时才转换后比较，类型不兼容时视为发生变化
+// 参数:
+//   - a: 快照中的值
+//   - b: 当前字段的值
+//
+// 返回:
+//   - bool: 两个值相等时返回true
+func valuesEqual(a, b any) bool {
+	a, b = indirectValue(a), indirectValue(b)
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	if a == nil || b == nil {
+		return false
+	}
+	if valuer, ok := b.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return false
+		}
+		return valuesEqual(a, v)
+	}
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isIntegerKind(va.Kind()) && isIntegerKind(vb.Kind()):
+		return integersEqual(va, vb)
+	case isNumberKind(va.Kind()) && isNumberKind(vb.Kind()):
+		return numberAsFloat(va) == numberAsFloat(vb)
+	case isTextValue(va) && isTextValue(vb):
+		return textOf(va) == textOf(vb)
+	case va.Kind() == vb.Kind() && va.Type().ConvertibleTo(vb.Type()):
+		return reflect.DeepEqual(va.Convert(vb.Type()).Interface(), b)
+	}
+	return false
+}
+
+// isIntegerKind 判断是否为有符号或无符号整数
+func isIntegerKind(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Int64) || (k >= reflect.Uint && k <= reflect.Uintptr)
+}
+
+// isNumberKind 判断是否为整数或浮点数
+func isNumberKind(k reflect.Kind) bool {
+	return isIntegerKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
+
+// integersEqual 比较两个整数，负数与无符号整数总是不相等
+func integersEqual(a, b reflect.Value) bool {
+	signed := func(k reflect.Kind) bool { return k >= reflect.Int && k <= reflect.Int64 }
+	switch {
+	case signed(a.Kind()) && signed(b.Kind()):
+		return a.Int() == b.Int()
+	case signed(a.Kind()):
+		return a.Int() >= 0 && uint64(a.Int()) == b.Uint()
+	case signed(b.Kind()):
+		return b.Int() >= 0 && uint64(b.Int()) == a.Uint()
+	default:
+		return a.Uint() == b.Uint()
+	}
+}
+
+// numberAsFloat 将数值转换为float64
+func numberAsFloat(v reflect.Value) float64 {
+	switch {
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		return float64(v.Int())
+	case isIntegerKind(v.Kind()):
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+// isTextValue 判断是否为字符串或[]byte
+func isTextValue(v reflect.Value) bool {
+	return v.Kind() == reflect.String || (v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8)
+}
+
+// textOf 返回字符串或[]byte的内容
+func textOf(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return v.String()
+	}
+	return string(v.Bytes())
+}
+
+// indirectValue 解引用指针，nil指针返回nil
+func indirectValue(v any) any {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() {
+		return nil
+	}
+	return val.Interface()
+}
+
+// TouchUpdatedAt 将满足条件的记录的更新时间设置为当前时间，不加载记录，只执行一条UPDATE，用于缓存失效或心跳
+// 更新时间字段为带有autoUpdateTime的字段，没有时使用updated_at列；当前时间取自db.NowFunc，测试时可以通过gorm.Config的NowFunc注入
+// 使用UpdateColumn执行，只更新时间字段，不触发钩子
+// 参数:
+//   - db: GORM数据库连接
+//   - model: 模型，例如 &User{}；设置了主键时同时按主键过滤
+//   - conds: 查询条件，与db.Where的参数相同，例如 "status = ?", 1；为空且model没有主键时GORM会拒绝全表更新
+//
+// 返回:
+//   - int64: 更新的记录数
+//   - error: 模型没有更新时间字段或更新失败时返回错误
+func TouchUpdatedAt(db *gorm.DB, model any, conds ...any) (int64, error) {
+	modelSchema, err := ParseSchema(db, model)
+	if err != nil {
+		return 0, fmt.Errorf("解析模型失败: %w", err)
+	}
+	field := updatedAtField(modelSchema)
+	if field == nil {
+		return 0, fmt.Errorf("模型 %s 没有更新时间字段", modelSchema.Name)
+	}
+
+	now := db.NowFunc()
+	var value any = now
+	switch field.AutoUpdateTime {
+	case schema.UnixSecond:
+		value = now.Unix()
+	case schema.UnixMillisecond:
+		value = now.UnixMilli()
+	case schema.UnixNanosecond:
+		value = now.UnixNano()
+	}
+
+	tx := db.Model(model)
+	if len(conds) > 0 {
+		tx = tx.Where(conds[0], conds[1:]...)
+	}
+	result := tx.UpdateColumn(field.DBName, value)
+	return result.RowsAffected, result.Error
+}
+
+// updatedAtField 返回模型的更新时间字段
+// 参数:
+//   - s: 模型的Schema信息
+//
+// 返回:
+//   - *schema.Field: 带有autoUpdateTime的字段，没有时为updated_at列，都不存在时返回nil
+func updatedAtField(s *schema.Schema) *schema.Field {
+	for _, field := range s.Fields {
+		if field.AutoUpdateTime > 0 && field.DBName != "" {
+			return field
+		}
+	}
+	return s.FieldsByDBName["updated_at"]
+}
@@ -0,0 +1,124 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type changedItem struct {
+	ID          uint
+	Name        string
+	Price       int
+	Note        *string
+	PublishedAt time.Time
+}
+
+func TestUpdateChangedNoChanges(t *testing.T) {
+	db, _ := mockDB(t)
+	// 快照来自map扫描，类型与结构体字段不同但值相等，不执行SQL
+	changed, err := gkit_gorm.UpdateChanged(db, &changedItem{ID: 1, Name: "a", Price: 3},
+		map[string]any{"name": []byte("a"), "price": int64(3), "note": nil})
+	if err != nil || len(changed) != 0 {
+		t.Fatalf("got %v %v", changed, err)
+	}
+}
+
+func TestUpdateChangedOneField(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `changed_items` SET `name`=\\? WHERE id = \\?").WithArgs("b", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	changed, err := gkit_gorm.UpdateChanged(db, &changedItem{ID: 1, Name: "b", Price: 3},
+		map[string]any{"id": int64(1), "name": "a", "price": int64(3)})
+	if err != nil || len(changed) != 1 || changed[0] != "name" {
+		t.Fatalf("got %v %v", changed, err)
+	}
+}
+
+func TestUpdateChangedSeveralFields(t *testing.T) {
+	db, mock := mockDB(t)
+	note := "n"
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `changed_items` SET `name`=\\?,`note`=\\?,`price`=\\? WHERE id = \\?").
+		WithArgs("b", "n", 4, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	changed, err := gkit_gorm.UpdateChanged(db, &changedItem{ID: 1, Name: "b", Price: 4, Note: &note, PublishedAt: at},
+		map[string]any{"name": "a", "price": int64(3), "note": nil, "published_at": at.In(time.Local)})
+	if err != nil || len(changed) != 3 {
+		t.Fatalf("got %v %v", changed, err)
+	}
+}
+
+func TestUpdateChangedTypeMismatch(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `changed_items` SET `price`=\\? WHERE id = \\?").WithArgs(3, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// 字符串"3"与整数3的格式化结果相同，但类型不兼容，视为发生变化
+	changed, err := gkit_gorm.UpdateChanged(db, &changedItem{ID: 1, Price: 3}, map[string]any{"price": "3"})
+	if err != nil || len(changed) != 1 || changed[0] != "price" {
+		t.Fatalf("got %v %v", changed, err)
+	}
+}
+
+type touchRow struct {
+	ID        uint
+	Status    int
+	Name      string
+	UpdatedAt time.Time
+}
+
+type touchMilli struct {
+	ID      uint
+	Touched int64 `gorm:"autoUpdateTime:milli"`
+}
+
+type touchNone struct {
+	ID uint
+}
+
+func TestTouchUpdatedAt(t *testing.T) {
+	db, mock := mockDB(t)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	db.Config.NowFunc = func() time.Time { return now }
+
+	// 只更新时间字段，时间取自注入的NowFunc
+	mock.ExpectBegin()
+	mock.ExpectExec("^UPDATE `touch_rows` SET `updated_at`=\\? WHERE status = \\?$").WithArgs(now, 1).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	n, err := gkit_gorm.TouchUpdatedAt(db, &touchRow{Name: "ignored"}, "status = ?", 1)
+	if err != nil || n != 3 {
+		t.Fatalf("got %d %v", n, err)
+	}
+}
+
+func TestTouchUpdatedAtAutoUpdateTime(t *testing.T) {
+	db, mock := mockDB(t)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	db.Config.NowFunc = func() time.Time { return now }
+
+	mock.ExpectBegin()
+	mock.ExpectExec("^UPDATE `touch_millis` SET `touched`=\\? WHERE `id` = \\?$").WithArgs(now.UnixMilli(), 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if n, err := gkit_gorm.TouchUpdatedAt(db, &touchMilli{ID: 5}); err != nil || n != 1 {
+		t.Fatalf("got %d %v", n, err)
+	}
+}
+
+func TestTouchUpdatedAtNoField(t *testing.T) {
+	db, _ := mockDB(t)
+	if _, err := gkit_gorm.TouchUpdatedAt(db, &touchNone{}, "id = 1"); err == nil {
+		t.Fatal("没有更新时间字段时应返回错误")
+	}
+}
@@ -0,0 +1,241 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertAction upsert对单行执行的操作
+type UpsertAction int
+
+const (
+	// UpsertInserted 新插入的行
+	UpsertInserted UpsertAction = iota + 1
+	// UpsertUpdated 已存在并被更新的行
+	UpsertUpdated
+	// UpsertSkipped 已存在但未被修改的行，例如WithOnConflict指定了DoNothing，或Postgres的DoUpdates带有不满足的Where条件
+	UpsertSkipped
+)
+
+// String 返回操作名称
+func (a UpsertAction) String() string {
+	switch a {
+	case UpsertInserted:
+		return "inserted"
+	case UpsertUpdated:
+		return "updated"
+	case UpsertSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// UpsertWithActions 批量upsert，并返回每一行是插入还是更新
+// Postgres使用 RETURNING (xmax = 0) 在同一条语句中得到每行的结果，并把返回的主键回写到实体；
+// 其他数据库先按DuplicatedKey查询已存在的记录再执行upsert，并发写入同一行时分类可能不准确
+// 冲突时不做修改的行报告为UpsertSkipped，而不是UpsertUpdated
+// 参数:
+//   - db: GORM数据库连接
+//   - data: 需要保存的数据集合，必须是切片或数组类型
+//   - options: 可选的配置选项，与BatchSave一致，DuplicatedKey需要有唯一索引
+//
+// 返回:
+//   - map[string]UpsertAction: 以DuplicatedKey的值用下划线连接为键，值为对该行的操作
+//   - error: 操作过程中发生的错误，如果成功则返回nil
+func UpsertWithActions(db *gorm.DB, data any, options ...BatchSaveOption) (map[string]UpsertAction, error) {
+	tool, err := newBatchSave(db, data, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make(map[string]UpsertAction, len(tool.Entities))
+	if len(tool.Entities) == 0 {
+		return actions, nil
+	}
+
+	upsert := func(tx *gorm.DB) error {
+		for _, batch := range tool.chunk(tool.BatchSize) {
+			var err error
+			if tx.Dialector.Name() == "postgres" {
+				err = tool.upsertReturning(tx, batch, actions)
+			} else {
+				err = tool.upsertPrechecked(tx, batch, actions)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if tool.Transaction {
+		err = db.Transaction(upsert)
+	} else {
+		err = upsert(db)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// upsertPrechecked 先查询已存在的记录完成分类，再执行upsert
+// 参数:
+//   - tx: GORM数据库连接或事务
+//   - entities: 本批实体
+//   - actions: 写入分类结果
+//
+// 返回:
+//   - error: 操作过程中发生的错误，如果成功则返回nil
+func (b *batchSave) upsertPrechecked(tx *gorm.DB, entities []any, actions map[string]UpsertAction) error {
+	existMap, err := b.findExistingEntities(tx, entities)
+	if err != nil {
+		return err
+	}
+
+	modelInstance := reflect.New(b.ModelSchema.ModelType).Interface()
+	err = tx.Model(modelInstance).Select(b.CreateSelect).Clauses(b.onConflict()).Create(b.typedEntities(entities)).Error
+	if err != nil {
+		return err
+	}
+
+	existingAction := UpsertUpdated
+	if b.conflictDoesNothing() {
+		existingAction = UpsertSkipped
+	}
+	for _, entity := range entities {
+		key := b.entityKey(entity)
+		if _, exists := existMap[key]; exists {
+			actions[key] = existingAction
+		} else {
+			actions[key] = UpsertInserted
+		}
+	}
+	return nil
+}
+
+// upsertReturning 使用Postgres的RETURNING在一条语句中完成upsert和分类，并回写主键
+// 新插入的行xmax为0，被ON CONFLICT更新的行xmax为当前事务ID；冲突但未被更新的行不会出现在RETURNING中
+// 参数:
+//   - tx: GORM数据库连接或事务
+//   - entities: 本批实体
+//   - actions: 写入分类结果
+//
+// 返回:
+//   - error: 操作过程中发生的错误，如果成功则返回nil
+func (b *batchSave) upsertReturning(tx *gorm.DB, entities []any, actions map[string]UpsertAction) error {
+	returning := clause.Returning{Columns: []clause.Column{{Name: "(xmax = 0) AS gkit_inserted", Raw: true}}}
+	columns := append([]string{}, b.DuplicatedKey...)
+	for _, field := range b.ModelSchema.PrimaryFields {
+		columns = append(columns, field.DBName)
+	}
+	for _, column := range slice.Unique(columns) {
+		returning.Columns = append(returning.Columns, clause.Column{Name: column})
+	}
+
+	byKey := make(map[string]any, len(entities))
+	for _, entity := range entities {
+		byKey[b.entityKey(entity)] = entity
+	}
+
+	// 只生成SQL，RETURNING的结果由下面的Raw读取
+	modelInstance := reflect.New(b.ModelSchema.ModelType).Interface()
+	stmt := tx.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true}).Model(modelInstance).Select(b.CreateSelect).
+		Clauses(b.onConflict(), returning).Create(b.typedEntities(entities)).Statement
+	if stmt.Error != nil {
+		return stmt.Error
+	}
+
+	rows, err := tx.Raw(stmt.SQL.String(), stmt.Vars...).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := make(map[string]any)
+		if err := tx.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		action := UpsertUpdated
+		if inserted, _ := row["gkit_inserted"].(bool); inserted {
+			action = UpsertInserted
+		}
+		key := generateKey(row, b.DuplicatedKey)
+		actions[key] = action
+
+		entity, ok := byKey[key]
+		if !ok {
+			continue
+		}
+		delete(byKey, key)
+		entityValue := reflect.ValueOf(entity).Elem()
+		for _, field := range b.ModelSchema.PrimaryFields {
+			if value, ok := row[field.DBName]; ok && value != nil {
+				if err := field.Set(tx.Statement.Context, entityValue, value); err != nil {
+					return fmt.Errorf("回写主键 %s 失败: %w", field.Name, err)
+				}
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 没有返回的行发生了冲突但没有被修改
+	for key := range byKey {
+		actions[key] = UpsertSkipped
+	}
+	return nil
+}
+
+// conflictDoesNothing 冲突时是否不修改已存在的行
+func (b *batchSave) conflictDoesNothing() bool {
+	onConflict := b.onConflict()
+	return onConflict.DoNothing || (len(onConflict.DoUpdates) == 0 && !onConflict.UpdateAll)
+}
+
+// onConflict 构建冲突时更新的子句，不更新定位字段、主键和创建时间；指定了WithOnConflict时直接使用
+func (b *batchSave) onConflict() clause.OnConflict {
+	if b.OnConflict != nil {
+		return *b.OnConflict
+	}
+	skip := append([]string{}, b.DuplicatedKey...)
+	for _, field := range b.ModelSchema.Fields {
+		if field.PrimaryKey || field.AutoCreateTime > 0 {
+			skip = append(skip, field.DBName)
+		}
+	}
+
+	columns := make([]clause.Column, 0, len(b.DuplicatedKey))
+	for _, key := range b.DuplicatedKey {
+		columns = append(columns, clause.Column{Name: key})
+	}
+	return clause.OnConflict{
+		Columns:   columns,
+		DoUpdates: clause.AssignmentColumns(slice.Difference(b.UpdateSelect, skip)),
+	}
+}
+
+// entityKey 按DuplicatedKey生成实体的唯一标识，与generateKey的结果一致
+func (b *batchSave) entityKey(entity any) string {
+	keyValues := make(map[string]any, len(b.DuplicatedKey))
+	for _, key := range b.DuplicatedKey {
+		val, _ := getFieldValue(entity, b.ModelSchema, key)
+		keyValues[key] = val
+	}
+	return generateKey(keyValues, b.DuplicatedKey)
+}
+
+// typedEntities 将实体转换为模型指针切片，便于GORM批量创建
+func (b *batchSave) typedEntities(entities []any) any {
+	sliceValue := reflect.MakeSlice(reflect.SliceOf(reflect.PointerTo(b.ModelSchema.ModelType)), 0, len(entities))
+	for _, entity := range entities {
+		sliceValue = reflect.Append(sliceValue, reflect.ValueOf(entity))
+	}
+	return sliceValue.Interface()
+}
@@ -0,0 +1,148 @@
+package gkit_gorm_test
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type upsertItem struct {
+	ID   uint
+	Code string
+	Name string
+}
+
+// mockPostgresReturning 返回支持RETURNING的postgres方言连接
+func mockPostgresReturning(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := mockPostgres(t)
+	db.Callback().Create().Clauses = append(db.Callback().Create().Clauses, "RETURNING")
+	return db, mock
+}
+
+func TestUpsertWithActions(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `code` FROM `upsert_items` WHERE code IN \\(\\?,\\?\\)").WithArgs("a", "b").
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("a"))
+	mock.ExpectExec("INSERT INTO `upsert_items` .* ON DUPLICATE KEY UPDATE `name`=VALUES\\(`name`\\)$").
+		WillReturnResult(sqlmock.NewResult(2, 3))
+	mock.ExpectCommit()
+
+	items := []upsertItem{{Code: "a", Name: "x"}, {Code: "b", Name: "y"}}
+	actions, err := gkit_gorm.UpsertWithActions(db, items, gkit_gorm.WithDuplicatedKey("code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions["a"] != gkit_gorm.UpsertUpdated || actions["b"] != gkit_gorm.UpsertInserted {
+		t.Fatalf("分类不正确: %v", actions)
+	}
+}
+
+func TestUpsertWithActionsDoNothing(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `code` FROM `upsert_items` WHERE code IN \\(\\?,\\?\\)").WithArgs("a", "b").
+		WillReturnRows(sqlmock.NewRows([]string{"code"}).AddRow("a"))
+	mock.ExpectExec("INSERT INTO `upsert_items` .* ON DUPLICATE KEY UPDATE").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	items := []upsertItem{{Code: "a", Name: "x"}, {Code: "b", Name: "y"}}
+	actions, err := gkit_gorm.UpsertWithActions(db, items, gkit_gorm.WithDuplicatedKey("code"),
+		gkit_gorm.WithOnConflict(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions["a"] != gkit_gorm.UpsertSkipped || actions["b"] != gkit_gorm.UpsertInserted {
+		t.Fatalf("DoNothing时已存在的行应为skipped: %v", actions)
+	}
+}
+
+func TestUpsertWithActionsReturning(t *testing.T) {
+	db, mock := mockPostgresReturning(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO `upsert_items` .* RETURNING \\(xmax = 0\\) AS gkit_inserted,`code`,`id`$").
+		WillReturnRows(sqlmock.NewRows([]string{"gkit_inserted", "code", "id"}).AddRow(false, "a", 3).AddRow(true, "b", 8))
+	mock.ExpectCommit()
+
+	items := []upsertItem{{Code: "a", Name: "x"}, {Code: "b", Name: "y"}}
+	actions, err := gkit_gorm.UpsertWithActions(db, items, gkit_gorm.WithDuplicatedKey("code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions["a"] != gkit_gorm.UpsertUpdated || actions["b"] != gkit_gorm.UpsertInserted {
+		t.Fatalf("分类不正确: %v", actions)
+	}
+	// 插入和更新的行都回写数据库中的主键
+	if items[0].ID != 3 || items[1].ID != 8 {
+		t.Fatalf("主键未回写: %+v", items)
+	}
+}
+
+func TestUpsertWithActionsReturningDoNothing(t *testing.T) {
+	db, mock := mockPostgresReturning(t)
+	mock.ExpectBegin()
+	// DO NOTHING冲突的行不出现在RETURNING中
+	mock.ExpectQuery("INSERT INTO `upsert_items` .* RETURNING").
+		WillReturnRows(sqlmock.NewRows([]string{"gkit_inserted", "code", "id"}).AddRow(true, "b", 8))
+	mock.ExpectCommit()
+
+	items := []upsertItem{{Code: "a", Name: "x"}, {Code: "b", Name: "y"}}
+	actions, err := gkit_gorm.UpsertWithActions(db, items, gkit_gorm.WithDuplicatedKey("code"),
+		gkit_gorm.WithOnConflict(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions["a"] != gkit_gorm.UpsertSkipped || actions["b"] != gkit_gorm.UpsertInserted {
+		t.Fatalf("分类不正确: %v", actions)
+	}
+	if actions["a"].String() != "skipped" {
+		t.Fatalf("名称不正确: %s", actions["a"])
+	}
+	if items[0].ID != 0 || items[1].ID != 8 {
+		t.Fatalf("主键回写不正确: %+v", items)
+	}
+}
+
+type upsertDayStat struct {
+	ID    int64
+	Day   string `gorm:"uniqueIndex"`
+	Count int
+}
+
+func TestUpsertWithActionsExpressionUpdate(t *testing.T) {
+	db, mock := mockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `day` FROM `upsert_day_stats` WHERE day IN \\(\\?\\)").WithArgs("d1").
+		WillReturnRows(sqlmock.NewRows([]string{"day"}).AddRow("d1"))
+	mock.ExpectExec("INSERT INTO `upsert_day_stats` \\(`day`,`count`\\) VALUES \\(\\?,\\?\\) "+
+		"ON DUPLICATE KEY UPDATE `count`=count \\+ VALUES\\(count\\)$").
+		WithArgs("d1", 3).WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	onConflict := gkit_gorm.WithOnConflict(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]any{"count": gorm.Expr("count + VALUES(count)")}),
+	})
+	actions, err := gkit_gorm.UpsertWithActions(db, []upsertDayStat{{Day: "d1", Count: 3}}, gkit_gorm.WithDuplicatedKey("day"), onConflict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actions["d1"] != gkit_gorm.UpsertUpdated {
+		t.Fatalf("表达式更新的行应为updated: %v", actions)
+	}
+}
+
+func TestWithOnConflictRejectedOutsideUpsert(t *testing.T) {
+	db, _ := mockDB(t)
+	onConflict := gkit_gorm.WithOnConflict(clause.OnConflict{DoNothing: true})
+	if err := gkit_gorm.BatchSave(db, []upsertDayStat{{Day: "d1"}}, onConflict); err == nil {
+		t.Error("BatchSave应拒绝WithOnConflict")
+	}
+	if err := gkit_gorm.BatchUpdateValues(db, []upsertDayStat{{Day: "d1"}}, "day", []string{"count"}, onConflict); err == nil {
+		t.Error("BatchUpdateValues应拒绝WithOnConflict")
+	}
+}
@@ -0,0 +1,94 @@
+package gkit_gorm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// columnNamePattern 合法的字段名
+var columnNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateModels 在启动时校验模型定义，尽早发现gorm标签配置错误
+// 检查主键是否存在、字段名是否合法且不重复、同名索引的声明是否一致
+// 参数:
+//   - db: GORM数据库连接，使用其命名策略解析模型
+//   - models: 需要校验的模型，例如 &User{}
+//
+// 返回:
+//   - error: 所有模型的全部问题合并成的错误，全部通过时返回nil
+func ValidateModels(db *gorm.DB, models ...any) error {
+	var errs []error
+	for _, model := range models {
+		s, err := ParseSchema(db, model)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("解析模型 %T 失败: %w", model, err))
+			continue
+		}
+		errs = append(errs, validateSchema(s)...)
+	}
+	return errors.Join(errs...)
+}
+
+// validateSchema 校验单个模型
+// 参数:
+//   - s: 模型的Schema信息
+//
+// 返回:
+//   - []error: 发现的问题，没有问题时为空
+func validateSchema(s *schema.Schema) []error {
+	var errs []error
+
+	// 1.主键
+	if len(s.PrimaryFields) == 0 {
+		errs = append(errs, fmt.Errorf("模型 %s 没有主键，BatchSave等工具需要通过主键或DuplicatedKey定位记录", s.Name))
+	}
+
+	// 2.字段名
+	columns := make(map[string]string, len(s.Fields))
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		if !columnNamePattern.MatchString(field.DBName) {
+			errs = append(errs, fmt.Errorf("模型 %s 的字段 %s 的列名 %q 不合法", s.Name, field.Name, field.DBName))
+		}
+		if other, ok := columns[field.DBName]; ok {
+			errs = append(errs, fmt.Errorf("模型 %s 的字段 %s 和 %s 映射到同一列 %s", s.Name, other, field.Name, field.DBName))
+		}
+		columns[field.DBName] = field.Name
+	}
+
+	// 3.索引：同名索引的字段都必须映射到列，且唯一性声明一致
+	for _, index := range s.ParseIndexes() {
+		for _, option := range index.Fields {
+			if option.Field == nil || option.DBName == "" {
+				errs = append(errs, fmt.Errorf("模型 %s 的索引 %s 包含不映射到列的字段", s.Name, index.Name))
+			}
+		}
+	}
+	unique := make(map[string]bool)
+	for _, field := range s.Fields {
+		for key, value := range field.TagSettings {
+			if key != "INDEX" && key != "UNIQUEINDEX" {
+				continue
+			}
+			name := strings.TrimSpace(strings.Split(value, ",")[0])
+			if name == "" || strings.Contains(name, ":") {
+				continue
+			}
+			upper := strings.ToUpper(value)
+			isUnique := key == "UNIQUEINDEX" || strings.Contains(upper, "CLASS:UNIQUE") || strings.Contains(upper, ",UNIQUE")
+			if prev, ok := unique[name]; ok && prev != isUnique {
+				errs = append(errs, fmt.Errorf("模型 %s 的索引 %s 在不同字段上的唯一性声明不一致", s.Name, name))
+			}
+			unique[name] = isUnique
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,64 @@
+package gkit_gorm_test
+
+import (
+	"strings"
+	"testing"
+
+	gkit_gorm "github.com/shaco-go/gkit-layout/pkg/gorm"
+)
+
+type validModel struct {
+	ID   uint
+	Code string `gorm:"uniqueIndex"`
+}
+
+type noPrimaryKeyModel struct {
+	Name string
+}
+
+type badColumnModel struct {
+	ID   uint
+	Name string `gorm:"column:bad-name"`
+}
+
+type inconsistentIndexModel struct {
+	ID   uint
+	Name string `gorm:"index:idx_name_code"`
+	Code string `gorm:"uniqueIndex:idx_name_code"`
+}
+
+func TestValidateModels(t *testing.T) {
+	db, _ := mockDB(t)
+	if err := gkit_gorm.ValidateModels(db, &validModel{}); err != nil {
+		t.Fatalf("合法的模型不应报错: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		model any
+		want  string
+	}{
+		{"no primary key", &noPrimaryKeyModel{}, "模型 noPrimaryKeyModel 没有主键"},
+		{"bad column", &badColumnModel{}, `列名 "bad-name" 不合法`},
+		{"inconsistent index", &inconsistentIndexModel{}, "索引 idx_name_code 在不同字段上的唯一性声明不一致"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := gkit_gorm.ValidateModels(db, tt.model)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("错误应包含%q，实际%v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateModelsJoinsErrors(t *testing.T) {
+	db, _ := mockDB(t)
+	err := gkit_gorm.ValidateModels(db, &noPrimaryKeyModel{}, &validModel{}, &badColumnModel{})
+	if err == nil {
+		t.Fatal("应返回错误")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "noPrimaryKeyModel") || !strings.Contains(msg, "bad-name") {
+		t.Fatalf("应合并所有模型的问题: %v", err)
+	}
+}
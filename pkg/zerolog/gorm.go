@@ -34,8 +34,22 @@ func ZeroToGormLevel(level zerolog.Level) gormLogger.LogLevel {
 	return gormLogger.Silent
 }
 
+// GormLoggerOption gorm日志的可选配置
+type GormLoggerOption func(*customGormLogger)
+
+// WithSlowQueryHook 慢查询时调用hook，返回的结果以slow_query_detail字段记录，例如EXPLAIN执行计划
+// hook内部执行的SQL不会再次触发hook
+func WithSlowQueryHook(hook func(ctx context.Context, sql string) (any, error)) GormLoggerOption {
+	return func(l *customGormLogger) {
+		l.slowQueryHook = hook
+	}
+}
+
+// slowQueryHookKey 标记当前ctx处于慢查询钩子中，防止递归
+type slowQueryHookKey struct{}
+
 // NewGormLogger initialize logger
-func NewGormLogger(z zerolog.Logger, config gormLogger.Config) gormLogger.Interface {
+func NewGormLogger(z zerolog.Logger, config gormLogger.Config, opts ...GormLoggerOption) gormLogger.Interface {
 	var (
 		infoStr      = "%s"
 		warnStr      = "%s"
@@ -54,7 +68,7 @@ func NewGormLogger(z zerolog.Logger, config gormLogger.Config) gormLogger.Interf
 		traceErrStr = gormLogger.RedBold + "%s " + gormLogger.MagentaBold + "%s\n" + gormLogger.Reset + gormLogger.Yellow + "[%.3fms] " + gormLogger.BlueBold + "[rows:%v]" + gormLogger.Reset + " %s"
 	}
 
-	return &customGormLogger{
+	l := &customGormLogger{
 		z:            z,
 		Config:       config,
 		infoStr:      infoStr,
@@ -64,6 +78,10 @@ func NewGormLogger(z zerolog.Logger, config gormLogger.Config) gormLogger.Interf
 		traceWarnStr: traceWarnStr,
 		traceErrStr:  traceErrStr,
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
 type customGormLogger struct {
@@ -71,6 +89,7 @@ type customGormLogger struct {
 	infoStr, warnStr, errStr            string
 	traceStr, traceErrStr, traceWarnStr string
 	z                                   zerolog.Logger
+	slowQueryHook                       func(ctx context.Context, sql string) (any, error)
 }
 
 // LogMode log mode
@@ -115,10 +134,19 @@ func (l *customGormLogger) Trace(ctx context.Context, begin time.Time, fc func()
 	case elapsed > l.SlowThreshold && l.SlowThreshold != 0 && l.LogLevel >= gormLogger.Warn:
 		sql, rows := fc()
 		slowLog := fmt.Sprintf("SLOW SQL >= %v", l.SlowThreshold)
+		event := l.z.Warn().Ctx(ctx)
+		if l.slowQueryHook != nil && ctx.Value(slowQueryHookKey{}) == nil {
+			detail, hookErr := l.slowQueryHook(context.WithValue(ctx, slowQueryHookKey{}, true), sql)
+			if hookErr != nil {
+				event = event.AnErr("slow_query_hook_error", hookErr)
+			} else if detail != nil {
+				event = event.Interface("slow_query_detail", detail)
+			}
+		}
 		if rows == -1 {
-			l.z.Warn().Ctx(ctx).Msgf(l.traceWarnStr, utils.FileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, "-", sql)
+			event.Msgf(l.traceWarnStr, utils.FileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, "-", sql)
 		} else {
-			l.z.Warn().Ctx(ctx).Msgf(l.traceWarnStr, utils.FileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, rows, sql)
+			event.Msgf(l.traceWarnStr, utils.FileWithLineNum(), slowLog, float64(elapsed.Nanoseconds())/1e6, rows, sql)
 		}
 	case l.LogLevel == gormLogger.Info:
 		sql, rows := fc()
@@ -0,0 +1,52 @@
+package gkit_zerolog
+
+import (
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+)
+
+// GoOption 定义了Go的函数式选项类型
+type GoOption func(*goOptions)
+
+// goOptions Go的配置
+type goOptions struct {
+	rePanic bool // 记录日志后是否重新panic
+}
+
+// WithRePanic 记录panic日志后重新panic，让进程按原有方式退出
+//
+// 返回:
+//   - GoOption: 返回一个可应用于Go的选项函数
+func WithRePanic() GoOption {
+	return func(o *goOptions) {
+		o.rePanic = true
+	}
+}
+
+// Go 在新的协程中执行fn，fn发生panic时以错误级别记录panic值和调用栈
+// 参数:
+//   - z: 记录panic的日志实例
+//   - fn: 需要在协程中执行的函数
+//   - opts: 可选的配置选项
+func Go(z zerolog.Logger, fn func(), opts ...GoOption) {
+	o := &goOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				z.Error().
+					Interface("panic", r).
+					Str("stack", string(debug.Stack())).
+					Msg("goroutine panic recovered")
+				if o.rePanic {
+					panic(r)
+				}
+			}
+		}()
+		fn()
+	}()
+}
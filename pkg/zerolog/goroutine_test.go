@@ -0,0 +1,59 @@
+package gkit_zerolog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// chanWriter 将每条日志发送到通道，便于等待协程中写入的日志
+type chanWriter chan []byte
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+func TestGoRecoversPanic(t *testing.T) {
+	out := make(chanWriter, 1)
+	Go(zerolog.New(out), func() {
+		panic("boom")
+	})
+
+	var line []byte
+	select {
+	case line = <-out:
+	case <-time.After(time.Second):
+		t.Fatal("未记录panic日志")
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(line, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event["level"] != "error" || event["panic"] != "boom" {
+		t.Fatalf("日志不正确: %s", line)
+	}
+	stack, _ := event["stack"].(string)
+	if !strings.Contains(stack, "TestGoRecoversPanic") {
+		t.Fatalf("调用栈应包含panic所在的函数: %s", stack)
+	}
+}
+
+func TestGoWithoutPanic(t *testing.T) {
+	out := make(chanWriter, 1)
+	done := make(chan struct{})
+	Go(zerolog.New(out), func() {
+		close(done)
+	})
+
+	<-done
+	select {
+	case line := <-out:
+		t.Fatalf("没有panic时不应记录日志: %s", line)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -76,6 +76,8 @@ type LogConfig struct {
 	MaxAge int
 	// Compress 是否压缩
 	Compress bool
+	// StaticFields 附加到每条日志的静态字段，例如service、version、env
+	StaticFields map[string]any
 }
 
 // DefaultLogConfig 返回默认日志配置
@@ -109,6 +111,17 @@ func NewProdLogConfig() *LogConfig {
 	return config
 }
 
+// WithStaticFields 设置附加到每条日志的静态字段，返回配置本身便于链式调用
+func (c *LogConfig) WithStaticFields(fields map[string]any) *LogConfig {
+	if c.StaticFields == nil {
+		c.StaticFields = make(map[string]any, len(fields))
+	}
+	for k, v := range fields {
+		c.StaticFields[k] = v
+	}
+	return c
+}
+
 // MarshalStack implements pkg/errors stack trace marshaling.
 func marshalStack(err error) interface{} {
 	type stackTracer interface {
@@ -161,7 +174,11 @@ func New(config *LogConfig) zerolog.Logger {
 		}
 	}
 
-	return zerolog.New(zerolog.MultiLevelWriter(output...))
+	logger := zerolog.New(zerolog.MultiLevelWriter(output...))
+	if len(config.StaticFields) > 0 {
+		logger = logger.With().Fields(config.StaticFields).Logger()
+	}
+	return logger
 }
 
 // createConsoleOutput 创建控制台输出
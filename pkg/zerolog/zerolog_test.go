@@ -0,0 +1,54 @@
+package gkit_zerolog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+func TestNewStaticFields(t *testing.T) {
+	config := DefaultLogConfig()
+	config.Level = zerolog.TraceLevel
+	config.Channel = []ChannelType{FileChannel}
+	config.HumanReadable = false
+	config.LogDir = t.TempDir()
+	config.Compress = false
+	config.WithStaticFields(map[string]any{"service": "api", "version": "1.2.3"})
+	defer zerolog.SetGlobalLevel(zerolog.TraceLevel)
+
+	z := New(config)
+	z.Info().Msg("app event")
+	gl := NewGormLogger(z, gormLogger.Config{LogLevel: gormLogger.Info})
+	gl.Trace(context.Background(), time.Now(), func() (string, int64) {
+		return "SELECT 1", 1
+	}, nil)
+
+	f, err := os.Open(filepath.Join(config.LogDir, config.LogFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		if event["service"] != "api" || event["version"] != "1.2.3" {
+			t.Errorf("日志缺少静态字段: %s", scanner.Text())
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Fatalf("期望2条日志，实际%d条", lines)
+	}
+}